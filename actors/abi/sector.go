@@ -207,6 +207,24 @@ func (p RegisteredSealProof) RegisteredWindowPoStProof() (RegisteredPoStProof, e
 	}
 }
 
+// RegisteredUpdateProof produces the replica update proof type corresponding to the receiving seal proof.
+func (p RegisteredSealProof) RegisteredUpdateProof() (RegisteredUpdateProof, error) {
+	switch p {
+	case RegisteredSealProof_StackedDrg64GiBV1:
+		return RegisteredUpdateProof_StackedDrg64GiBV1, nil
+	case RegisteredSealProof_StackedDrg32GiBV1:
+		return RegisteredUpdateProof_StackedDrg32GiBV1, nil
+	case RegisteredSealProof_StackedDrg2KiBV1:
+		return RegisteredUpdateProof_StackedDrg2KiBV1, nil
+	case RegisteredSealProof_StackedDrg8MiBV1:
+		return RegisteredUpdateProof_StackedDrg8MiBV1, nil
+	case RegisteredSealProof_StackedDrg512MiBV1:
+		return RegisteredUpdateProof_StackedDrg512MiBV1, nil
+	default:
+		return 0, errors.Errorf("unsupported mapping from %+v to update-specific RegisteredProof", p)
+	}
+}
+
 // SectorMaximumLifetime is the maximum duration a sector sealed with this proof may exist between activation and expiration
 func (p RegisteredSealProof) SectorMaximumLifetime() ChainEpoch {
 	// For all Stacked DRG sectors, the max is 5 years
@@ -236,6 +254,60 @@ type SealVerifyInfo struct {
 	UnsealedCID cid.Cid `checked:"true"` // CommD
 }
 
+// This ordering, defines mappings to UInt in a way which MUST never change.
+type RegisteredAggregationProof int64
+
+const (
+	RegisteredAggregationProof_SnarkPackV1 = RegisteredAggregationProof(0)
+)
+
+// Information needed to verify one of the seal proofs combined into an aggregate proof.
+// The miner and seal proof type are common to all the seals in the aggregate.
+type AggregateSealVerifyInfo struct {
+	Number                SectorNumber
+	Randomness            SealRandomness
+	InteractiveRandomness InteractiveSealRandomness
+
+	// Safe because we get those from the miner actor
+	SealedCID   cid.Cid `checked:"true"` // CommR
+	UnsealedCID cid.Cid `checked:"true"` // CommD
+}
+
+// Information needed to verify an aggregate proof of the seals of many sectors belonging to a single miner.
+type AggregateSealVerifyProofAndInfos struct {
+	Miner          ActorID
+	SealProof      RegisteredSealProof
+	AggregateProof RegisteredAggregationProof
+	Proof          []byte
+	Infos          []AggregateSealVerifyInfo
+}
+
+///
+/// Replica updates
+///
+
+// This ordering, defines mappings to UInt in a way which MUST never change.
+type RegisteredUpdateProof RegisteredProof
+
+const (
+	RegisteredUpdateProof_StackedDrg2KiBV1   = RegisteredUpdateProof(0)
+	RegisteredUpdateProof_StackedDrg8MiBV1   = RegisteredUpdateProof(1)
+	RegisteredUpdateProof_StackedDrg512MiBV1 = RegisteredUpdateProof(2)
+	RegisteredUpdateProof_StackedDrg32GiBV1  = RegisteredUpdateProof(3)
+	RegisteredUpdateProof_StackedDrg64GiBV1  = RegisteredUpdateProof(4)
+)
+
+// Information needed to verify a proof that a sector's replica has been updated to encode new data.
+type ReplicaUpdateInfo struct {
+	UpdateProofType RegisteredUpdateProof
+	Proof           []byte
+
+	// Safe because we get those from the miner actor
+	OldSealedSectorCID   cid.Cid `checked:"true"` // CommR of the replica being updated
+	NewSealedSectorCID   cid.Cid `checked:"true"` // CommR of the updated replica
+	NewUnsealedSectorCID cid.Cid `checked:"true"` // CommD of the new data
+}
+
 ///
 /// PoSting
 ///
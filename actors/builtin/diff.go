@@ -0,0 +1,532 @@
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Whether a value was added, removed or modified.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+// A single difference between two versions of some state.
+type Change struct {
+	// Location of the changed value, e.g. "Sectors[17].Expiration".
+	Path string
+	Kind ChangeKind
+	// The previous value, nil if added.
+	From interface{}
+	// The new value, nil if removed.
+	To interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %s", c.Path, formatValue(reflect.ValueOf(c.To)))
+	case Removed:
+		return fmt.Sprintf("%s removed: %s", c.Path, formatValue(reflect.ValueOf(c.From)))
+	default:
+		return fmt.Sprintf("%s changed %s → %s", c.Path, formatValue(reflect.ValueOf(c.From)), formatValue(reflect.ValueOf(c.To)))
+	}
+}
+
+// Formats a value for display, rendering bitfields as their set bits and pointers as the values they point to.
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	switch v.Type() {
+	case bigIntType:
+		i := v.Interface().(big.Int)
+		if i.Nil() {
+			return "nil"
+		}
+		return i.String()
+	case bitFieldType:
+		bf := v.Interface().(bitfield.BitField)
+		return fmt.Sprint(bitFieldBits(&bf))
+	case cidType, addressType:
+		return fmt.Sprint(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return formatValue(v.Elem())
+	case reflect.Struct:
+		var buf bytes.Buffer
+		buf.WriteString("{")
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if buf.Len() > 1 {
+				buf.WriteString(" ")
+			}
+			fmt.Fprintf(&buf, "%s:%s", field.Name, formatValue(v.Field(i)))
+		}
+		buf.WriteString("}")
+		return buf.String()
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("%x", v.Interface())
+		}
+		var buf bytes.Buffer
+		buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			buf.WriteString(formatValue(v.Index(i)))
+		}
+		buf.WriteString("]")
+		return buf.String()
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// Accumulates the changes between two versions of a state.
+// Changes are recorded at a path, which is shared with accumulators derived with WithField and WithKey.
+type DiffAccumulator struct {
+	// Accumulated changes.
+	// This is a pointer to support derived accumulators accumulating to the same underlying collection.
+	changes *[]Change
+	// Path to the value being compared.
+	path string
+}
+
+// Returns a new accumulator backed by the same collection, for changes within a named field.
+func (d *DiffAccumulator) WithField(name string) *DiffAccumulator {
+	d.initialize()
+	path := name
+	if d.path != "" {
+		path = d.path + "." + name
+	}
+	return &DiffAccumulator{changes: d.changes, path: path}
+}
+
+// Returns a new accumulator backed by the same collection, for changes within a collection entry.
+func (d *DiffAccumulator) WithKey(key interface{}) *DiffAccumulator {
+	d.initialize()
+	return &DiffAccumulator{changes: d.changes, path: fmt.Sprintf("%s[%v]", d.path, key)}
+}
+
+func (d *DiffAccumulator) IsEmpty() bool {
+	return d.changes == nil || len(*d.changes) == 0
+}
+
+func (d *DiffAccumulator) Changes() []Change {
+	if d.changes == nil {
+		return nil
+	}
+	return *d.changes
+}
+
+// Returns a description of each change.
+func (d *DiffAccumulator) Strings() []string {
+	var out []string
+	for _, c := range d.Changes() {
+		out = append(out, c.String())
+	}
+	return out
+}
+
+// Records that a value was added at the accumulator's path.
+func (d *DiffAccumulator) Added(to interface{}) {
+	d.add(Added, nil, to)
+}
+
+// Records that a value was removed from the accumulator's path.
+func (d *DiffAccumulator) Removed(from interface{}) {
+	d.add(Removed, from, nil)
+}
+
+// Adds the changes from another accumulator to this one, with paths relative to this one's.
+func (d *DiffAccumulator) AddAll(other *DiffAccumulator) {
+	for _, c := range other.Changes() {
+		path := c.Path
+		if d.path != "" && path != "" {
+			path = d.path + "." + path
+		} else if d.path != "" {
+			path = d.path
+		}
+		d.initialize()
+		*d.changes = append(*d.changes, Change{Path: path, Kind: c.Kind, From: c.From, To: c.To})
+	}
+}
+
+// Compares two values of the same type, recording a change for each differing leaf value.
+// Structs are compared field by field, pointers by the values they point to, and slices and arrays
+// element by element.
+// Big integers, CIDs, addresses, bitfields, byte slices and all other values are compared as leaves.
+// A nil from or to value records an addition or removal.
+func (d *DiffAccumulator) Compare(from, to interface{}) {
+	switch {
+	case from == nil && to == nil:
+	case from == nil:
+		d.Added(to)
+	case to == nil:
+		d.Removed(from)
+	default:
+		d.compareValues(reflect.ValueOf(from), reflect.ValueOf(to), nil)
+	}
+}
+
+// Compares two structs of the same type field by field, like Compare, but ignoring the named fields.
+// This is used for state objects whose collection fields are compared separately.
+func (d *DiffAccumulator) CompareExcept(from, to interface{}, skip ...string) {
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	d.compareValues(reflect.Indirect(reflect.ValueOf(from)), reflect.Indirect(reflect.ValueOf(to)), skipped)
+}
+
+// Compares two HAMTs, recording entries that were added or removed, and the changes within entries
+// that were modified. Unchanged subtrees are not loaded. An undefined root is treated as an empty map.
+// The key decoder renders keys for the change path, and newValue returns an empty value for decoding entries.
+func (d *DiffAccumulator) Map(store adt.Store, from, to cid.Cid, decodeKey KeyDecoder, newValue func() runtime.CBORUnmarshaler) error {
+	return d.diffMaps(store, from, to, decodeKey, func(key interface{}, from, to *cbg.Deferred) error {
+		entry := d.WithKey(key)
+		f, t, err := decodeValues(from, to, newValue)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s", entry.path)
+		}
+		entry.Compare(f, t)
+		return nil
+	})
+}
+
+// Compares two HAMT-backed sets, recording keys that were added or removed.
+func (d *DiffAccumulator) Set(store adt.Store, from, to cid.Cid, decodeKey KeyDecoder) error {
+	return d.diffMaps(store, from, to, decodeKey, func(key interface{}, from, to *cbg.Deferred) error {
+		if from == nil {
+			d.Added(key)
+		} else if to == nil {
+			d.Removed(key)
+		}
+		return nil
+	})
+}
+
+// Compares two HAMTs whose values are the roots of nested collections (e.g. a multimap).
+// The nested collections of each differing entry are compared with fn, which is passed an undefined root
+// for an entry that is absent.
+func (d *DiffAccumulator) MapOfRoots(store adt.Store, from, to cid.Cid, decodeKey KeyDecoder, fn func(entry *DiffAccumulator, from, to cid.Cid) error) error {
+	return d.diffMaps(store, from, to, decodeKey, func(key interface{}, from, to *cbg.Deferred) error {
+		fromRoot, err := decodeRoot(from)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s[%v]", d.path, key)
+		}
+		toRoot, err := decodeRoot(to)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s[%v]", d.path, key)
+		}
+		return fn(d.WithKey(key), fromRoot, toRoot)
+	})
+}
+
+// Compares two AMTs, like Map.
+func (d *DiffAccumulator) Array(store adt.Store, from, to cid.Cid, newValue func() runtime.CBORUnmarshaler) error {
+	return d.ArrayFunc(store, from, to, newValue, func(entry *DiffAccumulator, from, to interface{}) error {
+		entry.Compare(from, to)
+		return nil
+	})
+}
+
+// Compares two AMTs, calling fn with the decoded values of each differing entry.
+// The value passed for an absent entry is nil.
+// This supports values that themselves hold the roots of collections.
+func (d *DiffAccumulator) ArrayFunc(store adt.Store, from, to cid.Cid, newValue func() runtime.CBORUnmarshaler,
+	fn func(entry *DiffAccumulator, from, to interface{}) error) error {
+	if from.Equals(to) {
+		return nil
+	}
+	fromArr, err := loadArray(store, from)
+	if err != nil {
+		return err
+	}
+	toArr, err := loadArray(store, to)
+	if err != nil {
+		return err
+	}
+	return adt.DiffArrays(fromArr, toArr, &arrayDiffer{func(idx uint64, from, to *cbg.Deferred) error {
+		entry := d.WithKey(idx)
+		f, t, err := decodeValues(from, to, newValue)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s", entry.path)
+		}
+		return fn(entry, f, t)
+	}})
+}
+
+// Compares two objects stored by CID, recording the changes between the decoded objects.
+func (d *DiffAccumulator) Object(store adt.Store, from, to cid.Cid, fromObj, toObj runtime.CBORUnmarshaler) error {
+	if from.Equals(to) {
+		return nil
+	}
+	if err := store.Get(store.Context(), from, fromObj); err != nil {
+		return errors.Wrapf(err, "failed to load %s at %v", d.path, from)
+	}
+	if err := store.Get(store.Context(), to, toObj); err != nil {
+		return errors.Wrapf(err, "failed to load %s at %v", d.path, to)
+	}
+	d.Compare(fromObj, toObj)
+	return nil
+}
+
+// Decodes a map key for display in a change path.
+type KeyDecoder func(key string) (interface{}, error)
+
+func AddrKeyDecoder(key string) (interface{}, error) {
+	return addr.NewFromBytes([]byte(key))
+}
+
+func UIntKeyDecoder(key string) (interface{}, error) {
+	return adt.ParseUIntKey(key)
+}
+
+func IntKeyDecoder(key string) (interface{}, error) {
+	return adt.ParseIntKey(key)
+}
+
+func CidKeyDecoder(key string) (interface{}, error) {
+	_, c, err := cid.CidFromBytes([]byte(key))
+	return c, err
+}
+
+func (d *DiffAccumulator) add(kind ChangeKind, from, to interface{}) {
+	d.initialize()
+	*d.changes = append(*d.changes, Change{Path: d.path, Kind: kind, From: from, To: to})
+}
+
+func (d *DiffAccumulator) initialize() {
+	if d.changes == nil {
+		d.changes = &[]Change{}
+	}
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bitFieldType = reflect.TypeOf(bitfield.BitField{})
+	cidType      = reflect.TypeOf(cid.Cid{})
+	addressType  = reflect.TypeOf(addr.Address{})
+)
+
+func (d *DiffAccumulator) compareValues(from, to reflect.Value, skip map[string]bool) {
+	if from.Type() != to.Type() {
+		d.add(Modified, from.Interface(), to.Interface())
+		return
+	}
+	switch from.Type() {
+	case bigIntType:
+		f, t := from.Interface().(big.Int), to.Interface().(big.Int)
+		if f.Nil() != t.Nil() || (!f.Nil() && !f.Equals(t)) {
+			d.add(Modified, f, t)
+		}
+		return
+	case bitFieldType:
+		f, t := from.Interface().(bitfield.BitField), to.Interface().(bitfield.BitField)
+		fromBits, toBits := bitFieldBits(&f), bitFieldBits(&t)
+		if !reflect.DeepEqual(fromBits, toBits) {
+			d.add(Modified, fromBits, toBits)
+		}
+		return
+	case cidType, addressType:
+		if from.Interface() != to.Interface() {
+			d.add(Modified, from.Interface(), to.Interface())
+		}
+		return
+	}
+
+	switch from.Kind() {
+	case reflect.Ptr:
+		switch {
+		case from.IsNil() && to.IsNil():
+		case from.IsNil():
+			d.Added(to.Elem().Interface())
+		case to.IsNil():
+			d.Removed(from.Elem().Interface())
+		default:
+			d.compareValues(from.Elem(), to.Elem(), skip)
+		}
+	case reflect.Slice, reflect.Array:
+		if from.Type().Elem().Kind() == reflect.Uint8 {
+			if !reflect.DeepEqual(from.Interface(), to.Interface()) {
+				d.add(Modified, from.Interface(), to.Interface())
+			}
+			return
+		}
+		for i := 0; i < from.Len() || i < to.Len(); i++ {
+			switch {
+			case i >= to.Len():
+				d.WithKey(i).Removed(elemInterface(from.Index(i)))
+			case i >= from.Len():
+				d.WithKey(i).Added(elemInterface(to.Index(i)))
+			default:
+				d.WithKey(i).compareValues(from.Index(i), to.Index(i), nil)
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < from.NumField(); i++ {
+			field := from.Type().Field(i)
+			if field.PkgPath != "" || skip[field.Name] {
+				continue // Unexported or skipped.
+			}
+			d.WithField(field.Name).compareValues(from.Field(i), to.Field(i), nil)
+		}
+	default:
+		if !reflect.DeepEqual(from.Interface(), to.Interface()) {
+			d.add(Modified, from.Interface(), to.Interface())
+		}
+	}
+}
+
+// Returns the value of an element, dereferencing pointers so that they are rendered by value.
+func elemInterface(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+// Renders a bitfield as the list of its set bits, or its encoding if it cannot be expanded.
+func bitFieldBits(bf *bitfield.BitField) interface{} {
+	count, err := bf.Count()
+	if err == nil {
+		if bits, err := bf.All(count); err == nil {
+			return bits
+		}
+	}
+	var buf bytes.Buffer
+	_ = bf.MarshalCBOR(&buf)
+	return buf.Bytes()
+}
+
+func (d *DiffAccumulator) diffMaps(store adt.Store, from, to cid.Cid, decodeKey KeyDecoder, diffEntry func(key interface{}, from, to *cbg.Deferred) error) error {
+	if from.Equals(to) {
+		return nil
+	}
+	fromMap, err := loadMap(store, from)
+	if err != nil {
+		return err
+	}
+	toMap, err := loadMap(store, to)
+	if err != nil {
+		return err
+	}
+	return adt.DiffMaps(fromMap, toMap, &mapDiffer{func(key string, from, to *cbg.Deferred) error {
+		k, err := decodeKey(key)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode key in %s", d.path)
+		}
+		return diffEntry(k, from, to)
+	}})
+}
+
+// Decodes two encoded values, either of which may be absent.
+func decodeValues(from, to *cbg.Deferred, newValue func() runtime.CBORUnmarshaler) (f, t interface{}, err error) {
+	if from != nil {
+		if f, err = decodeValue(from, newValue); err != nil {
+			return nil, nil, err
+		}
+	}
+	if to != nil {
+		if t, err = decodeValue(to, newValue); err != nil {
+			return nil, nil, err
+		}
+	}
+	return f, t, nil
+}
+
+func loadMap(store adt.Store, root cid.Cid) (*adt.Map, error) {
+	if !root.Defined() {
+		return adt.MakeEmptyMap(store), nil
+	}
+	return adt.AsMap(store, root)
+}
+
+func loadArray(store adt.Store, root cid.Cid) (*adt.Array, error) {
+	if !root.Defined() {
+		return adt.MakeEmptyArray(store), nil
+	}
+	return adt.AsArray(store, root)
+}
+
+// Adapts a function of (possibly absent) values to the adt.MapDiffer interface.
+type mapDiffer struct {
+	diffEntry func(key string, from, to *cbg.Deferred) error
+}
+
+func (m *mapDiffer) Add(key string, val *cbg.Deferred) error {
+	return m.diffEntry(key, nil, val)
+}
+
+func (m *mapDiffer) Modify(key string, from, to *cbg.Deferred) error {
+	return m.diffEntry(key, from, to)
+}
+
+func (m *mapDiffer) Remove(key string, val *cbg.Deferred) error {
+	return m.diffEntry(key, val, nil)
+}
+
+// Adapts a function of (possibly absent) values to the adt.ArrayDiffer interface.
+type arrayDiffer struct {
+	diffEntry func(idx uint64, from, to *cbg.Deferred) error
+}
+
+func (a *arrayDiffer) Add(idx uint64, val *cbg.Deferred) error {
+	return a.diffEntry(idx, nil, val)
+}
+
+func (a *arrayDiffer) Modify(idx uint64, from, to *cbg.Deferred) error {
+	return a.diffEntry(idx, from, to)
+}
+
+func (a *arrayDiffer) Remove(idx uint64, val *cbg.Deferred) error {
+	return a.diffEntry(idx, val, nil)
+}
+
+// Decodes a value into a new object, returning the object itself rather than a pointer to it.
+func decodeValue(val *cbg.Deferred, newValue func() runtime.CBORUnmarshaler) (interface{}, error) {
+	v := newValue()
+	if err := v.UnmarshalCBOR(bytes.NewReader(val.Raw)); err != nil {
+		return nil, err
+	}
+	return reflect.Indirect(reflect.ValueOf(v)).Interface(), nil
+}
+
+// Decodes a CID value, returning an undefined CID for an absent value.
+func decodeRoot(val *cbg.Deferred) (cid.Cid, error) {
+	if val == nil {
+		return cid.Undef, nil
+	}
+	var c cbg.CborCid
+	if err := c.UnmarshalCBOR(bytes.NewReader(val.Raw)); err != nil {
+		return cid.Undef, err
+	}
+	return cid.Cid(c), nil
+}
@@ -0,0 +1,199 @@
+package exported
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	goruntime "runtime"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// MethodMeta describes an exported method of a built-in actor: its number, name, and parameter and return types.
+type MethodMeta struct {
+	Num  abi.MethodNum
+	Name string
+	// The method value, as exported by the actor, or nil for the implicit Send method.
+	Method interface{}
+	// Types of the method's parameter and return values, e.g. *miner.SectorPreCommitInfo.
+	// These are pointer types except for a few values such as addresses. Methods taking or returning nothing
+	// use *adt.EmptyValue.
+	Params reflect.Type
+	Return reflect.Type
+}
+
+var emptyValueType = reflect.TypeOf(adt.Empty)
+
+// The implicit method 0, which transfers value and invokes no code, on every actor.
+var sendMethod = MethodMeta{
+	Num:    builtin.MethodSend,
+	Name:   "Send",
+	Params: emptyValueType,
+	Return: emptyValueType,
+}
+
+// Registry of built-in actor methods, indexed by code CID and method number.
+var methodRegistry = buildMethodRegistry()
+
+func buildMethodRegistry() map[cid.Cid][]MethodMeta {
+	registry := make(map[cid.Cid][]MethodMeta)
+	for _, act := range BuiltinActors() {
+		exports := act.Exports()
+		methods := make([]MethodMeta, len(exports))
+		methods[builtin.MethodSend] = sendMethod
+		for i, m := range exports {
+			if i == int(builtin.MethodSend) || m == nil {
+				continue
+			}
+			typ := reflect.TypeOf(m)
+			methods[i] = MethodMeta{
+				Num:    abi.MethodNum(i),
+				Name:   methodName(m),
+				Method: m,
+				Params: typ.In(1),
+				Return: typ.Out(0),
+			}
+		}
+		registry[act.Code()] = methods
+	}
+	return registry
+}
+
+// Extracts a method's name from its function value, e.g. "PreCommitSector" from
+// "github.com/filecoin-project/specs-actors/actors/builtin/miner.Actor.PreCommitSector-fm".
+func methodName(m interface{}) string {
+	fullName := goruntime.FuncForPC(reflect.ValueOf(m).Pointer()).Name()
+	fullName = strings.TrimSuffix(fullName, "-fm")
+	return fullName[strings.LastIndex(fullName, ".")+1:]
+}
+
+// Methods returns the methods of a built-in actor type, indexed by method number.
+// Entries for unassigned method numbers are zero-valued. Returns nil if the code is not a built-in actor.
+func Methods(code cid.Cid) []MethodMeta {
+	methods, ok := methodRegistry[code]
+	if !ok {
+		return nil
+	}
+	out := make([]MethodMeta, len(methods))
+	copy(out, methods)
+	return out
+}
+
+// LookupMethod returns the method of a built-in actor with a method number.
+func LookupMethod(code cid.Cid, num abi.MethodNum) (MethodMeta, bool) {
+	methods, ok := methodRegistry[code]
+	if !ok || uint64(num) >= uint64(len(methods)) || methods[num].Params == nil {
+		return MethodMeta{}, false
+	}
+	return methods[num], true
+}
+
+// LookupMethodByName returns the method of a built-in actor with a name.
+func LookupMethodByName(code cid.Cid, name string) (MethodMeta, bool) {
+	for _, m := range methodRegistry[code] {
+		if m.Params != nil && m.Name == name {
+			return m, true
+		}
+	}
+	return MethodMeta{}, false
+}
+
+// DecodeParams decodes the serialized parameters for a built-in actor method into a new value of the method's
+// parameter type.
+func DecodeParams(code cid.Cid, num abi.MethodNum, raw []byte) (interface{}, error) {
+	m, ok := LookupMethod(code, num)
+	if !ok {
+		return nil, xerrors.Errorf("no method %d on actor %s", num, builtin.ActorNameByCode(code))
+	}
+	return m.DecodeParams(raw)
+}
+
+// DecodeReturn decodes the serialized return value from a built-in actor method into a new value of the method's
+// return type.
+func DecodeReturn(code cid.Cid, num abi.MethodNum, raw []byte) (interface{}, error) {
+	m, ok := LookupMethod(code, num)
+	if !ok {
+		return nil, xerrors.Errorf("no method %d on actor %s", num, builtin.ActorNameByCode(code))
+	}
+	return m.DecodeReturn(raw)
+}
+
+// String returns the method's qualified name, e.g. "PreCommitSector (6)".
+func (m MethodMeta) String() string {
+	return fmt.Sprintf("%s (%d)", m.Name, m.Num)
+}
+
+// DecodeParams decodes serialized parameters into a new value of the method's parameter type.
+func (m MethodMeta) DecodeParams(raw []byte) (interface{}, error) {
+	return decodeAs(m.Params, raw)
+}
+
+// DecodeReturn decodes a serialized return value into a new value of the method's return type.
+func (m MethodMeta) DecodeReturn(raw []byte) (interface{}, error) {
+	return decodeAs(m.Return, raw)
+}
+
+// EncodeParams serializes parameters for the method, which must be of the method's parameter type.
+func (m MethodMeta) EncodeParams(params interface{}) ([]byte, error) {
+	return encodeAs(m.Params, params)
+}
+
+// EncodeReturn serializes a return value for the method, which must be of the method's return type.
+func (m MethodMeta) EncodeReturn(ret interface{}) ([]byte, error) {
+	return encodeAs(m.Return, ret)
+}
+
+func decodeAs(typ reflect.Type, raw []byte) (interface{}, error) {
+	if typ == emptyValueType {
+		if len(raw) != 0 {
+			return nil, xerrors.Errorf("expected no value, got %d bytes", len(raw))
+		}
+		return adt.Empty, nil
+	}
+	var v reflect.Value
+	if typ.Kind() == reflect.Ptr {
+		v = reflect.New(typ.Elem())
+	} else {
+		v = reflect.New(typ)
+	}
+	um, ok := v.Interface().(runtime.CBORUnmarshaler)
+	if !ok {
+		return nil, xerrors.Errorf("type %v is not CBOR unmarshalable", typ)
+	}
+	r := bytes.NewReader(raw)
+	if err := um.UnmarshalCBOR(r); err != nil {
+		return nil, xerrors.Errorf("failed to decode %v: %w", typ, err)
+	}
+	if r.Len() != 0 {
+		return nil, xerrors.Errorf("%d trailing bytes after decoding %v", r.Len(), typ)
+	}
+	if typ.Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+func encodeAs(typ reflect.Type, v interface{}) ([]byte, error) {
+	if v == nil && typ == emptyValueType {
+		return []byte{}, nil
+	}
+	if reflect.TypeOf(v) != typ {
+		return nil, xerrors.Errorf("expected value of type %v, got %T", typ, v)
+	}
+	m, ok := v.(runtime.CBORMarshaler)
+	if !ok {
+		return nil, xerrors.Errorf("type %v is not CBOR marshalable", typ)
+	}
+	buf := bytes.Buffer{}
+	if err := m.MarshalCBOR(&buf); err != nil {
+		return nil, xerrors.Errorf("failed to encode %v: %w", typ, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,156 @@
+package exported_test
+
+import (
+	"reflect"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/exported"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+)
+
+func TestMethodRegistry(t *testing.T) {
+	methodNumbers := map[cid.Cid]interface{}{
+		builtin.AccountActorCodeID:          builtin.MethodsAccount,
+		builtin.InitActorCodeID:             builtin.MethodsInit,
+		builtin.CronActorCodeID:             builtin.MethodsCron,
+		builtin.RewardActorCodeID:           builtin.MethodsReward,
+		builtin.MultisigActorCodeID:         builtin.MethodsMultisig,
+		builtin.PaymentChannelActorCodeID:   builtin.MethodsPaych,
+		builtin.StorageMarketActorCodeID:    builtin.MethodsMarket,
+		builtin.StoragePowerActorCodeID:     builtin.MethodsPower,
+		builtin.StorageMinerActorCodeID:     builtin.MethodsMiner,
+		builtin.VerifiedRegistryActorCodeID: builtin.MethodsVerifiedRegistry,
+	}
+
+	t.Run("names and numbers match method tables", func(t *testing.T) {
+		for code, table := range methodNumbers { //nolint:nomaprange
+			tv := reflect.ValueOf(table)
+			for i := 0; i < tv.NumField(); i++ {
+				name := tv.Type().Field(i).Name
+				num := tv.Field(i).Interface().(abi.MethodNum)
+
+				m, ok := exported.LookupMethod(code, num)
+				require.True(t, ok, "%s method %d", builtin.ActorNameByCode(code), num)
+				assert.Equal(t, name, m.Name, "%s method %d", builtin.ActorNameByCode(code), num)
+
+				byName, ok := exported.LookupMethodByName(code, name)
+				require.True(t, ok)
+				assert.Equal(t, num, byName.Num)
+			}
+			assert.Len(t, exported.Methods(code), tv.NumField()+1, builtin.ActorNameByCode(code))
+		}
+	})
+
+	t.Run("all types are serializable", func(t *testing.T) {
+		marshaler := reflect.TypeOf((*runtime.CBORMarshaler)(nil)).Elem()
+		unmarshaler := reflect.TypeOf((*runtime.CBORUnmarshaler)(nil)).Elem()
+		for _, act := range exported.BuiltinActors() {
+			methods := exported.Methods(act.Code())
+			require.NotEmpty(t, methods)
+			assert.Equal(t, "Send", methods[builtin.MethodSend].Name)
+			for _, m := range methods[1:] {
+				if m.Params == nil {
+					continue
+				}
+				for _, typ := range []reflect.Type{m.Params, m.Return} {
+					assert.True(t, typ.Implements(marshaler), "%s %v: %v", builtin.ActorNameByCode(act.Code()), m, typ)
+					if typ.Kind() != reflect.Ptr {
+						typ = reflect.PtrTo(typ)
+					}
+					assert.True(t, typ.Implements(unmarshaler), "%s %v: %v", builtin.ActorNameByCode(act.Code()), m, typ)
+				}
+			}
+		}
+	})
+
+	t.Run("round trip params", func(t *testing.T) {
+		params := &miner.SectorPreCommitInfo{
+			SealProof:     abi.RegisteredSealProof_StackedDrg32GiBV1,
+			SectorNumber:  100,
+			SealedCID:     tutil.MakeCID("sealed", &miner.SealedCIDPrefix),
+			SealRandEpoch: 10,
+			DealIDs:       []abi.DealID{1, 2},
+			Expiration:    1000,
+		}
+		m, ok := exported.LookupMethod(builtin.StorageMinerActorCodeID, builtin.MethodsMiner.PreCommitSector)
+		require.True(t, ok)
+		assert.Equal(t, "PreCommitSector (6)", m.String())
+		raw, err := m.EncodeParams(params)
+		require.NoError(t, err)
+
+		decoded, err := exported.DecodeParams(builtin.StorageMinerActorCodeID, builtin.MethodsMiner.PreCommitSector, raw)
+		require.NoError(t, err)
+		assert.Equal(t, params, decoded)
+
+		_, err = m.EncodeParams(&miner.ProveCommitSectorParams{})
+		assert.EqualError(t, err, "expected value of type *miner.SectorPreCommitInfo, got *miner.ProveCommitSectorParams")
+		_, err = m.DecodeParams(append(raw, 0))
+		assert.Error(t, err)
+	})
+
+	t.Run("round trip return", func(t *testing.T) {
+		m, ok := exported.LookupMethodByName(builtin.RewardActorCodeID, "ThisEpochReward")
+		require.True(t, ok)
+		ret := &reward.ThisEpochRewardReturn{
+			ThisEpochReward:        big.NewInt(100),
+			ThisEpochBaselinePower: big.NewInt(200),
+		}
+		raw, err := m.EncodeReturn(ret)
+		require.NoError(t, err)
+		decoded, err := exported.DecodeReturn(builtin.RewardActorCodeID, m.Num, raw)
+		require.NoError(t, err)
+		assert.Equal(t, ret, decoded)
+	})
+
+	t.Run("empty values", func(t *testing.T) {
+		m, ok := exported.LookupMethod(builtin.CronActorCodeID, builtin.MethodsCron.EpochTick)
+		require.True(t, ok)
+		raw, err := m.EncodeParams(nil)
+		require.NoError(t, err)
+		assert.Empty(t, raw)
+		decoded, err := m.DecodeParams(raw)
+		require.NoError(t, err)
+		assert.Equal(t, adt.Empty, decoded)
+		_, err = m.DecodeParams([]byte{0x80})
+		assert.EqualError(t, err, "expected no value, got 1 bytes")
+
+		pubkey, ok := exported.LookupMethod(builtin.AccountActorCodeID, builtin.MethodsAccount.PubkeyAddress)
+		require.True(t, ok)
+		a := tutil.NewBLSAddr(t, 1)
+		raw, err = pubkey.EncodeReturn(a)
+		require.NoError(t, err)
+		decoded, err = pubkey.DecodeReturn(raw)
+		require.NoError(t, err)
+		assert.Equal(t, a, decoded)
+
+		send, ok := exported.LookupMethod(builtin.AccountActorCodeID, builtin.MethodSend)
+		require.True(t, ok)
+		assert.Nil(t, send.Method)
+		_, err = send.DecodeParams(nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown methods", func(t *testing.T) {
+		_, ok := exported.LookupMethod(builtin.CronActorCodeID, 3)
+		assert.False(t, ok)
+		_, ok = exported.LookupMethod(builtin.SystemActorCodeID, 2)
+		assert.False(t, ok)
+		_, ok = exported.LookupMethod(tutil.MakeCID("unknown", nil), builtin.MethodSend)
+		assert.False(t, ok)
+		_, ok = exported.LookupMethodByName(builtin.CronActorCodeID, "Bogus")
+		assert.False(t, ok)
+		_, err := exported.DecodeParams(builtin.CronActorCodeID, 3, nil)
+		assert.EqualError(t, err, "no method 3 on actor fil/1/cron")
+	})
+}
@@ -0,0 +1,19 @@
+package init
+
+import (
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one init state to another.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "AddressMap")
+	err := d.WithField("AddressMap").Map(store, pre.AddressMap, post.AddressMap, builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler {
+		return new(cbg.CborInt)
+	})
+	return d, err
+}
@@ -2,6 +2,7 @@ package init_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	cid "github.com/ipfs/go-cid"
@@ -50,6 +51,7 @@ func TestExec(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrForbidden, func() {
 			actor.execAndVerify(rt, cid.Undef, []byte{})
 		})
+		actor.checkState(rt)
 	})
 
 	var fakeParams = runtime.CBORBytes([]byte{'D', 'E', 'A', 'D', 'B', 'E', 'E', 'F'})
@@ -106,6 +108,7 @@ func TestExec(t *testing.T) {
 		actualIdAddr2, err := st2.ResolveAddress(adt.AsStore(rt), uniqueAddr2)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedIdAddr2, actualIdAddr2)
+		actor.checkState(rt)
 	})
 
 	t.Run("happy path exec create storage miner", func(t *testing.T) {
@@ -141,6 +144,7 @@ func TestExec(t *testing.T) {
 		actualUnknownAddr, err := st.ResolveAddress(adt.AsStore(rt), expUnknowAddr)
 		assert.Error(t, err)
 		assert.Equal(t, addr.Undef, actualUnknownAddr)
+		actor.checkState(rt)
 	})
 
 	t.Run("happy path create multisig actor", func(t *testing.T) {
@@ -164,6 +168,7 @@ func TestExec(t *testing.T) {
 		execRet := actor.execAndVerify(rt, builtin.MultisigActorCodeID, fakeParams)
 		assert.Equal(t, uniqueAddr, execRet.RobustAddress)
 		assert.Equal(t, expectedIdAddr, execRet.IDAddress)
+		actor.checkState(rt)
 	})
 
 	t.Run("sending to constructor failure", func(t *testing.T) {
@@ -196,7 +201,7 @@ func TestExec(t *testing.T) {
 		noResoAddr, err := st.ResolveAddress(adt.AsStore(rt), uniqueAddr)
 		assert.Error(t, err)
 		assert.Equal(t, addr.Undef, noResoAddr)
-
+		actor.checkState(rt)
 	})
 
 }
@@ -221,6 +226,13 @@ func (h *initHarness) constructAndVerify(rt *mock.Runtime) {
 	assert.Equal(h.t, "mock", st.NetworkName)
 }
 
+func (h *initHarness) checkState(rt *mock.Runtime) {
+	var st init_.State
+	rt.GetState(&st)
+	_, msgs := init_.CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func (h *initHarness) execAndVerify(rt *mock.Runtime, codeID cid.Cid, constructorParams []byte) *init_.ExecReturn {
 	rt.ExpectValidateCallerAny()
 	ret := rt.Call(h.Exec, &init_.ExecParams{
@@ -0,0 +1,55 @@
+package init
+
+import (
+	addr "github.com/filecoin-project/go-address"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type StateSummary struct {
+	AddrIDs map[addr.Address]abi.ActorID
+	NextID  abi.ActorID
+}
+
+// Checks internal invariants of init state.
+func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	acc.Require(len(st.NetworkName) > 0, "network name is empty")
+	acc.Require(st.NextID >= builtin.FirstNonSingletonActorId, "next id %d is too low", st.NextID)
+
+	addrIDs := make(map[addr.Address]abi.ActorID)
+	reverse := make(map[abi.ActorID]addr.Address)
+	if addrMap, err := adt.AsMap(store, st.AddressMap); err != nil {
+		acc.Addf("error loading address map: %v", err)
+	} else {
+		var value cbg.CborInt
+		err = addrMap.ForEach(&value, func(key string) error {
+			actorID := abi.ActorID(value)
+			keyAddr, err := addr.NewFromBytes([]byte(key))
+			if err != nil {
+				return err
+			}
+
+			acc.Require(keyAddr.Protocol() != addr.ID, "key %v is an ID address", keyAddr)
+			acc.Require(actorID >= builtin.FirstNonSingletonActorId, "unexpected singleton ID value %v", actorID)
+			acc.Require(actorID < st.NextID, "ID value %d for key %v is not less than next ID %d", actorID, keyAddr, st.NextID)
+
+			if foundAddr, found := reverse[actorID]; found {
+				acc.Addf("duplicate mapping to ID %v: %v, %v", actorID, keyAddr, foundAddr)
+			}
+			reverse[actorID] = keyAddr
+			addrIDs[keyAddr] = actorID
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating address map")
+	}
+
+	return &StateSummary{
+		AddrIDs: addrIDs,
+		NextID:  st.NextID,
+	}, acc
+}
@@ -0,0 +1,47 @@
+package market
+
+import (
+	cid "github.com/ipfs/go-cid"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one market state to another.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "Proposals", "States", "PendingProposals", "EscrowTable", "LockedTable", "DealOpsByEpoch")
+
+	if err := d.WithField("Proposals").Array(store, pre.Proposals, post.Proposals, newDealProposal); err != nil {
+		return d, err
+	}
+	if err := d.WithField("States").Array(store, pre.States, post.States, func() runtime.CBORUnmarshaler {
+		return new(DealState)
+	}); err != nil {
+		return d, err
+	}
+	if err := d.WithField("PendingProposals").Map(store, pre.PendingProposals, post.PendingProposals, builtin.CidKeyDecoder, newDealProposal); err != nil {
+		return d, err
+	}
+	if err := d.WithField("EscrowTable").Map(store, pre.EscrowTable, post.EscrowTable, builtin.AddrKeyDecoder, newTokenAmount); err != nil {
+		return d, err
+	}
+	if err := d.WithField("LockedTable").Map(store, pre.LockedTable, post.LockedTable, builtin.AddrKeyDecoder, newTokenAmount); err != nil {
+		return d, err
+	}
+	err := d.WithField("DealOpsByEpoch").MapOfRoots(store, pre.DealOpsByEpoch, post.DealOpsByEpoch, builtin.UIntKeyDecoder,
+		func(entry *builtin.DiffAccumulator, from, to cid.Cid) error {
+			return entry.Set(store, from, to, builtin.UIntKeyDecoder)
+		})
+	return d, err
+}
+
+func newDealProposal() runtime.CBORUnmarshaler {
+	return new(DealProposal)
+}
+
+func newTokenAmount() runtime.CBORUnmarshaler {
+	return new(abi.TokenAmount)
+}
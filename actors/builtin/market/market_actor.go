@@ -259,19 +259,27 @@ func (a Actor) PublishStorageDeals(rt Runtime, params *PublishStorageDealsParams
 }
 
 type VerifyDealsForActivationParams struct {
-	DealIDs      []abi.DealID
+	Sectors []SectorDeals
+}
+
+type SectorDeals struct {
 	SectorExpiry abi.ChainEpoch
-	SectorStart  abi.ChainEpoch
+	DealIDs      []abi.DealID
 }
 
 type VerifyDealsForActivationReturn struct {
+	Sectors []SectorWeights
+}
+
+type SectorWeights struct {
 	DealWeight         abi.DealWeight
 	VerifiedDealWeight abi.DealWeight
 }
 
-// Verify that a given set of storage deals is valid for a sector currently being PreCommitted
-// and return DealWeight of the set of storage deals given.
+// Verify that the given sets of storage deals are valid for sectors currently being PreCommitted
+// and return the DealWeight of each set of storage deals given, in order.
 // The weight is defined as the sum, over all deals in the set, of the product of deal size and duration.
+// A deal may appear at most once across all the sectors.
 func (A Actor) VerifyDealsForActivation(rt Runtime, params *VerifyDealsForActivationParams) *VerifyDealsForActivationReturn {
 	rt.ValidateImmediateCallerType(builtin.StorageMinerActorCodeID)
 	minerAddr := rt.Message().Caller()
@@ -280,13 +288,26 @@ func (A Actor) VerifyDealsForActivation(rt Runtime, params *VerifyDealsForActiva
 	rt.State().Readonly(&st)
 	store := adt.AsStore(rt)
 
-	dealWeight, verifiedWeight, err := ValidateDealsForActivation(&st, store, params.DealIDs, minerAddr, params.SectorExpiry, params.SectorStart)
-	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to validate dealProposals for activation")
+	seen := make(map[abi.DealID]struct{})
+	weights := make([]SectorWeights, len(params.Sectors))
+	for i, sector := range params.Sectors {
+		for _, dealID := range sector.DealIDs {
+			if _, ok := seen[dealID]; ok {
+				rt.Abortf(exitcode.ErrIllegalArgument, "deal %d present multiple times", dealID)
+			}
+			seen[dealID] = struct{}{}
+		}
+
+		dealWeight, verifiedWeight, err := ValidateDealsForActivation(&st, store, sector.DealIDs, minerAddr, sector.SectorExpiry, rt.CurrEpoch())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to validate dealProposals for activation")
 
-	return &VerifyDealsForActivationReturn{
-		DealWeight:         dealWeight,
-		VerifiedDealWeight: verifiedWeight,
+		weights[i] = SectorWeights{
+			DealWeight:         dealWeight,
+			VerifiedDealWeight: verifiedWeight,
+		}
 	}
+
+	return &VerifyDealsForActivationReturn{Sectors: weights}
 }
 
 type ActivateDealsParams struct {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/filecoin-project/go-address"
@@ -468,6 +469,7 @@ func TestPublishStorageDeals(t *testing.T) {
 		prop := actor.getDealProposal(rt, dealId)
 		require.EqualValues(t, clientResolved, prop.Client)
 		require.EqualValues(t, providerResolved, prop.Provider)
+		actor.checkState(rt)
 	})
 
 	t.Run("publish a deal after activating a previous deal which has a start epoch far in the future", func(t *testing.T) {
@@ -491,6 +493,7 @@ func TestPublishStorageDeals(t *testing.T) {
 		rt.SetEpoch(newEpoch)
 		deal2ID := actor.publishDeals(rt, mAddr, deal2)[0]
 		actor.activateDeals(rt, endEpoch+1, provider, newEpoch, deal2ID)
+		actor.checkState(rt)
 	})
 
 	t.Run("publish multiple deals for different clients and ensure balances are correct", func(t *testing.T) {
@@ -582,6 +585,7 @@ func TestPublishStorageDeals(t *testing.T) {
 		require.EqualValues(t, big.Add(providerLocked, provider2Locked), st.TotalProviderLockedCollateral)
 		totalStorageFee = big.Add(totalStorageFee, big.Add(deal6.TotalStorageFee(), deal7.TotalStorageFee()))
 		require.EqualValues(t, totalStorageFee, st.TotalClientStorageFee)
+		actor.checkState(rt)
 	})
 }
 
@@ -879,6 +883,7 @@ func TestPublishStorageDealsFailures(t *testing.T) {
 		})
 
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -921,6 +926,7 @@ func TestActivateDeals(t *testing.T) {
 		// provider1 activates deal3
 		actor.activateDeals(rt, sectorExpiry, provider, currentEpoch, dealId3)
 		actor.assertDealsNotActivated(rt, currentEpoch, dealId4)
+		actor.checkState(rt)
 	})
 }
 
@@ -1112,6 +1118,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		// provider2 terminates deal4
 		actor.terminateDeals(rt, provider2, dealId4)
 		actor.assertDealsTerminated(rt, currentEpoch, dealId4)
+		actor.checkState(rt)
 	})
 
 	t.Run("ignore deal proposal that does not exist", func(t *testing.T) {
@@ -1125,6 +1132,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		actor.terminateDeals(rt, provider, dealId1, abi.DealID(42))
 		st := actor.getDealState(rt, dealId1)
 		require.EqualValues(t, currentEpoch, st.SlashEpoch)
+		actor.checkState(rt)
 	})
 
 	t.Run("terminate valid deals along with expired deals - only valid deals are terminated", func(t *testing.T) {
@@ -1145,7 +1153,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		actor.terminateDeals(rt, provider, dealId1, dealId2, dealId3)
 		actor.assertDealsTerminated(rt, newEpoch, dealId1, dealId2)
 		actor.assertDeaslNotTerminated(rt, dealId3)
-
+		actor.checkState(rt)
 	})
 
 	t.Run("terminating a deal the second time does not change it's slash epoch", func(t *testing.T) {
@@ -1164,6 +1172,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		actor.terminateDeals(rt, provider, dealId1)
 		st := actor.getDealState(rt, dealId1)
 		require.EqualValues(t, currentEpoch, st.SlashEpoch)
+		actor.checkState(rt)
 	})
 
 	t.Run("terminating new deals and an already terminated deal only terminates the new deals", func(t *testing.T) {
@@ -1192,6 +1201,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 
 		st3 := actor.getDealState(rt, dealId3)
 		require.EqualValues(t, newEpoch, st3.SlashEpoch)
+		actor.checkState(rt)
 	})
 
 	t.Run("do not terminate deal if end epoch is equal to or less than current epoch", func(t *testing.T) {
@@ -1212,6 +1222,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		rt.SetEpoch(endEpoch + 1)
 		actor.terminateDeals(rt, provider, dealId2)
 		actor.assertDeaslNotTerminated(rt, dealId2)
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when caller is not a StorageMinerActor", func(t *testing.T) {
@@ -1223,6 +1234,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		})
 
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when caller is not the provider of the deal", func(t *testing.T) {
@@ -1242,6 +1254,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		})
 
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when deal has been published but not activated", func(t *testing.T) {
@@ -1258,6 +1271,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 		})
 
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("termination of all deals should fail when one deal fails", func(t *testing.T) {
@@ -1280,6 +1294,7 @@ func TestOnMinerSectorsTerminate(t *testing.T) {
 
 		// verify deal1 has not been terminated
 		actor.assertDeaslNotTerminated(rt, dealId1)
+		actor.checkState(rt)
 	})
 }
 
@@ -1342,6 +1357,7 @@ func TestCronTick(t *testing.T) {
 		// deal proposal and state should NOT be deleted
 		require.NotNil(t, actor.getDealProposal(rt, dealId))
 		require.NotNil(t, actor.getDealState(rt, dealId))
+		actor.checkState(rt)
 	})
 
 	t.Run("slash a deal and make payment for another deal in the same epoch", func(t *testing.T) {
@@ -1366,6 +1382,7 @@ func TestCronTick(t *testing.T) {
 		actor.assertDealDeleted(rt, dealId1, d1)
 		s2 := actor.getDealState(rt, dealId2)
 		require.EqualValues(t, current, s2.LastUpdatedEpoch)
+		actor.checkState(rt)
 	})
 
 	t.Run("cannot publish the same deal twice BEFORE a cron tick", func(t *testing.T) {
@@ -1392,6 +1409,7 @@ func TestCronTick(t *testing.T) {
 		rt.SetEpoch(d1.StartEpoch)
 		actor.cronTick(rt)
 		actor.publishDeals(rt, mAddrs, d2)
+		actor.checkState(rt)
 	})
 }
 
@@ -1514,6 +1532,7 @@ func TestCronTickTimedoutDeals(t *testing.T) {
 		actor.assertAccountZero(rt, provider)
 
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	t.Run("publishing timed out deal again should work after cron tick as it should no longer be pending", func(t *testing.T) {
@@ -1541,6 +1560,7 @@ func TestCronTickTimedoutDeals(t *testing.T) {
 
 		// now publishing should work
 		actor.generateAndPublishDeal(rt, client, mAddrs, startEpoch, endEpoch)
+		actor.checkState(rt)
 	})
 
 	t.Run("timed out and verified deals are slashed, deleted AND sent to the Registry actor", func(t *testing.T) {
@@ -1587,6 +1607,7 @@ func TestCronTickTimedoutDeals(t *testing.T) {
 		actor.assertDealDeleted(rt, dealIds[0], &deal1)
 		actor.assertDealDeleted(rt, dealIds[1], &deal2)
 		actor.assertDealDeleted(rt, dealIds[2], &deal3)
+		actor.checkState(rt)
 	})
 }
 
@@ -1626,6 +1647,7 @@ func TestCronTickDealExpiry(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	t.Run("deal expiry -> regular payments till deal expires and then locked funds are unlocked", func(t *testing.T) {
@@ -1680,6 +1702,7 @@ func TestCronTickDealExpiry(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	t.Run("deal expiry -> payment for a deal if deal is already expired before a cron tick", func(t *testing.T) {
@@ -1701,6 +1724,7 @@ func TestCronTickDealExpiry(t *testing.T) {
 
 		// running cron tick again doesn't do anything
 		actor.cronTickNoChange(rt, client, provider)
+		actor.checkState(rt)
 	})
 
 	t.Run("expired deal should unlock the remaining client and provider locked balance after payment and deal should be deleted", func(t *testing.T) {
@@ -1726,6 +1750,7 @@ func TestCronTickDealExpiry(t *testing.T) {
 
 		// deal should be deleted
 		actor.assertDealDeleted(rt, dealId, deal)
+		actor.checkState(rt)
 	})
 
 	t.Run("all payments are made for a deal -> deal expires -> client withdraws collateral and client account is removed", func(t *testing.T) {
@@ -1741,6 +1766,7 @@ func TestCronTickDealExpiry(t *testing.T) {
 		// client withdraws collateral -> account should be removed as it now has zero balance
 		actor.withdrawClientBalance(rt, client, deal.ClientCollateral, deal.ClientCollateral)
 		actor.assertAccountZero(rt, client)
+		actor.checkState(rt)
 	})
 }
 
@@ -1895,6 +1921,7 @@ func TestCronTickDealSlashing(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	t.Run("deal is correctly processed twice in the same crontick and slashed", func(t *testing.T) {
@@ -1924,6 +1951,7 @@ func TestCronTickDealSlashing(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	// end-end tests for slashing
@@ -1956,6 +1984,7 @@ func TestCronTickDealSlashing(t *testing.T) {
 		actor.assertDealDeleted(rt, dealId1, d1)
 		actor.assertDealDeleted(rt, dealId2, d2)
 		actor.assertDealDeleted(rt, dealId3, d3)
+		actor.checkState(rt)
 	})
 
 	t.Run("regular payments till deal is slashed and then slashing is processed", func(t *testing.T) {
@@ -2012,6 +2041,7 @@ func TestCronTickDealSlashing(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 
 	// expired deals should NOT be slashed
@@ -2052,6 +2082,7 @@ func TestCronTickDealSlashing(t *testing.T) {
 
 		// deal should be deleted as it should have expired
 		actor.assertDealDeleted(rt, dealId, d)
+		actor.checkState(rt)
 	})
 }
 
@@ -2135,6 +2166,7 @@ func TestComputeDataCommitment(t *testing.T) {
 		require.True(t, ok)
 		require.Equal(t, c, *(*cid.Cid)(val))
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when deal proposal is absent", func(t *testing.T) {
@@ -2145,6 +2177,7 @@ func TestComputeDataCommitment(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.ComputeDataCommitment, param)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when syscall returns an error", func(t *testing.T) {
@@ -2161,6 +2194,7 @@ func TestComputeDataCommitment(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
 			rt.Call(actor.ComputeDataCommitment, param)
 		})
+		actor.checkState(rt)
 	})
 }
 
@@ -2170,7 +2204,6 @@ func TestVerifyDealsForActivation(t *testing.T) {
 	worker := tutil.NewIDAddr(t, 103)
 	client := tutil.NewIDAddr(t, 104)
 	mAddrs := &minerAddrs{owner, worker, provider}
-	sectorStart := abi.ChainEpoch(1)
 	start := abi.ChainEpoch(10)
 	end := start + 200*builtin.EpochsInDay
 	sectorExpiry := end + 200
@@ -2180,9 +2213,10 @@ func TestVerifyDealsForActivation(t *testing.T) {
 		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
 		d := actor.getDealProposal(rt, dealId)
 
-		resp := actor.verifyDealsForActivation(rt, provider, sectorStart, sectorExpiry, dealId)
+		resp := actor.verifyDealsForActivation(rt, provider, sectorExpiry, dealId)
 		require.EqualValues(t, big.Zero(), resp.VerifiedDealWeight)
 		require.EqualValues(t, market.DealWeight(d), resp.DealWeight)
+		actor.checkState(rt)
 	})
 
 	t.Run("verify deal and get deal weight for verified deal proposal", func(t *testing.T) {
@@ -2191,9 +2225,10 @@ func TestVerifyDealsForActivation(t *testing.T) {
 		deal.VerifiedDeal = true
 		dealIds := actor.publishDeals(rt, mAddrs, deal)
 
-		resp := actor.verifyDealsForActivation(rt, provider, sectorStart, sectorExpiry, dealIds...)
+		resp := actor.verifyDealsForActivation(rt, provider, sectorExpiry, dealIds...)
 		require.EqualValues(t, market.DealWeight(&deal), resp.VerifiedDealWeight)
 		require.EqualValues(t, big.Zero(), resp.DealWeight)
+		actor.checkState(rt)
 	})
 
 	t.Run("verification and weights for verified and unverified deals", func(T *testing.T) {
@@ -2210,40 +2245,83 @@ func TestVerifyDealsForActivation(t *testing.T) {
 
 		dealIds := actor.publishDeals(rt, mAddrs, vd1, vd2, d1, d2)
 
-		resp := actor.verifyDealsForActivation(rt, provider, sectorStart, sectorExpiry, dealIds...)
+		resp := actor.verifyDealsForActivation(rt, provider, sectorExpiry, dealIds...)
 
 		verifiedWeight := big.Add(market.DealWeight(&vd1), market.DealWeight(&vd2))
 		nvweight := big.Add(market.DealWeight(&d1), market.DealWeight(&d2))
 		require.EqualValues(t, verifiedWeight, resp.VerifiedDealWeight)
 		require.EqualValues(t, nvweight, resp.DealWeight)
+		actor.checkState(rt)
+	})
+
+	t.Run("verification and weights for multiple sectors", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+
+		vd := actor.generateDealAndAddFunds(rt, client, mAddrs, start, end)
+		vd.VerifiedDeal = true
+		d1 := actor.generateDealAndAddFunds(rt, client, mAddrs, start, end+1)
+		d2 := actor.generateDealAndAddFunds(rt, client, mAddrs, start, end+2)
+		dealIds := actor.publishDeals(rt, mAddrs, vd, d1, d2)
+
+		resp := actor.verifyDealsForActivationBatch(rt, provider,
+			market.SectorDeals{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealIds[0], dealIds[1]}},
+			market.SectorDeals{SectorExpiry: sectorExpiry}, // No deals.
+			market.SectorDeals{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealIds[2]}},
+		)
+
+		assert.Equal(t, market.DealWeight(&vd), resp.Sectors[0].VerifiedDealWeight)
+		assert.Equal(t, market.DealWeight(&d1), resp.Sectors[0].DealWeight)
+		assert.Equal(t, big.Zero(), resp.Sectors[1].VerifiedDealWeight)
+		assert.Equal(t, big.Zero(), resp.Sectors[1].DealWeight)
+		assert.Equal(t, big.Zero(), resp.Sectors[2].VerifiedDealWeight)
+		assert.Equal(t, market.DealWeight(&d2), resp.Sectors[2].DealWeight)
+		actor.checkState(rt)
+	})
+
+	t.Run("fail when deal is included in multiple sectors", func(t *testing.T) {
+		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
+		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{
+			{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealId}},
+			{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealId}},
+		}}
+
+		rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
+		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "present multiple times", func() {
+			rt.Call(actor.VerifyDealsForActivation, param)
+		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when caller is not a StorageMinerActor", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
 
-		param := &market.VerifyDealsForActivationParams{DealIDs: []abi.DealID{dealId}, SectorStart: sectorStart, SectorExpiry: sectorExpiry}
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealId}}}}
 		rt.SetCaller(worker, builtin.AccountActorCodeID)
 		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 		rt.ExpectAbort(exitcode.ErrForbidden, func() {
 			rt.Call(actor.VerifyDealsForActivation, param)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when deal proposal is not found", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
-		param := &market.VerifyDealsForActivationParams{DealIDs: []abi.DealID{1}, SectorStart: sectorStart, SectorExpiry: sectorExpiry}
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{1}}}}
 		rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
 		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.VerifyDealsForActivation, param)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when caller is not the provider", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
-		param := &market.VerifyDealsForActivationParams{DealIDs: []abi.DealID{dealId}, SectorStart: sectorStart, SectorExpiry: sectorExpiry}
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealId}}}}
 
 		provider2 := tutil.NewIDAddr(t, 205)
 		rt.SetCaller(provider2, builtin.StorageMinerActorCodeID)
@@ -2252,30 +2330,34 @@ func TestVerifyDealsForActivation(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.VerifyDealsForActivation, param)
 		})
+		actor.checkState(rt)
 	})
 
-	t.Run("fail when sector start epoch is greater than proposal start epoch", func(t *testing.T) {
+	t.Run("fail when current epoch is greater than proposal start epoch", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
-		param := &market.VerifyDealsForActivationParams{DealIDs: []abi.DealID{dealId}, SectorStart: start + 1, SectorExpiry: sectorExpiry}
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{SectorExpiry: sectorExpiry, DealIDs: []abi.DealID{dealId}}}}
 
+		rt.SetEpoch(start + 1)
 		rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
 		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.VerifyDealsForActivation, param)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail when deal end epoch is greater than sector expiration", func(t *testing.T) {
 		rt, actor := basicMarketSetup(t, owner, provider, worker, client)
 		dealId := actor.generateAndPublishDeal(rt, client, mAddrs, start, end)
-		param := &market.VerifyDealsForActivationParams{DealIDs: []abi.DealID{dealId}, SectorStart: start, SectorExpiry: end - 1}
+		param := &market.VerifyDealsForActivationParams{Sectors: []market.SectorDeals{{SectorExpiry: end - 1, DealIDs: []abi.DealID{dealId}}}}
 
 		rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
 		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.VerifyDealsForActivation, param)
 		})
+		actor.checkState(rt)
 	})
 }
 
@@ -2291,9 +2373,22 @@ func (h *marketActorTestHarness) constructAndVerify(rt *mock.Runtime) {
 	rt.Verify()
 }
 
+func (h *marketActorTestHarness) checkState(rt *mock.Runtime) {
+	var st market.State
+	rt.GetState(&st)
+	_, msgs := market.CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func (h *marketActorTestHarness) verifyDealsForActivation(rt *mock.Runtime, provider address.Address,
-	sectorStart, sectorExpiry abi.ChainEpoch, dealIds ...abi.DealID) *market.VerifyDealsForActivationReturn {
-	param := &market.VerifyDealsForActivationParams{DealIDs: dealIds, SectorStart: sectorStart, SectorExpiry: sectorExpiry}
+	sectorExpiry abi.ChainEpoch, dealIds ...abi.DealID) *market.SectorWeights {
+	ret := h.verifyDealsForActivationBatch(rt, provider, market.SectorDeals{SectorExpiry: sectorExpiry, DealIDs: dealIds})
+	return &ret.Sectors[0]
+}
+
+func (h *marketActorTestHarness) verifyDealsForActivationBatch(rt *mock.Runtime, provider address.Address,
+	sectors ...market.SectorDeals) *market.VerifyDealsForActivationReturn {
+	param := &market.VerifyDealsForActivationParams{Sectors: sectors}
 	rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 	rt.SetCaller(provider, builtin.StorageMinerActorCodeID)
 
@@ -2303,6 +2398,7 @@ func (h *marketActorTestHarness) verifyDealsForActivation(rt *mock.Runtime, prov
 	val, ok := ret.(*market.VerifyDealsForActivationReturn)
 	require.True(h.t, ok)
 	require.NotNil(h.t, val)
+	require.Len(h.t, val.Sectors, len(sectors))
 	return val
 }
 
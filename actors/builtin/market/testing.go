@@ -0,0 +1,269 @@
+package market
+
+import (
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type DealSummary struct {
+	Provider         addr.Address
+	Client           addr.Address
+	StartEpoch       abi.ChainEpoch
+	EndEpoch         abi.ChainEpoch
+	SectorStartEpoch abi.ChainEpoch
+	LastUpdatedEpoch abi.ChainEpoch
+	SlashEpoch       abi.ChainEpoch
+	VerifiedDeal     bool
+}
+
+type StateSummary struct {
+	Deals                map[abi.DealID]*DealSummary
+	PendingProposalCount uint64
+	DealStateCount       uint64
+	LockTableCount       uint64
+	DealOpEpochCount     uint64
+	DealOpCount          uint64
+}
+
+// Checks internal invariants of market state.
+func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	acc.Require(st.TotalClientLockedCollateral.GreaterThanEqual(big.Zero()), "negative total client locked collateral: %v", st.TotalClientLockedCollateral)
+	acc.Require(st.TotalProviderLockedCollateral.GreaterThanEqual(big.Zero()), "negative total provider locked collateral: %v", st.TotalProviderLockedCollateral)
+	acc.Require(st.TotalClientStorageFee.GreaterThanEqual(big.Zero()), "negative total client storage fee: %v", st.TotalClientStorageFee)
+
+	//
+	// Proposals
+	//
+
+	proposalCids := make(map[cid.Cid]struct{})
+	maxDealID := int64(-1)
+	proposalStats := make(map[abi.DealID]*DealSummary)
+	expectedDealOps := make(map[abi.DealID]struct{})
+	totalProposalCollateral := big.Zero()
+
+	if proposals, err := adt.AsArray(store, st.Proposals); err != nil {
+		acc.Addf("error loading proposals: %v", err)
+	} else {
+		var proposal DealProposal
+		err = proposals.ForEach(&proposal, func(dealID int64) error {
+			pcid, err := proposal.Cid()
+			if err != nil {
+				return err
+			}
+
+			// An identical proposal may be published again once the first is no longer pending.
+			proposalCids[pcid] = struct{}{}
+
+			acc.Require(proposal.StartEpoch < proposal.EndEpoch, "deal %d start epoch %d is not before end epoch %d",
+				dealID, proposal.StartEpoch, proposal.EndEpoch)
+
+			if dealID > maxDealID {
+				maxDealID = dealID
+			}
+
+			proposalStats[abi.DealID(dealID)] = &DealSummary{
+				Provider:         proposal.Provider,
+				Client:           proposal.Client,
+				StartEpoch:       proposal.StartEpoch,
+				EndEpoch:         proposal.EndEpoch,
+				SectorStartEpoch: epochUndefined,
+				LastUpdatedEpoch: epochUndefined,
+				SlashEpoch:       epochUndefined,
+				VerifiedDeal:     proposal.VerifiedDeal,
+			}
+			expectedDealOps[abi.DealID(dealID)] = struct{}{}
+
+			totalProposalCollateral = big.Sum(totalProposalCollateral, proposal.ClientCollateral, proposal.ProviderCollateral)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating proposals")
+	}
+
+	// next id should be higher than any existing deal
+	acc.Require(int64(st.NextID) > maxDealID, "next id, %d, is not greater than highest id in proposals, %d", st.NextID, maxDealID)
+
+	//
+	// Deal States
+	//
+
+	dealStateCount := uint64(0)
+	if dealStates, err := adt.AsArray(store, st.States); err != nil {
+		acc.Addf("error loading deal states: %v", err)
+	} else {
+		var dealState DealState
+		err = dealStates.ForEach(&dealState, func(dealID int64) error {
+			acc.Require(dealState.SectorStartEpoch >= 0, "deal state %d has negative sector start epoch", dealID)
+			acc.Require(dealState.LastUpdatedEpoch <= st.LastCron,
+				"deal state %d last updated epoch %d is after last cron %d", dealID, dealState.LastUpdatedEpoch, st.LastCron)
+
+			stats, found := proposalStats[abi.DealID(dealID)]
+			if !found {
+				acc.Addf("no deal proposal for deal state %d", dealID)
+				return nil
+			}
+			stats.SectorStartEpoch = dealState.SectorStartEpoch
+			stats.LastUpdatedEpoch = dealState.LastUpdatedEpoch
+			stats.SlashEpoch = dealState.SlashEpoch
+
+			acc.Require(dealState.SlashEpoch == epochUndefined || dealState.SlashEpoch <= stats.EndEpoch,
+				"deal state %d slash epoch %d is after deal end %d", dealID, dealState.SlashEpoch, stats.EndEpoch)
+
+			dealStateCount++
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating deal states")
+	}
+
+	//
+	// Pending Proposals
+	//
+
+	pendingProposalCount := uint64(0)
+	if pendingProposals, err := adt.AsMap(store, st.PendingProposals); err != nil {
+		acc.Addf("error loading pending proposals: %v", err)
+	} else {
+		var dealProposal DealProposal
+		err = pendingProposals.ForEach(&dealProposal, func(key string) error {
+			proposalCID, err := cid.Cast([]byte(key))
+			if err != nil {
+				return err
+			}
+
+			pcid, err := dealProposal.Cid()
+			if err != nil {
+				return err
+			}
+			acc.Require(pcid.Equals(proposalCID), "pending proposal %v is keyed by mismatched CID %v", pcid, proposalCID)
+
+			_, found := proposalCids[proposalCID]
+			acc.Require(found, "pending proposal with cid %v not found within proposals", proposalCID)
+
+			pendingProposalCount++
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating pending proposals")
+	}
+
+	// Deals that have not yet been processed by cron at their start epoch are pending.
+	expectedPendingCount := uint64(0)
+	for _, stats := range proposalStats { //nolint:nomaprange
+		if stats.LastUpdatedEpoch == epochUndefined {
+			expectedPendingCount++
+		}
+	}
+	acc.Require(pendingProposalCount == expectedPendingCount,
+		"pending proposal count %d does not match count of deals not yet updated %d", pendingProposalCount, expectedPendingCount)
+
+	//
+	// Escrow Table
+	//
+
+	escrowTotal := abi.NewTokenAmount(0)
+	escrowByAddr := make(map[addr.Address]abi.TokenAmount)
+	if escrowTable, err := adt.AsMap(store, st.EscrowTable); err != nil {
+		acc.Addf("error loading escrow table: %v", err)
+	} else {
+		var escrow abi.TokenAmount
+		err = escrowTable.ForEach(&escrow, func(key string) error {
+			a, err := addr.NewFromBytes([]byte(key))
+			if err != nil {
+				return err
+			}
+			acc.Require(escrow.GreaterThanEqual(big.Zero()), "escrow balance for %v is negative: %v", a, escrow)
+			escrowByAddr[a] = escrow.Copy()
+			escrowTotal = big.Add(escrowTotal, escrow)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating escrow table")
+	}
+
+	acc.Require(escrowTotal.LessThanEqual(balance), "escrow total %v exceeds actor balance %v", escrowTotal, balance)
+
+	//
+	// Locked Table
+	//
+
+	lockTableCount := uint64(0)
+	lockedTotal := abi.NewTokenAmount(0)
+	if lockTable, err := adt.AsMap(store, st.LockedTable); err != nil {
+		acc.Addf("error loading locked table: %v", err)
+	} else {
+		var lockAmount abi.TokenAmount
+		err = lockTable.ForEach(&lockAmount, func(key string) error {
+			a, err := addr.NewFromBytes([]byte(key))
+			if err != nil {
+				return err
+			}
+			acc.Require(lockAmount.GreaterThanEqual(big.Zero()), "locked balance for %v is negative: %v", a, lockAmount)
+
+			escrow, found := escrowByAddr[a]
+			if !found {
+				escrow = big.Zero()
+			}
+			acc.Require(lockAmount.LessThanEqual(escrow), "locked funds %v for %v exceed escrow %v", lockAmount, a, escrow)
+
+			lockedTotal = big.Add(lockedTotal, lockAmount)
+			lockTableCount++
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating locked table")
+	}
+
+	expectedLocked := big.Sum(st.TotalClientLockedCollateral, st.TotalProviderLockedCollateral, st.TotalClientStorageFee)
+	acc.Require(lockedTotal.Equals(expectedLocked), "locked table total %v does not match recorded totals %v",
+		lockedTotal, expectedLocked)
+	recordedCollateral := big.Add(st.TotalClientLockedCollateral, st.TotalProviderLockedCollateral)
+	acc.Require(totalProposalCollateral.Equals(recordedCollateral),
+		"sum of collateral in deal proposals %v does not match recorded locked collateral %v",
+		totalProposalCollateral, recordedCollateral)
+
+	//
+	// Deal Ops by Epoch
+	//
+
+	dealOpEpochCount := uint64(0)
+	dealOpCount := uint64(0)
+	if dealOps, err := AsSetMultimap(store, st.DealOpsByEpoch); err != nil {
+		acc.Addf("error loading deal ops: %v", err)
+	} else {
+		// get into internals just to iterate through full data structure
+		var setRoot cbg.CborCid
+		err = dealOps.mp.ForEach(&setRoot, func(key string) error {
+			epoch, err := adt.ParseUIntKey(key)
+			if err != nil {
+				return err
+			}
+			acc.Require(abi.ChainEpoch(epoch) >= st.LastCron, "deal ops scheduled at epoch %d, before last cron %d",
+				epoch, st.LastCron)
+
+			dealOpEpochCount++
+			return dealOps.ForEach(abi.ChainEpoch(epoch), func(id abi.DealID) error {
+				_, found := proposalStats[id]
+				acc.Require(found, "deal op found for deal id %d with missing proposal at epoch %d", id, epoch)
+				delete(expectedDealOps, id)
+				dealOpCount++
+				return nil
+			})
+		})
+		acc.RequireNoError(err, "error iterating deal ops")
+	}
+
+	acc.Require(len(expectedDealOps) == 0, "missing deal ops for %d proposals", len(expectedDealOps))
+
+	return &StateSummary{
+		Deals:                proposalStats,
+		PendingProposalCount: pendingProposalCount,
+		DealStateCount:       dealStateCount,
+		LockTableCount:       lockTableCount,
+		DealOpEpochCount:     dealOpEpochCount,
+		DealOpCount:          dealOpCount,
+	}, acc
+}
@@ -75,29 +75,44 @@ var MethodsPower = struct {
 	OnConsensusFault         abi.MethodNum
 	SubmitPoRepForBulkVerify abi.MethodNum
 	CurrentTotalPower        abi.MethodNum
-}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9}
+	OnMinerExit              abi.MethodNum
+}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 
 var MethodsMiner = struct {
-	Constructor              abi.MethodNum
-	ControlAddresses         abi.MethodNum
-	ChangeWorkerAddress      abi.MethodNum
-	ChangePeerID             abi.MethodNum
-	SubmitWindowedPoSt       abi.MethodNum
-	PreCommitSector          abi.MethodNum
-	ProveCommitSector        abi.MethodNum
-	ExtendSectorExpiration   abi.MethodNum
-	TerminateSectors         abi.MethodNum
-	DeclareFaults            abi.MethodNum
-	DeclareFaultsRecovered   abi.MethodNum
-	OnDeferredCronEvent      abi.MethodNum
-	CheckSectorProven        abi.MethodNum
-	AddLockedFund            abi.MethodNum
-	ReportConsensusFault     abi.MethodNum
-	WithdrawBalance          abi.MethodNum
-	ConfirmSectorProofsValid abi.MethodNum
-	ChangeMultiaddrs         abi.MethodNum
-	CompactPartitions        abi.MethodNum
-}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}
+	Constructor                   abi.MethodNum
+	ControlAddresses              abi.MethodNum
+	ChangeWorkerAddress           abi.MethodNum
+	ChangePeerID                  abi.MethodNum
+	SubmitWindowedPoSt            abi.MethodNum
+	PreCommitSector               abi.MethodNum
+	ProveCommitSector             abi.MethodNum
+	ExtendSectorExpiration        abi.MethodNum
+	TerminateSectors              abi.MethodNum
+	DeclareFaults                 abi.MethodNum
+	DeclareFaultsRecovered        abi.MethodNum
+	OnDeferredCronEvent           abi.MethodNum
+	CheckSectorProven             abi.MethodNum
+	AddLockedFund                 abi.MethodNum
+	ReportConsensusFault          abi.MethodNum
+	WithdrawBalance               abi.MethodNum
+	ConfirmSectorProofsValid      abi.MethodNum
+	ChangeMultiaddrs              abi.MethodNum
+	CompactPartitions             abi.MethodNum
+	ChangeOwnerAddress            abi.MethodNum
+	PreCommitSectorBatch          abi.MethodNum
+	ProveCommitAggregate          abi.MethodNum
+	DisputeWindowedPoSt           abi.MethodNum
+	RepayDebt                     abi.MethodNum
+	ChangeBeneficiary             abi.MethodNum
+	GetBeneficiary                abi.MethodNum
+	ProveReplicaUpdates           abi.MethodNum
+	DeclareFaultsPartial          abi.MethodNum
+	DeclareFaultsRecoveredPartial abi.MethodNum
+	WithdrawPreCommits            abi.MethodNum
+	MaskSectorNumbers             abi.MethodNum
+	ExitMiner                     abi.MethodNum
+	CompactVestingFunds           abi.MethodNum
+}{MethodConstructor, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33}
 
 var MethodsVerifiedRegistry = struct {
 	Constructor       abi.MethodNum
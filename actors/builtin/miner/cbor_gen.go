@@ -6,16 +6,17 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-bitfield"
 	"github.com/filecoin-project/specs-actors/actors/abi"
-	cid "github.com/ipfs/go-cid"
+	"github.com/ipfs/go-cid"
 	cbg "github.com/whyrusleeping/cbor-gen"
 	xerrors "golang.org/x/xerrors"
 )
 
 var _ = xerrors.Errorf
 
-var lengthBufState = []byte{140}
+var lengthBufState = []byte{143}
 
 func (t *State) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -50,6 +51,11 @@ func (t *State) MarshalCBOR(w io.Writer) error {
 		return xerrors.Errorf("failed to write cid field t.VestingFunds: %w", err)
 	}
 
+	// t.FeeDebt (big.Int) (struct)
+	if err := t.FeeDebt.MarshalCBOR(w); err != nil {
+		return err
+	}
+
 	// t.InitialPledgeRequirement (big.Int) (struct)
 	if err := t.InitialPledgeRequirement.MarshalCBOR(w); err != nil {
 		return err
@@ -67,6 +73,11 @@ func (t *State) MarshalCBOR(w io.Writer) error {
 		return xerrors.Errorf("failed to write cid field t.Sectors: %w", err)
 	}
 
+	// t.AllocatedSectors (bitfield.BitField) (struct)
+	if err := t.AllocatedSectors.MarshalCBOR(w); err != nil {
+		return err
+	}
+
 	// t.ProvingPeriodStart (abi.ChainEpoch) (int64)
 	if t.ProvingPeriodStart >= 0 {
 		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.ProvingPeriodStart)); err != nil {
@@ -99,6 +110,11 @@ func (t *State) MarshalCBOR(w io.Writer) error {
 	if err := t.FaultyPower.MarshalCBOR(w); err != nil {
 		return err
 	}
+
+	// t.Exiting (bool) (bool)
+	if err := cbg.WriteBool(w, t.Exiting); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -116,7 +132,7 @@ func (t *State) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 12 {
+	if extra != 15 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -161,6 +177,15 @@ func (t *State) UnmarshalCBOR(r io.Reader) error {
 
 		t.VestingFunds = c
 
+	}
+	// t.FeeDebt (big.Int) (struct)
+
+	{
+
+		if err := t.FeeDebt.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.FeeDebt: %w", err)
+		}
+
 	}
 	// t.InitialPledgeRequirement (big.Int) (struct)
 
@@ -194,6 +219,27 @@ func (t *State) UnmarshalCBOR(r io.Reader) error {
 
 		t.Sectors = c
 
+	}
+	// t.AllocatedSectors (bitfield.BitField) (struct)
+
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.AllocatedSectors = new(bitfield.BitField)
+			if err := t.AllocatedSectors.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.AllocatedSectors pointer: %w", err)
+			}
+		}
+
 	}
 	// t.ProvingPeriodStart (abi.ChainEpoch) (int64)
 	{
@@ -276,10 +322,27 @@ func (t *State) UnmarshalCBOR(r io.Reader) error {
 		}
 
 	}
+	// t.Exiting (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.Exiting = false
+	case 21:
+		t.Exiting = true
+	default:
+		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+	}
 	return nil
 }
 
-var lengthBufMinerInfo = []byte{136}
+var lengthBufMinerInfo = []byte{141}
 
 func (t *MinerInfo) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -302,6 +365,20 @@ func (t *MinerInfo) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
+	// t.ControlAddresses ([]address.Address) (slice)
+	if len(t.ControlAddresses) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.ControlAddresses was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.ControlAddresses))); err != nil {
+		return err
+	}
+	for _, v := range t.ControlAddresses {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+
 	// t.PendingWorkerKey (miner.WorkerKeyChange) (struct)
 	if err := t.PendingWorkerKey.MarshalCBOR(w); err != nil {
 		return err
@@ -365,6 +442,25 @@ func (t *MinerInfo) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
+	// t.PendingOwner (miner.PendingOwnerChange) (struct)
+	if err := t.PendingOwner.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Beneficiary (address.Address) (struct)
+	if err := t.Beneficiary.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.BeneficiaryTerm (miner.BeneficiaryTerm) (struct)
+	if err := t.BeneficiaryTerm.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.PendingBeneficiaryTerm (miner.PendingBeneficiaryChange) (struct)
+	if err := t.PendingBeneficiaryTerm.MarshalCBOR(w); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -382,7 +478,7 @@ func (t *MinerInfo) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 8 {
+	if extra != 13 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -404,6 +500,35 @@ func (t *MinerInfo) UnmarshalCBOR(r io.Reader) error {
 		}
 
 	}
+	// t.ControlAddresses ([]address.Address) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.ControlAddresses: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.ControlAddresses = make([]address.Address, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v address.Address
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.ControlAddresses[i] = v
+	}
+
 	// t.PendingWorkerKey (miner.WorkerKeyChange) (struct)
 
 	{
@@ -537,6 +662,66 @@ func (t *MinerInfo) UnmarshalCBOR(r io.Reader) error {
 		}
 		t.WindowPoStPartitionSectors = uint64(extra)
 
+	}
+	// t.PendingOwner (miner.PendingOwnerChange) (struct)
+
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.PendingOwner = new(PendingOwnerChange)
+			if err := t.PendingOwner.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.PendingOwner pointer: %w", err)
+			}
+		}
+
+	}
+	// t.Beneficiary (address.Address) (struct)
+
+	{
+
+		if err := t.Beneficiary.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Beneficiary: %w", err)
+		}
+
+	}
+	// t.BeneficiaryTerm (miner.BeneficiaryTerm) (struct)
+
+	{
+
+		if err := t.BeneficiaryTerm.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.BeneficiaryTerm: %w", err)
+		}
+
+	}
+	// t.PendingBeneficiaryTerm (miner.PendingBeneficiaryChange) (struct)
+
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.PendingBeneficiaryTerm = new(PendingBeneficiaryChange)
+			if err := t.PendingBeneficiaryTerm.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.PendingBeneficiaryTerm pointer: %w", err)
+			}
+		}
+
 	}
 	return nil
 }
@@ -621,7 +806,7 @@ func (t *Deadlines) UnmarshalCBOR(r io.Reader) error {
 	return nil
 }
 
-var lengthBufDeadline = []byte{134}
+var lengthBufDeadline = []byte{139}
 
 func (t *Deadline) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -668,6 +853,36 @@ func (t *Deadline) MarshalCBOR(w io.Writer) error {
 		return err
 	}
 
+	// t.Renewals (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.Renewals); err != nil {
+		return xerrors.Errorf("failed to write cid field t.Renewals: %w", err)
+	}
+
+	// t.OptimisticPoStSubmissions (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.OptimisticPoStSubmissions); err != nil {
+		return xerrors.Errorf("failed to write cid field t.OptimisticPoStSubmissions: %w", err)
+	}
+
+	// t.PartitionsSnapshot (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.PartitionsSnapshot); err != nil {
+		return xerrors.Errorf("failed to write cid field t.PartitionsSnapshot: %w", err)
+	}
+
+	// t.SectorsSnapshot (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.SectorsSnapshot); err != nil {
+		return xerrors.Errorf("failed to write cid field t.SectorsSnapshot: %w", err)
+	}
+
+	// t.OptimisticPoStSubmissionsSnapshot (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.OptimisticPoStSubmissionsSnapshot); err != nil {
+		return xerrors.Errorf("failed to write cid field t.OptimisticPoStSubmissionsSnapshot: %w", err)
+	}
+
 	return nil
 }
 
@@ -685,7 +900,7 @@ func (t *Deadline) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 6 {
+	if extra != 11 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -782,6 +997,66 @@ func (t *Deadline) UnmarshalCBOR(r io.Reader) error {
 		}
 		t.TotalSectors = uint64(extra)
 
+	}
+	// t.Renewals (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.Renewals: %w", err)
+		}
+
+		t.Renewals = c
+
+	}
+	// t.OptimisticPoStSubmissions (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.OptimisticPoStSubmissions: %w", err)
+		}
+
+		t.OptimisticPoStSubmissions = c
+
+	}
+	// t.PartitionsSnapshot (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.PartitionsSnapshot: %w", err)
+		}
+
+		t.PartitionsSnapshot = c
+
+	}
+	// t.SectorsSnapshot (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.SectorsSnapshot: %w", err)
+		}
+
+		t.SectorsSnapshot = c
+
+	}
+	// t.OptimisticPoStSubmissionsSnapshot (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.OptimisticPoStSubmissionsSnapshot: %w", err)
+		}
+
+		t.OptimisticPoStSubmissionsSnapshot = c
+
 	}
 	return nil
 }
@@ -1194,7 +1469,7 @@ func (t *PowerPair) UnmarshalCBOR(r io.Reader) error {
 	return nil
 }
 
-var lengthBufSectorPreCommitOnChainInfo = []byte{133}
+var lengthBufSectorPreCommitOnChainInfo = []byte{134}
 
 func (t *SectorPreCommitOnChainInfo) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -1237,6 +1512,17 @@ func (t *SectorPreCommitOnChainInfo) MarshalCBOR(w io.Writer) error {
 	if err := t.VerifiedDealWeight.MarshalCBOR(w); err != nil {
 		return err
 	}
+
+	// t.ProofSubmittedEpoch (abi.ChainEpoch) (int64)
+	if t.ProofSubmittedEpoch >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.ProofSubmittedEpoch)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.ProofSubmittedEpoch-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -1254,7 +1540,7 @@ func (t *SectorPreCommitOnChainInfo) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 5 {
+	if extra != 6 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -1319,6 +1605,31 @@ func (t *SectorPreCommitOnChainInfo) UnmarshalCBOR(r io.Reader) error {
 		}
 
 	}
+	// t.ProofSubmittedEpoch (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.ProofSubmittedEpoch = abi.ChainEpoch(extraI)
+	}
 	return nil
 }
 
@@ -1634,7 +1945,7 @@ func (t *SectorPreCommitInfo) UnmarshalCBOR(r io.Reader) error {
 	return nil
 }
 
-var lengthBufSectorOnChainInfo = []byte{137}
+var lengthBufSectorOnChainInfo = []byte{139}
 
 func (t *SectorOnChainInfo) MarshalCBOR(w io.Writer) error {
 	if t == nil {
@@ -1695,6 +2006,17 @@ func (t *SectorOnChainInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.PowerBaseEpoch (abi.ChainEpoch) (int64)
+	if t.PowerBaseEpoch >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.PowerBaseEpoch)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.PowerBaseEpoch-1)); err != nil {
+			return err
+		}
+	}
+
 	// t.Expiration (abi.ChainEpoch) (int64)
 	if t.Expiration >= 0 {
 		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Expiration)); err != nil {
@@ -1706,6 +2028,17 @@ func (t *SectorOnChainInfo) MarshalCBOR(w io.Writer) error {
 		}
 	}
 
+	// t.ExtendedExpiration (abi.ChainEpoch) (int64)
+	if t.ExtendedExpiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.ExtendedExpiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.ExtendedExpiration-1)); err != nil {
+			return err
+		}
+	}
+
 	// t.DealWeight (big.Int) (struct)
 	if err := t.DealWeight.MarshalCBOR(w); err != nil {
 		return err
@@ -1737,7 +2070,7 @@ func (t *SectorOnChainInfo) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 9 {
+	if extra != 11 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
@@ -1850,7 +2183,7 @@ func (t *SectorOnChainInfo) UnmarshalCBOR(r io.Reader) error {
 
 		t.Activation = abi.ChainEpoch(extraI)
 	}
-	// t.Expiration (abi.ChainEpoch) (int64)
+	// t.PowerBaseEpoch (abi.ChainEpoch) (int64)
 	{
 		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
 		var extraI int64
@@ -1873,14 +2206,64 @@ func (t *SectorOnChainInfo) UnmarshalCBOR(r io.Reader) error {
 			return fmt.Errorf("wrong type for int64 field: %d", maj)
 		}
 
-		t.Expiration = abi.ChainEpoch(extraI)
+		t.PowerBaseEpoch = abi.ChainEpoch(extraI)
 	}
-	// t.DealWeight (big.Int) (struct)
-
+	// t.Expiration (abi.ChainEpoch) (int64)
 	{
-
-		if err := t.DealWeight.UnmarshalCBOR(br); err != nil {
-			return xerrors.Errorf("unmarshaling t.DealWeight: %w", err)
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.Expiration = abi.ChainEpoch(extraI)
+	}
+	// t.ExtendedExpiration (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.ExtendedExpiration = abi.ChainEpoch(extraI)
+	}
+	// t.DealWeight (big.Int) (struct)
+
+	{
+
+		if err := t.DealWeight.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.DealWeight: %w", err)
 		}
 
 	}
@@ -1991,37 +2374,70 @@ func (t *WorkerKeyChange) UnmarshalCBOR(r io.Reader) error {
 	return nil
 }
 
-var lengthBufSubmitWindowedPoStParams = []byte{131}
+var lengthBufPendingOwnerChange = []byte{129}
 
-func (t *SubmitWindowedPoStParams) MarshalCBOR(w io.Writer) error {
+func (t *PendingOwnerChange) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufSubmitWindowedPoStParams); err != nil {
+	if _, err := w.Write(lengthBufPendingOwnerChange); err != nil {
 		return err
 	}
 
-	scratch := make([]byte, 9)
+	// t.NewOwner (address.Address) (struct)
+	if err := t.NewOwner.MarshalCBOR(w); err != nil {
+		return err
+	}
+	return nil
+}
 
-	// t.Deadline (uint64) (uint64)
+func (t *PendingOwnerChange) UnmarshalCBOR(r io.Reader) error {
+	*t = PendingOwnerChange{}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
 		return err
 	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
 
-	// t.Partitions ([]miner.PoStPartition) (slice)
-	if len(t.Partitions) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Partitions was too long")
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Partitions))); err != nil {
+	// t.NewOwner (address.Address) (struct)
+
+	{
+
+		if err := t.NewOwner.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewOwner: %w", err)
+		}
+
+	}
+	return nil
+}
+
+var lengthBufWindowedPoSt = []byte{130}
+
+func (t *WindowedPoSt) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	for _, v := range t.Partitions {
-		if err := v.MarshalCBOR(w); err != nil {
-			return err
-		}
+	if _, err := w.Write(lengthBufWindowedPoSt); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Partitions (bitfield.BitField) (struct)
+	if err := t.Partitions.MarshalCBOR(w); err != nil {
+		return err
 	}
 
 	// t.Proofs ([]abi.PoStProof) (slice)
@@ -2040,8 +2456,8 @@ func (t *SubmitWindowedPoStParams) MarshalCBOR(w io.Writer) error {
 	return nil
 }
 
-func (t *SubmitWindowedPoStParams) UnmarshalCBOR(r io.Reader) error {
-	*t = SubmitWindowedPoStParams{}
+func (t *WindowedPoSt) UnmarshalCBOR(r io.Reader) error {
+	*t = WindowedPoSt{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2054,53 +2470,31 @@ func (t *SubmitWindowedPoStParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 3 {
+	if extra != 2 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Deadline (uint64) (uint64)
+	// t.Partitions (bitfield.BitField) (struct)
 
 	{
 
-		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		pb, err := br.PeekByte()
 		if err != nil {
 			return err
 		}
-		if maj != cbg.MajUnsignedInt {
-			return fmt.Errorf("wrong type for uint64 field")
-		}
-		t.Deadline = uint64(extra)
-
-	}
-	// t.Partitions ([]miner.PoStPartition) (slice)
-
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
-	}
-
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.Partitions: array too large (%d)", extra)
-	}
-
-	if maj != cbg.MajArray {
-		return fmt.Errorf("expected cbor array")
-	}
-
-	if extra > 0 {
-		t.Partitions = make([]PoStPartition, extra)
-	}
-
-	for i := 0; i < int(extra); i++ {
-
-		var v PoStPartition
-		if err := v.UnmarshalCBOR(br); err != nil {
-			return err
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.Partitions = new(bitfield.BitField)
+			if err := t.Partitions.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Partitions pointer: %w", err)
+			}
 		}
 
-		t.Partitions[i] = v
 	}
-
 	// t.Proofs ([]abi.PoStProof) (slice)
 
 	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
@@ -2133,37 +2527,44 @@ func (t *SubmitWindowedPoStParams) UnmarshalCBOR(r io.Reader) error {
 	return nil
 }
 
-var lengthBufTerminateSectorsParams = []byte{129}
+var lengthBufBeneficiaryTerm = []byte{131}
 
-func (t *TerminateSectorsParams) MarshalCBOR(w io.Writer) error {
+func (t *BeneficiaryTerm) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufTerminateSectorsParams); err != nil {
+	if _, err := w.Write(lengthBufBeneficiaryTerm); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.Terminations ([]miner.TerminationDeclaration) (slice)
-	if len(t.Terminations) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Terminations was too long")
+	// t.Quota (big.Int) (struct)
+	if err := t.Quota.MarshalCBOR(w); err != nil {
+		return err
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Terminations))); err != nil {
+	// t.UsedQuota (big.Int) (struct)
+	if err := t.UsedQuota.MarshalCBOR(w); err != nil {
 		return err
 	}
-	for _, v := range t.Terminations {
-		if err := v.MarshalCBOR(w); err != nil {
+
+	// t.Expiration (abi.ChainEpoch) (int64)
+	if t.Expiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Expiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.Expiration-1)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (t *TerminateSectorsParams) UnmarshalCBOR(r io.Reader) error {
-	*t = TerminateSectorsParams{}
+func (t *BeneficiaryTerm) UnmarshalCBOR(r io.Reader) error {
+	*t = BeneficiaryTerm{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2176,62 +2577,104 @@ func (t *TerminateSectorsParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 3 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Terminations ([]miner.TerminationDeclaration) (slice)
+	// t.Quota (big.Int) (struct)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
-	}
+	{
 
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.Terminations: array too large (%d)", extra)
-	}
+		if err := t.Quota.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Quota: %w", err)
+		}
 
-	if maj != cbg.MajArray {
-		return fmt.Errorf("expected cbor array")
 	}
+	// t.UsedQuota (big.Int) (struct)
 
-	if extra > 0 {
-		t.Terminations = make([]TerminationDeclaration, extra)
-	}
+	{
 
-	for i := 0; i < int(extra); i++ {
+		if err := t.UsedQuota.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.UsedQuota: %w", err)
+		}
 
-		var v TerminationDeclaration
-		if err := v.UnmarshalCBOR(br); err != nil {
+	}
+	// t.Expiration (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
 			return err
 		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
 
-		t.Terminations[i] = v
+		t.Expiration = abi.ChainEpoch(extraI)
 	}
-
 	return nil
 }
 
-var lengthBufTerminateSectorsReturn = []byte{129}
+var lengthBufPendingBeneficiaryChange = []byte{133}
 
-func (t *TerminateSectorsReturn) MarshalCBOR(w io.Writer) error {
+func (t *PendingBeneficiaryChange) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufTerminateSectorsReturn); err != nil {
+	if _, err := w.Write(lengthBufPendingBeneficiaryChange); err != nil {
 		return err
 	}
 
-	// t.Done (bool) (bool)
-	if err := cbg.WriteBool(w, t.Done); err != nil {
+	scratch := make([]byte, 9)
+
+	// t.NewBeneficiary (address.Address) (struct)
+	if err := t.NewBeneficiary.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.NewQuota (big.Int) (struct)
+	if err := t.NewQuota.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.NewExpiration (abi.ChainEpoch) (int64)
+	if t.NewExpiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.NewExpiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.NewExpiration-1)); err != nil {
+			return err
+		}
+	}
+
+	// t.ApprovedByBeneficiary (bool) (bool)
+	if err := cbg.WriteBool(w, t.ApprovedByBeneficiary); err != nil {
+		return err
+	}
+
+	// t.ApprovedByNominee (bool) (bool)
+	if err := cbg.WriteBool(w, t.ApprovedByNominee); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *TerminateSectorsReturn) UnmarshalCBOR(r io.Reader) error {
-	*t = TerminateSectorsReturn{}
+func (t *PendingBeneficiaryChange) UnmarshalCBOR(r io.Reader) error {
+	*t = PendingBeneficiaryChange{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2244,60 +2687,1229 @@ func (t *TerminateSectorsReturn) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 5 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Done (bool) (bool)
+	// t.NewBeneficiary (address.Address) (struct)
+
+	{
+
+		if err := t.NewBeneficiary.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewBeneficiary: %w", err)
+		}
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
-	}
-	if maj != cbg.MajOther {
-		return fmt.Errorf("booleans must be major type 7")
 	}
-	switch extra {
-	case 20:
-		t.Done = false
+	// t.NewQuota (big.Int) (struct)
+
+	{
+
+		if err := t.NewQuota.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewQuota: %w", err)
+		}
+
+	}
+	// t.NewExpiration (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.NewExpiration = abi.ChainEpoch(extraI)
+	}
+	// t.ApprovedByBeneficiary (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.ApprovedByBeneficiary = false
 	case 21:
-		t.Done = true
+		t.ApprovedByBeneficiary = true
+	default:
+		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+	}
+	// t.ApprovedByNominee (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.ApprovedByNominee = false
+	case 21:
+		t.ApprovedByNominee = true
 	default:
 		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
 	}
 	return nil
 }
 
-var lengthBufChangePeerIDParams = []byte{129}
+var lengthBufSubmitWindowedPoStParams = []byte{131}
+
+func (t *SubmitWindowedPoStParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufSubmitWindowedPoStParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Deadline (uint64) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
+		return err
+	}
+
+	// t.Partitions ([]miner.PoStPartition) (slice)
+	if len(t.Partitions) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Partitions was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Partitions))); err != nil {
+		return err
+	}
+	for _, v := range t.Partitions {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+
+	// t.Proofs ([]abi.PoStProof) (slice)
+	if len(t.Proofs) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Proofs was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Proofs))); err != nil {
+		return err
+	}
+	for _, v := range t.Proofs {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *SubmitWindowedPoStParams) UnmarshalCBOR(r io.Reader) error {
+	*t = SubmitWindowedPoStParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Deadline (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Deadline = uint64(extra)
+
+	}
+	// t.Partitions ([]miner.PoStPartition) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Partitions: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Partitions = make([]PoStPartition, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v PoStPartition
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Partitions[i] = v
+	}
+
+	// t.Proofs ([]abi.PoStProof) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Proofs: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Proofs = make([]abi.PoStProof, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v abi.PoStProof
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Proofs[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufTerminateSectorsParams = []byte{129}
+
+func (t *TerminateSectorsParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufTerminateSectorsParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Terminations ([]miner.TerminationDeclaration) (slice)
+	if len(t.Terminations) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Terminations was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Terminations))); err != nil {
+		return err
+	}
+	for _, v := range t.Terminations {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TerminateSectorsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = TerminateSectorsParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Terminations ([]miner.TerminationDeclaration) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Terminations: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Terminations = make([]TerminationDeclaration, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v TerminationDeclaration
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Terminations[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufTerminateSectorsReturn = []byte{129}
+
+func (t *TerminateSectorsReturn) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufTerminateSectorsReturn); err != nil {
+		return err
+	}
+
+	// t.Done (bool) (bool)
+	if err := cbg.WriteBool(w, t.Done); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *TerminateSectorsReturn) UnmarshalCBOR(r io.Reader) error {
+	*t = TerminateSectorsReturn{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Done (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.Done = false
+	case 21:
+		t.Done = true
+	default:
+		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+	}
+	return nil
+}
+
+var lengthBufChangePeerIDParams = []byte{129}
+
+func (t *ChangePeerIDParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufChangePeerIDParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.NewID ([]uint8) (slice)
+	if len(t.NewID) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.NewID was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.NewID))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.NewID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ChangePeerIDParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ChangePeerIDParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.NewID ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.NewID: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.NewID = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.NewID); err != nil {
+		return err
+	}
+	return nil
+}
+
+var lengthBufChangeMultiaddrsParams = []byte{129}
+
+func (t *ChangeMultiaddrsParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufChangeMultiaddrsParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.NewMultiaddrs ([][]uint8) (slice)
+	if len(t.NewMultiaddrs) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.NewMultiaddrs was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.NewMultiaddrs))); err != nil {
+		return err
+	}
+	for _, v := range t.NewMultiaddrs {
+		if len(v) > cbg.ByteArrayMaxLen {
+			return xerrors.Errorf("Byte array in field v was too long")
+		}
+
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(v))); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ChangeMultiaddrsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ChangeMultiaddrsParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.NewMultiaddrs ([][]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.NewMultiaddrs: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.NewMultiaddrs = make([][]uint8, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+		{
+			var maj byte
+			var extra uint64
+			var err error
+
+			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+			if err != nil {
+				return err
+			}
+
+			if extra > cbg.ByteArrayMaxLen {
+				return fmt.Errorf("t.NewMultiaddrs[i]: byte array too large (%d)", extra)
+			}
+			if maj != cbg.MajByteString {
+				return fmt.Errorf("expected byte array")
+			}
+			t.NewMultiaddrs[i] = make([]byte, extra)
+			if _, err := io.ReadFull(br, t.NewMultiaddrs[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var lengthBufProveCommitSectorParams = []byte{130}
+
+func (t *ProveCommitSectorParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufProveCommitSectorParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.SectorNumber (abi.SectorNumber) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SectorNumber)); err != nil {
+		return err
+	}
+
+	// t.Proof ([]uint8) (slice)
+	if len(t.Proof) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.Proof was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Proof))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.Proof); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ProveCommitSectorParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ProveCommitSectorParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 2 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.SectorNumber (abi.SectorNumber) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.SectorNumber = abi.SectorNumber(extra)
+
+	}
+	// t.Proof ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.Proof: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.Proof = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.Proof); err != nil {
+		return err
+	}
+	return nil
+}
+
+var lengthBufChangeWorkerAddressParams = []byte{131}
+
+func (t *ChangeWorkerAddressParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufChangeWorkerAddressParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.NewWorker (address.Address) (struct)
+	if err := t.NewWorker.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.NewControlAddrs ([]address.Address) (slice)
+	if len(t.NewControlAddrs) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.NewControlAddrs was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.NewControlAddrs))); err != nil {
+		return err
+	}
+	for _, v := range t.NewControlAddrs {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+
+	// t.ClearControlAddrs (bool) (bool)
+	if err := cbg.WriteBool(w, t.ClearControlAddrs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ChangeWorkerAddressParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ChangeWorkerAddressParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.NewWorker (address.Address) (struct)
+
+	{
+
+		if err := t.NewWorker.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewWorker: %w", err)
+		}
+
+	}
+	// t.NewControlAddrs ([]address.Address) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.NewControlAddrs: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.NewControlAddrs = make([]address.Address, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v address.Address
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.NewControlAddrs[i] = v
+	}
+
+	// t.ClearControlAddrs (bool) (bool)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajOther {
+		return fmt.Errorf("booleans must be major type 7")
+	}
+	switch extra {
+	case 20:
+		t.ClearControlAddrs = false
+	case 21:
+		t.ClearControlAddrs = true
+	default:
+		return fmt.Errorf("booleans are either major type 7, value 20 or 21 (got %d)", extra)
+	}
+	return nil
+}
+
+var lengthBufExtendSectorExpirationParams = []byte{129}
+
+func (t *ExtendSectorExpirationParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufExtendSectorExpirationParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Extensions ([]miner.ExpirationExtension) (slice)
+	if len(t.Extensions) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Extensions was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Extensions))); err != nil {
+		return err
+	}
+	for _, v := range t.Extensions {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ExtendSectorExpirationParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ExtendSectorExpirationParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Extensions ([]miner.ExpirationExtension) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Extensions: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Extensions = make([]ExpirationExtension, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v ExpirationExtension
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Extensions[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufDeclareFaultsParams = []byte{129}
+
+func (t *DeclareFaultsParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufDeclareFaultsParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Faults ([]miner.FaultDeclaration) (slice)
+	if len(t.Faults) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Faults was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Faults))); err != nil {
+		return err
+	}
+	for _, v := range t.Faults {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *DeclareFaultsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = DeclareFaultsParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Faults ([]miner.FaultDeclaration) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Faults: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Faults = make([]FaultDeclaration, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v FaultDeclaration
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Faults[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufDeclareFaultsRecoveredParams = []byte{129}
+
+func (t *DeclareFaultsRecoveredParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufDeclareFaultsRecoveredParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Recoveries ([]miner.RecoveryDeclaration) (slice)
+	if len(t.Recoveries) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Recoveries was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Recoveries))); err != nil {
+		return err
+	}
+	for _, v := range t.Recoveries {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *DeclareFaultsRecoveredParams) UnmarshalCBOR(r io.Reader) error {
+	*t = DeclareFaultsRecoveredParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Recoveries ([]miner.RecoveryDeclaration) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Recoveries: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.Recoveries = make([]RecoveryDeclaration, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v RecoveryDeclaration
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Recoveries[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufReportConsensusFaultParams = []byte{131}
+
+func (t *ReportConsensusFaultParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufReportConsensusFaultParams); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.BlockHeader1 ([]uint8) (slice)
+	if len(t.BlockHeader1) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.BlockHeader1 was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeader1))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.BlockHeader1); err != nil {
+		return err
+	}
+
+	// t.BlockHeader2 ([]uint8) (slice)
+	if len(t.BlockHeader2) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.BlockHeader2 was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeader2))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.BlockHeader2); err != nil {
+		return err
+	}
+
+	// t.BlockHeaderExtra ([]uint8) (slice)
+	if len(t.BlockHeaderExtra) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.BlockHeaderExtra was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeaderExtra))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.BlockHeaderExtra); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *ReportConsensusFaultParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ReportConsensusFaultParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.BlockHeader1 ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.BlockHeader1: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.BlockHeader1 = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.BlockHeader1); err != nil {
+		return err
+	}
+	// t.BlockHeader2 ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.BlockHeader2: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.BlockHeader2 = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.BlockHeader2); err != nil {
+		return err
+	}
+	// t.BlockHeaderExtra ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.BlockHeaderExtra: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.BlockHeaderExtra = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.BlockHeaderExtra); err != nil {
+		return err
+	}
+	return nil
+}
+
+var lengthBufGetControlAddressesReturn = []byte{131}
+
+func (t *GetControlAddressesReturn) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufGetControlAddressesReturn); err != nil {
+		return err
+	}
+
+	scratch := make([]byte, 9)
+
+	// t.Owner (address.Address) (struct)
+	if err := t.Owner.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Worker (address.Address) (struct)
+	if err := t.Worker.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.ControlAddrs ([]address.Address) (slice)
+	if len(t.ControlAddrs) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.ControlAddrs was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.ControlAddrs))); err != nil {
+		return err
+	}
+	for _, v := range t.ControlAddrs {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *GetControlAddressesReturn) UnmarshalCBOR(r io.Reader) error {
+	*t = GetControlAddressesReturn{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 3 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.Owner (address.Address) (struct)
+
+	{
+
+		if err := t.Owner.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Owner: %w", err)
+		}
+
+	}
+	// t.Worker (address.Address) (struct)
+
+	{
+
+		if err := t.Worker.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Worker: %w", err)
+		}
+
+	}
+	// t.ControlAddrs ([]address.Address) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.ControlAddrs: array too large (%d)", extra)
+	}
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
+	}
+
+	if extra > 0 {
+		t.ControlAddrs = make([]address.Address, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v address.Address
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.ControlAddrs[i] = v
+	}
+
+	return nil
+}
+
+var lengthBufCheckSectorProvenParams = []byte{129}
 
-func (t *ChangePeerIDParams) MarshalCBOR(w io.Writer) error {
+func (t *CheckSectorProvenParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufChangePeerIDParams); err != nil {
+	if _, err := w.Write(lengthBufCheckSectorProvenParams); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.NewID ([]uint8) (slice)
-	if len(t.NewID) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.NewID was too long")
-	}
+	// t.SectorNumber (abi.SectorNumber) (uint64)
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.NewID))); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SectorNumber)); err != nil {
 		return err
 	}
 
-	if _, err := w.Write(t.NewID); err != nil {
-		return err
-	}
 	return nil
 }
 
-func (t *ChangePeerIDParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ChangePeerIDParams{}
+func (t *CheckSectorProvenParams) UnmarshalCBOR(r io.Reader) error {
+	*t = CheckSectorProvenParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2314,65 +3926,99 @@ func (t *ChangePeerIDParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.NewID ([]uint8) (slice)
+	// t.SectorNumber (abi.SectorNumber) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.SectorNumber = abi.SectorNumber(extra)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
 	}
+	return nil
+}
 
-	if extra > cbg.ByteArrayMaxLen {
-		return fmt.Errorf("t.NewID: byte array too large (%d)", extra)
+var lengthBufWithdrawBalanceParams = []byte{129}
+
+func (t *WithdrawBalanceParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
 	}
-	if maj != cbg.MajByteString {
-		return fmt.Errorf("expected byte array")
+	if _, err := w.Write(lengthBufWithdrawBalanceParams); err != nil {
+		return err
 	}
-	t.NewID = make([]byte, extra)
-	if _, err := io.ReadFull(br, t.NewID); err != nil {
+
+	// t.AmountRequested (big.Int) (struct)
+	if err := t.AmountRequested.MarshalCBOR(w); err != nil {
 		return err
 	}
 	return nil
 }
 
-var lengthBufChangeMultiaddrsParams = []byte{129}
+func (t *WithdrawBalanceParams) UnmarshalCBOR(r io.Reader) error {
+	*t = WithdrawBalanceParams{}
 
-func (t *ChangeMultiaddrsParams) MarshalCBOR(w io.Writer) error {
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+	if maj != cbg.MajArray {
+		return fmt.Errorf("cbor input should be of type array")
+	}
+
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
+	}
+
+	// t.AmountRequested (big.Int) (struct)
+
+	{
+
+		if err := t.AmountRequested.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.AmountRequested: %w", err)
+		}
+
+	}
+	return nil
+}
+
+var lengthBufCompactPartitionsParams = []byte{130}
+
+func (t *CompactPartitionsParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufChangeMultiaddrsParams); err != nil {
+	if _, err := w.Write(lengthBufCompactPartitionsParams); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.NewMultiaddrs ([][]uint8) (slice)
-	if len(t.NewMultiaddrs) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.NewMultiaddrs was too long")
-	}
+	// t.Deadline (uint64) (uint64)
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.NewMultiaddrs))); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
 		return err
 	}
-	for _, v := range t.NewMultiaddrs {
-		if len(v) > cbg.ByteArrayMaxLen {
-			return xerrors.Errorf("Byte array in field v was too long")
-		}
-
-		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(v))); err != nil {
-			return err
-		}
 
-		if _, err := w.Write(v); err != nil {
-			return err
-		}
+	// t.Partitions (bitfield.BitField) (struct)
+	if err := t.Partitions.MarshalCBOR(w); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (t *ChangeMultiaddrsParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ChangeMultiaddrsParams{}
+func (t *CompactPartitionsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = CompactPartitionsParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2385,92 +4031,79 @@ func (t *ChangeMultiaddrsParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 2 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.NewMultiaddrs ([][]uint8) (slice)
-
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
-	}
+	// t.Deadline (uint64) (uint64)
 
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.NewMultiaddrs: array too large (%d)", extra)
-	}
+	{
 
-	if maj != cbg.MajArray {
-		return fmt.Errorf("expected cbor array")
-	}
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Deadline = uint64(extra)
 
-	if extra > 0 {
-		t.NewMultiaddrs = make([][]uint8, extra)
 	}
+	// t.Partitions (bitfield.BitField) (struct)
 
-	for i := 0; i < int(extra); i++ {
-		{
-			var maj byte
-			var extra uint64
-			var err error
+	{
 
-			maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-			if err != nil {
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
 				return err
 			}
-
-			if extra > cbg.ByteArrayMaxLen {
-				return fmt.Errorf("t.NewMultiaddrs[i]: byte array too large (%d)", extra)
-			}
-			if maj != cbg.MajByteString {
-				return fmt.Errorf("expected byte array")
-			}
-			t.NewMultiaddrs[i] = make([]byte, extra)
-			if _, err := io.ReadFull(br, t.NewMultiaddrs[i]); err != nil {
-				return err
+		} else {
+			t.Partitions = new(bitfield.BitField)
+			if err := t.Partitions.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Partitions pointer: %w", err)
 			}
 		}
-	}
 
+	}
 	return nil
 }
 
-var lengthBufProveCommitSectorParams = []byte{130}
+var lengthBufPreCommitSectorBatchParams = []byte{129}
 
-func (t *ProveCommitSectorParams) MarshalCBOR(w io.Writer) error {
+func (t *PreCommitSectorBatchParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufProveCommitSectorParams); err != nil {
+	if _, err := w.Write(lengthBufPreCommitSectorBatchParams); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.SectorNumber (abi.SectorNumber) (uint64)
-
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SectorNumber)); err != nil {
-		return err
-	}
-
-	// t.Proof ([]uint8) (slice)
-	if len(t.Proof) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.Proof was too long")
+	// t.Sectors ([]miner.SectorPreCommitInfo) (slice)
+	if len(t.Sectors) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Sectors was too long")
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.Proof))); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Sectors))); err != nil {
 		return err
 	}
-
-	if _, err := w.Write(t.Proof); err != nil {
-		return err
+	for _, v := range t.Sectors {
+		if err := v.MarshalCBOR(w); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (t *ProveCommitSectorParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ProveCommitSectorParams{}
+func (t *PreCommitSectorBatchParams) UnmarshalCBOR(r io.Reader) error {
+	*t = PreCommitSectorBatchParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2483,64 +4116,77 @@ func (t *ProveCommitSectorParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 2 {
+	if extra != 1 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.SectorNumber (abi.SectorNumber) (uint64)
-
-	{
-
-		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-		if err != nil {
-			return err
-		}
-		if maj != cbg.MajUnsignedInt {
-			return fmt.Errorf("wrong type for uint64 field")
-		}
-		t.SectorNumber = abi.SectorNumber(extra)
-
-	}
-	// t.Proof ([]uint8) (slice)
+	// t.Sectors ([]miner.SectorPreCommitInfo) (slice)
 
 	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
 	if err != nil {
 		return err
 	}
 
-	if extra > cbg.ByteArrayMaxLen {
-		return fmt.Errorf("t.Proof: byte array too large (%d)", extra)
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Sectors: array too large (%d)", extra)
 	}
-	if maj != cbg.MajByteString {
-		return fmt.Errorf("expected byte array")
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
 	}
-	t.Proof = make([]byte, extra)
-	if _, err := io.ReadFull(br, t.Proof); err != nil {
-		return err
+
+	if extra > 0 {
+		t.Sectors = make([]SectorPreCommitInfo, extra)
+	}
+
+	for i := 0; i < int(extra); i++ {
+
+		var v SectorPreCommitInfo
+		if err := v.UnmarshalCBOR(br); err != nil {
+			return err
+		}
+
+		t.Sectors[i] = v
 	}
+
 	return nil
 }
 
-var lengthBufChangeWorkerAddressParams = []byte{129}
+var lengthBufProveCommitAggregateParams = []byte{130}
 
-func (t *ChangeWorkerAddressParams) MarshalCBOR(w io.Writer) error {
+func (t *ProveCommitAggregateParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufChangeWorkerAddressParams); err != nil {
+	if _, err := w.Write(lengthBufProveCommitAggregateParams); err != nil {
 		return err
 	}
 
-	// t.NewWorker (address.Address) (struct)
-	if err := t.NewWorker.MarshalCBOR(w); err != nil {
+	scratch := make([]byte, 9)
+
+	// t.SectorNumbers (bitfield.BitField) (struct)
+	if err := t.SectorNumbers.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.AggregateProof ([]uint8) (slice)
+	if len(t.AggregateProof) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.AggregateProof was too long")
+	}
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.AggregateProof))); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(t.AggregateProof); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *ChangeWorkerAddressParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ChangeWorkerAddressParams{}
+func (t *ProveCommitAggregateParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ProveCommitAggregateParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2553,44 +4199,73 @@ func (t *ChangeWorkerAddressParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 2 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.NewWorker (address.Address) (struct)
+	// t.SectorNumbers (bitfield.BitField) (struct)
 
 	{
 
-		if err := t.NewWorker.UnmarshalCBOR(br); err != nil {
-			return xerrors.Errorf("unmarshaling t.NewWorker: %w", err)
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.SectorNumbers = new(bitfield.BitField)
+			if err := t.SectorNumbers.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.SectorNumbers pointer: %w", err)
+			}
 		}
 
 	}
+	// t.AggregateProof ([]uint8) (slice)
+
+	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
+
+	if extra > cbg.ByteArrayMaxLen {
+		return fmt.Errorf("t.AggregateProof: byte array too large (%d)", extra)
+	}
+	if maj != cbg.MajByteString {
+		return fmt.Errorf("expected byte array")
+	}
+	t.AggregateProof = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.AggregateProof); err != nil {
+		return err
+	}
 	return nil
 }
 
-var lengthBufExtendSectorExpirationParams = []byte{129}
+var lengthBufProveReplicaUpdatesParams = []byte{129}
 
-func (t *ExtendSectorExpirationParams) MarshalCBOR(w io.Writer) error {
+func (t *ProveReplicaUpdatesParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufExtendSectorExpirationParams); err != nil {
+	if _, err := w.Write(lengthBufProveReplicaUpdatesParams); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.Extensions ([]miner.ExpirationExtension) (slice)
-	if len(t.Extensions) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Extensions was too long")
+	// t.Updates ([]miner.ReplicaUpdate) (slice)
+	if len(t.Updates) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Updates was too long")
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Extensions))); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Updates))); err != nil {
 		return err
 	}
-	for _, v := range t.Extensions {
+	for _, v := range t.Updates {
 		if err := v.MarshalCBOR(w); err != nil {
 			return err
 		}
@@ -2598,8 +4273,8 @@ func (t *ExtendSectorExpirationParams) MarshalCBOR(w io.Writer) error {
 	return nil
 }
 
-func (t *ExtendSectorExpirationParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ExtendSectorExpirationParams{}
+func (t *ProveReplicaUpdatesParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ProveReplicaUpdatesParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2616,7 +4291,7 @@ func (t *ExtendSectorExpirationParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Extensions ([]miner.ExpirationExtension) (slice)
+	// t.Updates ([]miner.ReplicaUpdate) (slice)
 
 	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
 	if err != nil {
@@ -2624,7 +4299,7 @@ func (t *ExtendSectorExpirationParams) UnmarshalCBOR(r io.Reader) error {
 	}
 
 	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.Extensions: array too large (%d)", extra)
+		return fmt.Errorf("t.Updates: array too large (%d)", extra)
 	}
 
 	if maj != cbg.MajArray {
@@ -2632,53 +4307,42 @@ func (t *ExtendSectorExpirationParams) UnmarshalCBOR(r io.Reader) error {
 	}
 
 	if extra > 0 {
-		t.Extensions = make([]ExpirationExtension, extra)
+		t.Updates = make([]ReplicaUpdate, extra)
 	}
 
 	for i := 0; i < int(extra); i++ {
 
-		var v ExpirationExtension
+		var v ReplicaUpdate
 		if err := v.UnmarshalCBOR(br); err != nil {
 			return err
 		}
 
-		t.Extensions[i] = v
+		t.Updates[i] = v
 	}
 
 	return nil
 }
 
-var lengthBufDeclareFaultsParams = []byte{129}
+var lengthBufDeclarationsReturn = []byte{129}
 
-func (t *DeclareFaultsParams) MarshalCBOR(w io.Writer) error {
+func (t *DeclarationsReturn) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufDeclareFaultsParams); err != nil {
+	if _, err := w.Write(lengthBufDeclarationsReturn); err != nil {
 		return err
 	}
 
-	scratch := make([]byte, 9)
-
-	// t.Faults ([]miner.FaultDeclaration) (slice)
-	if len(t.Faults) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Faults was too long")
-	}
-
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Faults))); err != nil {
+	// t.Applied (bitfield.BitField) (struct)
+	if err := t.Applied.MarshalCBOR(w); err != nil {
 		return err
 	}
-	for _, v := range t.Faults {
-		if err := v.MarshalCBOR(w); err != nil {
-			return err
-		}
-	}
 	return nil
 }
 
-func (t *DeclareFaultsParams) UnmarshalCBOR(r io.Reader) error {
-	*t = DeclareFaultsParams{}
+func (t *DeclarationsReturn) UnmarshalCBOR(r io.Reader) error {
+	*t = DeclarationsReturn{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2695,69 +4359,110 @@ func (t *DeclareFaultsParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Faults ([]miner.FaultDeclaration) (slice)
+	// t.Applied (bitfield.BitField) (struct)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.Applied = new(bitfield.BitField)
+			if err := t.Applied.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Applied pointer: %w", err)
+			}
+		}
+
+	}
+	return nil
+}
+
+var lengthBufWithdrawPreCommitsParams = []byte{129}
+
+func (t *WithdrawPreCommitsParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufWithdrawPreCommitsParams); err != nil {
 		return err
 	}
 
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.Faults: array too large (%d)", extra)
+	// t.Sectors (bitfield.BitField) (struct)
+	if err := t.Sectors.MarshalCBOR(w); err != nil {
+		return err
 	}
+	return nil
+}
+
+func (t *WithdrawPreCommitsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = WithdrawPreCommitsParams{}
 
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
+
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
 	if maj != cbg.MajArray {
-		return fmt.Errorf("expected cbor array")
+		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra > 0 {
-		t.Faults = make([]FaultDeclaration, extra)
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	for i := 0; i < int(extra); i++ {
+	// t.Sectors (bitfield.BitField) (struct)
 
-		var v FaultDeclaration
-		if err := v.UnmarshalCBOR(br); err != nil {
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
 			return err
 		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.Sectors = new(bitfield.BitField)
+			if err := t.Sectors.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Sectors pointer: %w", err)
+			}
+		}
 
-		t.Faults[i] = v
 	}
-
 	return nil
 }
 
-var lengthBufDeclareFaultsRecoveredParams = []byte{129}
+var lengthBufMaskSectorNumbersParams = []byte{129}
 
-func (t *DeclareFaultsRecoveredParams) MarshalCBOR(w io.Writer) error {
+func (t *MaskSectorNumbersParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufDeclareFaultsRecoveredParams); err != nil {
+	if _, err := w.Write(lengthBufMaskSectorNumbersParams); err != nil {
 		return err
 	}
 
-	scratch := make([]byte, 9)
-
-	// t.Recoveries ([]miner.RecoveryDeclaration) (slice)
-	if len(t.Recoveries) > cbg.MaxLength {
-		return xerrors.Errorf("Slice value in field t.Recoveries was too long")
-	}
-
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Recoveries))); err != nil {
+	// t.Mask (bitfield.BitField) (struct)
+	if err := t.Mask.MarshalCBOR(w); err != nil {
 		return err
 	}
-	for _, v := range t.Recoveries {
-		if err := v.MarshalCBOR(w); err != nil {
-			return err
-		}
-	}
 	return nil
 }
 
-func (t *DeclareFaultsRecoveredParams) UnmarshalCBOR(r io.Reader) error {
-	*t = DeclareFaultsRecoveredParams{}
+func (t *MaskSectorNumbersParams) UnmarshalCBOR(r io.Reader) error {
+	*t = MaskSectorNumbersParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2774,94 +4479,181 @@ func (t *DeclareFaultsRecoveredParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Recoveries ([]miner.RecoveryDeclaration) (slice)
+	// t.Mask (bitfield.BitField) (struct)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
+	{
+
+		pb, err := br.PeekByte()
+		if err != nil {
+			return err
+		}
+		if pb == cbg.CborNull[0] {
+			var nbuf [1]byte
+			if _, err := br.Read(nbuf[:]); err != nil {
+				return err
+			}
+		} else {
+			t.Mask = new(bitfield.BitField)
+			if err := t.Mask.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Mask pointer: %w", err)
+			}
+		}
+
+	}
+	return nil
+}
+
+var lengthBufCompactVestingFundsParams = []byte{129}
+
+func (t *CompactVestingFundsParams) MarshalCBOR(w io.Writer) error {
+	if t == nil {
+		_, err := w.Write(cbg.CborNull)
+		return err
+	}
+	if _, err := w.Write(lengthBufCompactVestingFundsParams); err != nil {
 		return err
 	}
 
-	if extra > cbg.MaxLength {
-		return fmt.Errorf("t.Recoveries: array too large (%d)", extra)
+	scratch := make([]byte, 9)
+
+	// t.Quantization (abi.ChainEpoch) (int64)
+	if t.Quantization >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Quantization)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.Quantization-1)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+func (t *CompactVestingFundsParams) UnmarshalCBOR(r io.Reader) error {
+	*t = CompactVestingFundsParams{}
+
+	br := cbg.GetPeeker(r)
+	scratch := make([]byte, 8)
 
+	maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+	if err != nil {
+		return err
+	}
 	if maj != cbg.MajArray {
-		return fmt.Errorf("expected cbor array")
+		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra > 0 {
-		t.Recoveries = make([]RecoveryDeclaration, extra)
+	if extra != 1 {
+		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	for i := 0; i < int(extra); i++ {
-
-		var v RecoveryDeclaration
-		if err := v.UnmarshalCBOR(br); err != nil {
+	// t.Quantization (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
 			return err
 		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
 
-		t.Recoveries[i] = v
+		t.Quantization = abi.ChainEpoch(extraI)
 	}
-
 	return nil
 }
 
-var lengthBufReportConsensusFaultParams = []byte{131}
+var lengthBufReplicaUpdate = []byte{135}
 
-func (t *ReportConsensusFaultParams) MarshalCBOR(w io.Writer) error {
+func (t *ReplicaUpdate) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufReportConsensusFaultParams); err != nil {
+	if _, err := w.Write(lengthBufReplicaUpdate); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.BlockHeader1 ([]uint8) (slice)
-	if len(t.BlockHeader1) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.BlockHeader1 was too long")
+	// t.SectorNumber (abi.SectorNumber) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SectorNumber)); err != nil {
+		return err
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeader1))); err != nil {
+	// t.Deadline (uint64) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
 		return err
 	}
 
-	if _, err := w.Write(t.BlockHeader1); err != nil {
+	// t.Partition (uint64) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Partition)); err != nil {
 		return err
 	}
 
-	// t.BlockHeader2 ([]uint8) (slice)
-	if len(t.BlockHeader2) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.BlockHeader2 was too long")
+	// t.NewSealedSectorCID (cid.Cid) (struct)
+
+	if err := cbg.WriteCidBuf(scratch, w, t.NewSealedSectorCID); err != nil {
+		return xerrors.Errorf("failed to write cid field t.NewSealedSectorCID: %w", err)
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeader2))); err != nil {
-		return err
+	// t.Deals ([]abi.DealID) (slice)
+	if len(t.Deals) > cbg.MaxLength {
+		return xerrors.Errorf("Slice value in field t.Deals was too long")
 	}
 
-	if _, err := w.Write(t.BlockHeader2); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajArray, uint64(len(t.Deals))); err != nil {
 		return err
 	}
+	for _, v := range t.Deals {
+		if err := cbg.CborWriteHeader(w, cbg.MajUnsignedInt, uint64(v)); err != nil {
+			return err
+		}
+	}
+
+	// t.UpdateProofType (abi.RegisteredUpdateProof) (int64)
+	if t.UpdateProofType >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.UpdateProofType)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.UpdateProofType-1)); err != nil {
+			return err
+		}
+	}
 
-	// t.BlockHeaderExtra ([]uint8) (slice)
-	if len(t.BlockHeaderExtra) > cbg.ByteArrayMaxLen {
-		return xerrors.Errorf("Byte array in field t.BlockHeaderExtra was too long")
+	// t.ReplicaProof ([]uint8) (slice)
+	if len(t.ReplicaProof) > cbg.ByteArrayMaxLen {
+		return xerrors.Errorf("Byte array in field t.ReplicaProof was too long")
 	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.BlockHeaderExtra))); err != nil {
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajByteString, uint64(len(t.ReplicaProof))); err != nil {
 		return err
 	}
 
-	if _, err := w.Write(t.BlockHeaderExtra); err != nil {
+	if _, err := w.Write(t.ReplicaProof); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *ReportConsensusFaultParams) UnmarshalCBOR(r io.Reader) error {
-	*t = ReportConsensusFaultParams{}
+func (t *ReplicaUpdate) UnmarshalCBOR(r io.Reader) error {
+	*t = ReplicaUpdate{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2874,45 +4666,123 @@ func (t *ReportConsensusFaultParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 3 {
+	if extra != 7 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.BlockHeader1 ([]uint8) (slice)
+	// t.SectorNumber (abi.SectorNumber) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.SectorNumber = abi.SectorNumber(extra)
 
-	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-	if err != nil {
-		return err
 	}
+	// t.Deadline (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Deadline = uint64(extra)
 
-	if extra > cbg.ByteArrayMaxLen {
-		return fmt.Errorf("t.BlockHeader1: byte array too large (%d)", extra)
 	}
-	if maj != cbg.MajByteString {
-		return fmt.Errorf("expected byte array")
+	// t.Partition (uint64) (uint64)
+
+	{
+
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Partition = uint64(extra)
+
 	}
-	t.BlockHeader1 = make([]byte, extra)
-	if _, err := io.ReadFull(br, t.BlockHeader1); err != nil {
-		return err
+	// t.NewSealedSectorCID (cid.Cid) (struct)
+
+	{
+
+		c, err := cbg.ReadCid(br)
+		if err != nil {
+			return xerrors.Errorf("failed to read cid field t.NewSealedSectorCID: %w", err)
+		}
+
+		t.NewSealedSectorCID = c
+
 	}
-	// t.BlockHeader2 ([]uint8) (slice)
+	// t.Deals ([]abi.DealID) (slice)
 
 	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
 	if err != nil {
 		return err
 	}
 
-	if extra > cbg.ByteArrayMaxLen {
-		return fmt.Errorf("t.BlockHeader2: byte array too large (%d)", extra)
+	if extra > cbg.MaxLength {
+		return fmt.Errorf("t.Deals: array too large (%d)", extra)
 	}
-	if maj != cbg.MajByteString {
-		return fmt.Errorf("expected byte array")
+
+	if maj != cbg.MajArray {
+		return fmt.Errorf("expected cbor array")
 	}
-	t.BlockHeader2 = make([]byte, extra)
-	if _, err := io.ReadFull(br, t.BlockHeader2); err != nil {
-		return err
+
+	if extra > 0 {
+		t.Deals = make([]abi.DealID, extra)
 	}
-	// t.BlockHeaderExtra ([]uint8) (slice)
+
+	for i := 0; i < int(extra); i++ {
+
+		maj, val, err := cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return xerrors.Errorf("failed to read uint64 for t.Deals slice: %w", err)
+		}
+
+		if maj != cbg.MajUnsignedInt {
+			return xerrors.Errorf("value read for array t.Deals was not a uint, instead got %d", maj)
+		}
+
+		t.Deals[i] = abi.DealID(val)
+	}
+
+	// t.UpdateProofType (abi.RegisteredUpdateProof) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
+		if err != nil {
+			return err
+		}
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
+		}
+
+		t.UpdateProofType = abi.RegisteredUpdateProof(extraI)
+	}
+	// t.ReplicaProof ([]uint8) (slice)
 
 	maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
 	if err != nil {
@@ -2920,43 +4790,48 @@ func (t *ReportConsensusFaultParams) UnmarshalCBOR(r io.Reader) error {
 	}
 
 	if extra > cbg.ByteArrayMaxLen {
-		return fmt.Errorf("t.BlockHeaderExtra: byte array too large (%d)", extra)
+		return fmt.Errorf("t.ReplicaProof: byte array too large (%d)", extra)
 	}
 	if maj != cbg.MajByteString {
 		return fmt.Errorf("expected byte array")
 	}
-	t.BlockHeaderExtra = make([]byte, extra)
-	if _, err := io.ReadFull(br, t.BlockHeaderExtra); err != nil {
+	t.ReplicaProof = make([]byte, extra)
+	if _, err := io.ReadFull(br, t.ReplicaProof); err != nil {
 		return err
 	}
 	return nil
 }
 
-var lengthBufGetControlAddressesReturn = []byte{130}
+var lengthBufDisputeWindowedPoStParams = []byte{130}
 
-func (t *GetControlAddressesReturn) MarshalCBOR(w io.Writer) error {
+func (t *DisputeWindowedPoStParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufGetControlAddressesReturn); err != nil {
+	if _, err := w.Write(lengthBufDisputeWindowedPoStParams); err != nil {
 		return err
 	}
 
-	// t.Owner (address.Address) (struct)
-	if err := t.Owner.MarshalCBOR(w); err != nil {
+	scratch := make([]byte, 9)
+
+	// t.Deadline (uint64) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
 		return err
 	}
 
-	// t.Worker (address.Address) (struct)
-	if err := t.Worker.MarshalCBOR(w); err != nil {
+	// t.PoStIndex (uint64) (uint64)
+
+	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.PoStIndex)); err != nil {
 		return err
 	}
+
 	return nil
 }
 
-func (t *GetControlAddressesReturn) UnmarshalCBOR(r io.Reader) error {
-	*t = GetControlAddressesReturn{}
+func (t *DisputeWindowedPoStParams) UnmarshalCBOR(r io.Reader) error {
+	*t = DisputeWindowedPoStParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -2973,51 +4848,75 @@ func (t *GetControlAddressesReturn) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Owner (address.Address) (struct)
+	// t.Deadline (uint64) (uint64)
 
 	{
 
-		if err := t.Owner.UnmarshalCBOR(br); err != nil {
-			return xerrors.Errorf("unmarshaling t.Owner: %w", err)
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
 		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
+		}
+		t.Deadline = uint64(extra)
 
 	}
-	// t.Worker (address.Address) (struct)
+	// t.PoStIndex (uint64) (uint64)
 
 	{
 
-		if err := t.Worker.UnmarshalCBOR(br); err != nil {
-			return xerrors.Errorf("unmarshaling t.Worker: %w", err)
+		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err != nil {
+			return err
+		}
+		if maj != cbg.MajUnsignedInt {
+			return fmt.Errorf("wrong type for uint64 field")
 		}
+		t.PoStIndex = uint64(extra)
 
 	}
 	return nil
 }
 
-var lengthBufCheckSectorProvenParams = []byte{129}
+var lengthBufChangeBeneficiaryParams = []byte{131}
 
-func (t *CheckSectorProvenParams) MarshalCBOR(w io.Writer) error {
+func (t *ChangeBeneficiaryParams) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufCheckSectorProvenParams); err != nil {
+	if _, err := w.Write(lengthBufChangeBeneficiaryParams); err != nil {
 		return err
 	}
 
 	scratch := make([]byte, 9)
 
-	// t.SectorNumber (abi.SectorNumber) (uint64)
+	// t.NewBeneficiary (address.Address) (struct)
+	if err := t.NewBeneficiary.MarshalCBOR(w); err != nil {
+		return err
+	}
 
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.SectorNumber)); err != nil {
+	// t.NewQuota (big.Int) (struct)
+	if err := t.NewQuota.MarshalCBOR(w); err != nil {
 		return err
 	}
 
+	// t.NewExpiration (abi.ChainEpoch) (int64)
+	if t.NewExpiration >= 0 {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.NewExpiration)); err != nil {
+			return err
+		}
+	} else {
+		if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajNegativeInt, uint64(-t.NewExpiration-1)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *CheckSectorProvenParams) UnmarshalCBOR(r io.Reader) error {
-	*t = CheckSectorProvenParams{}
+func (t *ChangeBeneficiaryParams) UnmarshalCBOR(r io.Reader) error {
+	*t = ChangeBeneficiaryParams{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -3030,47 +4929,81 @@ func (t *CheckSectorProvenParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 3 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.SectorNumber (abi.SectorNumber) (uint64)
+	// t.NewBeneficiary (address.Address) (struct)
 
 	{
 
-		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
+		if err := t.NewBeneficiary.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewBeneficiary: %w", err)
+		}
+
+	}
+	// t.NewQuota (big.Int) (struct)
+
+	{
+
+		if err := t.NewQuota.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.NewQuota: %w", err)
+		}
+
+	}
+	// t.NewExpiration (abi.ChainEpoch) (int64)
+	{
+		maj, extra, err := cbg.CborReadHeaderBuf(br, scratch)
+		var extraI int64
 		if err != nil {
 			return err
 		}
-		if maj != cbg.MajUnsignedInt {
-			return fmt.Errorf("wrong type for uint64 field")
+		switch maj {
+		case cbg.MajUnsignedInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 positive overflow")
+			}
+		case cbg.MajNegativeInt:
+			extraI = int64(extra)
+			if extraI < 0 {
+				return fmt.Errorf("int64 negative oveflow")
+			}
+			extraI = -1 - extraI
+		default:
+			return fmt.Errorf("wrong type for int64 field: %d", maj)
 		}
-		t.SectorNumber = abi.SectorNumber(extra)
 
+		t.NewExpiration = abi.ChainEpoch(extraI)
 	}
 	return nil
 }
 
-var lengthBufWithdrawBalanceParams = []byte{129}
+var lengthBufActiveBeneficiary = []byte{130}
 
-func (t *WithdrawBalanceParams) MarshalCBOR(w io.Writer) error {
+func (t *ActiveBeneficiary) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufWithdrawBalanceParams); err != nil {
+	if _, err := w.Write(lengthBufActiveBeneficiary); err != nil {
 		return err
 	}
 
-	// t.AmountRequested (big.Int) (struct)
-	if err := t.AmountRequested.MarshalCBOR(w); err != nil {
+	// t.Beneficiary (address.Address) (struct)
+	if err := t.Beneficiary.MarshalCBOR(w); err != nil {
+		return err
+	}
+
+	// t.Term (miner.BeneficiaryTerm) (struct)
+	if err := t.Term.MarshalCBOR(w); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *WithdrawBalanceParams) UnmarshalCBOR(r io.Reader) error {
-	*t = WithdrawBalanceParams{}
+func (t *ActiveBeneficiary) UnmarshalCBOR(r io.Reader) error {
+	*t = ActiveBeneficiary{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -3083,50 +5016,56 @@ func (t *WithdrawBalanceParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input should be of type array")
 	}
 
-	if extra != 1 {
+	if extra != 2 {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.AmountRequested (big.Int) (struct)
+	// t.Beneficiary (address.Address) (struct)
 
 	{
 
-		if err := t.AmountRequested.UnmarshalCBOR(br); err != nil {
-			return xerrors.Errorf("unmarshaling t.AmountRequested: %w", err)
+		if err := t.Beneficiary.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Beneficiary: %w", err)
+		}
+
+	}
+	// t.Term (miner.BeneficiaryTerm) (struct)
+
+	{
+
+		if err := t.Term.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Term: %w", err)
 		}
 
 	}
 	return nil
 }
 
-var lengthBufCompactPartitionsParams = []byte{130}
+var lengthBufGetBeneficiaryReturn = []byte{130}
 
-func (t *CompactPartitionsParams) MarshalCBOR(w io.Writer) error {
+func (t *GetBeneficiaryReturn) MarshalCBOR(w io.Writer) error {
 	if t == nil {
 		_, err := w.Write(cbg.CborNull)
 		return err
 	}
-	if _, err := w.Write(lengthBufCompactPartitionsParams); err != nil {
+	if _, err := w.Write(lengthBufGetBeneficiaryReturn); err != nil {
 		return err
 	}
 
-	scratch := make([]byte, 9)
-
-	// t.Deadline (uint64) (uint64)
-
-	if err := cbg.WriteMajorTypeHeaderBuf(scratch, w, cbg.MajUnsignedInt, uint64(t.Deadline)); err != nil {
+	// t.Active (miner.ActiveBeneficiary) (struct)
+	if err := t.Active.MarshalCBOR(w); err != nil {
 		return err
 	}
 
-	// t.Partitions (bitfield.BitField) (struct)
-	if err := t.Partitions.MarshalCBOR(w); err != nil {
+	// t.Proposed (miner.PendingBeneficiaryChange) (struct)
+	if err := t.Proposed.MarshalCBOR(w); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (t *CompactPartitionsParams) UnmarshalCBOR(r io.Reader) error {
-	*t = CompactPartitionsParams{}
+func (t *GetBeneficiaryReturn) UnmarshalCBOR(r io.Reader) error {
+	*t = GetBeneficiaryReturn{}
 
 	br := cbg.GetPeeker(r)
 	scratch := make([]byte, 8)
@@ -3143,21 +5082,16 @@ func (t *CompactPartitionsParams) UnmarshalCBOR(r io.Reader) error {
 		return fmt.Errorf("cbor input had wrong number of fields")
 	}
 
-	// t.Deadline (uint64) (uint64)
+	// t.Active (miner.ActiveBeneficiary) (struct)
 
 	{
 
-		maj, extra, err = cbg.CborReadHeaderBuf(br, scratch)
-		if err != nil {
-			return err
+		if err := t.Active.UnmarshalCBOR(br); err != nil {
+			return xerrors.Errorf("unmarshaling t.Active: %w", err)
 		}
-		if maj != cbg.MajUnsignedInt {
-			return fmt.Errorf("wrong type for uint64 field")
-		}
-		t.Deadline = uint64(extra)
 
 	}
-	// t.Partitions (bitfield.BitField) (struct)
+	// t.Proposed (miner.PendingBeneficiaryChange) (struct)
 
 	{
 
@@ -3171,9 +5105,9 @@ func (t *CompactPartitionsParams) UnmarshalCBOR(r io.Reader) error {
 				return err
 			}
 		} else {
-			t.Partitions = new(bitfield.BitField)
-			if err := t.Partitions.UnmarshalCBOR(br); err != nil {
-				return xerrors.Errorf("unmarshaling t.Partitions pointer: %w", err)
+			t.Proposed = new(PendingBeneficiaryChange)
+			if err := t.Proposed.UnmarshalCBOR(br); err != nil {
+				return xerrors.Errorf("unmarshaling t.Proposed pointer: %w", err)
 			}
 		}
 
@@ -51,6 +51,38 @@ type Deadline struct {
 
 	// The total number of sectors in this deadline (incl dead).
 	TotalSectors uint64
+
+	// Maps epochs to sectors with deal weight that have been extended, to be renewed in or before that epoch.
+	// Each sector keeps its quality-adjusted power until its current expiration, when its deal weight expires,
+	// and is then renewed with raw-byte power until its extended expiration.
+	// Keys are quantized to final epochs in each proving deadline.
+	//
+	// NOTE: Sectors are not removed from this queue when terminated or expired early, and are ignored
+	// if no longer live when their renewal is due.
+	Renewals cid.Cid // AMT[ChainEpoch]BitField
+
+	// AMT of optimistically accepted WindowPoSt proofs, submitted during the current challenge window.
+	// At the end of the challenge window, this AMT is moved to OptimisticPoStSubmissionsSnapshot.
+	// WindowPoSt proofs verified on-chain do not appear in this AMT.
+	OptimisticPoStSubmissions cid.Cid // AMT[]WindowedPoSt
+
+	// Snapshot of the partitions AMT at the end of the previous challenge window for this deadline.
+	PartitionsSnapshot cid.Cid // AMT[PartitionNumber]Partition
+
+	// Snapshot of the miner's sectors AMT at the end of the previous challenge window for this deadline.
+	SectorsSnapshot cid.Cid // AMT[SectorNumber]SectorOnChainInfo
+
+	// Snapshot of the proofs submitted optimistically during the previous challenge window.
+	// These may be disputed until the dispute window closes.
+	OptimisticPoStSubmissionsSnapshot cid.Cid // AMT[]WindowedPoSt
+}
+
+// A WindowedPoSt proof accepted optimistically, without on-chain verification.
+type WindowedPoSt struct {
+	// Partitions proven by this PoSt.
+	Partitions *abi.BitField
+	// The proofs, as submitted.
+	Proofs []abi.PoStProof
 }
 
 //
@@ -114,6 +146,12 @@ func ConstructDeadline(emptyArrayCid cid.Cid) *Deadline {
 		PostSubmissions:   abi.NewBitField(),
 		EarlyTerminations: abi.NewBitField(),
 		LiveSectors:       0,
+		Renewals:          emptyArrayCid,
+
+		OptimisticPoStSubmissions:         emptyArrayCid,
+		PartitionsSnapshot:                emptyArrayCid,
+		SectorsSnapshot:                   emptyArrayCid,
+		OptimisticPoStSubmissionsSnapshot: emptyArrayCid,
 	}
 }
 
@@ -426,6 +464,63 @@ func (dl *Deadline) AddPoStSubmissions(idxs []uint64) {
 	}
 }
 
+// Records a proof to be verified only if it is later disputed.
+func (dl *Deadline) RecordOptimisticPoSt(store adt.Store, partitions *abi.BitField, proofs []abi.PoStProof) error {
+	proofArr, err := adt.AsArray(store, dl.OptimisticPoStSubmissions)
+	if err != nil {
+		return xerrors.Errorf("failed to load proofs: %w", err)
+	}
+	err = proofArr.AppendContinuous(&WindowedPoSt{
+		Partitions: partitions,
+		Proofs:     proofs,
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to store proof: %w", err)
+	}
+	if dl.OptimisticPoStSubmissions, err = proofArr.Root(); err != nil {
+		return xerrors.Errorf("failed to save proofs: %w", err)
+	}
+	return nil
+}
+
+// Removes and returns a proof from the snapshot of the previous challenge window's optimistic submissions.
+// Returns false if there is no such proof, e.g. because it was already disputed.
+func (dl *Deadline) TakeSnapshotPoSt(store adt.Store, idx uint64) (*WindowedPoSt, bool, error) {
+	proofArr, err := adt.AsArray(store, dl.OptimisticPoStSubmissionsSnapshot)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load proofs snapshot: %w", err)
+	}
+	var post WindowedPoSt
+	found, err := proofArr.Get(idx, &post)
+	if err != nil {
+		return nil, false, xerrors.Errorf("failed to load proof %d: %w", idx, err)
+	} else if !found {
+		return nil, false, nil
+	}
+	// Remove the proof so that it cannot be disputed again.
+	if err = proofArr.Delete(idx); err != nil {
+		return nil, false, xerrors.Errorf("failed to delete proof %d: %w", idx, err)
+	}
+	if dl.OptimisticPoStSubmissionsSnapshot, err = proofArr.Root(); err != nil {
+		return nil, false, xerrors.Errorf("failed to save proofs snapshot: %w", err)
+	}
+	return &post, true, nil
+}
+
+// Captures the deadline's partitions, the miner's sectors, and the optimistically accepted proofs at the end of
+// the challenge window, so that the proofs may be disputed, and resets the optimistic submissions.
+func (dl *Deadline) SnapshotOptimisticPoSts(store adt.Store, sectors cid.Cid) error {
+	emptyArray, err := adt.MakeEmptyArray(store).Root()
+	if err != nil {
+		return xerrors.Errorf("failed to construct empty proofs array: %w", err)
+	}
+	dl.PartitionsSnapshot = dl.Partitions
+	dl.SectorsSnapshot = sectors
+	dl.OptimisticPoStSubmissionsSnapshot = dl.OptimisticPoStSubmissions
+	dl.OptimisticPoStSubmissions = emptyArray
+	return nil
+}
+
 // Returns nil if nothing was popped.
 func (dl *Deadline) popExpiredPartitions(store adt.Store, until abi.ChainEpoch, quant QuantSpec) (*abi.BitField, bool, error) {
 	expirations, err := LoadBitfieldQueue(store, dl.ExpirationsEpochs, quant)
@@ -465,7 +560,7 @@ func (dl *Deadline) TerminateSectors(
 	for partIdx := range partitionSectors { //nolint:nomaprange
 		partitionIdxs = append(partitionIdxs, partIdx)
 	}
-	sort.Slice(partitionIdxs, func(i, j int) bool { return i < j })
+	sort.Slice(partitionIdxs, func(i, j int) bool { return partitionIdxs[i] < partitionIdxs[j] })
 
 	removedPower = NewPowerPairZero()
 
@@ -505,6 +600,146 @@ func (dl *Deadline) TerminateSectors(
 	return removedPower, nil
 }
 
+// Schedules extended sectors to be renewed when their current expiration is reached.
+func (dl *Deadline) AddRenewals(store adt.Store, sectors []*SectorOnChainInfo, quant QuantSpec) error {
+	if len(sectors) == 0 {
+		return nil
+	}
+	queue, err := LoadBitfieldQueue(store, dl.Renewals, quant)
+	if err != nil {
+		return xerrors.Errorf("failed to load renewal queue: %w", err)
+	}
+	sectorsByEpoch := map[abi.ChainEpoch][]uint64{}
+	for _, sector := range sectors {
+		sectorsByEpoch[sector.Expiration] = append(sectorsByEpoch[sector.Expiration], uint64(sector.SectorNumber))
+	}
+	if err = queue.AddManyToQueueValues(sectorsByEpoch); err != nil {
+		return xerrors.Errorf("failed to mutate renewal queue: %w", err)
+	}
+	if dl.Renewals, err = queue.Root(); err != nil {
+		return xerrors.Errorf("failed to save renewal queue: %w", err)
+	}
+	return nil
+}
+
+// Renews extended sectors whose renewal is due in or before some epoch. Each sector is replaced
+// with one expiring at its extended expiration, with no deals and so raw-byte power.
+// A sector that is faulty (or recovering) when its renewal is due is renewed as a fault, scheduled to expire early
+// at the fault expiration epoch unless it recovers before then. Its fault age thus restarts from the renewal.
+// This must be invoked before the deadline's expired sectors are popped.
+// Returns the renewed sectors, the change in power of the active sectors renewed, and the change in faulty power.
+func (dl *Deadline) RenewExtendedSectors(store adt.Store, sectors Sectors, until, faultExpiration abi.ChainEpoch,
+	ssize abi.SectorSize, quant QuantSpec) (renewed []*SectorOnChainInfo, powerDelta, faultyPowerDelta PowerPair, err error) {
+	queue, err := LoadBitfieldQueue(store, dl.Renewals, quant)
+	if err != nil {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to load renewal queue: %w", err)
+	}
+	due, modified, err := queue.PopUntil(until)
+	if err != nil {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to pop renewal queue: %w", err)
+	} else if !modified {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), nil // nothing to do.
+	}
+	if dl.Renewals, err = queue.Root(); err != nil {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to save renewal queue: %w", err)
+	}
+
+	partitions, err := dl.PartitionsArray(store)
+	if err != nil {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to load partitions: %w", err)
+	}
+
+	powerDelta = NewPowerPairZero()
+	faultyPowerDelta = NewPowerPairZero()
+	var partition Partition
+	for partIdx := uint64(0); partIdx < partitions.Length(); partIdx++ {
+		if found, err := partitions.Get(partIdx, &partition); err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to load partition %d: %w", partIdx, err)
+		} else if !found {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("missing expected partition %d", partIdx)
+		}
+
+		active, err := partition.ActiveSectors()
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), err
+		}
+		partDue, err := bitfield.IntersectBitField(active, due)
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to find renewals in partition %d: %w", partIdx, err)
+		}
+		// Faulty sectors include those recovering.
+		partFaultyDue, err := bitfield.IntersectBitField(partition.Faults, due)
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to find faulty renewals in partition %d: %w", partIdx, err)
+		}
+
+		oldSectors, newSectors, err := loadRenewedSectors(sectors, partDue)
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to load sectors to renew in partition %d: %w", partIdx, err)
+		}
+		partPowerDelta, _, err := partition.ReplaceSectors(store, oldSectors, newSectors, ssize, quant)
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to renew sectors in partition %d: %w", partIdx, err)
+		}
+		oldFaultySectors, newFaultySectors, err := loadRenewedSectors(sectors, partFaultyDue)
+		if err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to load faulty sectors to renew in partition %d: %w", partIdx, err)
+		}
+		if len(newSectors) == 0 && len(newFaultySectors) == 0 {
+			continue
+		}
+		partFaultyPowerDelta := NewPowerPairZero()
+		if len(newFaultySectors) > 0 {
+			partFaultyPowerDelta, err = partition.ReplaceFaultySectors(store, oldFaultySectors, newFaultySectors, faultExpiration, ssize, quant)
+			if err != nil {
+				return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to renew faulty sectors in partition %d: %w", partIdx, err)
+			}
+		}
+
+		if err = partitions.Set(partIdx, &partition); err != nil {
+			return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to store partition %d: %w", partIdx, err)
+		}
+		// Record the partition as having expirations at each new expiration epoch, and at the fault expiration.
+		added := map[abi.ChainEpoch]bool{}
+		if len(newFaultySectors) > 0 {
+			added[faultExpiration] = true
+			if err = dl.AddExpirationPartitions(store, faultExpiration, []uint64{partIdx}, quant); err != nil {
+				return nil, NewPowerPairZero(), NewPowerPairZero(), err
+			}
+		}
+		for _, sector := range append(newSectors, newFaultySectors...) {
+			if added[sector.Expiration] {
+				continue
+			}
+			added[sector.Expiration] = true
+			if err = dl.AddExpirationPartitions(store, sector.Expiration, []uint64{partIdx}, quant); err != nil {
+				return nil, NewPowerPairZero(), NewPowerPairZero(), err
+			}
+		}
+		renewed = append(renewed, newSectors...)
+		renewed = append(renewed, newFaultySectors...)
+		powerDelta = powerDelta.Add(partPowerDelta)
+		faultyPowerDelta = faultyPowerDelta.Add(partFaultyPowerDelta)
+	}
+
+	if dl.Partitions, err = partitions.Root(); err != nil {
+		return nil, NewPowerPairZero(), NewPowerPairZero(), xerrors.Errorf("failed to store partitions: %w", err)
+	}
+	return renewed, powerDelta, faultyPowerDelta, nil
+}
+
+// Loads the sectors due for renewal, returning them along with their renewals.
+func loadRenewedSectors(sectors Sectors, due *abi.BitField) (oldSectors, newSectors []*SectorOnChainInfo, err error) {
+	if oldSectors, err = sectors.Load(due); err != nil {
+		return nil, nil, err
+	}
+	newSectors = make([]*SectorOnChainInfo, len(oldSectors))
+	for i, sector := range oldSectors {
+		newSectors[i] = renewedSector(sector)
+	}
+	return oldSectors, newSectors, nil
+}
+
 // RemovePartitions removes the specified partitions, shifting the remaining
 // ones to the left, and returning the live and dead sectors they contained.
 //
@@ -646,3 +881,14 @@ func (dl *Deadline) RemovePartitions(store adt.Store, toRemove *bitfield.BitFiel
 
 	return live, dead, removedPower, nil
 }
+
+// Returns the sector with which an extended sector is replaced when its current expiration is reached.
+func renewedSector(sector *SectorOnChainInfo) *SectorOnChainInfo {
+	renewed := *sector
+	renewed.Expiration = sector.ExtendedExpiration
+	renewed.ExtendedExpiration = 0
+	renewed.DealIDs = nil
+	renewed.DealWeight = big.Zero()
+	renewed.VerifiedDealWeight = big.Zero()
+	return &renewed
+}
@@ -2,6 +2,7 @@ package miner_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/filecoin-project/go-address"
@@ -10,6 +11,7 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	"github.com/filecoin-project/specs-actors/support/mock"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -146,6 +148,23 @@ func TestDeadlines(t *testing.T) {
 		addThenTerminate(t, rt, dl)
 	})
 
+	t.Run("terminates partitions in index order", func(t *testing.T) {
+		// The first missing partition in index order is reported, independent of map iteration order.
+		for i := 0; i < 10; i++ {
+			rt := builder.Build(t)
+			dl := emptyDeadline(t, rt)
+			addSectors(t, rt, dl)
+
+			toTerminate := map[uint64][]*miner.SectorOnChainInfo{}
+			for partIdx := uint64(3); partIdx < 11; partIdx++ {
+				toTerminate[partIdx] = selectSectors(t, sectors, bf(9))
+			}
+			_, err := dl.TerminateSectors(adt.AsStore(rt), 15, toTerminate, sectorSize, quantSpec)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to find partition 3")
+		}
+	})
+
 	t.Run("pops early terminations", func(t *testing.T) {
 		rt := builder.Build(t)
 		dl := emptyDeadline(t, rt)
@@ -258,6 +277,202 @@ func TestDeadlines(t *testing.T) {
 		}
 	})
 
+	// Extends sectors 1 and 5, which expire at quantized epochs 5 and 13, until epoch 21.
+	// Returns the sectors with their extended expiration.
+	extendSectors := func(t *testing.T, rt *mock.Runtime, dl *miner.Deadline) []*miner.SectorOnChainInfo {
+		extended := append([]*miner.SectorOnChainInfo{}, sectors...)
+		for i, sector := range extended {
+			if sector.SectorNumber == 1 || sector.SectorNumber == 5 {
+				ext := *sector
+				ext.ExtendedExpiration = 21
+				extended[i] = &ext
+			}
+		}
+		require.NoError(t, dl.AddRenewals(adt.AsStore(rt), selectSectors(t, extended, bf(1, 5)), quantSpec))
+		return extended
+	}
+
+	t.Run("renews extended sectors when due", func(t *testing.T) {
+		rt := builder.Build(t)
+		dl := emptyDeadline(t, rt)
+		addSectors(t, rt, dl)
+		store := adt.AsStore(rt)
+		extended := extendSectors(t, rt, dl)
+		sectorsArr := sectorsArray(t, store, extended)
+
+		// Nothing is due before the first expiration.
+		renewed, powerDelta, faultyPowerDelta, err := dl.RenewExtendedSectors(store, sectorsArr, 4, 17, sectorSize, quantSpec)
+		require.NoError(t, err)
+		assert.Empty(t, renewed)
+		assert.True(t, powerDelta.IsZero())
+		assert.True(t, faultyPowerDelta.IsZero())
+
+		renewed, powerDelta, faultyPowerDelta, err = dl.RenewExtendedSectors(store, sectorsArr, 5, 17, sectorSize, quantSpec)
+		require.NoError(t, err)
+		require.Len(t, renewed, 1)
+		assert.True(t, faultyPowerDelta.IsZero())
+		assert.Equal(t, abi.SectorNumber(1), renewed[0].SectorNumber)
+		assert.Equal(t, abi.ChainEpoch(21), renewed[0].Expiration)
+		assert.Equal(t, abi.ChainEpoch(0), renewed[0].ExtendedExpiration)
+		assert.True(t, renewed[0].DealWeight.IsZero())
+		assert.True(t, renewed[0].VerifiedDealWeight.IsZero())
+
+		// The sector keeps its raw-byte power and loses its quality-adjusted power bonus.
+		expectedDelta := miner.PowerForSector(sectorSize, renewed[0]).Sub(miner.PowerForSector(sectorSize, sectors[0]))
+		assert.True(t, expectedDelta.Equals(powerDelta))
+
+		// Only sector 5 remains queued for renewal.
+		queue, err := miner.LoadBitfieldQueue(store, dl.Renewals, quantSpec)
+		require.NoError(t, err)
+		ExpectBQ().Add(13, 5).Equals(t, queue)
+
+		renewedSectors := append([]*miner.SectorOnChainInfo{renewed[0]}, extended[1:]...)
+		expectedDeadlineState{
+			quant:         quantSpec,
+			partitionSize: partitionSize,
+			sectorSize:    sectorSize,
+			sectors:       renewedSectors,
+		}.withPartitions(
+			bf(1, 2, 3, 4),
+			bf(5, 6, 7, 8),
+			bf(9),
+		).assert(t, rt, dl)
+	})
+
+	// Marks partition 0, including sector 1, faulty, with a fault expiration after the sector's current expiration.
+	faultFirstPartition := func(t *testing.T, rt *mock.Runtime, dl *miner.Deadline, recovering []*miner.SectorOnChainInfo) {
+		store := adt.AsStore(rt)
+		partitions, err := dl.PartitionsArray(store)
+		require.NoError(t, err)
+		var part miner.Partition
+		found, err := partitions.Get(0, &part)
+		require.NoError(t, err)
+		require.True(t, found)
+		_, _, err = part.RecordMissedPost(store, 17, quantSpec)
+		require.NoError(t, err)
+		for _, sector := range recovering {
+			require.NoError(t, part.AddRecoveries(bf(uint64(sector.SectorNumber)), miner.PowerForSector(sectorSize, sector)))
+		}
+		require.NoError(t, partitions.Set(0, &part))
+		require.NoError(t, dl.AddExpirationPartitions(store, 17, []uint64{0}, quantSpec))
+		dl.Partitions, err = partitions.Root()
+		require.NoError(t, err)
+	}
+
+	t.Run("faulty extended sector is renewed as a fault", func(t *testing.T) {
+		rt := builder.Build(t)
+		dl := emptyDeadline(t, rt)
+		addSectors(t, rt, dl)
+		store := adt.AsStore(rt)
+		extended := extendSectors(t, rt, dl)
+		faultFirstPartition(t, rt, dl, nil)
+
+		renewed, powerDelta, faultyPowerDelta, err := dl.RenewExtendedSectors(store, sectorsArray(t, store, extended), 5, 17, sectorSize, quantSpec)
+		require.NoError(t, err)
+		require.Len(t, renewed, 1)
+		assert.Equal(t, abi.SectorNumber(1), renewed[0].SectorNumber)
+		assert.Equal(t, abi.ChainEpoch(21), renewed[0].Expiration)
+		assert.True(t, powerDelta.IsZero())
+		expectedDelta := miner.PowerForSector(sectorSize, renewed[0]).Sub(miner.PowerForSector(sectorSize, sectors[0]))
+		assert.True(t, expectedDelta.Equals(faultyPowerDelta))
+
+		renewedSectors := append([]*miner.SectorOnChainInfo{renewed[0]}, extended[1:]...)
+		expectedDeadlineState{
+			quant:         quantSpec,
+			partitionSize: partitionSize,
+			sectorSize:    sectorSize,
+			sectors:       renewedSectors,
+		}.withFaults(1, 2, 3, 4).withPartitions(
+			bf(1, 2, 3, 4),
+			bf(5, 6, 7, 8),
+			bf(9),
+		).assert(t, rt, dl)
+
+		// Sector 2 expires on time, but the renewed sector 1 does not.
+		expired, err := dl.PopExpiredSectors(store, 5, quantSpec)
+		require.NoError(t, err)
+		assertBitfieldEquals(t, expired.OnTimeSectors, 2)
+		assertBitfieldEquals(t, expired.EarlySectors)
+
+		// Unless it recovers, the renewed sector expires early at the fault expiration, after all the others.
+		expired, err = dl.PopExpiredSectors(store, 17, quantSpec)
+		require.NoError(t, err)
+		assertBitfieldEquals(t, expired.OnTimeSectors, 3, 4, 5, 6, 7, 8, 9)
+		assertBitfieldEquals(t, expired.EarlySectors, 1)
+		faultyPower := miner.PowerForSectors(sectorSize, append(selectSectors(t, extended, bf(3, 4)), renewed[0]))
+		assert.True(t, faultyPower.Equals(expired.FaultyPower))
+
+		// Sector 5 remains queued for renewal.
+		queue, err := miner.LoadBitfieldQueue(store, dl.Renewals, quantSpec)
+		require.NoError(t, err)
+		ExpectBQ().Add(13, 5).Equals(t, queue)
+	})
+
+	t.Run("recovering extended sector is renewed as recovering", func(t *testing.T) {
+		rt := builder.Build(t)
+		dl := emptyDeadline(t, rt)
+		addSectors(t, rt, dl)
+		store := adt.AsStore(rt)
+		extended := extendSectors(t, rt, dl)
+		faultFirstPartition(t, rt, dl, selectSectors(t, extended, bf(1)))
+
+		renewed, powerDelta, _, err := dl.RenewExtendedSectors(store, sectorsArray(t, store, extended), 5, 17, sectorSize, quantSpec)
+		require.NoError(t, err)
+		require.Len(t, renewed, 1)
+		assert.True(t, powerDelta.IsZero())
+
+		// The partition's recovering power is that of the renewed sector.
+		partitions, err := dl.PartitionsArray(store)
+		require.NoError(t, err)
+		var part miner.Partition
+		found, err := partitions.Get(0, &part)
+		require.NoError(t, err)
+		require.True(t, found)
+		renewedPower := miner.PowerForSector(sectorSize, renewed[0])
+		assert.True(t, renewedPower.Equals(part.RecoveringPower))
+
+		renewedSectors := append([]*miner.SectorOnChainInfo{renewed[0]}, extended[1:]...)
+		expectedDeadlineState{
+			quant:         quantSpec,
+			partitionSize: partitionSize,
+			sectorSize:    sectorSize,
+			sectors:       renewedSectors,
+		}.withFaults(1, 2, 3, 4).withRecovering(1).withPartitions(
+			bf(1, 2, 3, 4),
+			bf(5, 6, 7, 8),
+			bf(9),
+		).assert(t, rt, dl)
+	})
+
+	t.Run("does not renew terminated extended sectors", func(t *testing.T) {
+		rt := builder.Build(t)
+		dl := emptyDeadline(t, rt)
+		store := adt.AsStore(rt)
+		addThenTerminate(t, rt, dl)
+
+		// Sector 1 was terminated, and remains queued without being renewed.
+		extended := extendSectors(t, rt, dl)
+		renewed, powerDelta, faultyPowerDelta, err := dl.RenewExtendedSectors(store, sectorsArray(t, store, extended), 5, 17, sectorSize, quantSpec)
+		require.NoError(t, err)
+		assert.Empty(t, renewed)
+		assert.True(t, powerDelta.IsZero())
+		assert.True(t, faultyPowerDelta.IsZero())
+
+		queue, err := miner.LoadBitfieldQueue(store, dl.Renewals, quantSpec)
+		require.NoError(t, err)
+		ExpectBQ().Add(13, 5).Equals(t, queue)
+	})
+}
+
+// Stores sectors in a new sectors array, with a sealed CID so they may be serialized.
+func sectorsArray(t *testing.T, store adt.Store, sectors []*miner.SectorOnChainInfo) miner.Sectors {
+	arr := adt.MakeEmptyArray(store)
+	for _, sector := range sectors {
+		stored := *sector
+		stored.SealedCID = tutil.MakeCID(fmt.Sprintf("sealed-%d", sector.SectorNumber), &miner.SealedCIDPrefix)
+		require.NoError(t, arr.Set(uint64(sector.SectorNumber), &stored))
+	}
+	return miner.Sectors{Array: arr}
 }
 
 func emptyDeadline(t *testing.T, rt *mock.Runtime) *miner.Deadline {
@@ -153,3 +153,26 @@ func deadlineIsMutable(provingPeriodStart abi.ChainEpoch, dlIdx uint64, currentE
 	// that deadline opens.
 	return currentEpoch < dlInfo.Open-WPoStChallengeWindow
 }
+
+// Returns the most recent occurrence of the deadline at the given index that has elapsed.
+func lastElapsedDeadline(provingPeriodStart abi.ChainEpoch, dlIdx uint64, currentEpoch abi.ChainEpoch) *DeadlineInfo {
+	dlInfo := NewDeadlineInfo(provingPeriodStart, dlIdx, currentEpoch).NextNotElapsed()
+	return NewDeadlineInfo(dlInfo.PeriodStart-WPoStProvingPeriod, dlIdx, currentEpoch)
+}
+
+// Returns true if optimistically accepted proofs for the deadline at the given index may currently be disputed,
+// i.e. the deadline's most recent challenge window closed no more than WPoStDisputeWindow epochs ago.
+func deadlineAvailableForOptimisticPoStDispute(provingPeriodStart abi.ChainEpoch, dlIdx uint64, currentEpoch abi.ChainEpoch) bool {
+	if provingPeriodStart > currentEpoch {
+		// The miner hasn't started proving yet, so there are no proofs.
+		return false
+	}
+	dlInfo := lastElapsedDeadline(provingPeriodStart, dlIdx, currentEpoch)
+	return currentEpoch < dlInfo.Close+WPoStDisputeWindow
+}
+
+// Returns true if the deadline at the given index may currently be compacted.
+func deadlineAvailableForCompaction(provingPeriodStart abi.ChainEpoch, dlIdx uint64, currentEpoch abi.ChainEpoch) bool {
+	return deadlineIsMutable(provingPeriodStart, dlIdx, currentEpoch) &&
+		!deadlineAvailableForOptimisticPoStDispute(provingPeriodStart, dlIdx, currentEpoch)
+}
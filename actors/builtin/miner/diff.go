@@ -0,0 +1,93 @@
+package miner
+
+import (
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one miner state to another.
+// Deadlines and partitions are compared in depth, loading only those that differ.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "Info", "VestingFunds", "PreCommittedSectors", "Sectors", "Deadlines")
+
+	if err := d.WithField("Info").Object(store, pre.Info, post.Info, new(MinerInfo), new(MinerInfo)); err != nil {
+		return d, err
+	}
+	if err := d.WithField("VestingFunds").Array(store, pre.VestingFunds, post.VestingFunds, func() runtime.CBORUnmarshaler {
+		return new(abi.TokenAmount)
+	}); err != nil {
+		return d, err
+	}
+	if err := d.WithField("PreCommittedSectors").Map(store, pre.PreCommittedSectors, post.PreCommittedSectors, builtin.UIntKeyDecoder, func() runtime.CBORUnmarshaler {
+		return new(SectorPreCommitOnChainInfo)
+	}); err != nil {
+		return d, err
+	}
+	if err := d.WithField("Sectors").Array(store, pre.Sectors, post.Sectors, func() runtime.CBORUnmarshaler {
+		return new(SectorOnChainInfo)
+	}); err != nil {
+		return d, err
+	}
+
+	if pre.Deadlines.Equals(post.Deadlines) {
+		return d, nil
+	}
+	preDeadlines, err := pre.LoadDeadlines(store)
+	if err != nil {
+		return d, err
+	}
+	postDeadlines, err := post.LoadDeadlines(store)
+	if err != nil {
+		return d, err
+	}
+	for dlIdx := range preDeadlines.Due {
+		if preDeadlines.Due[dlIdx].Equals(postDeadlines.Due[dlIdx]) {
+			continue
+		}
+		preDeadline, err := preDeadlines.LoadDeadline(store, uint64(dlIdx))
+		if err != nil {
+			return d, err
+		}
+		postDeadline, err := postDeadlines.LoadDeadline(store, uint64(dlIdx))
+		if err != nil {
+			return d, err
+		}
+		if err := diffDeadlines(d.WithField("Deadlines").WithKey(dlIdx), preDeadline, postDeadline, store); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+func diffDeadlines(d *builtin.DiffAccumulator, pre, post *Deadline, store adt.Store) error {
+	d.CompareExcept(pre, post, "Partitions", "ExpirationsEpochs")
+	if err := d.WithField("ExpirationsEpochs").Array(store, pre.ExpirationsEpochs, post.ExpirationsEpochs, newBitField); err != nil {
+		return err
+	}
+	return d.WithField("Partitions").ArrayFunc(store, pre.Partitions, post.Partitions, func() runtime.CBORUnmarshaler {
+		return new(Partition)
+	}, func(entry *builtin.DiffAccumulator, from, to interface{}) error {
+		if from == nil || to == nil {
+			entry.Compare(from, to)
+			return nil
+		}
+		return diffPartitions(entry, from.(Partition), to.(Partition), store)
+	})
+}
+
+func diffPartitions(d *builtin.DiffAccumulator, pre, post Partition, store adt.Store) error {
+	d.CompareExcept(pre, post, "ExpirationsEpochs", "EarlyTerminated")
+	if err := d.WithField("ExpirationsEpochs").Array(store, pre.ExpirationsEpochs, post.ExpirationsEpochs, func() runtime.CBORUnmarshaler {
+		return new(ExpirationSet)
+	}); err != nil {
+		return err
+	}
+	return d.WithField("EarlyTerminated").Array(store, pre.EarlyTerminated, post.EarlyTerminated, newBitField)
+}
+
+func newBitField() runtime.CBORUnmarshaler {
+	return new(abi.BitField)
+}
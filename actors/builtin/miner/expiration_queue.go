@@ -492,6 +492,22 @@ func (q ExpirationQueue) PopUntil(until abi.ChainEpoch) (*ExpirationSet, error)
 	return NewExpirationSet(allOnTime, allEarly, onTimePledge, activePower, faultyPower), nil
 }
 
+// Invokes a function for each entry in the queue up to and including some epoch, in order of increasing epoch.
+// The queue is not modified.
+func (q ExpirationQueue) ForEachUntil(until abi.ChainEpoch, f func(epoch abi.ChainEpoch, es *ExpirationSet) error) error {
+	var es ExpirationSet
+	stopErr := fmt.Errorf("stop")
+	if err := q.Array.ForEach(&es, func(i int64) error {
+		if abi.ChainEpoch(i) > until {
+			return stopErr
+		}
+		return f(abi.ChainEpoch(i), &es)
+	}); err != nil && err != stopErr {
+		return err
+	}
+	return nil
+}
+
 func (q ExpirationQueue) add(rawEpoch abi.ChainEpoch, onTimeSectors, earlySectors *abi.BitField, activePower, faultyPower PowerPair,
 	pledge abi.TokenAmount) error {
 	epoch := q.quant.QuantizeUp(rawEpoch)
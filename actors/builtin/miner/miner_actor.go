@@ -32,6 +32,7 @@ const (
 	CronEventPreCommitExpiry
 	CronEventProvingDeadline
 	CronEventProcessEarlyTerminations
+	CronEventExitMiner
 )
 
 type CronEventPayload struct {
@@ -68,6 +69,20 @@ func (a Actor) Exports() []interface{} {
 		17:                        a.ConfirmSectorProofsValid,
 		18:                        a.ChangeMultiaddrs,
 		19:                        a.CompactPartitions,
+		20:                        a.ChangeOwnerAddress,
+		21:                        a.PreCommitSectorBatch,
+		22:                        a.ProveCommitAggregate,
+		23:                        a.DisputeWindowedPoSt,
+		24:                        a.RepayDebt,
+		25:                        a.ChangeBeneficiary,
+		26:                        a.GetBeneficiary,
+		27:                        a.ProveReplicaUpdates,
+		28:                        a.DeclareFaultsPartial,
+		29:                        a.DeclareFaultsRecoveredPartial,
+		30:                        a.WithdrawPreCommits,
+		31:                        a.MaskSectorNumbers,
+		32:                        a.ExitMiner,
+		33:                        a.CompactVestingFunds,
 	}
 }
 
@@ -91,6 +106,7 @@ func (a Actor) Constructor(rt Runtime, params *ConstructorParams) *adt.EmptyValu
 
 	owner := resolveOwnerAddress(rt, params.OwnerAddr)
 	worker := resolveWorkerAddress(rt, params.WorkerAddr)
+	controlAddrs := resolveControlAddresses(rt, params.ControlAddrs)
 
 	emptyMap, err := adt.MakeEmptyMap(adt.AsStore(rt)).Root()
 	if err != nil {
@@ -114,7 +130,7 @@ func (a Actor) Constructor(rt Runtime, params *ConstructorParams) *adt.EmptyValu
 	periodStart := nextProvingPeriodStart(currEpoch, offset)
 	Assert(periodStart > currEpoch)
 
-	info, err := ConstructMinerInfo(owner, worker, params.PeerId, params.Multiaddrs, params.SealProofType)
+	info, err := ConstructMinerInfo(owner, worker, controlAddrs, params.PeerId, params.Multiaddrs, params.SealProofType)
 	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to construct initial miner info")
 	infoCid := rt.Store().Put(info)
 
@@ -134,8 +150,9 @@ func (a Actor) Constructor(rt Runtime, params *ConstructorParams) *adt.EmptyValu
 /////////////
 
 type GetControlAddressesReturn struct {
-	Owner  addr.Address
-	Worker addr.Address
+	Owner        addr.Address
+	Worker       addr.Address
+	ControlAddrs []addr.Address
 }
 
 func (a Actor) ControlAddresses(rt Runtime, _ *adt.EmptyValue) *GetControlAddressesReturn {
@@ -144,44 +161,229 @@ func (a Actor) ControlAddresses(rt Runtime, _ *adt.EmptyValue) *GetControlAddres
 	rt.State().Readonly(&st)
 	info := getMinerInfo(rt, &st)
 	return &GetControlAddressesReturn{
-		Owner:  info.Owner,
-		Worker: info.Worker,
+		Owner:        info.Owner,
+		Worker:       info.Worker,
+		ControlAddrs: info.ControlAddresses,
 	}
 }
 
 type ChangeWorkerAddressParams struct {
-	NewWorker addr.Address
+	NewWorker         addr.Address
+	NewControlAddrs   []addr.Address // If non-empty, replaces the control addresses.
+	ClearControlAddrs bool           // If set, removes all control addresses. NewControlAddrs must be empty.
 }
 
+// Replaces or clears the control addresses, if requested, effective immediately, and schedules a change of
+// worker address to take effect after WorkerKeyChangeDelay. If the new worker is the current worker, no key
+// change is scheduled.
 func (a Actor) ChangeWorkerAddress(rt Runtime, params *ChangeWorkerAddressParams) *adt.EmptyValue {
-	var effectiveEpoch abi.ChainEpoch
 	var st State
+	rt.State().Readonly(&st)
+	rt.ValidateImmediateCallerIs(getMinerInfo(rt, &st).Owner)
+	if params.ClearControlAddrs && len(params.NewControlAddrs) > 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "cannot both clear and replace control addresses")
+	}
+
+	// Resolution may send messages, so must happen outside the state transaction.
+	worker := resolveWorkerAddress(rt, params.NewWorker)
+	controlAddrs := resolveControlAddresses(rt, params.NewControlAddrs)
+
+	var keyChange *WorkerKeyChange
 	rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
+		if len(controlAddrs) > 0 || params.ClearControlAddrs {
+			info.ControlAddresses = controlAddrs
+		}
 
-		rt.ValidateImmediateCallerIs(info.Owner)
+		if worker != info.Worker {
+			// This may replace another pending key change.
+			keyChange = &WorkerKeyChange{
+				NewWorker:   worker,
+				EffectiveAt: rt.CurrEpoch() + WorkerKeyChangeDelay,
+			}
+			info.PendingWorkerKey = keyChange
+		}
+		err := st.SaveInfo(adt.AsStore(rt), info)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
+		return nil
+	})
+
+	if keyChange != nil {
+		cronPayload := CronEventPayload{
+			EventType: CronEventWorkerKeyChange,
+		}
+		enrollCronEvent(rt, keyChange.EffectiveAt, &cronPayload)
+	}
+	return nil
+}
+
+// Proposes or confirms a change of owner address.
+// If invoked by the current owner, proposes a new owner address for confirmation. If the proposed address is the
+// current owner address, revokes any existing proposal.
+// If invoked by the previously proposed address, with the same proposal, changes the current owner address to be
+// that proposed address.
+func (a Actor) ChangeOwnerAddress(rt Runtime, newAddress *addr.Address) *adt.EmptyValue {
+	if newAddress.Empty() {
+		rt.Abortf(exitcode.ErrIllegalArgument, "empty address")
+	}
 
-		worker := resolveWorkerAddress(rt, params.NewWorker)
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
 
-		effectiveEpoch = rt.CurrEpoch() + WorkerKeyChangeDelay
+		if rt.Message().Caller() == info.Owner || info.PendingOwner == nil {
+			// Propose a new owner.
+			rt.ValidateImmediateCallerIs(info.Owner)
+			newOwner := resolveOwnerAddress(rt, *newAddress)
+			info.PendingOwner = &PendingOwnerChange{NewOwner: newOwner}
+		} else {
+			// Confirm the proposal.
+			// This validates that the operator can in fact use the proposed new address to send messages.
+			rt.ValidateImmediateCallerIs(info.PendingOwner.NewOwner)
+			newOwner, ok := rt.ResolveAddress(*newAddress)
+			if !ok || newOwner != info.PendingOwner.NewOwner {
+				rt.Abortf(exitcode.ErrIllegalArgument, "expected confirmation of %v, got %v",
+					info.PendingOwner.NewOwner, *newAddress)
+			}
+			// The beneficiary follows the owner, unless it has been changed to some other account.
+			if info.Beneficiary == info.Owner {
+				info.Beneficiary = newOwner
+			}
+			// A beneficiary change proposed by the previous owner is abandoned.
+			info.PendingBeneficiaryTerm = nil
+			info.Owner = newOwner
+		}
 
-		// This may replace another pending key change.
-		info.PendingWorkerKey = &WorkerKeyChange{
-			NewWorker:   worker,
-			EffectiveAt: effectiveEpoch,
+		// Clear any resulting no-op change.
+		if info.PendingOwner != nil && info.PendingOwner.NewOwner == info.Owner {
+			info.PendingOwner = nil
 		}
+
 		err := st.SaveInfo(adt.AsStore(rt), info)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
 		return nil
 	})
+	return nil
+}
 
-	cronPayload := CronEventPayload{
-		EventType: CronEventWorkerKeyChange,
+type ChangeBeneficiaryParams struct {
+	NewBeneficiary addr.Address
+	NewQuota       abi.TokenAmount
+	NewExpiration  abi.ChainEpoch
+}
+
+// Proposes or approves a change of the beneficiary that receives withdrawn funds.
+// If invoked by the owner, proposes a new beneficiary, replacing any existing proposal. Changing the beneficiary
+// back to the owner must be proposed with a zero quota and expiration.
+// If invoked by the nominated or current beneficiary with the same terms as the proposal, approves the proposal.
+// The change takes effect once approved by both. The nominee's approval is implied if it is the owner, and the
+// current beneficiary's approval is implied if it is the owner or if its term has expired or its quota is used up.
+func (a Actor) ChangeBeneficiary(rt Runtime, params *ChangeBeneficiaryParams) *adt.EmptyValue {
+	if params.NewQuota.LessThan(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "negative beneficiary quota %v", params.NewQuota)
 	}
-	enrollCronEvent(rt, effectiveEpoch, &cronPayload)
+
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		caller := rt.Message().Caller()
+
+		if caller == info.Owner {
+			// Propose a new beneficiary.
+			rt.ValidateImmediateCallerIs(info.Owner)
+			newBeneficiary := resolveOwnerAddress(rt, params.NewBeneficiary)
+			if st.Exiting && newBeneficiary != info.Owner {
+				rt.Abortf(exitcode.ErrForbidden, "cannot change beneficiary while exiting")
+			}
+			if newBeneficiary == info.Owner {
+				if !params.NewQuota.IsZero() || params.NewExpiration != 0 {
+					rt.Abortf(exitcode.ErrIllegalArgument, "owner as beneficiary must have zero quota and expiration, got %v and %d",
+						params.NewQuota, params.NewExpiration)
+				}
+			} else if !params.NewQuota.GreaterThan(big.Zero()) {
+				rt.Abortf(exitcode.ErrIllegalArgument, "beneficiary quota %v must be positive", params.NewQuota)
+			} else if params.NewExpiration <= rt.CurrEpoch() {
+				rt.Abortf(exitcode.ErrIllegalArgument, "beneficiary expiration %d must be after current epoch %d",
+					params.NewExpiration, rt.CurrEpoch())
+			}
+
+			currentTerm := info.BeneficiaryTerm
+			info.PendingBeneficiaryTerm = &PendingBeneficiaryChange{
+				NewBeneficiary: newBeneficiary,
+				NewQuota:       params.NewQuota,
+				NewExpiration:  params.NewExpiration,
+				ApprovedByBeneficiary: info.Beneficiary == info.Owner ||
+					currentTerm.IsExpired(rt.CurrEpoch()) || currentTerm.IsUsedUp(),
+				ApprovedByNominee: newBeneficiary == info.Owner,
+			}
+		} else {
+			// Approve the proposal.
+			pending := info.PendingBeneficiaryTerm
+			if pending == nil {
+				rt.Abortf(exitcode.ErrForbidden, "no beneficiary change proposed")
+			}
+			rt.ValidateImmediateCallerIs(pending.NewBeneficiary, info.Beneficiary)
+			newBeneficiary, ok := rt.ResolveAddress(params.NewBeneficiary)
+			if !ok || newBeneficiary != pending.NewBeneficiary || !params.NewQuota.Equals(pending.NewQuota) ||
+				params.NewExpiration != pending.NewExpiration {
+				rt.Abortf(exitcode.ErrIllegalArgument, "expected approval of %v with quota %v and expiration %d, got %v, %v and %d",
+					pending.NewBeneficiary, pending.NewQuota, pending.NewExpiration,
+					params.NewBeneficiary, params.NewQuota, params.NewExpiration)
+			}
+			if pending.NewBeneficiary != info.Owner && pending.NewExpiration <= rt.CurrEpoch() {
+				rt.Abortf(exitcode.ErrForbidden, "proposed beneficiary term expired at %d", pending.NewExpiration)
+			}
+			// The caller may be both the nominee and the current beneficiary.
+			if caller == pending.NewBeneficiary {
+				pending.ApprovedByNominee = true
+			}
+			if caller == info.Beneficiary {
+				pending.ApprovedByBeneficiary = true
+			}
+		}
+
+		if pending := info.PendingBeneficiaryTerm; pending.ApprovedByBeneficiary && pending.ApprovedByNominee {
+			info.Beneficiary = pending.NewBeneficiary
+			info.BeneficiaryTerm = BeneficiaryTerm{
+				Quota:      pending.NewQuota,
+				UsedQuota:  big.Zero(),
+				Expiration: pending.NewExpiration,
+			}
+			info.PendingBeneficiaryTerm = nil
+		}
+
+		err := st.SaveInfo(adt.AsStore(rt), info)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
+		return nil
+	})
 	return nil
 }
 
+type ActiveBeneficiary struct {
+	Beneficiary addr.Address
+	Term        BeneficiaryTerm
+}
+
+type GetBeneficiaryReturn struct {
+	Active   ActiveBeneficiary
+	Proposed *PendingBeneficiaryChange
+}
+
+// Returns the current beneficiary and its term, and any proposed change of beneficiary.
+func (a Actor) GetBeneficiary(rt Runtime, _ *adt.EmptyValue) *GetBeneficiaryReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+	var st State
+	rt.State().Readonly(&st)
+	info := getMinerInfo(rt, &st)
+	return &GetBeneficiaryReturn{
+		Active: ActiveBeneficiary{
+			Beneficiary: info.Beneficiary,
+			Term:        info.BeneficiaryTerm,
+		},
+		Proposed: info.PendingBeneficiaryTerm,
+	}
+}
+
 type ChangePeerIDParams struct {
 	NewID abi.PeerID
 }
@@ -193,7 +395,7 @@ func (a Actor) ChangePeerID(rt Runtime, params *ChangePeerIDParams) *adt.EmptyVa
 	rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
 
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
 		info.PeerId = params.NewID
 		err := st.SaveInfo(adt.AsStore(rt), info)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
@@ -212,7 +414,7 @@ func (a Actor) ChangeMultiaddrs(rt Runtime, params *ChangeMultiaddrsParams) *adt
 	var st State
 	rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
 		info.Multiaddrs = params.NewMultiaddrs
 		err := st.SaveInfo(adt.AsStore(rt), info)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not save miner info")
@@ -243,7 +445,10 @@ type SubmitWindowedPoStParams struct {
 	Proofs []abi.PoStProof
 }
 
-// Invoked by miner's worker address to submit their fallback post
+// Invoked by miner's worker address to submit their fallback post.
+// A proof that recovers no faulty power is accepted optimistically, without verification, and may be disputed
+// with DisputeWindowedPoSt for a period after the deadline's challenge window closes.
+// A proof that recovers power is verified immediately.
 func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams) *adt.EmptyValue {
 	currEpoch := rt.CurrEpoch()
 	store := adt.AsStore(rt)
@@ -267,7 +472,7 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 	var info *MinerInfo
 	rt.State().Transaction(&st, func() interface{} {
 		info = getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
 
 		// Validate that the miner didn't try to prove too many partitions at once.
 		submissionPartitionLimit := loadPartitionsSectorsMax(info.WindowPoStPartitionSectors)
@@ -303,6 +508,7 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 		faultExpiration := currDeadline.Last() + FaultMaxAge
 
 		partitionIdxs := make([]uint64, 0, len(params.Partitions))
+		var faultyPartitionIdxs []uint64 // partitions with new faults, which may now expire early
 		allSectors := make([]*abi.BitField, 0, len(params.Partitions))
 		allIgnored := make([]*abi.BitField, 0, len(params.Partitions))
 
@@ -335,7 +541,10 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 			err = partitions.Set(post.Index, &partition)
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update partition %v", key)
 
-			st.FaultyPower = st.FaultyPower.Add(newFaultPowerTotal)
+			st.FaultyPower = st.FaultyPower.Add(newFaultPower).Sub(recoveredPower)
+			if !newFaultPower.IsZero() {
+				faultyPartitionIdxs = append(faultyPartitionIdxs, post.Index)
+			}
 
 			newFaultPowerTotal = newFaultPowerTotal.Add(newFaultPower)
 			retractedRecoveryPowerTotal = retractedRecoveryPowerTotal.Add(retractedRecoveryPower)
@@ -349,24 +558,31 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 			allIgnored = append(allIgnored, partition.Terminated)
 		}
 
-		// Collect all sectors, faults, and recoveries for proof verification.
-		allSectorNos, err := bitfield.MultiMerge(allSectors...)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge all sectors bitfields")
-		allIgnoredNos, err := bitfield.MultiMerge(allIgnored...)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge ignored sectors bitfields")
-
-		// Load sector infos for proof, substituting a known-good sector for known-faulty sectors.
-		// Note: this is slightly sub-optimal, loading info for the recovering sectors again after they were already
-		// loaded above.
-		sectorInfos, err := st.LoadSectorInfosForProof(store, allSectorNos, allIgnoredNos)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load proven sector info")
-
-		// Skip verification if all sectors are faults.
-		// We still need to allow this call to succeed so the miner can declare a whole partition as skipped.
-		if len(sectorInfos) > 0 {
-			// Verify the proof.
+		if recoveredPowerTotal.IsZero() {
+			// If the proof recovers no power, accept it optimistically, to be verified only if disputed.
+			if len(partitionIdxs) > 0 {
+				err = deadline.RecordOptimisticPoSt(store, bitfield.NewFromSet(partitionIdxs), params.Proofs)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record proof for deadline %d", params.Deadline)
+			}
+		} else {
+			// Collect all sectors, faults, and recoveries for proof verification.
+			allSectorNos, err := bitfield.MultiMerge(allSectors...)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge all sectors bitfields")
+			allIgnoredNos, err := bitfield.MultiMerge(allIgnored...)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge ignored sectors bitfields")
+
+			// Load sector infos for proof, substituting a known-good sector for known-faulty sectors.
+			// Note: this is slightly sub-optimal, loading info for the recovering sectors again after they were already
+			// loaded above.
+			sectorInfos, err := st.LoadSectorInfosForProof(store, allSectorNos, allIgnoredNos)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load proven sector info")
+
+			// Verify the proof immediately, since recovered power must not be restored on the strength of a
+			// proof that might later turn out to be invalid.
+			// Recovered sectors are never faulty, so there is always at least one sector to verify.
 			// A failed verification doesn't immediately cause a penalty; the miner can try again.
-			verifyWindowedPost(rt, currDeadline.Challenge, sectorInfos, params.Proofs)
+			err = verifyWindowedPost(rt, currDeadline.Challenge, sectorInfos, params.Proofs)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to verify window PoSt")
 		}
 
 		// Penalize new skipped faults and retracted recoveries as undeclared faults.
@@ -394,6 +610,10 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 		// Record the successful submission
 		deadline.AddPoStSubmissions(partitionIdxs)
 
+		// Record partitions with sectors now scheduled to expire at the fault expiration.
+		err = deadline.AddExpirationPartitions(store, faultExpiration, faultyPartitionIdxs, st.QuantEndOfDeadline())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add fault expirations for deadline %d", params.Deadline)
+
 		// Save everything back.
 		deadline.Partitions, err = partitions.Root()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store partitions")
@@ -418,6 +638,157 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 	return nil
 }
 
+type DisputeWindowedPoStParams struct {
+	Deadline  uint64
+	PoStIndex uint64 // Only one proof may be disputed at a time, to bound the number of sector infos loaded.
+}
+
+// Disputes a window PoSt that was accepted optimistically during the most recent challenge window of a deadline.
+// The dispute must be made within WPoStDisputeWindow epochs after the challenge window closes.
+// If the proof is invalid, the sectors it claimed to prove are marked faulty, the miner is penalized,
+// and the disputer is rewarded from the penalty. A dispute of a valid proof fails.
+func (a Actor) DisputeWindowedPoSt(rt Runtime, params *DisputeWindowedPoStParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerType(builtin.CallerTypesSignable...)
+	reporter := rt.Message().Caller()
+
+	if params.Deadline >= WPoStPeriodDeadlines {
+		rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d of %d", params.Deadline, WPoStPeriodDeadlines)
+	}
+	currEpoch := rt.CurrEpoch()
+
+	// Note: the reward and power estimates are taken at the time of the dispute rather than the submission.
+	epochReward := requestCurrentEpochBlockReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+
+	powerDelta := NewPowerPairZero()
+	penaltyTotal := abi.NewTokenAmount(0)
+	pledgeDelta := abi.NewTokenAmount(0)
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		if !deadlineAvailableForOptimisticPoStDispute(st.ProvingPeriodStart, params.Deadline, currEpoch) {
+			rt.Abortf(exitcode.ErrForbidden, "can only dispute window posts within %d epochs after the challenge window closes",
+				WPoStDisputeWindow)
+		}
+		info := getMinerInfo(rt, &st)
+		targetDeadline := lastElapsedDeadline(st.ProvingPeriodStart, params.Deadline, currEpoch)
+
+		deadlines, err := st.LoadDeadlines(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+		deadline, err := deadlines.LoadDeadline(store, params.Deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", params.Deadline)
+
+		// Take the proof from the snapshot, so it cannot be disputed again.
+		post, found, err := deadline.TakeSnapshotPoSt(store, params.PoStIndex)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load proof %d for deadline %d", params.PoStIndex, params.Deadline)
+		if !found {
+			rt.Abortf(exitcode.ErrNotFound, "no proof %d for deadline %d", params.PoStIndex, params.Deadline)
+		}
+
+		// Collect the sectors claimed to be proven, as of the end of the challenge window.
+		partitionsSnapshot, err := adt.AsArray(store, deadline.PartitionsSnapshot)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions snapshot for deadline %d", params.Deadline)
+		var allSectors, allIgnored []*abi.BitField
+		provenSectors := map[uint64]*abi.BitField{}
+		err = post.Partitions.ForEach(func(partIdx uint64) error {
+			var partition Partition
+			found, err := partitionsSnapshot.Get(partIdx, &partition)
+			if err != nil {
+				return err
+			} else if !found {
+				return fmt.Errorf("no partition %d", partIdx)
+			}
+			proven, err := partition.ActiveSectors()
+			if err != nil {
+				return err
+			}
+			provenSectors[partIdx] = proven
+			allSectors = append(allSectors, partition.Sectors)
+			allIgnored = append(allIgnored, partition.Faults, partition.Terminated)
+			return nil
+		})
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions snapshot for deadline %d", params.Deadline)
+
+		allSectorNos, err := bitfield.MultiMerge(allSectors...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge all sectors bitfields")
+		allIgnoredNos, err := bitfield.MultiMerge(allIgnored...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to merge ignored sectors bitfields")
+		sectorInfos, err := loadSectorInfosForProof(store, deadline.SectorsSnapshot, allSectorNos, allIgnoredNos)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load proven sector info")
+
+		// A proof of only faulty sectors proves nothing, so is trivially valid.
+		if len(sectorInfos) == 0 || verifyWindowedPost(rt, targetDeadline.Challenge, sectorInfos, post.Proofs) == nil {
+			rt.Abortf(exitcode.ErrIllegalArgument, "failed to dispute valid post")
+		}
+
+		// The proof is invalid. Mark the sectors it claimed to prove as faulty, if they are still active.
+		partitions, err := deadline.PartitionsArray(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d partitions", params.Deadline)
+		faultExpiration := targetDeadline.Last() + FaultMaxAge
+		var faultyPartitionIdxs []uint64
+		newFaultPowerTotal := NewPowerPairZero()
+		retractedRecoveryPowerTotal := NewPowerPairZero()
+		err = post.Partitions.ForEach(func(partIdx uint64) error {
+			key := PartitionKey{params.Deadline, partIdx}
+			var partition Partition
+			found, err := partitions.Get(partIdx, &partition)
+			if err != nil {
+				return fmt.Errorf("failed to load partition %v: %w", key, err)
+			} else if !found {
+				return fmt.Errorf("no partition %v", key)
+			}
+
+			newFaultPower, retractedRecoveryPower := processSkippedFaults(rt, &st, store, faultExpiration, &partition, provenSectors[partIdx], info.SectorSize)
+			if !newFaultPower.IsZero() {
+				faultyPartitionIdxs = append(faultyPartitionIdxs, partIdx)
+			}
+			newFaultPowerTotal = newFaultPowerTotal.Add(newFaultPower)
+			retractedRecoveryPowerTotal = retractedRecoveryPowerTotal.Add(retractedRecoveryPower)
+
+			if err = partitions.Set(partIdx, &partition); err != nil {
+				return fmt.Errorf("failed to update partition %v: %w", key, err)
+			}
+			return nil
+		})
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record faults for deadline %d", params.Deadline)
+
+		st.FaultyPower = st.FaultyPower.Add(newFaultPowerTotal)
+		powerDelta = powerDelta.Sub(newFaultPowerTotal)
+
+		err = deadline.AddExpirationPartitions(store, faultExpiration, faultyPartitionIdxs, st.QuantEndOfDeadline())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add fault expirations for deadline %d", params.Deadline)
+
+		deadline.Partitions, err = partitions.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store partitions")
+		err = deadlines.UpdateDeadline(store, params.Deadline, deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update deadline %d", params.Deadline)
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
+
+		// Penalize the faulted power as undeclared faults, plus a flat penalty for the invalid proof.
+		penaltyPower := newFaultPowerTotal.Add(retractedRecoveryPowerTotal)
+		penaltyTarget := PledgePenaltyForInvalidWindowPoSt(epochReward, pwrTotal.QualityAdjPower, penaltyPower.QA)
+		unlockedBalance := st.GetUnlockedBalance(rt.CurrentBalance())
+		penaltyFromVesting, penaltyFromBalance, err := st.PenalizeFundsInPriorityOrder(store, currEpoch, penaltyTarget, unlockedBalance)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to unlock penalty for invalid PoSt")
+		penaltyTotal = big.Add(penaltyFromVesting, penaltyFromBalance)
+		pledgeDelta = big.Sub(pledgeDelta, penaltyFromVesting)
+		return nil
+	})
+
+	// Reward the disputer from the penalty, and burn the remainder.
+	disputerReward := big.Min(RewardForDisputedWindowPoSt(), penaltyTotal)
+	if disputerReward.GreaterThan(big.Zero()) {
+		_, code := rt.Send(reporter, builtin.MethodSend, nil, disputerReward)
+		builtin.RequireSuccess(rt, code, "failed to reward disputer")
+	}
+
+	requestUpdatePower(rt, powerDelta)
+	burnFunds(rt, big.Sub(penaltyTotal, disputerReward))
+	notifyPledgeChanged(rt, pledgeDelta)
+	return nil
+}
+
 ///////////////////////
 // Sector Commitment //
 ///////////////////////
@@ -425,109 +796,176 @@ func (a Actor) SubmitWindowedPoSt(rt Runtime, params *SubmitWindowedPoStParams)
 // Proposals must be posted on chain via sma.PublishStorageDeals before PreCommitSector.
 // Optimization: PreCommitSector could contain a list of deals that are not published yet.
 func (a Actor) PreCommitSector(rt Runtime, params *SectorPreCommitInfo) *adt.EmptyValue {
-	if _, ok := SupportedProofTypes[params.SealProof]; !ok {
-		rt.Abortf(exitcode.ErrIllegalArgument, "unsupported seal proof type: %s", params.SealProof)
-	}
-	if params.SectorNumber > abi.MaxSectorNumber {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sector number %d out of range 0..(2^63-1)", params.SectorNumber)
-	}
-	if !params.SealedCID.Defined() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID undefined")
-	}
-	if params.SealedCID.Prefix() != SealedCIDPrefix {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID had wrong prefix")
-	}
-	if params.SealRandEpoch >= rt.CurrEpoch() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v must be before now %v", params.SealRandEpoch, rt.CurrEpoch())
-	}
+	preCommitSectors(rt, []*SectorPreCommitInfo{params})
+	return nil
+}
 
-	challengeEarliest := sealChallengeEarliest(rt.CurrEpoch(), params.SealProof)
-	if params.SealRandEpoch < challengeEarliest {
-		// The subsequent commitment proof can't possibly be accepted because the seal challenge will be deemed
-		// too old. Note that passing this check doesn't guarantee the proof will be soon enough, depending on
-		// when it arrives.
-		rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v too old, must be after %v", params.SealRandEpoch, challengeEarliest)
-	}
+type PreCommitSectorBatchParams struct {
+	Sectors []SectorPreCommitInfo
+}
 
-	if params.Expiration <= rt.CurrEpoch() {
-		rt.Abortf(exitcode.ErrIllegalArgument, "sector expiration %v must be after now (%v)", params.Expiration, rt.CurrEpoch())
-	}
-	if params.ReplaceCapacity && len(params.DealIDs) == 0 {
-		rt.Abortf(exitcode.ErrIllegalArgument, "cannot replace sector without committing deals")
+// Pre-commits a batch of sectors, validating them together. The deal weights for all sectors are requested
+// from the market in a single call, and a single cron event is enrolled for the expiry of all the pre-commitments.
+// The whole batch fails if any one pre-commitment is invalid.
+func (a Actor) PreCommitSectorBatch(rt Runtime, params *PreCommitSectorBatchParams) *adt.EmptyValue {
+	if len(params.Sectors) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "batch empty")
+	} else if len(params.Sectors) > PreCommitSectorBatchMaxSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "batch of %d too large, max %d", len(params.Sectors), PreCommitSectorBatchMaxSize)
 	}
-	if params.ReplaceSectorDeadline >= WPoStPeriodDeadlines {
-		rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d", params.ReplaceSectorDeadline)
+	sectors := make([]*SectorPreCommitInfo, len(params.Sectors))
+	for i := range params.Sectors {
+		sectors[i] = &params.Sectors[i]
 	}
-	if params.ReplaceSectorNumber > abi.MaxSectorNumber {
-		rt.Abortf(exitcode.ErrIllegalArgument, "invalid sector number %d", params.ReplaceSectorNumber)
+	preCommitSectors(rt, sectors)
+	return nil
+}
+
+func preCommitSectors(rt Runtime, sectors []*SectorPreCommitInfo) {
+	sectorNumbers := make(map[abi.SectorNumber]struct{}, len(sectors))
+	for _, params := range sectors {
+		if _, ok := SupportedProofTypes[params.SealProof]; !ok {
+			rt.Abortf(exitcode.ErrIllegalArgument, "unsupported seal proof type: %s", params.SealProof)
+		}
+		if params.SectorNumber > abi.MaxSectorNumber {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sector number %d out of range 0..(2^63-1)", params.SectorNumber)
+		}
+		if _, ok := sectorNumbers[params.SectorNumber]; ok {
+			rt.Abortf(exitcode.ErrIllegalArgument, "duplicate sector number %d", params.SectorNumber)
+		}
+		sectorNumbers[params.SectorNumber] = struct{}{}
+		if !params.SealedCID.Defined() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID undefined")
+		}
+		if params.SealedCID.Prefix() != SealedCIDPrefix {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sealed CID had wrong prefix")
+		}
+		if params.SealRandEpoch >= rt.CurrEpoch() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v must be before now %v", params.SealRandEpoch, rt.CurrEpoch())
+		}
+
+		challengeEarliest := sealChallengeEarliest(rt.CurrEpoch(), params.SealProof)
+		if params.SealRandEpoch < challengeEarliest {
+			// The subsequent commitment proof can't possibly be accepted because the seal challenge will be deemed
+			// too old. Note that passing this check doesn't guarantee the proof will be soon enough, depending on
+			// when it arrives.
+			rt.Abortf(exitcode.ErrIllegalArgument, "seal challenge epoch %v too old, must be after %v", params.SealRandEpoch, challengeEarliest)
+		}
+
+		if params.Expiration <= rt.CurrEpoch() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "sector expiration %v must be after now (%v)", params.Expiration, rt.CurrEpoch())
+		}
+		if params.ReplaceCapacity && len(params.DealIDs) == 0 {
+			rt.Abortf(exitcode.ErrIllegalArgument, "cannot replace sector without committing deals")
+		}
+		if params.ReplaceSectorDeadline >= WPoStPeriodDeadlines {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d", params.ReplaceSectorDeadline)
+		}
+		if params.ReplaceSectorNumber > abi.MaxSectorNumber {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid sector number %d", params.ReplaceSectorNumber)
+		}
 	}
 
 	// gather information from other actors
 	_, epochReward := requestCurrentEpochBaselinePowerAndReward(rt)
 	pwrTotal := requestCurrentTotalPower(rt)
-	dealWeight := requestDealWeight(rt, params.DealIDs, rt.CurrEpoch(), params.Expiration)
+	sectorDeals := make([]market.SectorDeals, len(sectors))
+	for i, sector := range sectors {
+		sectorDeals[i] = market.SectorDeals{
+			SectorExpiry: sector.Expiration,
+			DealIDs:      sector.DealIDs,
+		}
+	}
+	dealWeights := requestDealWeights(rt, sectorDeals)
 
 	store := adt.AsStore(rt)
 	var st State
+	var sealProof abi.RegisteredSealProof
 	newlyVestedAmount := rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
-		if params.SealProof != info.SealProofType {
-			rt.Abortf(exitcode.ErrIllegalArgument, "sector seal proof %v must match miner seal proof type %d", params.SealProof, info.SealProofType)
-		}
+		validateWorkerOrControlCaller(rt, info)
+		sealProof = info.SealProofType
 
-		maxDealLimit := dealPerSectorLimit(info.SectorSize)
-		if uint64(len(params.DealIDs)) > maxDealLimit {
-			rt.Abortf(exitcode.ErrIllegalArgument, "too many deals for sector %d > %d", len(params.DealIDs), maxDealLimit)
-		}
+		// Stop miners with unpaid penalties from committing new sectors.
+		verifyNoFeeDebt(rt, &st)
+		verifyNotExiting(rt, &st)
 
-		_, preCommitFound, err := st.GetPrecommittedSector(store, params.SectorNumber)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check pre-commit %v", params.SectorNumber)
-		if preCommitFound {
-			rt.Abortf(exitcode.ErrIllegalArgument, "sector %v already pre-committed", params.SectorNumber)
-		}
+		newlyVestedFund, err := st.UnlockVestedFunds(store, rt.CurrEpoch())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to vest funds")
+		availableBalance := st.GetAvailableBalance(rt.CurrentBalance())
+		totalDepositRequired := big.Zero()
 
-		sectorFound, err := st.HasSectorNo(store, params.SectorNumber)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %v", params.SectorNumber)
-		if sectorFound {
-			rt.Abortf(exitcode.ErrIllegalArgument, "sector %v already committed", params.SectorNumber)
-		}
+		for i, params := range sectors {
+			if params.SealProof != info.SealProofType {
+				rt.Abortf(exitcode.ErrIllegalArgument, "sector seal proof %v must match miner seal proof type %d", params.SealProof, info.SealProofType)
+			}
 
-		validateExpiration(rt, rt.CurrEpoch(), params.Expiration, params.SealProof)
+			maxDealLimit := dealPerSectorLimit(info.SectorSize)
+			if uint64(len(params.DealIDs)) > maxDealLimit {
+				rt.Abortf(exitcode.ErrIllegalArgument, "too many deals for sector %d > %d", len(params.DealIDs), maxDealLimit)
+			}
 
-		depositMinimum := big.Zero()
-		if params.ReplaceCapacity {
-			replaceSector := validateReplaceSector(rt, &st, store, params)
-			// Note the replaced sector's initial pledge as a lower bound for the new sector's deposit
-			depositMinimum = replaceSector.InitialPledge
-		}
+			_, preCommitFound, err := st.GetPrecommittedSector(store, params.SectorNumber)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check pre-commit %v", params.SectorNumber)
+			if preCommitFound {
+				rt.Abortf(exitcode.ErrIllegalArgument, "sector %v already pre-committed", params.SectorNumber)
+			}
 
-		newlyVestedFund, err := st.UnlockVestedFunds(store, rt.CurrEpoch())
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to vest funds")
-		availableBalance := st.GetAvailableBalance(rt.CurrentBalance())
-		duration := params.Expiration - rt.CurrEpoch()
+			sectorFound, err := st.HasSectorNo(store, params.SectorNumber)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %v", params.SectorNumber)
+			if sectorFound {
+				rt.Abortf(exitcode.ErrIllegalArgument, "sector %v already committed", params.SectorNumber)
+			}
 
-		sectorWeight := QAPowerForWeight(info.SectorSize, duration, dealWeight.DealWeight, dealWeight.VerifiedDealWeight)
-		depositReq := big.Max(
-			PreCommitDepositForPower(epochReward, pwrTotal.QualityAdjPower, sectorWeight),
-			depositMinimum,
-		)
-		if availableBalance.LessThan(depositReq) {
-			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for pre-commit deposit: %v", depositReq)
+			allocated, err := st.IsSectorNumberAllocated(params.SectorNumber)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector number %v", params.SectorNumber)
+			if allocated {
+				rt.Abortf(exitcode.ErrIllegalArgument, "sector number %v already allocated", params.SectorNumber)
+			}
+
+			validateExpiration(rt, rt.CurrEpoch(), params.Expiration, params.SealProof)
+
+			depositMinimum := big.Zero()
+			if params.ReplaceCapacity {
+				replaceSector := validateReplaceSector(rt, &st, store, params)
+				// Note the replaced sector's initial pledge as a lower bound for the new sector's deposit
+				depositMinimum = replaceSector.InitialPledge
+			}
+
+			duration := params.Expiration - rt.CurrEpoch()
+			dealWeight := dealWeights.Sectors[i]
+			sectorWeight := QAPowerForWeight(info.SectorSize, duration, dealWeight.DealWeight, dealWeight.VerifiedDealWeight)
+			depositReq := big.Max(
+				PreCommitDepositForPower(epochReward, pwrTotal.QualityAdjPower, sectorWeight),
+				depositMinimum,
+			)
+			totalDepositRequired = big.Add(totalDepositRequired, depositReq)
+
+			if err := st.PutPrecommittedSector(store, &SectorPreCommitOnChainInfo{
+				Info:                *params,
+				PreCommitDeposit:    depositReq,
+				PreCommitEpoch:      rt.CurrEpoch(),
+				DealWeight:          dealWeight.DealWeight,
+				VerifiedDealWeight:  dealWeight.VerifiedDealWeight,
+				ProofSubmittedEpoch: NoProofSubmittedEpoch,
+			}); err != nil {
+				rt.Abortf(exitcode.ErrIllegalState, "failed to write pre-committed sector %v: %v", params.SectorNumber, err)
+			}
 		}
 
-		st.AddPreCommitDeposit(depositReq)
-		st.AssertBalanceInvariants(rt.CurrentBalance())
+		if availableBalance.LessThan(totalDepositRequired) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for pre-commit deposit: %v", totalDepositRequired)
+		}
 
-		if err := st.PutPrecommittedSector(store, &SectorPreCommitOnChainInfo{
-			Info:               *params,
-			PreCommitDeposit:   depositReq,
-			PreCommitEpoch:     rt.CurrEpoch(),
-			DealWeight:         dealWeight.DealWeight,
-			VerifiedDealWeight: dealWeight.VerifiedDealWeight,
-		}); err != nil {
-			rt.Abortf(exitcode.ErrIllegalState, "failed to write pre-committed sector %v: %v", params.SectorNumber, err)
+		sectorNos := abi.NewBitField()
+		for _, params := range sectors {
+			sectorNos.Set(uint64(params.SectorNumber))
 		}
+		err = st.MaskSectorNumbers(sectorNos)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to allocate sector numbers")
+
+		st.AddPreCommitDeposit(totalDepositRequired)
+		st.AssertBalanceInvariants(rt.CurrentBalance())
 
 		return newlyVestedFund
 	}).(abi.TokenAmount)
@@ -535,7 +973,9 @@ func (a Actor) PreCommitSector(rt Runtime, params *SectorPreCommitInfo) *adt.Emp
 	notifyPledgeChanged(rt, newlyVestedAmount.Neg())
 
 	bf := abi.NewBitField()
-	bf.Set(uint64(params.SectorNumber))
+	for _, params := range sectors {
+		bf.Set(uint64(params.SectorNumber))
+	}
 
 	// Request deferred Cron check for PreCommit expiry check.
 	cronPayload := CronEventPayload{
@@ -543,9 +983,10 @@ func (a Actor) PreCommitSector(rt Runtime, params *SectorPreCommitInfo) *adt.Emp
 		Sectors:   bf,
 	}
 
-	msd, ok := MaxSealDuration[params.SealProof]
+	// All sectors have the miner's seal proof type, and so the same max seal duration.
+	msd, ok := MaxSealDuration[sealProof]
 	if !ok {
-		rt.Abortf(exitcode.ErrIllegalArgument, "no max seal duration set for proof type: %d", params.SealProof)
+		rt.Abortf(exitcode.ErrIllegalArgument, "no max seal duration set for proof type: %d", sealProof)
 	}
 
 	// The +1 here is critical for the batch verification of proofs. Without it, if a proof arrived exactly on the
@@ -553,42 +994,124 @@ func (a Actor) PreCommitSector(rt Runtime, params *SectorPreCommitInfo) *adt.Emp
 	// ConfirmSectorProofsValid would fail to find it.
 	expiryBound := rt.CurrEpoch() + msd + 1
 	enrollCronEvent(rt, expiryBound, &cronPayload)
-
-	return nil
 }
 
-type ProveCommitSectorParams struct {
-	SectorNumber abi.SectorNumber
-	Proof        []byte
+type WithdrawPreCommitsParams struct {
+	Sectors *abi.BitField
 }
 
-// Checks state of the corresponding sector pre-commitment, then schedules the proof to be verified in bulk
-// by the power actor.
-// If valid, the power actor will call ConfirmSectorProofsValid at the end of the same epoch as this message.
-func (a Actor) ProveCommitSector(rt Runtime, params *ProveCommitSectorParams) *adt.EmptyValue {
-	rt.ValidateImmediateCallerAcceptAny()
+// Withdraws pre-committed sectors that have not yet been proven. The sector numbers are not freed: they remain
+// allocated, and may not be pre-committed again, so a number is never used for two different sealed sectors.
+// Part of each pre-commit deposit is burnt as a penalty, and the remainder returned to available balance.
+// A sector with a proof awaiting verification, which happens at the end of the epoch in which the proof was
+// submitted, may not be withdrawn.
+func (a Actor) WithdrawPreCommits(rt Runtime, params *WithdrawPreCommitsParams) *adt.EmptyValue {
+	count, err := params.Sectors.Count()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to count sectors")
+	if count > AddressedSectorsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many sectors for withdrawal %d, max %d", count, AddressedSectorsMax)
+	}
 
 	store := adt.AsStore(rt)
 	var st State
-	rt.State().Readonly(&st)
+	penalty := rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		validateWorkerOrControlCaller(rt, info)
 
-	// Verify locked funds are are at least the sum of sector initial pledges.
-	// Note that this call does not actually compute recent vesting, so the reported locked funds may be
-	// slightly higher than the true amount (i.e. slightly in the miner's favour).
-	// Computing vesting here would be almost always redundant since vesting is quantized to ~daily units.
-	// Vesting will be at most one proving period old if computed in the cron callback.
-	verifyPledgeMeetsInitialRequirements(rt, &st)
+		var sectorNos []abi.SectorNumber
+		depositReleased := big.Zero()
+		penalty := big.Zero()
+		err := params.Sectors.ForEach(func(i uint64) error {
+			sectorNo := abi.SectorNumber(i)
+			precommit, found, err := st.GetPrecommittedSector(store, sectorNo)
+			if err != nil {
+				return err
+			}
+			if !found {
+				rt.Abortf(exitcode.ErrNotFound, "no pre-commitment for sector %d", sectorNo)
+			}
+			if precommit.ProofSubmittedEpoch == rt.CurrEpoch() {
+				rt.Abortf(exitcode.ErrForbidden, "proof for sector %d awaiting verification", sectorNo)
+			}
 
-	sectorNo := params.SectorNumber
-	precommit, found, err := st.GetPrecommittedSector(store, sectorNo)
-	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sector %v", sectorNo)
-	if !found {
-		rt.Abortf(exitcode.ErrNotFound, "no pre-committed sector %v", sectorNo)
-	}
+			sectorNos = append(sectorNos, sectorNo)
+			depositReleased = big.Add(depositReleased, precommit.PreCommitDeposit)
+			penalty = big.Add(penalty, PreCommitWithdrawalPenalty(precommit.PreCommitDeposit))
+			return nil
+		})
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-commitments")
 
-	msd, ok := MaxSealDuration[precommit.Info.SealProof]
-	if !ok {
-		rt.Abortf(exitcode.ErrIllegalState, "no max seal duration for proof type: %d", precommit.Info.SealProof)
+		err = st.DeletePrecommittedSectors(store, sectorNos...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to delete pre-commitments")
+
+		// The penalty is burnt from the released deposit, and the rest of the deposit becomes available balance.
+		st.PreCommitDeposits = big.Sub(st.PreCommitDeposits, depositReleased)
+		Assert(st.PreCommitDeposits.GreaterThanEqual(big.Zero()))
+		st.AssertBalanceInvariants(big.Sub(rt.CurrentBalance(), penalty))
+		return penalty
+	}).(abi.TokenAmount)
+
+	// This deposit was locked separately to pledge collateral so there's no pledge change here.
+	burnFunds(rt, penalty)
+	return nil
+}
+
+type MaskSectorNumbersParams struct {
+	Mask *abi.BitField
+}
+
+// Marks sector numbers as allocated, so they will not be pre-committed.
+// The owner may use this to reserve ranges of sector numbers, or to retire numbers that were used elsewhere.
+// Masking a number that is already allocated has no effect.
+func (a Actor) MaskSectorNumbers(rt Runtime, params *MaskSectorNumbersParams) *adt.EmptyValue {
+	err := validateSectorNumberMask(params.Mask)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid sector number mask")
+
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		rt.ValidateImmediateCallerIs(info.Owner)
+
+		err := st.MaskSectorNumbers(params.Mask)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to mask sector numbers")
+		return nil
+	})
+	return nil
+}
+
+type ProveCommitSectorParams struct {
+	SectorNumber abi.SectorNumber
+	Proof        []byte
+}
+
+// Checks state of the corresponding sector pre-commitment, then schedules the proof to be verified in bulk
+// by the power actor.
+// If valid, the power actor will call ConfirmSectorProofsValid at the end of the same epoch as this message.
+func (a Actor) ProveCommitSector(rt Runtime, params *ProveCommitSectorParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Readonly(&st)
+	verifyNotExiting(rt, &st)
+
+	// Verify locked funds are are at least the sum of sector initial pledges.
+	// Note that this call does not actually compute recent vesting, so the reported locked funds may be
+	// slightly higher than the true amount (i.e. slightly in the miner's favour).
+	// Computing vesting here would be almost always redundant since vesting is quantized to ~daily units.
+	// Vesting will be at most one proving period old if computed in the cron callback.
+	verifyPledgeMeetsInitialRequirements(rt, &st)
+
+	sectorNo := params.SectorNumber
+	precommit, found, err := st.GetPrecommittedSector(store, sectorNo)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sector %v", sectorNo)
+	if !found {
+		rt.Abortf(exitcode.ErrNotFound, "no pre-committed sector %v", sectorNo)
+	}
+
+	msd, ok := MaxSealDuration[precommit.Info.SealProof]
+	if !ok {
+		rt.Abortf(exitcode.ErrIllegalState, "no max seal duration for proof type: %d", precommit.Info.SealProof)
 	}
 	proveCommitDue := precommit.PreCommitEpoch + msd
 	if rt.CurrEpoch() > proveCommitDue {
@@ -605,6 +1128,15 @@ func (a Actor) ProveCommitSector(rt Runtime, params *ProveCommitSectorParams) *a
 		RegisteredSealProof: precommit.Info.SealProof,
 	})
 
+	// Record the submission, so the pre-commitment cannot be withdrawn before the proof is confirmed.
+	// This is the only state written here; the sector is activated when the proof is confirmed.
+	rt.State().Transaction(&st, func() interface{} {
+		precommit.ProofSubmittedEpoch = rt.CurrEpoch()
+		err := st.PutPrecommittedSector(store, precommit)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record proof submission for sector %v", sectorNo)
+		return nil
+	})
+
 	_, code := rt.Send(
 		builtin.StoragePowerActorAddr,
 		builtin.MethodsPower.SubmitPoRepForBulkVerify,
@@ -618,6 +1150,22 @@ func (a Actor) ProveCommitSector(rt Runtime, params *ProveCommitSectorParams) *a
 func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSectorProofsParams) *adt.EmptyValue {
 	rt.ValidateImmediateCallerIs(builtin.StoragePowerActorAddr)
 
+	var st State
+	rt.State().Readonly(&st)
+	verifyNotExiting(rt, &st)
+	store := adt.AsStore(rt)
+
+	// This skips missing pre-commits.
+	precommittedSectors, err := st.FindPrecommittedSectors(store, params.Sectors...)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
+
+	confirmSectorProofsValid(rt, precommittedSectors)
+	return nil
+}
+
+// Activates the sectors of pre-commitments for which the seal proofs have been verified.
+// Pre-commitments for which the deals cannot be activated are dropped, but at least one must succeed.
+func confirmSectorProofsValid(rt Runtime, precommittedSectors []*SectorPreCommitOnChainInfo) {
 	// get network stats from other actors
 	baselinePower, epochReward := requestCurrentEpochBaselinePowerAndReward(rt)
 	pwrTotal := requestCurrentTotalPower(rt)
@@ -644,10 +1192,6 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 	// Activate storage deals.
 	//
 
-	// This skips missing pre-commits.
-	precommittedSectors, err := st.FindPrecommittedSectors(store, params.Sectors...)
-	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
-
 	// Committed-capacity sectors licensed for early removal by new sectors being proven.
 	var replaceSectorLocations []SectorLocation
 	// Pre-commits for new sectors.
@@ -695,7 +1239,7 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 		quant := st.QuantEndOfDeadline()
 		// Schedule expiration for replaced sectors to the end of their next deadline window.
 		// They can't be removed right now because we want to challenge them immediately before termination.
-		err = st.RescheduleSectorExpirations(store, rt.CurrEpoch(), replaceSectorLocations, info.SectorSize, quant)
+		err := st.RescheduleSectorExpirations(store, rt.CurrEpoch(), replaceSectorLocations, info.SectorSize, quant)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to replace sector expirations")
 
 		newSectorNos := make([]abi.SectorNumber, 0, len(preCommits))
@@ -716,6 +1260,7 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 				DealIDs:            precommit.Info.DealIDs,
 				Expiration:         precommit.Info.Expiration,
 				Activation:         activation,
+				PowerBaseEpoch:     activation,
 				DealWeight:         precommit.DealWeight,
 				VerifiedDealWeight: precommit.VerifiedDealWeight,
 				InitialPledge:      initialPledge,
@@ -756,7 +1301,304 @@ func (a Actor) ConfirmSectorProofsValid(rt Runtime, params *builtin.ConfirmSecto
 	// Request power and pledge update for activated sector.
 	requestUpdatePower(rt, newPower)
 	notifyPledgeChanged(rt, big.Sub(totalPledge, newlyVestedAmount))
+}
+
+type ProveCommitAggregateParams struct {
+	SectorNumbers  *abi.BitField
+	AggregateProof []byte
+}
+
+// Checks state of the corresponding sector pre-commitments and verifies an aggregate proof of their seals.
+// Unlike ProveCommitSector, the proof is verified immediately rather than being scheduled for bulk verification
+// by the power actor, and the sectors are activated synchronously.
+func (a Actor) ProveCommitAggregate(rt Runtime, params *ProveCommitAggregateParams) *adt.EmptyValue {
+	if params.SectorNumbers == nil {
+		rt.Abortf(exitcode.ErrIllegalArgument, "sector numbers missing")
+	}
+	aggSectorsCount, err := params.SectorNumbers.Count()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to count aggregated sectors")
+	if aggSectorsCount > MaxAggregatedSectors {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many sectors addressed, addressed %d want <= %d", aggSectorsCount, MaxAggregatedSectors)
+	} else if aggSectorsCount < MinAggregatedSectors {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too few sectors addressed, addressed %d want >= %d", aggSectorsCount, MinAggregatedSectors)
+	}
+	if len(params.AggregateProof) > MaxAggregateProofSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "sector prove-commit proof of size %d exceeds max size of %d",
+			len(params.AggregateProof), MaxAggregateProofSize)
+	}
+
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Readonly(&st)
+	info := getMinerInfo(rt, &st)
+	validateWorkerOrControlCaller(rt, info)
+	verifyNotExiting(rt, &st)
+
+	// Verify locked funds are are at least the sum of sector initial pledges, as for ProveCommitSector.
+	verifyPledgeMeetsInitialRequirements(rt, &st)
+
+	var precommits []*SectorPreCommitOnChainInfo
+	err = params.SectorNumbers.ForEach(func(i uint64) error {
+		sectorNo := abi.SectorNumber(i)
+		precommit, found, err := st.GetPrecommittedSector(store, sectorNo)
+		if err != nil {
+			return fmt.Errorf("failed to load pre-committed sector %v: %w", sectorNo, err)
+		}
+		if !found {
+			rt.Abortf(exitcode.ErrNotFound, "no pre-committed sector %v", sectorNo)
+		}
+		precommits = append(precommits, precommit)
+		return nil
+	})
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load pre-committed sectors")
+
+	minerActorID, err := addr.IDFromAddress(rt.Message().Receiver())
+	AssertNoError(err) // Runtime always provides ID-addresses
+
+	svInfos := make([]abi.AggregateSealVerifyInfo, 0, len(precommits))
+	for _, precommit := range precommits {
+		msd, ok := MaxSealDuration[precommit.Info.SealProof]
+		if !ok {
+			rt.Abortf(exitcode.ErrIllegalState, "no max seal duration for proof type: %d", precommit.Info.SealProof)
+		}
+		proveCommitDue := precommit.PreCommitEpoch + msd
+		if rt.CurrEpoch() > proveCommitDue {
+			rt.Abortf(exitcode.ErrIllegalArgument, "commitment proof for %d too late at %d, due %d",
+				precommit.Info.SectorNumber, rt.CurrEpoch(), proveCommitDue)
+		}
+
+		svi := getVerifyInfo(rt, &SealVerifyStuff{
+			SealedCID:           precommit.Info.SealedCID,
+			InteractiveEpoch:    precommit.PreCommitEpoch + PreCommitChallengeDelay,
+			SealRandEpoch:       precommit.Info.SealRandEpoch,
+			DealIDs:             precommit.Info.DealIDs,
+			SectorNumber:        precommit.Info.SectorNumber,
+			RegisteredSealProof: precommit.Info.SealProof,
+		})
+		svInfos = append(svInfos, abi.AggregateSealVerifyInfo{
+			Number:                svi.SectorID.Number,
+			Randomness:            svi.Randomness,
+			InteractiveRandomness: svi.InteractiveRandomness,
+			SealedCID:             svi.SealedCID,
+			UnsealedCID:           svi.UnsealedCID,
+		})
+	}
+
+	err = rt.Syscalls().VerifyAggregateSeals(abi.AggregateSealVerifyProofAndInfos{
+		Miner:          abi.ActorID(minerActorID),
+		SealProof:      info.SealProofType,
+		AggregateProof: abi.RegisteredAggregationProof_SnarkPackV1,
+		Proof:          params.AggregateProof,
+		Infos:          svInfos,
+	})
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "aggregate seal verify failed")
+
+	confirmSectorProofsValid(rt, precommits)
+	return nil
+}
+
+type ReplicaUpdate struct {
+	SectorNumber       abi.SectorNumber
+	Deadline           uint64
+	Partition          uint64
+	NewSealedSectorCID cid.Cid `checked:"true"`
+	Deals              []abi.DealID
+	UpdateProofType    abi.RegisteredUpdateProof
+	ReplicaProof       []byte
+}
+
+type ProveReplicaUpdatesParams struct {
+	Updates []ReplicaUpdate
+}
+
+// Updates committed-capacity sectors in place to hold deals, with a proof that each sector's replica has been
+// updated to encode the deals' data.
+// Each sector keeps its number, deadline, partition, activation and expiration. Its sealed CID, deals and deal
+// weight are replaced, and its power base epoch set to the current epoch, from which the new deal weight is spread.
+// The initial pledge is raised, if necessary, to that required for the sector's new power.
+func (a Actor) ProveReplicaUpdates(rt Runtime, params *ProveReplicaUpdatesParams) *adt.EmptyValue {
+	if len(params.Updates) == 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "no replica updates")
+	} else if len(params.Updates) > ProveReplicaUpdatesMaxSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many replica updates %d, max %d", len(params.Updates), ProveReplicaUpdatesMaxSize)
+	}
+
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Readonly(&st)
+	info := getMinerInfo(rt, &st)
+	validateWorkerOrControlCaller(rt, info)
+	verifyNotExiting(rt, &st)
+
+	// Stop miners with unpaid penalties from activating new deals.
+	verifyNoFeeDebt(rt, &st)
+
+	updateProofType, err := info.SealProofType.RegisteredUpdateProof()
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to determine update proof type")
+
+	deadlines, err := st.LoadDeadlines(store)
+	builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+
+	sectorNumbers := make(map[abi.SectorNumber]struct{}, len(params.Updates))
+	oldSectors := make([]*SectorOnChainInfo, len(params.Updates))
+	sectorDeals := make([]market.SectorDeals, len(params.Updates))
+	for i, update := range params.Updates {
+		if _, ok := sectorNumbers[update.SectorNumber]; ok {
+			rt.Abortf(exitcode.ErrIllegalArgument, "duplicate sector number %d", update.SectorNumber)
+		}
+		sectorNumbers[update.SectorNumber] = struct{}{}
+		if update.Deadline >= WPoStPeriodDeadlines {
+			rt.Abortf(exitcode.ErrIllegalArgument, "invalid deadline %d", update.Deadline)
+		}
+		if !update.NewSealedSectorCID.Defined() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "new sealed CID undefined for sector %d", update.SectorNumber)
+		}
+		if update.NewSealedSectorCID.Prefix() != SealedCIDPrefix {
+			rt.Abortf(exitcode.ErrIllegalArgument, "new sealed CID had wrong prefix for sector %d", update.SectorNumber)
+		}
+		if len(update.Deals) == 0 {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no deals for replica update of sector %d", update.SectorNumber)
+		}
+		if update.UpdateProofType != updateProofType {
+			rt.Abortf(exitcode.ErrIllegalArgument, "update proof type %d must match miner's %d", update.UpdateProofType, updateProofType)
+		}
+		if len(update.ReplicaProof) > MaxReplicaUpdateProofSize {
+			rt.Abortf(exitcode.ErrIllegalArgument, "replica update proof of size %d exceeds max size of %d",
+				len(update.ReplicaProof), MaxReplicaUpdateProofSize)
+		}
+		// The sealed CID challenged by window PoSt must not change while a proof of the deadline may be computed.
+		if !deadlineIsMutable(st.ProvingPeriodStart, update.Deadline, rt.CurrEpoch()) {
+			rt.Abortf(exitcode.ErrForbidden, "cannot update sectors in deadline %d during its challenge window, "+
+				"or the prior challenge window", update.Deadline)
+		}
+
+		sector, found, err := st.GetSector(store, update.SectorNumber)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sector %d", update.SectorNumber)
+		if !found {
+			rt.Abortf(exitcode.ErrNotFound, "no such sector %d", update.SectorNumber)
+		}
+		if len(sector.DealIDs) > 0 || !sector.DealWeight.IsZero() || !sector.VerifiedDealWeight.IsZero() {
+			rt.Abortf(exitcode.ErrIllegalArgument, "cannot update sector %d with deals", update.SectorNumber)
+		}
+
+		key := PartitionKey{update.Deadline, update.Partition}
+		deadline, err := deadlines.LoadDeadline(store, update.Deadline)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", update.Deadline)
+		partition, err := deadline.LoadPartition(store, update.Partition)
+		builtin.RequireNoErr(rt, err, exitcode.ErrNotFound, "failed to load partition %v", key)
+		active, err := partition.ActiveSectors()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load active sectors for %v", key)
+		isActive, err := active.IsSet(uint64(update.SectorNumber))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check sector %d", update.SectorNumber)
+		if !isActive {
+			rt.Abortf(exitcode.ErrForbidden, "sector %d is not active in %v", update.SectorNumber, key)
+		}
+
+		oldSectors[i] = sector
+		sectorDeals[i] = market.SectorDeals{
+			SectorExpiry: sector.Expiration,
+			DealIDs:      update.Deals,
+		}
+	}
+
+	dealWeights := requestDealWeights(rt, sectorDeals)
+
+	for i, update := range params.Updates {
+		unsealedCID := requestUnsealedSectorCID(rt, info.SealProofType, update.Deals)
+		err = rt.Syscalls().VerifyReplicaUpdate(abi.ReplicaUpdateInfo{
+			UpdateProofType:      update.UpdateProofType,
+			Proof:                update.ReplicaProof,
+			OldSealedSectorCID:   oldSectors[i].SealedCID,
+			NewSealedSectorCID:   update.NewSealedSectorCID,
+			NewUnsealedSectorCID: unsealedCID,
+		})
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed to verify replica update of sector %d", update.SectorNumber)
+
+		_, code := rt.Send(
+			builtin.StorageMarketActorAddr,
+			builtin.MethodsMarket.ActivateDeals,
+			&market.ActivateDealsParams{
+				DealIDs:      update.Deals,
+				SectorExpiry: oldSectors[i].Expiration,
+			},
+			abi.NewTokenAmount(0),
+		)
+		builtin.RequireSuccess(rt, code, "failed to activate deals for sector %d", update.SectorNumber)
+	}
+
+	// Network stats for the initial pledge of the updated sectors.
+	baselinePower, epochReward := requestCurrentEpochBaselinePowerAndReward(rt)
+	pwrTotal := requestCurrentTotalPower(rt)
+	circulatingSupply := rt.TotalFilCircSupply()
+
+	powerDelta := NewPowerPairZero()
+	pledgeDelta := big.Zero()
+	rt.State().Transaction(&st, func() interface{} {
+		quant := st.QuantEndOfDeadline()
+		deadlines, err := st.LoadDeadlines(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+
+		newSectors := make([]*SectorOnChainInfo, len(params.Updates))
+		for i, update := range params.Updates {
+			oldSector := oldSectors[i]
+			newSector := *oldSector
+			newSector.SealedCID = update.NewSealedSectorCID
+			newSector.DealIDs = update.Deals
+			// The sector keeps its age, but its power is now based on the new deals' weight from this epoch.
+			newSector.PowerBaseEpoch = rt.CurrEpoch()
+			newSector.DealWeight = dealWeights.Sectors[i].DealWeight
+			newSector.VerifiedDealWeight = dealWeights.Sectors[i].VerifiedDealWeight
+
+			// The pledge is never reduced, since the sector's power can only increase.
+			qaPower := QAPowerForSector(info.SectorSize, &newSector)
+			newPledge := InitialPledgeForPower(qaPower, pwrTotal.QualityAdjPower, baselinePower,
+				pwrTotal.PledgeCollateral, epochReward, circulatingSupply)
+			newSector.InitialPledge = big.Max(oldSector.InitialPledge, newPledge)
+			newSectors[i] = &newSector
+
+			key := PartitionKey{update.Deadline, update.Partition}
+			deadline, err := deadlines.LoadDeadline(store, update.Deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", update.Deadline)
+			partitions, err := deadline.PartitionsArray(store)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", update.Deadline)
+			var partition Partition
+			found, err := partitions.Get(update.Partition, &partition)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partition %v", key)
+			if !found {
+				rt.Abortf(exitcode.ErrNotFound, "no such partition %v", key)
+			}
+
+			partitionPowerDelta, partitionPledgeDelta, err := partition.ReplaceSectors(store,
+				[]*SectorOnChainInfo{oldSector}, []*SectorOnChainInfo{&newSector}, info.SectorSize, quant)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to replace sector %d in %v", update.SectorNumber, key)
+			powerDelta = powerDelta.Add(partitionPowerDelta)
+			pledgeDelta = big.Add(pledgeDelta, partitionPledgeDelta)
+
+			err = partitions.Set(update.Partition, &partition)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partition %v", key)
+			deadline.Partitions, err = partitions.Root()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partitions for deadline %d", update.Deadline)
+			err = deadlines.UpdateDeadline(store, update.Deadline, deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadline %d", update.Deadline)
+		}
+
+		err = st.PutSectors(store, newSectors...)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update sectors")
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
 
+		availableBalance := st.GetAvailableBalance(rt.CurrentBalance())
+		if availableBalance.LessThan(pledgeDelta) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "insufficient funds for additional initial pledge requirement %s, available: %s",
+				pledgeDelta, availableBalance)
+		}
+		st.AddInitialPledgeRequirement(pledgeDelta)
+		st.AssertBalanceInvariants(rt.CurrentBalance())
+		return nil
+	})
+
+	requestUpdatePower(rt, powerDelta)
+	notifyPledgeChanged(rt, pledgeDelta)
 	return nil
 }
 
@@ -797,7 +1639,9 @@ type ExpirationExtension struct {
 
 // Changes the expiration epoch for a sector to a new, later one.
 // The sector must not be terminated or faulty.
-// The sector's power is recomputed for the new expiration.
+// A sector without deal weight is rescheduled to the new expiration immediately, its power unchanged.
+// A sector with deal weight keeps its expiration and quality-adjusted power until its deals expire, and is then
+// renewed with raw-byte power until the new expiration. The deal weight is not spread over the extended lifetime.
 func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpirationParams) *adt.EmptyValue {
 	if uint64(len(params.Extensions)) > AddressedPartitionsMax {
 		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(params.Extensions), AddressedPartitionsMax)
@@ -830,7 +1674,8 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 	var st State
 	rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
+		verifyNotExiting(rt, &st)
 
 		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
@@ -839,11 +1684,12 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 		// Group declarations by deadline, and remember iteration order.
 		declsByDeadline := map[uint64][]*ExpirationExtension{}
 		var deadlinesToLoad []uint64
-		for _, decl := range params.Extensions {
+		for i := range params.Extensions {
+			decl := &params.Extensions[i]
 			if _, ok := declsByDeadline[decl.Deadline]; !ok {
 				deadlinesToLoad = append(deadlinesToLoad, decl.Deadline)
 			}
-			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], &decl)
+			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], decl)
 		}
 
 		for _, dlIdx := range deadlinesToLoad {
@@ -864,28 +1710,55 @@ func (a Actor) ExtendSectorExpiration(rt Runtime, params *ExtendSectorExpiration
 
 				oldSectors, err := st.LoadSectorInfos(store, decl.Sectors)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
-				newSectors := make([]*SectorOnChainInfo, len(oldSectors))
-				for i, sector := range oldSectors {
-					if decl.NewExpiration < sector.Expiration {
+				var replacedSectors, newSectors, extendedSectors []*SectorOnChainInfo
+				extendedSnos := abi.NewBitField()
+				for _, sector := range oldSectors {
+					currentExpiration := sector.Expiration
+					if sector.ExtendedExpiration > currentExpiration {
+						currentExpiration = sector.ExtendedExpiration
+					}
+					if decl.NewExpiration < currentExpiration {
 						rt.Abortf(exitcode.ErrIllegalArgument, "cannot reduce sector expiration to %d from %d",
-							decl.NewExpiration, sector.Expiration)
+							decl.NewExpiration, currentExpiration)
 					}
 					validateExpiration(rt, sector.Activation, decl.NewExpiration, sector.SealProof)
 
 					newSector := *sector
-					newSector.Expiration = decl.NewExpiration
-					//qaPowerDelta := big.Sub(QAPowerForSector(info.SectorSize, &newSector), QAPowerForSector(info.SectorSize, sector))
-
-					newSectors[i] = &newSector
+					if sector.DealWeight.IsZero() && sector.VerifiedDealWeight.IsZero() {
+						newSector.Expiration = decl.NewExpiration
+						replacedSectors = append(replacedSectors, sector)
+						newSectors = append(newSectors, &newSector)
+					} else if decl.NewExpiration > sector.Expiration {
+						newSector.ExtendedExpiration = decl.NewExpiration
+						extendedSectors = append(extendedSectors, &newSector)
+						extendedSnos.Set(uint64(sector.SectorNumber))
+					}
 				}
 
 				// Overwrite sector infos.
-				err = st.PutSectors(store, newSectors...)
+				err = st.PutSectors(store, append(newSectors, extendedSectors...)...)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update sectors %v", decl.Sectors)
 
 				// Remove old sectors from partition and assign new sectors.
-				powerDelta, pledgeDelta, err = partition.ReplaceSectors(store, oldSectors, newSectors, info.SectorSize, quant)
+				partitionPowerDelta, partitionPledgeDelta, err := partition.ReplaceSectors(store, replacedSectors, newSectors, info.SectorSize, quant)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to replaces sector expirations at %v", key)
+				powerDelta = powerDelta.Add(partitionPowerDelta)
+				pledgeDelta = big.Add(pledgeDelta, partitionPledgeDelta)
+				if len(newSectors) > 0 {
+					err = deadline.AddExpirationPartitions(store, decl.NewExpiration, []uint64{decl.Partition}, quant)
+					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add expirations for %v", key)
+				}
+
+				// Sectors with deal weight are renewed when their current expiration is reached.
+				active, err := partition.ActiveSectors()
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load active sectors for %v", key)
+				allActive, err := abi.BitFieldContainsAll(active, extendedSnos)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check active sectors for %v", key)
+				if !allActive {
+					rt.Abortf(exitcode.ErrForbidden, "cannot extend inactive sectors in %v", key)
+				}
+				err = deadline.AddRenewals(store, extendedSectors, quant)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to schedule renewals for %v", key)
 
 				err = partitions.Set(decl.Partition, &partition)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partition", key)
@@ -940,309 +1813,35 @@ type TerminateSectorsReturn struct {
 //  1. The miner will be unable to withdraw funds.
 //  2. The chain will process up to AddressedSectorsMax sectors and
 //     AddressedPartitionsMax per epoch until the queue is empty.
-//
-// The sectors are immediately ignored for Window PoSt proofs, and should be
-// masked in the same way as faulty sectors. A miner terminating sectors in the
-// current deadline must be careful to compute an appropriate Window PoSt proof
-// for the sectors that will be active at the time the PoSt is submitted.
-//
-// This function may be invoked with no new sectors to explicitly process the
-// next batch of sectors.
-func (a Actor) TerminateSectors(rt Runtime, params *TerminateSectorsParams) *TerminateSectorsReturn {
-	// Note: this cannot terminate pre-committed but un-proven sectors.
-	// They must be allowed to expire (and deposit burnt).
-
-	// Enforce partition/sector maximums.
-	// https://github.com/filecoin-project/specs-actors/issues/416
-	if uint64(len(params.Terminations)) > AddressedPartitionsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument,
-			"too many partitions for declarations %d, max %d",
-			len(params.Terminations), AddressedPartitionsMax,
-		)
-	}
-	var sectorCount uint64
-	for _, term := range params.Terminations {
-		if term.Deadline >= WPoStPeriodDeadlines {
-			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", term.Deadline, WPoStPeriodDeadlines)
-		}
-		count, err := term.Sectors.Count()
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument,
-			"failed to count sectors for deadline %d, partition %d",
-			term.Deadline, term.Partition,
-		)
-		sectorCount += count
-	}
-	if sectorCount > AddressedSectorsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument,
-			"too many sectors for declaration %d, max %d",
-			sectorCount, AddressedSectorsMax,
-		)
-	}
-
-	var hadEarlyTerminations bool
-	var st State
-	store := adt.AsStore(rt)
-	currEpoch := rt.CurrEpoch()
-	powerDelta := NewPowerPairZero()
-	rt.State().Transaction(&st, func() interface{} {
-		hadEarlyTerminations = havePendingEarlyTerminations(rt, &st)
-
-		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
-
-		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
-		quant := st.QuantEndOfDeadline()
-
-		// Group declarations by deadline, and remember iteration order.
-		declsByDeadline := map[uint64][]*TerminationDeclaration{}
-		var deadlinesToLoad []uint64
-		for _, decl := range params.Terminations {
-			if _, ok := declsByDeadline[decl.Deadline]; !ok {
-				deadlinesToLoad = append(deadlinesToLoad, decl.Deadline)
-			}
-			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], &decl)
-		}
-
-		// We're only reading the sectors, so there's no need to save this back.
-		// However, we still want to avoid re-loading this array per-partition.
-		sectors, err := LoadSectors(store, st.Sectors)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
-
-		for _, dlIdx := range deadlinesToLoad {
-			decls := declsByDeadline[dlIdx]
-
-			deadline, err := deadlines.LoadDeadline(store, dlIdx)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
-
-			byPartition := make(map[uint64][]*SectorOnChainInfo, len(decls))
-			for _, decl := range decls {
-				sectors, err := sectors.Load(decl.Sectors)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
-				byPartition[decl.Partition] = append(byPartition[decl.Partition], sectors...)
-			}
-
-			removedPower, err := deadline.TerminateSectors(store, currEpoch, byPartition, info.SectorSize, quant)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to terminate sectors in deadline %d", dlIdx)
-
-			powerDelta = powerDelta.Sub(removedPower)
-
-			err = deadlines.UpdateDeadline(store, dlIdx, deadline)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update deadline %d", dlIdx)
-		}
-
-		err = st.SaveDeadlines(store, deadlines)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
-
-		return nil
-	})
-
-	// Now, try to process these sectors.
-	more := processEarlyTerminations(rt)
-	if more && !hadEarlyTerminations {
-		// We have remaining terminations, and we didn't _previously_
-		// have early terminations to process, schedule a cron job.
-		// NOTE: This isn't quite correct. If we repeatedly fill, empty,
-		// fill, and empty, the queue, we'll keep scheduling new cron
-		// jobs. However, in practice, that shouldn't be all that bad.
-		scheduleEarlyTerminationWork(rt)
-	}
-
-	requestUpdatePower(rt, powerDelta)
-
-	// Pledge requirement is not released until termination fee is paid.
-	// The termination fee is paid later, in early-termination queue processing.
-	// We could charge at least the undeclared fault fee here, which is a lower bound on the penalty.
-	// https://github.com/filecoin-project/specs-actors/issues/674
-
-	return &TerminateSectorsReturn{Done: !more}
-}
-
-////////////
-// Faults //
-////////////
-
-type DeclareFaultsParams struct {
-	Faults []FaultDeclaration
-}
-
-type FaultDeclaration struct {
-	// The deadline to which the faulty sectors are assigned, in range [0..WPoStPeriodDeadlines)
-	Deadline uint64
-	// Partition index within the deadline containing the faulty sectors.
-	Partition uint64
-	// Sectors in the partition being declared faulty.
-	Sectors *abi.BitField
-}
-
-func (a Actor) DeclareFaults(rt Runtime, params *DeclareFaultsParams) *adt.EmptyValue {
-	if uint64(len(params.Faults)) > AddressedPartitionsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(params.Faults), AddressedPartitionsMax)
-	}
-	var sectorCount uint64
-	for _, decl := range params.Faults {
-		if decl.Deadline >= WPoStPeriodDeadlines {
-			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", decl.Deadline, WPoStPeriodDeadlines)
-		}
-		count, err := decl.Sectors.Count()
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument,
-			"failed to count sectors for deadline %d, partition %d",
-			decl.Deadline, decl.Partition,
-		)
-		sectorCount += count
-	}
-	if sectorCount > AddressedSectorsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument,
-			"too many sectors for declaration %d, max %d",
-			sectorCount, AddressedSectorsMax,
-		)
-	}
-
-	store := adt.AsStore(rt)
-	var st State
-	newFaultPowerTotal := NewPowerPairZero()
-	rt.State().Transaction(&st, func() interface{} {
-		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
-
-		deadlines, err := st.LoadDeadlines(store)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
-		quant := st.QuantEndOfDeadline()
-
-		// Group declarations by deadline, and remember iteration order.
-		declsByDeadline := map[uint64][]*FaultDeclaration{}
-		var deadlinesToLoad []uint64
-		for _, decl := range params.Faults {
-			if _, ok := declsByDeadline[decl.Deadline]; !ok {
-				deadlinesToLoad = append(deadlinesToLoad, decl.Deadline)
-			}
-			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], &decl)
-		}
-
-		for _, dlIdx := range deadlinesToLoad {
-			targetDeadline, err := declarationDeadlineInfo(st.ProvingPeriodStart, dlIdx, rt.CurrEpoch())
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid fault declaration deadline %d", dlIdx)
-			err = validateFRDeclarationDeadline(targetDeadline)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed fault declaration at deadline %d", dlIdx)
-
-			deadline, err := deadlines.LoadDeadline(store, dlIdx)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
-
-			partitions, err := deadline.PartitionsArray(store)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", dlIdx)
-
-			// Record partitions with some fault, for subsequently indexing in the deadline.
-			// Duplicate entries don't matter, they'll be stored in a bitfield (a set).
-			partitionsWithFault := make([]uint64, 0, len(declsByDeadline))
-			faultExpirationEpoch := targetDeadline.Last() + FaultMaxAge
-
-			for _, decl := range declsByDeadline[dlIdx] {
-				key := PartitionKey{dlIdx, decl.Partition}
-				var partition Partition
-				found, err := partitions.Get(decl.Partition, &partition)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partition %v", key)
-				if !found {
-					rt.Abortf(exitcode.ErrNotFound, "no such partition %v", key)
-				}
-
-				err = validateFRDeclarationPartition(key, &partition, decl.Sectors)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed fault declaration for partition %v", key)
-
-				// Split declarations into declarations of new faults, and retraction of declared recoveries.
-				retractedRecoveries, err := bitfield.IntersectBitField(partition.Recoveries, decl.Sectors)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to intersect sectors with recoveries")
-
-				newFaults, err := bitfield.SubtractBitField(decl.Sectors, retractedRecoveries)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract recoveries from sectors")
-				// Ignore any terminated sectors and previously declared or detected faults
-				newFaults, err = bitfield.SubtractBitField(newFaults, partition.Terminated)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract terminations from faults")
-				newFaults, err = bitfield.SubtractBitField(newFaults, partition.Faults)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract existing faults from faults")
-
-				// Add new faults to state.
-				empty, err := newFaults.IsEmpty()
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check if bitfield was empty")
-				if !empty {
-					newFaultSectors, err := st.LoadSectorInfos(store, newFaults)
-					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load fault sectors")
-
-					newFaultPower, err := partition.AddFaults(store, newFaults, newFaultSectors, faultExpirationEpoch, info.SectorSize, quant)
-					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add faults")
-
-					st.FaultyPower = st.FaultyPower.Add(newFaultPower)
-					newFaultPowerTotal = newFaultPowerTotal.Add(newFaultPower)
-					partitionsWithFault = append(partitionsWithFault, decl.Partition)
-				}
-
-				// Remove faulty recoveries from state.
-				empty, err = retractedRecoveries.IsEmpty()
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check if bitfield was empty")
-				if !empty {
-					retractedRecoverySectors, err := st.LoadSectorInfos(store, retractedRecoveries)
-					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load recovery sectors")
-					retractedRecoveryPower := PowerForSectors(info.SectorSize, retractedRecoverySectors)
-
-					err = partition.RemoveRecoveries(retractedRecoveries, retractedRecoveryPower)
-					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove recoveries")
-				}
-
-				err = partitions.Set(decl.Partition, &partition)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store partition %v", key)
-			}
-			deadline.Partitions, err = partitions.Root()
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d partitions root", dlIdx)
-
-			err = deadline.AddExpirationPartitions(store, faultExpirationEpoch, partitionsWithFault, quant)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update fault epochs for deadline %d", dlIdx)
-
-			err = deadlines.UpdateDeadline(store, dlIdx, deadline)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d partitions", dlIdx)
-		}
-
-		err = st.SaveDeadlines(store, deadlines)
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
-
-		return nil
-	})
-
-	// Remove power for new faulty sectors.
-	// NOTE: It would be permissible to delay the power loss until the deadline closes, but that would require
-	// additional accounting state.
-	// https://github.com/filecoin-project/specs-actors/issues/414
-	requestUpdatePower(rt, newFaultPowerTotal.Neg())
-
-	// Payment of penalty for declared faults is deferred to the deadline cron.
-	return nil
-}
-
-type DeclareFaultsRecoveredParams struct {
-	Recoveries []RecoveryDeclaration
-}
-
-type RecoveryDeclaration struct {
-	// The deadline to which the recovered sectors are assigned, in range [0..WPoStPeriodDeadlines)
-	Deadline uint64
-	// Partition index within the deadline containing the recovered sectors.
-	Partition uint64
-	// Sectors in the partition being declared recovered.
-	Sectors *abi.BitField
-}
+//
+// The sectors are immediately ignored for Window PoSt proofs, and should be
+// masked in the same way as faulty sectors. A miner terminating sectors in the
+// current deadline must be careful to compute an appropriate Window PoSt proof
+// for the sectors that will be active at the time the PoSt is submitted.
+//
+// This function may be invoked with no new sectors to explicitly process the
+// next batch of sectors.
+func (a Actor) TerminateSectors(rt Runtime, params *TerminateSectorsParams) *TerminateSectorsReturn {
+	// Note: this cannot terminate pre-committed but un-proven sectors.
+	// They must be allowed to expire (and deposit burnt).
 
-func (a Actor) DeclareFaultsRecovered(rt Runtime, params *DeclareFaultsRecoveredParams) *adt.EmptyValue {
-	if uint64(len(params.Recoveries)) > AddressedPartitionsMax {
-		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(params.Recoveries), AddressedPartitionsMax)
+	// Enforce partition/sector maximums.
+	// https://github.com/filecoin-project/specs-actors/issues/416
+	if uint64(len(params.Terminations)) > AddressedPartitionsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument,
+			"too many partitions for declarations %d, max %d",
+			len(params.Terminations), AddressedPartitionsMax,
+		)
 	}
-
 	var sectorCount uint64
-	for _, decl := range params.Recoveries {
-		if decl.Deadline >= WPoStPeriodDeadlines {
-			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", decl.Deadline, WPoStPeriodDeadlines)
+	for _, term := range params.Terminations {
+		if term.Deadline >= WPoStPeriodDeadlines {
+			rt.Abortf(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", term.Deadline, WPoStPeriodDeadlines)
 		}
-		count, err := decl.Sectors.Count()
+		count, err := term.Sectors.Count()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument,
 			"failed to count sectors for deadline %d, partition %d",
-			decl.Deadline, decl.Partition,
+			term.Deadline, term.Partition,
 		)
 		sectorCount += count
 	}
@@ -1253,72 +1852,58 @@ func (a Actor) DeclareFaultsRecovered(rt Runtime, params *DeclareFaultsRecovered
 		)
 	}
 
-	store := adt.AsStore(rt)
+	var hadEarlyTerminations bool
 	var st State
+	store := adt.AsStore(rt)
+	currEpoch := rt.CurrEpoch()
+	powerDelta := NewPowerPairZero()
 	rt.State().Transaction(&st, func() interface{} {
+		hadEarlyTerminations = havePendingEarlyTerminations(rt, &st)
+
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
+		verifyNotExiting(rt, &st)
 
 		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+		quant := st.QuantEndOfDeadline()
 
 		// Group declarations by deadline, and remember iteration order.
-		declsByDeadline := map[uint64][]*RecoveryDeclaration{}
+		declsByDeadline := map[uint64][]*TerminationDeclaration{}
 		var deadlinesToLoad []uint64
-		for _, decl := range params.Recoveries {
+		for _, decl := range params.Terminations {
 			if _, ok := declsByDeadline[decl.Deadline]; !ok {
 				deadlinesToLoad = append(deadlinesToLoad, decl.Deadline)
 			}
 			declsByDeadline[decl.Deadline] = append(declsByDeadline[decl.Deadline], &decl)
 		}
 
+		// We're only reading the sectors, so there's no need to save this back.
+		// However, we still want to avoid re-loading this array per-partition.
+		sectors, err := LoadSectors(store, st.Sectors)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+
 		for _, dlIdx := range deadlinesToLoad {
-			targetDeadline, err := declarationDeadlineInfo(st.ProvingPeriodStart, dlIdx, rt.CurrEpoch())
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid recovery declaration deadline %d", dlIdx)
-			err = validateFRDeclarationDeadline(targetDeadline)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed recovery declaration at deadline %d", dlIdx)
+			decls := declsByDeadline[dlIdx]
 
 			deadline, err := deadlines.LoadDeadline(store, dlIdx)
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
 
-			partitions, err := deadline.PartitionsArray(store)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", dlIdx)
-
-			for _, decl := range declsByDeadline[dlIdx] {
-				key := PartitionKey{dlIdx, decl.Partition}
-				var partition Partition
-				found, err := partitions.Get(decl.Partition, &partition)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partition %v", key)
-				if !found {
-					rt.Abortf(exitcode.ErrNotFound, "no such partition %v", key)
-				}
-
-				err = validateFRDeclarationPartition(key, &partition, decl.Sectors)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "failed recovery declaration for partition %v", key)
-
-				// Ignore sectors not faulty or already declared recovered
-				recoveries, err := bitfield.IntersectBitField(decl.Sectors, partition.Faults)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to intersect recoveries with faults")
-				recoveries, err = bitfield.SubtractBitField(recoveries, partition.Recoveries)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract existing recoveries")
-
-				// Record the new recoveries for processing at Window PoSt or deadline cron.
-				recoverySectors, err := st.LoadSectorInfos(store, recoveries)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load recovery sectors")
-				recoveryPower := PowerForSectors(info.SectorSize, recoverySectors)
-
-				err = partition.AddRecoveries(recoveries, recoveryPower)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add recoveries")
-
-				err = partitions.Set(decl.Partition, &partition)
-				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update partition %v", key)
+			byPartition := make(map[uint64][]*SectorOnChainInfo, len(decls))
+			for _, decl := range decls {
+				sectors, err := sectors.Load(decl.Sectors)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
+				byPartition[decl.Partition] = append(byPartition[decl.Partition], sectors...)
 			}
 
-			deadline.Partitions, err = partitions.Root()
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partitions array")
+			removedPower, err := deadline.TerminateSectors(store, currEpoch, byPartition, info.SectorSize, quant)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to terminate sectors in deadline %d", dlIdx)
+
+			st.EarlyTerminations.Set(dlIdx)
+			powerDelta = powerDelta.Sub(removedPower)
 
 			err = deadlines.UpdateDeadline(store, dlIdx, deadline)
-			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d", dlIdx)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update deadline %d", dlIdx)
 		}
 
 		err = st.SaveDeadlines(store, deadlines)
@@ -1327,10 +1912,88 @@ func (a Actor) DeclareFaultsRecovered(rt Runtime, params *DeclareFaultsRecovered
 		return nil
 	})
 
-	// Power is not restored yet, but when the recovered sectors are successfully PoSted.
+	// Now, try to process these sectors.
+	more := processEarlyTerminations(rt)
+	if more && !hadEarlyTerminations {
+		// We have remaining terminations, and we didn't _previously_
+		// have early terminations to process, schedule a cron job.
+		// NOTE: This isn't quite correct. If we repeatedly fill, empty,
+		// fill, and empty, the queue, we'll keep scheduling new cron
+		// jobs. However, in practice, that shouldn't be all that bad.
+		scheduleEarlyTerminationWork(rt)
+	}
+
+	requestUpdatePower(rt, powerDelta)
+
+	// Pledge requirement is not released until termination fee is paid.
+	// The termination fee is paid later, in early-termination queue processing.
+	// We could charge at least the undeclared fault fee here, which is a lower bound on the penalty.
+	// https://github.com/filecoin-project/specs-actors/issues/674
+
+	return &TerminateSectorsReturn{Done: !more}
+}
+
+////////////
+// Faults //
+////////////
+
+type DeclareFaultsParams struct {
+	Faults []FaultDeclaration
+}
+
+type FaultDeclaration struct {
+	// The deadline to which the faulty sectors are assigned, in range [0..WPoStPeriodDeadlines)
+	Deadline uint64
+	// Partition index within the deadline containing the faulty sectors.
+	Partition uint64
+	// Sectors in the partition being declared faulty.
+	Sectors *abi.BitField
+}
+
+func (a Actor) DeclareFaults(rt Runtime, params *DeclareFaultsParams) *adt.EmptyValue {
+	declareFaults(rt, params.Faults, false)
+	return nil
+}
+
+type DeclarationsReturn struct {
+	// Indices of the declarations that were applied. Declarations not included were invalid and ignored.
+	Applied *abi.BitField
+}
+
+// Declares faults like DeclareFaults, but ignores invalid declarations rather than aborting.
+// A declaration is invalid if its deadline is out of range or past the fault cutoff, if its partition doesn't
+// exist, or if it includes sectors not assigned to the partition.
+// The limits on the number of declarations and sectors still apply to the message as a whole.
+func (a Actor) DeclareFaultsPartial(rt Runtime, params *DeclareFaultsParams) *DeclarationsReturn {
+	applied := declareFaults(rt, params.Faults, true)
+	return &DeclarationsReturn{Applied: applied}
+}
+
+type DeclareFaultsRecoveredParams struct {
+	Recoveries []RecoveryDeclaration
+}
+
+type RecoveryDeclaration struct {
+	// The deadline to which the recovered sectors are assigned, in range [0..WPoStPeriodDeadlines)
+	Deadline uint64
+	// Partition index within the deadline containing the recovered sectors.
+	Partition uint64
+	// Sectors in the partition being declared recovered.
+	Sectors *abi.BitField
+}
+
+func (a Actor) DeclareFaultsRecovered(rt Runtime, params *DeclareFaultsRecoveredParams) *adt.EmptyValue {
+	declareRecoveries(rt, params.Recoveries, false)
 	return nil
 }
 
+// Declares recoveries like DeclareFaultsRecovered, but ignores invalid declarations rather than aborting.
+// Declarations are invalid under the same conditions as for DeclareFaultsPartial.
+func (a Actor) DeclareFaultsRecoveredPartial(rt Runtime, params *DeclareFaultsRecoveredParams) *DeclarationsReturn {
+	applied := declareRecoveries(rt, params.Recoveries, true)
+	return &DeclarationsReturn{Applied: applied}
+}
+
 type CompactPartitionsParams struct {
 	Deadline   uint64
 	Partitions *abi.BitField
@@ -1354,11 +2017,13 @@ func (a Actor) CompactPartitions(rt Runtime, params *CompactPartitionsParams) *a
 	var st State
 	rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker)
+		validateWorkerOrControlCaller(rt, info)
+		verifyNotExiting(rt, &st)
 
-		if !deadlineIsMutable(st.ProvingPeriodStart, params.Deadline, rt.CurrEpoch()) {
+		if !deadlineAvailableForCompaction(st.ProvingPeriodStart, params.Deadline, rt.CurrEpoch()) {
 			rt.Abortf(exitcode.ErrForbidden,
-				"cannot compact deadline %d during its challenge window or the prior challenge window", params.Deadline)
+				"cannot compact deadline %d during its challenge window, the prior challenge window, "+
+					"or within %d epochs after its challenge window closes", params.Deadline, WPoStDisputeWindow)
 		}
 
 		submissionPartitionLimit := loadPartitionsSectorsMax(info.WindowPoStPartitionSectors)
@@ -1410,7 +2075,7 @@ func (a Actor) AddLockedFund(rt Runtime, amountToLock *abi.TokenAmount) *adt.Emp
 
 	newlyVested := rt.State().Transaction(&st, func() interface{} {
 		info := getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Worker, info.Owner, builtin.RewardActorAddr)
+		validateWorkerOrControlCaller(rt, info, info.Owner, builtin.RewardActorAddr)
 
 		newlyVestedFund, err := st.UnlockVestedFunds(store, rt.CurrEpoch())
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to vest funds")
@@ -1484,15 +2149,23 @@ type WithdrawBalanceParams struct {
 	AmountRequested abi.TokenAmount
 }
 
+// Withdraws available balance to the beneficiary, which is the owner unless a beneficiary change has been agreed.
+// A beneficiary other than the owner may withdraw no more than the remaining quota of its term, before the
+// term expires.
 func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *adt.EmptyValue {
 	var st State
 	if params.AmountRequested.LessThan(big.Zero()) {
 		rt.Abortf(exitcode.ErrIllegalArgument, "negative fund requested for withdrawal: %s", params.AmountRequested)
 	}
 	var info *MinerInfo
-	newlyVestedAmount := rt.State().Transaction(&st, func() interface{} {
+	var newlyVestedAmount, amountWithdrawn abi.TokenAmount
+	rt.State().Transaction(&st, func() interface{} {
 		info = getMinerInfo(rt, &st)
-		rt.ValidateImmediateCallerIs(info.Owner)
+		if info.Beneficiary != info.Owner {
+			rt.ValidateImmediateCallerIs(info.Owner, info.Beneficiary)
+		} else {
+			rt.ValidateImmediateCallerIs(info.Owner)
+		}
 		// Ensure we don't have any pending terminations.
 		if count, err := st.EarlyTerminations.Count(); err != nil {
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to count early terminations")
@@ -1504,7 +2177,8 @@ func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *adt.E
 		}
 
 		// Unlock vested funds so we can spend them.
-		newlyVestedFund, err := st.UnlockVestedFunds(adt.AsStore(rt), rt.CurrEpoch())
+		var err error
+		newlyVestedAmount, err = st.UnlockVestedFunds(adt.AsStore(rt), rt.CurrEpoch())
 		if err != nil {
 			rt.Abortf(exitcode.ErrIllegalState, "failed to vest fund: %v", err)
 		}
@@ -1512,15 +2186,30 @@ func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *adt.E
 		// Verify InitialPledgeRequirement does not exceed unlocked funds
 		verifyPledgeMeetsInitialRequirements(rt, &st)
 
-		return newlyVestedFund
-	}).(abi.TokenAmount)
+		// Verify there are no unpaid penalties.
+		verifyNoFeeDebt(rt, &st)
+
+		amountWithdrawn = big.Min(st.GetAvailableBalance(rt.CurrentBalance()), params.AmountRequested)
+		if info.Beneficiary != info.Owner {
+			// Limit the withdrawal to the beneficiary's remaining quota, and record its use.
+			remainingQuota := info.BeneficiaryTerm.Available(rt.CurrEpoch())
+			if remainingQuota.IsZero() {
+				rt.Abortf(exitcode.ErrForbidden, "beneficiary term expired at epoch %d or quota %v used up",
+					info.BeneficiaryTerm.Expiration, info.BeneficiaryTerm.Quota)
+			}
+			amountWithdrawn = big.Min(amountWithdrawn, remainingQuota)
+			info.BeneficiaryTerm.UsedQuota = big.Add(info.BeneficiaryTerm.UsedQuota, amountWithdrawn)
+			err = st.SaveInfo(adt.AsStore(rt), info)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save miner info")
+		}
+		return nil
+	})
 
 	currBalance := rt.CurrentBalance()
-	amountWithdrawn := big.Min(st.GetAvailableBalance(currBalance), params.AmountRequested)
 	Assert(amountWithdrawn.GreaterThanEqual(big.Zero()))
 	Assert(amountWithdrawn.LessThanEqual(currBalance))
 
-	_, code := rt.Send(info.Owner, builtin.MethodSend, nil, amountWithdrawn)
+	_, code := rt.Send(info.Beneficiary, builtin.MethodSend, nil, amountWithdrawn)
 	builtin.RequireSuccess(rt, code, "failed to withdraw balance")
 
 	pledgeDelta := newlyVestedAmount.Neg()
@@ -1530,6 +2219,95 @@ func (a Actor) WithdrawBalance(rt Runtime, params *WithdrawBalanceParams) *adt.E
 	return nil
 }
 
+// Pays off as much of the miner's fee debt as possible, from unvested funds and then the unlocked balance,
+// including any value sent with this message. The payment is burnt.
+func (a Actor) RepayDebt(rt Runtime, _ *adt.EmptyValue) *adt.EmptyValue {
+	store := adt.AsStore(rt)
+	var st State
+	var fromVesting, fromBalance abi.TokenAmount
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		validateWorkerOrControlCaller(rt, info, info.Owner)
+
+		unlockedBalance := st.GetUnlockedBalance(rt.CurrentBalance())
+		var err error
+		fromVesting, fromBalance, err = st.RepayDebtsInPriorityOrder(store, rt.CurrEpoch(), unlockedBalance)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to repay fee debt")
+		st.AssertBalanceInvariants(big.Sub(rt.CurrentBalance(), big.Add(fromVesting, fromBalance)))
+		return nil
+	})
+
+	burnFunds(rt, big.Add(fromVesting, fromBalance))
+	notifyPledgeChanged(rt, fromVesting.Neg())
+	return nil
+}
+
+type CompactVestingFundsParams struct {
+	Quantization abi.ChainEpoch
+}
+
+// Merges the vesting table's entries into buckets of the given number of epochs, to reduce the size of the table.
+// This is at the owner's discretion, since funds in an entry merged into a later bucket unlock later than scheduled.
+// The total locked funds is unchanged.
+func (a Actor) CompactVestingFunds(rt Runtime, params *CompactVestingFundsParams) *adt.EmptyValue {
+	if params.Quantization <= 0 {
+		rt.Abortf(exitcode.ErrIllegalArgument, "non-positive vesting quantization %d", params.Quantization)
+	}
+
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		rt.ValidateImmediateCallerIs(info.Owner)
+
+		err := st.CompactVestingFunds(store, params.Quantization)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to compact vesting funds")
+		return nil
+	})
+	return nil
+}
+
+//////////
+// Exit //
+//////////
+
+// Begins the exit of this miner, after which it accepts no new sectors or other changes to its sectors,
+// except declarations of faults.
+// A cron callback then terminates all sectors, in batches of up to AddressedSectorsMax, and processes the
+// early terminations to pay termination fees, release pledge and terminate deals. Once all sectors are
+// terminated and the vesting funds have unlocked, the miner's power claim is removed and the actor deleted,
+// returning its remaining balance to the owner.
+// The miner's escrow in the market actor is not reconciled. The owner must withdraw it before the actor is deleted,
+// after which the market can no longer resolve the miner's owner to permit the withdrawal.
+// The miner must have no pre-committed sectors (see WithdrawPreCommits), and its beneficiary must be the owner.
+func (a Actor) ExitMiner(rt Runtime, _ *adt.EmptyValue) *adt.EmptyValue {
+	store := adt.AsStore(rt)
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		rt.ValidateImmediateCallerIs(info.Owner)
+
+		if st.Exiting {
+			rt.Abortf(exitcode.ErrForbidden, "miner is already exiting")
+		}
+		if info.Beneficiary != info.Owner || info.PendingBeneficiaryTerm != nil {
+			rt.Abortf(exitcode.ErrForbidden, "cannot exit with beneficiary %v other than owner, or pending beneficiary change",
+				info.Beneficiary)
+		}
+		hasPreCommits, err := st.HasPreCommittedSectors(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check pre-commitments")
+		if hasPreCommits {
+			rt.Abortf(exitcode.ErrForbidden, "cannot exit with pre-committed sectors")
+		}
+
+		st.Exiting = true
+		return nil
+	})
+
+	scheduleMinerExitWork(rt, rt.CurrEpoch()+1)
+	return nil
+}
+
 //////////
 // Cron //
 //////////
@@ -1550,6 +2328,8 @@ func (a Actor) OnDeferredCronEvent(rt Runtime, payload *CronEventPayload) *adt.E
 		if processEarlyTerminations(rt) {
 			scheduleEarlyTerminationWork(rt)
 		}
+	case CronEventExitMiner:
+		processMinerExit(rt)
 	}
 
 	return nil
@@ -1703,6 +2483,7 @@ func handleProvingDeadline(rt Runtime) {
 			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", dlInfo.Index)
 
 			detectedAny := false
+			var faultyPartitionIdxs []uint64 // partitions with new faults, which may now expire early
 			for i := uint64(0); i < partitions.Length(); i++ {
 				key := PartitionKey{dlInfo.Index, i}
 				proven, err := deadline.PostSubmissions.IsSet(i)
@@ -1721,6 +2502,9 @@ func handleProvingDeadline(rt Runtime) {
 
 				newFaultPower, failedRecoveryPower, err := partition.RecordMissedPost(store, faultExpiration, quant)
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to record missed PoSt for %v", key)
+				if !newFaultPower.IsZero() {
+					faultyPartitionIdxs = append(faultyPartitionIdxs, i)
+				}
 
 				// Save new partition state.
 				err = partitions.Set(i, &partition)
@@ -1748,8 +2532,17 @@ func handleProvingDeadline(rt Runtime) {
 				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store partitions")
 			}
 
+			// Record partitions with sectors now scheduled to expire at the fault expiration.
+			err = deadline.AddExpirationPartitions(store, faultExpiration, faultyPartitionIdxs, quant)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add fault expirations for deadline %d", dlInfo.Index)
+
 			// Reset PoSt submissions.
 			deadline.PostSubmissions = abi.NewBitField()
+
+			// Capture the state against which proofs accepted optimistically during the challenge window
+			// may be disputed.
+			err = deadline.SnapshotOptimisticPoSts(store, st.Sectors)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to snapshot proofs for deadline %d", dlInfo.Index)
 		}
 		{
 			// Record faulty power for penalisation of ongoing faults, before popping expirations.
@@ -1762,6 +2555,21 @@ func handleProvingDeadline(rt Runtime) {
 			penaltyTotal = big.Sum(penaltyTotal, penaltyFromVesting, penaltyFromBalance)
 			pledgeDelta = big.Sum(pledgeDelta, penaltyFromVesting.Neg())
 		}
+		{
+			// Renew extended sectors whose deal weight expires now, at raw-byte power, before expiring the rest.
+			// Faulty sectors are renewed as faults, and expire early unless they recover within the maximum fault age.
+			// Their power is not claimed, so only the faulty power changes.
+			info := getMinerInfo(rt, &st)
+			sectors, err := LoadSectors(store, st.Sectors)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load sectors")
+			faultExpiration := dlInfo.Last() + FaultMaxAge
+			renewed, renewedPowerDelta, renewedFaultyPowerDelta, err := deadline.RenewExtendedSectors(store, sectors, dlInfo.Last(), faultExpiration, info.SectorSize, quant)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to renew extended sectors")
+			err = st.PutSectors(store, renewed...)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save renewed sectors")
+			powerDelta = powerDelta.Add(renewedPowerDelta)
+			st.FaultyPower = st.FaultyPower.Add(renewedFaultyPowerDelta)
+		}
 		{
 			// Expire sectors that are due, either for on-time expiration or "early" faulty-for-too-long.
 			expired, err := deadline.PopExpiredSectors(store, dlInfo.Last(), quant)
@@ -2050,22 +2858,117 @@ func requestTerminateDeals(rt Runtime, epoch abi.ChainEpoch, dealIDs []abi.DealI
 			abi.NewTokenAmount(0),
 		)
 		builtin.RequireSuccess(rt, code, "failed to terminate deals, exit code %v", code)
-		dealIDs = dealIDs[:size]
+		dealIDs = dealIDs[size:]
+	}
+}
+
+func requestTerminateAllDeals(rt Runtime, st *State) {
+	// TODO: red flag this is an ~unbounded computation.
+	// Transform into an idempotent partial computation that can be progressed on each invocation.
+	// https://github.com/filecoin-project/specs-actors/issues/675
+	dealIds := []abi.DealID{}
+	if err := st.ForEachSector(adt.AsStore(rt), func(sector *SectorOnChainInfo) {
+		dealIds = append(dealIds, sector.DealIDs...)
+	}); err != nil {
+		rt.Abortf(exitcode.ErrIllegalState, "failed to traverse sectors for termination: %v", err)
+	}
+
+	requestTerminateDeals(rt, rt.CurrEpoch(), dealIds)
+}
+
+// Progresses the exit of a miner, terminating a batch of live sectors and processing early terminations.
+// Reschedules itself until no sectors remain, then completes the exit.
+func processMinerExit(rt Runtime) {
+	store := adt.AsStore(rt)
+	var st State
+	var moreSectors bool
+	powerDelta := NewPowerPairZero()
+	newlyVested := rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		removedPower, more, err := st.TerminateLiveSectors(store, rt.CurrEpoch(), AddressedSectorsMax, info.SectorSize)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to terminate sectors")
+		powerDelta = removedPower.Neg()
+		moreSectors = more
+
+		newlyVested, err := st.UnlockVestedFunds(store, rt.CurrEpoch())
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to vest funds")
+		return newlyVested
+	}).(abi.TokenAmount)
+
+	requestUpdatePower(rt, powerDelta)
+	notifyPledgeChanged(rt, newlyVested.Neg())
+
+	// Termination fees and pledge are settled as the early terminations are processed.
+	moreTerminations := processEarlyTerminations(rt)
+	if moreSectors || moreTerminations {
+		scheduleMinerExitWork(rt, rt.CurrEpoch()+1)
+		return
 	}
+	finishMinerExit(rt)
 }
 
-func requestTerminateAllDeals(rt Runtime, st *State) { //nolint:deadcode,unused
-	// TODO: red flag this is an ~unbounded computation.
-	// Transform into an idempotent partial computation that can be progressed on each invocation.
-	// https://github.com/filecoin-project/specs-actors/issues/675
-	dealIds := []abi.DealID{}
-	if err := st.ForEachSector(adt.AsStore(rt), func(sector *SectorOnChainInfo) {
-		dealIds = append(dealIds, sector.DealIDs...)
-	}); err != nil {
-		rt.Abortf(exitcode.ErrIllegalState, "failed to traverse sectors for termination: %v", err)
+// Completes the exit of a miner with no remaining sectors.
+// Fee debt is repaid from vesting funds and then balance. Any debt exceeding the miner's funds is forgiven:
+// it is cleared from state and logged, since nothing remains to burn.
+// If funds remain vesting, this is rescheduled for after they unlock. Otherwise the miner's power claim is
+// removed and the actor deleted, returning the remaining balance to the owner.
+// Any balance left in the market actor's escrow for this miner is left behind.
+func finishMinerExit(rt Runtime) {
+	store := adt.AsStore(rt)
+	var st State
+	var owner addr.Address
+	var stillVesting bool
+	var vestingEnd abi.ChainEpoch
+	var penaltyTotal, pledgeDelta abi.TokenAmount
+	forgivenDebt := big.Zero()
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		owner = info.Owner
+		Assert(st.InitialPledgeRequirement.IsZero())
+
+		penaltyFromVesting, penaltyFromBalance, err := st.RepayDebtsInPriorityOrder(store, rt.CurrEpoch(),
+			st.GetUnlockedBalance(rt.CurrentBalance()))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to repay debts")
+		penaltyTotal = big.Add(penaltyFromVesting, penaltyFromBalance)
+		pledgeDelta = penaltyFromVesting.Neg()
+
+		if !st.LockedFunds.IsZero() {
+			summary, err := st.SummarizeVestingFunds(store, rt.CurrEpoch())
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to summarize vesting funds")
+			stillVesting = true
+			vestingEnd = summary.LastEpoch
+		} else if !st.FeeDebt.IsZero() {
+			// Vesting funds are exhausted before balance, so no further funds can become available.
+			forgivenDebt = st.FeeDebt
+			st.FeeDebt = big.Zero()
+		}
+		return nil
+	})
+
+	if !forgivenDebt.IsZero() {
+		rt.Log(vmr.WARN, "forgiving fee debt %v of exiting miner %v", forgivenDebt, rt.Message().Receiver())
+	}
+
+	burnFunds(rt, penaltyTotal)
+	notifyPledgeChanged(rt, pledgeDelta)
+
+	if stillVesting {
+		// Funds are unlocked in the epoch after they vest.
+		scheduleMinerExitWork(rt, vestingEnd+1)
+		return
 	}
 
-	requestTerminateDeals(rt, rt.CurrEpoch(), dealIds)
+	_, code := rt.Send(builtin.StoragePowerActorAddr, builtin.MethodsPower.OnMinerExit, nil, big.Zero())
+	builtin.RequireSuccess(rt, code, "failed to remove power claim")
+
+	// With no sectors, pre-commitments or locked funds, the whole balance is available.
+	rt.DeleteActor(owner)
+}
+
+func scheduleMinerExitWork(rt Runtime, epoch abi.ChainEpoch) {
+	enrollCronEvent(rt, epoch, &CronEventPayload{
+		EventType: CronEventExitMiner,
+	})
 }
 
 func scheduleEarlyTerminationWork(rt Runtime) {
@@ -2081,7 +2984,8 @@ func havePendingEarlyTerminations(rt Runtime, st *State) bool {
 	return !noEarlyTerminations
 }
 
-func verifyWindowedPost(rt Runtime, challengeEpoch abi.ChainEpoch, sectors []*SectorOnChainInfo, proofs []abi.PoStProof) {
+// Verifies a window PoSt for a set of sectors, returning an error if the proof is invalid.
+func verifyWindowedPost(rt Runtime, challengeEpoch abi.ChainEpoch, sectors []*SectorOnChainInfo, proofs []abi.PoStProof) error {
 	minerActorID, err := addr.IDFromAddress(rt.Message().Receiver())
 	AssertNoError(err) // Runtime always provides ID-addresses
 
@@ -2110,8 +3014,9 @@ func verifyWindowedPost(rt Runtime, challengeEpoch abi.ChainEpoch, sectors []*Se
 
 	// Verify the PoSt Proof
 	if err = rt.Syscalls().VerifyPoSt(pvInfo); err != nil {
-		rt.Abortf(exitcode.ErrIllegalArgument, "invalid PoSt %+v: %s", pvInfo, err)
+		return fmt.Errorf("invalid PoSt %+v: %w", pvInfo, err)
 	}
+	return nil
 }
 
 // SealVerifyParams is the structure of information that must be sent with a
@@ -2194,22 +3099,27 @@ func requestUnsealedSectorCID(rt Runtime, proofType abi.RegisteredSealProof, dea
 	return cid.Cid(unsealedCID)
 }
 
-func requestDealWeight(rt Runtime, dealIDs []abi.DealID, sectorStart, sectorExpiry abi.ChainEpoch) market.VerifyDealsForActivationReturn {
+// Requests the deal weights for a batch of sectors from the market, in a single call.
+// The weights are returned in the same order as the sectors.
+func requestDealWeights(rt Runtime, sectors []market.SectorDeals) market.VerifyDealsForActivationReturn {
+	params := market.VerifyDealsForActivationParams{
+		Sectors: sectors,
+	}
+
 	var dealWeights market.VerifyDealsForActivationReturn
 	ret, code := rt.Send(
 		builtin.StorageMarketActorAddr,
 		builtin.MethodsMarket.VerifyDealsForActivation,
-		&market.VerifyDealsForActivationParams{
-			DealIDs:      dealIDs,
-			SectorStart:  sectorStart,
-			SectorExpiry: sectorExpiry,
-		},
+		&params,
 		abi.NewTokenAmount(0),
 	)
 	builtin.RequireSuccess(rt, code, "failed to verify deals and get deal weight")
 	AssertNoError(ret.Into(&dealWeights))
+	if len(dealWeights.Sectors) != len(sectors) {
+		rt.Abortf(exitcode.ErrIllegalState, "deal weight request returned %d records, expected %d",
+			len(dealWeights.Sectors), len(sectors))
+	}
 	return dealWeights
-
 }
 
 func commitWorkerKeyChange(rt Runtime) *adt.EmptyValue {
@@ -2268,6 +3178,20 @@ func verifyPledgeMeetsInitialRequirements(rt Runtime, st *State) {
 	}
 }
 
+// Verifies that the miner has no unpaid penalties.
+func verifyNoFeeDebt(rt Runtime, st *State) {
+	if !st.IsDebtFree() {
+		rt.Abortf(exitcode.ErrInsufficientFunds, "unpaid fee debt %v", st.FeeDebt)
+	}
+}
+
+// Verifies that the miner is not exiting, so its sectors may be changed.
+func verifyNotExiting(rt Runtime, st *State) {
+	if st.Exiting {
+		rt.Abortf(exitcode.ErrForbidden, "miner is exiting")
+	}
+}
+
 // Resolves an address to an ID address and verifies that it is address of an account or multisig actor.
 func resolveOwnerAddress(rt Runtime, raw addr.Address) addr.Address {
 	resolved, ok := rt.ResolveAddress(raw)
@@ -2318,6 +3242,40 @@ func resolveWorkerAddress(rt Runtime, raw addr.Address) addr.Address {
 	return resolved
 }
 
+// Validates that the immediate caller is the worker, one of the control addresses, or one of the other
+// addresses given.
+func validateWorkerOrControlCaller(rt Runtime, info *MinerInfo, others ...addr.Address) {
+	allowed := make([]addr.Address, 0, 1+len(info.ControlAddresses)+len(others))
+	allowed = append(allowed, info.Worker)
+	allowed = append(allowed, info.ControlAddresses...)
+	rt.ValidateImmediateCallerIs(append(allowed, others...)...)
+}
+
+// Resolves control addresses to ID addresses and verifies that each is the address of an account actor.
+func resolveControlAddresses(rt Runtime, raw []addr.Address) []addr.Address {
+	if len(raw) > MaxControlAddresses {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many control addresses %d, max %d", len(raw), MaxControlAddresses)
+	}
+	var resolved []addr.Address
+	for _, a := range raw {
+		r, ok := rt.ResolveAddress(a)
+		if !ok {
+			rt.Abortf(exitcode.ErrIllegalArgument, "unable to resolve address %v", a)
+		}
+		Assert(r.Protocol() == addr.ID)
+
+		code, ok := rt.GetActorCodeCID(r)
+		if !ok {
+			rt.Abortf(exitcode.ErrIllegalArgument, "no code for address %v", r)
+		}
+		if code != builtin.AccountActorCodeID {
+			rt.Abortf(exitcode.ErrIllegalArgument, "control address %v must be an account, was %v", r, code)
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved
+}
+
 func burnFunds(rt Runtime, amt abi.TokenAmount) {
 	if amt.GreaterThan(big.Zero()) {
 		_, code := rt.Send(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, amt)
@@ -2386,6 +3344,321 @@ func declarationDeadlineInfo(periodStart abi.ChainEpoch, deadlineIdx uint64, cur
 	return deadline, nil
 }
 
+// Records faults for the declared sectors, returning the indices of the declarations applied.
+// If partial is false, any invalid declaration aborts. Otherwise invalid declarations are ignored.
+func declareFaults(rt Runtime, decls []FaultDeclaration, partial bool) *abi.BitField {
+	// A single rejection function handles both modes so that the validations are identical.
+	reject := func(code exitcode.ExitCode, msg string, args ...interface{}) {
+		if !partial {
+			rt.Abortf(code, msg, args...)
+		}
+	}
+
+	keys := make([]PartitionKey, len(decls))
+	sectors := make([]*abi.BitField, len(decls))
+	for i, decl := range decls {
+		keys[i] = PartitionKey{decl.Deadline, decl.Partition}
+		sectors[i] = decl.Sectors
+	}
+	validDecls := validateFRDeclarations(rt, keys, sectors, reject)
+
+	store := adt.AsStore(rt)
+	var st State
+	var applied []uint64
+	newFaultPowerTotal := NewPowerPairZero()
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		validateWorkerOrControlCaller(rt, info)
+		// Faults may be declared while exiting, since the sectors remain until the exit terminates them.
+
+		deadlines, err := st.LoadDeadlines(store)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+		quant := st.QuantEndOfDeadline()
+
+		// Group declarations by deadline, and remember iteration order.
+		declsByDeadline, deadlinesToLoad := groupFRDeclarations(keys, validDecls)
+
+		for _, dlIdx := range deadlinesToLoad {
+			targetDeadline, err := declarationDeadlineInfo(st.ProvingPeriodStart, dlIdx, rt.CurrEpoch())
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid fault declaration deadline %d", dlIdx)
+			if err = validateFRDeclarationDeadline(targetDeadline); err != nil {
+				reject(exitcode.ErrIllegalArgument, "failed fault declaration at deadline %d: %s", dlIdx, err)
+				continue
+			}
+
+			deadline, err := deadlines.LoadDeadline(store, dlIdx)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
+
+			partitions, err := deadline.PartitionsArray(store)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", dlIdx)
+
+			// Record partitions with some fault, for subsequently indexing in the deadline.
+			// Duplicate entries don't matter, they'll be stored in a bitfield (a set).
+			partitionsWithFault := make([]uint64, 0, len(declsByDeadline))
+			faultExpirationEpoch := targetDeadline.Last() + FaultMaxAge
+
+			for _, i := range declsByDeadline[dlIdx] {
+				decl := &decls[i]
+				key := keys[i]
+				var partition Partition
+				found, err := partitions.Get(decl.Partition, &partition)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partition %v", key)
+				if !found {
+					reject(exitcode.ErrNotFound, "no such partition %v", key)
+					continue
+				}
+
+				if err = validateFRDeclarationPartition(key, &partition, decl.Sectors); err != nil {
+					reject(exitcode.ErrIllegalArgument, "failed fault declaration for partition %v: %s", key, err)
+					continue
+				}
+
+				// Split declarations into declarations of new faults, and retraction of declared recoveries.
+				retractedRecoveries, err := bitfield.IntersectBitField(partition.Recoveries, decl.Sectors)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to intersect sectors with recoveries")
+
+				newFaults, err := bitfield.SubtractBitField(decl.Sectors, retractedRecoveries)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract recoveries from sectors")
+				// Ignore any terminated sectors and previously declared or detected faults
+				newFaults, err = bitfield.SubtractBitField(newFaults, partition.Terminated)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract terminations from faults")
+				newFaults, err = bitfield.SubtractBitField(newFaults, partition.Faults)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract existing faults from faults")
+
+				// Add new faults to state.
+				empty, err := newFaults.IsEmpty()
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check if bitfield was empty")
+				if !empty {
+					newFaultSectors, err := st.LoadSectorInfos(store, newFaults)
+					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load fault sectors")
+
+					newFaultPower, err := partition.AddFaults(store, newFaults, newFaultSectors, faultExpirationEpoch, info.SectorSize, quant)
+					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add faults")
+
+					st.FaultyPower = st.FaultyPower.Add(newFaultPower)
+					newFaultPowerTotal = newFaultPowerTotal.Add(newFaultPower)
+					partitionsWithFault = append(partitionsWithFault, decl.Partition)
+				}
+
+				// Remove faulty recoveries from state.
+				empty, err = retractedRecoveries.IsEmpty()
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to check if bitfield was empty")
+				if !empty {
+					retractedRecoverySectors, err := st.LoadSectorInfos(store, retractedRecoveries)
+					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load recovery sectors")
+					retractedRecoveryPower := PowerForSectors(info.SectorSize, retractedRecoverySectors)
+
+					err = partition.RemoveRecoveries(retractedRecoveries, retractedRecoveryPower)
+					builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove recoveries")
+				}
+
+				err = partitions.Set(decl.Partition, &partition)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store partition %v", key)
+				applied = append(applied, i)
+			}
+			deadline.Partitions, err = partitions.Root()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d partitions root", dlIdx)
+
+			err = deadline.AddExpirationPartitions(store, faultExpirationEpoch, partitionsWithFault, quant)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update fault epochs for deadline %d", dlIdx)
+
+			err = deadlines.UpdateDeadline(store, dlIdx, deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d partitions", dlIdx)
+		}
+
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
+
+		return nil
+	})
+
+	// Remove power for new faulty sectors.
+	// NOTE: It would be permissible to delay the power loss until the deadline closes, but that would require
+	// additional accounting state.
+	// https://github.com/filecoin-project/specs-actors/issues/414
+	requestUpdatePower(rt, newFaultPowerTotal.Neg())
+
+	// Payment of penalty for declared faults is deferred to the deadline cron.
+	return bitfield.NewFromSet(applied)
+}
+
+// Records recoveries for the declared sectors, returning the indices of the declarations applied.
+// If partial is false, any invalid declaration aborts. Otherwise invalid declarations are ignored.
+func declareRecoveries(rt Runtime, decls []RecoveryDeclaration, partial bool) *abi.BitField {
+	reject := func(code exitcode.ExitCode, msg string, args ...interface{}) {
+		if !partial {
+			rt.Abortf(code, msg, args...)
+		}
+	}
+
+	keys := make([]PartitionKey, len(decls))
+	sectors := make([]*abi.BitField, len(decls))
+	for i, decl := range decls {
+		keys[i] = PartitionKey{decl.Deadline, decl.Partition}
+		sectors[i] = decl.Sectors
+	}
+	validDecls := validateFRDeclarations(rt, keys, sectors, reject)
+
+	store := adt.AsStore(rt)
+	var st State
+	var applied []uint64
+	rt.State().Transaction(&st, func() interface{} {
+		info := getMinerInfo(rt, &st)
+		validateWorkerOrControlCaller(rt, info)
+		verifyNotExiting(rt, &st)
+
+		deadlines, err := st.LoadDeadlines(adt.AsStore(rt))
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadlines")
+
+		// Group declarations by deadline, and remember iteration order.
+		declsByDeadline, deadlinesToLoad := groupFRDeclarations(keys, validDecls)
+
+		for _, dlIdx := range deadlinesToLoad {
+			targetDeadline, err := declarationDeadlineInfo(st.ProvingPeriodStart, dlIdx, rt.CurrEpoch())
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalArgument, "invalid recovery declaration deadline %d", dlIdx)
+			if err = validateFRDeclarationDeadline(targetDeadline); err != nil {
+				reject(exitcode.ErrIllegalArgument, "failed recovery declaration at deadline %d: %s", dlIdx, err)
+				continue
+			}
+
+			deadline, err := deadlines.LoadDeadline(store, dlIdx)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load deadline %d", dlIdx)
+
+			partitions, err := deadline.PartitionsArray(store)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partitions for deadline %d", dlIdx)
+
+			for _, i := range declsByDeadline[dlIdx] {
+				decl := &decls[i]
+				key := keys[i]
+				var partition Partition
+				found, err := partitions.Get(decl.Partition, &partition)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load partition %v", key)
+				if !found {
+					reject(exitcode.ErrNotFound, "no such partition %v", key)
+					continue
+				}
+
+				if err = validateFRDeclarationPartition(key, &partition, decl.Sectors); err != nil {
+					reject(exitcode.ErrIllegalArgument, "failed recovery declaration for partition %v: %s", key, err)
+					continue
+				}
+
+				// Ignore sectors not faulty or already declared recovered
+				recoveries, err := bitfield.IntersectBitField(decl.Sectors, partition.Faults)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to intersect recoveries with faults")
+				recoveries, err = bitfield.SubtractBitField(recoveries, partition.Recoveries)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to subtract existing recoveries")
+
+				// Record the new recoveries for processing at Window PoSt or deadline cron.
+				recoverySectors, err := st.LoadSectorInfos(store, recoveries)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load recovery sectors")
+				recoveryPower := PowerForSectors(info.SectorSize, recoverySectors)
+
+				err = partition.AddRecoveries(recoveries, recoveryPower)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to add recoveries")
+
+				err = partitions.Set(decl.Partition, &partition)
+				builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to update partition %v", key)
+				applied = append(applied, i)
+			}
+
+			deadline.Partitions, err = partitions.Root()
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save partitions array")
+
+			err = deadlines.UpdateDeadline(store, dlIdx, deadline)
+			builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to store deadline %d", dlIdx)
+		}
+
+		err = st.SaveDeadlines(store, deadlines)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to save deadlines")
+
+		return nil
+	})
+
+	// Power is not restored yet, but when the recovered sectors are successfully PoSted.
+	return bitfield.NewFromSet(applied)
+}
+
+// Validates the number of fault or recovery declarations and the sectors they address, and the range of each
+// declaration's deadline. Returns the indices of the declarations with a valid deadline and sector set.
+// Invalid declarations are passed to reject, which may abort. Limits on the message as a whole always abort.
+func validateFRDeclarations(rt Runtime, keys []PartitionKey, sectors []*abi.BitField,
+	reject func(code exitcode.ExitCode, msg string, args ...interface{})) []uint64 {
+	if uint64(len(keys)) > AddressedPartitionsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument, "too many declarations %d, max %d", len(keys), AddressedPartitionsMax)
+	}
+
+	var sectorCount uint64
+	valid := make([]uint64, 0, len(keys))
+	for i, key := range keys {
+		if key.Deadline >= WPoStPeriodDeadlines {
+			reject(exitcode.ErrIllegalArgument, "deadline %d not in range 0..%d", key.Deadline, WPoStPeriodDeadlines)
+			continue
+		}
+		count, err := sectors[i].Count()
+		if err != nil {
+			reject(exitcode.ErrIllegalArgument, "failed to count sectors for deadline %d, partition %d: %s",
+				key.Deadline, key.Partition, err)
+			continue
+		}
+		sectorCount += count
+		valid = append(valid, uint64(i))
+	}
+	if sectorCount > AddressedSectorsMax {
+		rt.Abortf(exitcode.ErrIllegalArgument,
+			"too many sectors for declaration %d, max %d",
+			sectorCount, AddressedSectorsMax,
+		)
+	}
+	return valid
+}
+
+// Groups the indices of fault or recovery declarations by deadline, returning the deadlines in the order
+// in which they first appear.
+func groupFRDeclarations(keys []PartitionKey, indices []uint64) (map[uint64][]uint64, []uint64) {
+	byDeadline := map[uint64][]uint64{}
+	var deadlines []uint64
+	for _, i := range indices {
+		dlIdx := keys[i].Deadline
+		if _, ok := byDeadline[dlIdx]; !ok {
+			deadlines = append(deadlines, dlIdx)
+		}
+		byDeadline[dlIdx] = append(byDeadline[dlIdx], i)
+	}
+	return byDeadline, deadlines
+}
+
+// Checks that a sector number mask does not include any number greater than abi.MaxSectorNumber.
+func validateSectorNumberMask(mask *abi.BitField) error {
+	runs, err := mask.RunIterator()
+	if err != nil {
+		return err
+	}
+	var end, count, setRuns uint64
+	for runs.HasNext() {
+		run, err := runs.NextRun()
+		if err != nil {
+			return err
+		}
+		end += run.Len
+		if !run.Val {
+			continue
+		}
+		if end-1 > abi.MaxSectorNumber {
+			return fmt.Errorf("sector number %d exceeds max %d", end-1, abi.MaxSectorNumber)
+		}
+		setRuns++
+		if setRuns > SectorNumberMaskRunsMax {
+			return fmt.Errorf("too many runs in mask, max %d", SectorNumberMaskRunsMax)
+		}
+		count += run.Len
+		if count > AddressedSectorsMax {
+			return fmt.Errorf("too many sector numbers in mask, max %d", AddressedSectorsMax)
+		}
+	}
+	return nil
+}
+
 // Checks that a fault or recovery declaration at a specific deadline is outside the exclusion window for the deadline.
 func validateFRDeclarationDeadline(deadline *DeadlineInfo) error {
 	if deadline.FaultCutoffPassed() {
@@ -22,6 +22,8 @@ import (
 // It is possible for balance to fall below the sum of
 // PCD, LF and InitialPledgeRequirements, and this is a bad
 // state (IP Debt) that limits a miner actor's behavior (i.e. no balance withdrawals)
+// Penalties that cannot be paid from the vesting table or unlocked balance accrue as FeeDebt,
+// which also blocks withdrawals and pre-commitments until repaid.
 // Excess balance as computed by st.GetAvailableBalance will be
 // withdrawable or usable for pre-commit deposit or pledge lock-up.
 type State struct {
@@ -33,6 +35,8 @@ type State struct {
 
 	VestingFunds cid.Cid // Array, AMT[ChainEpoch]TokenAmount
 
+	FeeDebt abi.TokenAmount // Penalties that have been charged but not yet paid
+
 	InitialPledgeRequirement abi.TokenAmount // Sum of initial pledge requirements of all active sectors
 
 	// Sectors that have been pre-committed but not yet proven.
@@ -41,6 +45,10 @@ type State struct {
 	// Information for all proven and not-yet-expired sectors.
 	Sectors cid.Cid // Array, AMT[SectorNumber]SectorOnChainInfo (sparse)
 
+	// Sector numbers that have been pre-committed or masked by the owner, and so may not be pre-committed again.
+	// Numbers remain allocated after their sectors expire or are terminated, or their pre-commits are withdrawn.
+	AllocatedSectors *bitfield.BitField
+
 	// The first epoch in this miner's current proving period. This is the first epoch in which a PoSt for a
 	// partition at the miner's first deadline may arrive. Alternatively, it is after the last epoch at which
 	// a PoSt for the previous window is valid.
@@ -65,6 +73,10 @@ type State struct {
 
 	// Memoized power information
 	FaultyPower PowerPair
+
+	// Set when the owner has requested that the miner exit. An exiting miner accepts no new sectors, and is
+	// deleted once all its sectors are terminated and its funds have vested.
+	Exiting bool
 }
 
 type MinerInfo struct {
@@ -77,6 +89,10 @@ type MinerInfo struct {
 	// The associated pubkey-type address is used to sign blocks and messages on behalf of this miner.
 	Worker addr.Address // Must be an ID-address.
 
+	// Additional addresses that may submit messages on behalf of this miner, such as window PoSts and
+	// sector pre-commitments, alongside the owner and worker.
+	ControlAddresses []addr.Address // Must all be ID-addresses.
+
 	PendingWorkerKey *WorkerKeyChange
 
 	// Byte array representing a Libp2p identity that should be used when connecting to this miner.
@@ -95,6 +111,20 @@ type MinerInfo struct {
 	// The number of sectors in each Window PoSt partition (proof).
 	// This is computed from the proof type and represented here redundantly.
 	WindowPoStPartitionSectors uint64
+
+	// A proposed new owner account for this miner.
+	// Must be confirmed by a message from the pending address itself.
+	PendingOwner *PendingOwnerChange
+
+	// Account that receives withdrawn funds.
+	// This is the owner, unless a change of beneficiary has been agreed by the owner and the beneficiary.
+	Beneficiary addr.Address // Must be an ID-address.
+
+	// The limits on withdrawals by a beneficiary other than the owner.
+	BeneficiaryTerm BeneficiaryTerm
+
+	// A proposed change of beneficiary, awaiting approval.
+	PendingBeneficiaryTerm *PendingBeneficiaryChange
 }
 
 type WorkerKeyChange struct {
@@ -102,6 +132,48 @@ type WorkerKeyChange struct {
 	EffectiveAt abi.ChainEpoch
 }
 
+type PendingOwnerChange struct {
+	NewOwner addr.Address // Must be an ID address
+}
+
+// The limits on the funds that a beneficiary other than the owner may withdraw.
+type BeneficiaryTerm struct {
+	// Total amount the beneficiary may withdraw.
+	Quota abi.TokenAmount
+	// Amount the beneficiary has withdrawn so far.
+	UsedQuota abi.TokenAmount
+	// Epoch from which the beneficiary may no longer withdraw.
+	Expiration abi.ChainEpoch
+}
+
+// Whether the beneficiary has withdrawn its full quota.
+func (t *BeneficiaryTerm) IsUsedUp() bool {
+	return t.UsedQuota.GreaterThanEqual(t.Quota)
+}
+
+// Whether the beneficiary's term has expired.
+func (t *BeneficiaryTerm) IsExpired(currEpoch abi.ChainEpoch) bool {
+	return currEpoch >= t.Expiration
+}
+
+// The amount the beneficiary may still withdraw.
+func (t *BeneficiaryTerm) Available(currEpoch abi.ChainEpoch) abi.TokenAmount {
+	if t.IsExpired(currEpoch) || t.IsUsedUp() {
+		return big.Zero()
+	}
+	return big.Sub(t.Quota, t.UsedQuota)
+}
+
+// A proposed change of beneficiary.
+// Takes effect once approved by both the nominated beneficiary and the current beneficiary.
+type PendingBeneficiaryChange struct {
+	NewBeneficiary        addr.Address // Must be an ID-address.
+	NewQuota              abi.TokenAmount
+	NewExpiration         abi.ChainEpoch
+	ApprovedByBeneficiary bool
+	ApprovedByNominee     bool
+}
+
 // Information provided by a miner when pre-committing a sector.
 type SectorPreCommitInfo struct {
 	SealProof       abi.RegisteredSealProof
@@ -124,8 +196,13 @@ type SectorPreCommitOnChainInfo struct {
 	PreCommitEpoch     abi.ChainEpoch
 	DealWeight         abi.DealWeight // Integral of active deals over sector lifetime
 	VerifiedDealWeight abi.DealWeight // Integral of active verified deals over sector lifetime
+	// Epoch at which a seal proof was last submitted for bulk verification, or NoProofSubmittedEpoch.
+	ProofSubmittedEpoch abi.ChainEpoch
 }
 
+// Value of ProofSubmittedEpoch for a pre-committed sector for which no proof has been submitted.
+const NoProofSubmittedEpoch = abi.ChainEpoch(-1)
+
 // Information stored on-chain for a proven sector.
 type SectorOnChainInfo struct {
 	SectorNumber       abi.SectorNumber
@@ -133,7 +210,9 @@ type SectorOnChainInfo struct {
 	SealedCID          cid.Cid                 // CommR
 	DealIDs            []abi.DealID
 	Activation         abi.ChainEpoch  // Epoch during which the sector proof was accepted
+	PowerBaseEpoch     abi.ChainEpoch  // Epoch from which the deal weight accrues: the activation, or the latest replica update
 	Expiration         abi.ChainEpoch  // Epoch during which the sector expires
+	ExtendedExpiration abi.ChainEpoch  // If non-zero, the epoch to which the sector is extended once its deal weight expires
 	DealWeight         abi.DealWeight  // Integral of active deals over sector lifetime
 	VerifiedDealWeight abi.DealWeight  // Integral of active verified deals over sector lifetime
 	InitialPledge      abi.TokenAmount // Pledge collected to commit this sector
@@ -160,11 +239,13 @@ func ConstructState(infoCid cid.Cid, periodStart abi.ChainEpoch, emptyArrayCid,
 
 		PreCommitDeposits:        abi.NewTokenAmount(0),
 		LockedFunds:              abi.NewTokenAmount(0),
+		FeeDebt:                  abi.NewTokenAmount(0),
 		VestingFunds:             emptyArrayCid,
 		InitialPledgeRequirement: abi.NewTokenAmount(0),
 
 		PreCommittedSectors: emptyMapCid,
 		Sectors:             emptyArrayCid,
+		AllocatedSectors:    abi.NewBitField(),
 		ProvingPeriodStart:  periodStart,
 		CurrentDeadline:     0,
 		Deadlines:           emptyDeadlinesCid,
@@ -174,7 +255,7 @@ func ConstructState(infoCid cid.Cid, periodStart abi.ChainEpoch, emptyArrayCid,
 	}, nil
 }
 
-func ConstructMinerInfo(owner addr.Address, worker addr.Address, pid []byte, multiAddrs [][]byte, sealProofType abi.RegisteredSealProof) (*MinerInfo, error) {
+func ConstructMinerInfo(owner addr.Address, worker addr.Address, controlAddrs []addr.Address, pid []byte, multiAddrs [][]byte, sealProofType abi.RegisteredSealProof) (*MinerInfo, error) {
 
 	sectorSize, err := sealProofType.SectorSize()
 	if err != nil {
@@ -188,12 +269,19 @@ func ConstructMinerInfo(owner addr.Address, worker addr.Address, pid []byte, mul
 	return &MinerInfo{
 		Owner:                      owner,
 		Worker:                     worker,
+		ControlAddresses:           controlAddrs,
 		PendingWorkerKey:           nil,
 		PeerId:                     pid,
 		Multiaddrs:                 multiAddrs,
 		SealProofType:              sealProofType,
 		SectorSize:                 sectorSize,
 		WindowPoStPartitionSectors: partitionSectors,
+		Beneficiary:                owner,
+		BeneficiaryTerm: BeneficiaryTerm{
+			Quota:      big.Zero(),
+			UsedQuota:  big.Zero(),
+			Expiration: 0,
+		},
 	}, nil
 }
 
@@ -306,6 +394,25 @@ func (st *State) DeletePrecommittedSectors(store adt.Store, sectorNos ...abi.Sec
 	return err
 }
 
+// Checks whether any sector is pre-committed.
+func (st *State) HasPreCommittedSectors(store adt.Store) (bool, error) {
+	precommitted, err := adt.AsMap(store, st.PreCommittedSectors)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	var info SectorPreCommitOnChainInfo
+	stopErr := errors.New("stop")
+	if err = precommitted.ForEach(&info, func(_ string) error {
+		found = true
+		return stopErr
+	}); err != nil && err != stopErr {
+		return false, xerrors.Errorf("failed to iterate pre-commitments: %w", err)
+	}
+	return found, nil
+}
+
 func (st *State) HasSectorNo(store adt.Store, sectorNo abi.SectorNumber) (bool, error) {
 	sectors, err := adt.AsArray(store, st.Sectors)
 	if err != nil {
@@ -320,6 +427,24 @@ func (st *State) HasSectorNo(store adt.Store, sectorNo abi.SectorNumber) (bool,
 	return found, nil
 }
 
+func (st *State) IsSectorNumberAllocated(sectorNo abi.SectorNumber) (bool, error) {
+	allocated, err := st.AllocatedSectors.IsSet(uint64(sectorNo))
+	if err != nil {
+		return false, xerrors.Errorf("failed to check allocation of sector number %v: %w", sectorNo, err)
+	}
+	return allocated, nil
+}
+
+// Marks sector numbers as allocated, whether or not they were already.
+func (st *State) MaskSectorNumbers(sectorNos *abi.BitField) error {
+	allocated, err := bitfield.MergeBitFields(st.AllocatedSectors, sectorNos)
+	if err != nil {
+		return xerrors.Errorf("failed to mask sector numbers: %w", err)
+	}
+	st.AllocatedSectors = allocated
+	return nil
+}
+
 func (st *State) PutSectors(store adt.Store, newSectors ...*SectorOnChainInfo) error {
 	sectors, err := adt.AsArray(store, st.Sectors)
 	if err != nil {
@@ -642,6 +767,99 @@ func (st *State) AssignSectorsToDeadlines(
 	return newPower, nil
 }
 
+// Terminates up to maxSectors live sectors at an epoch, in order of deadline and partition.
+// The sectors are added to the early termination queue, from which they are subsequently processed.
+//
+// Returns the power removed, and hasMore if some live sectors may remain.
+func (st *State) TerminateLiveSectors(store adt.Store, epoch abi.ChainEpoch, maxSectors uint64, ssize abi.SectorSize) (removedPower PowerPair, hasMore bool, err error) {
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return NewPowerPairZero(), false, xerrors.Errorf("failed to load deadlines: %w", err)
+	}
+	sectors, err := LoadSectors(store, st.Sectors)
+	if err != nil {
+		return NewPowerPairZero(), false, xerrors.Errorf("failed to load sectors: %w", err)
+	}
+	quant := st.QuantEndOfDeadline()
+
+	removedPower = NewPowerPairZero()
+	remaining := maxSectors
+	for dlIdx := uint64(0); dlIdx < WPoStPeriodDeadlines; dlIdx++ {
+		dl, err := deadlines.LoadDeadline(store, dlIdx)
+		if err != nil {
+			return NewPowerPairZero(), false, xerrors.Errorf("failed to load deadline %d: %w", dlIdx, err)
+		}
+		if dl.LiveSectors == 0 {
+			continue
+		}
+		if remaining == 0 {
+			hasMore = true
+			break
+		}
+
+		partitions, err := dl.PartitionsArray(store)
+		if err != nil {
+			return NewPowerPairZero(), false, xerrors.Errorf("failed to load partitions for deadline %d: %w", dlIdx, err)
+		}
+		byPartition := map[uint64][]*SectorOnChainInfo{}
+		var partition Partition
+		if err = partitions.ForEach(&partition, func(partIdx int64) error {
+			if remaining == 0 {
+				return nil
+			}
+			live, err := bitfield.SubtractBitField(partition.Sectors, partition.Terminated)
+			if err != nil {
+				return err
+			}
+			count, err := live.Count()
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return nil
+			}
+			if count > remaining {
+				if live, err = live.Slice(0, remaining); err != nil {
+					return err
+				}
+				count = remaining
+			}
+			infos, err := sectors.Load(live)
+			if err != nil {
+				return err
+			}
+			byPartition[uint64(partIdx)] = infos
+			remaining -= count
+			return nil
+		}); err != nil {
+			return NewPowerPairZero(), false, xerrors.Errorf("failed to select live sectors in deadline %d: %w", dlIdx, err)
+		}
+		if len(byPartition) == 0 {
+			continue
+		}
+
+		dlRemovedPower, err := dl.TerminateSectors(store, epoch, byPartition, ssize, quant)
+		if err != nil {
+			return NewPowerPairZero(), false, xerrors.Errorf("failed to terminate sectors in deadline %d: %w", dlIdx, err)
+		}
+		removedPower = removedPower.Add(dlRemovedPower)
+		st.EarlyTerminations.Set(dlIdx)
+
+		if err = deadlines.UpdateDeadline(store, dlIdx, dl); err != nil {
+			return NewPowerPairZero(), false, xerrors.Errorf("failed to store deadline %d: %w", dlIdx, err)
+		}
+		if dl.LiveSectors > 0 {
+			hasMore = true
+			break
+		}
+	}
+
+	if err = st.SaveDeadlines(store, deadlines); err != nil {
+		return NewPowerPairZero(), false, xerrors.Errorf("failed to save deadlines: %w", err)
+	}
+	return removedPower, hasMore, nil
+}
+
 // Pops up to max early terminated sectors from all deadlines.
 //
 // Returns hasMore if we still have more early terminations to process.
@@ -786,6 +1004,11 @@ func (st *State) LoadSectorInfos(store adt.Store, sectors *abi.BitField) ([]*Sec
 // If any of the sectors are declared faulty and not to be recovered, info for the first non-faulty sector is substituted instead.
 // If any of the sectors are declared recovered, they are returned from this method.
 func (st *State) LoadSectorInfosForProof(store adt.Store, provenSectors, expectedFaults *abi.BitField) ([]*SectorOnChainInfo, error) {
+	return loadSectorInfosForProof(store, st.Sectors, provenSectors, expectedFaults)
+}
+
+// Loads sector info for a set of sectors to be proven from a sectors AMT, such as a deadline's sectors snapshot.
+func loadSectorInfosForProof(store adt.Store, sectors cid.Cid, provenSectors, expectedFaults *abi.BitField) ([]*SectorOnChainInfo, error) {
 	nonFaults, err := bitfield.SubtractBitField(provenSectors, expectedFaults)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to diff bitfields: %w", err)
@@ -805,7 +1028,7 @@ func (st *State) LoadSectorInfosForProof(store adt.Store, provenSectors, expecte
 	}
 
 	// Load sector infos
-	sectorInfos, err := st.LoadSectorInfosWithFaultMask(store, provenSectors, expectedFaults, abi.SectorNumber(goodSectorNo))
+	sectorInfos, err := loadSectorInfosWithFaultMask(store, sectors, provenSectors, expectedFaults, abi.SectorNumber(goodSectorNo))
 	if err != nil {
 		return nil, xerrors.Errorf("failed to load sector infos: %w", err)
 	}
@@ -814,7 +1037,11 @@ func (st *State) LoadSectorInfosForProof(store adt.Store, provenSectors, expecte
 
 // Loads sector info for a sequence of sectors, substituting info for a stand-in sector for any that are faulty.
 func (st *State) LoadSectorInfosWithFaultMask(store adt.Store, sectors *abi.BitField, faults *abi.BitField, faultStandIn abi.SectorNumber) ([]*SectorOnChainInfo, error) {
-	sectorArr, err := adt.AsArray(store, st.Sectors)
+	return loadSectorInfosWithFaultMask(store, st.Sectors, sectors, faults, faultStandIn)
+}
+
+func loadSectorInfosWithFaultMask(store adt.Store, sectorsRoot cid.Cid, sectors *abi.BitField, faults *abi.BitField, faultStandIn abi.SectorNumber) ([]*SectorOnChainInfo, error) {
+	sectorArr, err := adt.AsArray(store, sectorsRoot)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to load sectors array: %w", err)
 	}
@@ -948,9 +1175,30 @@ func (st *State) AddLockedFunds(store adt.Store, currEpoch abi.ChainEpoch, vesti
 // If the target is not yet hit it deducts funds from the (new) available balance.
 // Returns the amount unlocked from the vesting table and the amount taken from current balance.
 // If the penalty exceeds the total amount available in the vesting table and unlocked funds
-// the penalty is reduced to match.  This must be fixed when handling bankrupcy:
-// https://github.com/filecoin-project/specs-actors/issues/627
+// the remainder is added to the miner's fee debt, to be paid later.
 func (st *State) PenalizeFundsInPriorityOrder(store adt.Store, currEpoch abi.ChainEpoch, target, unlockedBalance abi.TokenAmount) (fromVesting abi.TokenAmount, fromBalance abi.TokenAmount, err error) {
+	fromVesting, fromBalance, err = st.takeFundsInPriorityOrder(store, currEpoch, target, unlockedBalance)
+	if err != nil {
+		return abi.NewTokenAmount(0), abi.NewTokenAmount(0), err
+	}
+	unpaid := big.Subtract(target, fromVesting, fromBalance)
+	st.FeeDebt = big.Add(st.FeeDebt, unpaid)
+	return fromVesting, fromBalance, nil
+}
+
+// RepayDebtsInPriorityOrder pays off as much fee debt as possible, first from unvested funds in the vesting
+// table and then from the unlocked balance.
+// Returns the amount unlocked from the vesting table and the amount taken from current balance.
+func (st *State) RepayDebtsInPriorityOrder(store adt.Store, currEpoch abi.ChainEpoch, unlockedBalance abi.TokenAmount) (fromVesting abi.TokenAmount, fromBalance abi.TokenAmount, err error) {
+	fromVesting, fromBalance, err = st.takeFundsInPriorityOrder(store, currEpoch, st.FeeDebt, unlockedBalance)
+	if err != nil {
+		return abi.NewTokenAmount(0), abi.NewTokenAmount(0), err
+	}
+	st.FeeDebt = big.Subtract(st.FeeDebt, fromVesting, fromBalance)
+	return fromVesting, fromBalance, nil
+}
+
+func (st *State) takeFundsInPriorityOrder(store adt.Store, currEpoch abi.ChainEpoch, target, unlockedBalance abi.TokenAmount) (fromVesting abi.TokenAmount, fromBalance abi.TokenAmount, err error) {
 	fromVesting, err = st.UnlockUnvestedFunds(store, currEpoch, target)
 	if err != nil {
 		return abi.NewTokenAmount(0), abi.NewTokenAmount(0), err
@@ -1091,6 +1339,124 @@ func (st *State) CheckVestedFunds(store adt.Store, currEpoch abi.ChainEpoch) (ab
 	return amountUnlocked, nil
 }
 
+// An entry in the vesting table: an amount of locked funds that vests at an epoch.
+type VestingFund struct {
+	Epoch  abi.ChainEpoch
+	Amount abi.TokenAmount
+}
+
+// Aggregate totals of the vesting table with respect to some epoch.
+type VestingSummary struct {
+	Entries    uint64
+	FirstEpoch abi.ChainEpoch  // Epoch of the earliest entry, or zero if the table is empty.
+	LastEpoch  abi.ChainEpoch  // Epoch of the latest entry, or zero if the table is empty.
+	Total      abi.TokenAmount // Sum of all entries, expected to equal LockedFunds.
+	Vested     abi.TokenAmount // Sum of entries that have vested but are not yet unlocked.
+	Unvested   abi.TokenAmount // Sum of entries yet to vest.
+}
+
+// Loads the vesting table, in order of increasing epoch.
+func (st *State) LoadVestingFunds(store adt.Store) ([]VestingFund, error) {
+	vestingFunds, err := adt.AsArray(store, st.VestingFunds)
+	if err != nil {
+		return nil, err
+	}
+
+	var funds []VestingFund
+	lockedEntry := abi.NewTokenAmount(0)
+	err = vestingFunds.ForEach(&lockedEntry, func(k int64) error {
+		funds = append(funds, VestingFund{Epoch: abi.ChainEpoch(k), Amount: lockedEntry})
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load vesting funds: %w", err)
+	}
+	return funds, nil
+}
+
+// Summarizes the vesting table as at the provided epoch.
+// Funds vest at the epoch after their entry, consistent with UnlockVestedFunds.
+func (st *State) SummarizeVestingFunds(store adt.Store, currEpoch abi.ChainEpoch) (*VestingSummary, error) {
+	funds, err := st.LoadVestingFunds(store)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &VestingSummary{
+		Entries:  uint64(len(funds)),
+		Total:    big.Zero(),
+		Vested:   big.Zero(),
+		Unvested: big.Zero(),
+	}
+	if len(funds) > 0 {
+		summary.FirstEpoch = funds[0].Epoch
+		summary.LastEpoch = funds[len(funds)-1].Epoch
+	}
+	for _, fund := range funds {
+		summary.Total = big.Add(summary.Total, fund.Amount)
+		if fund.Epoch < currEpoch {
+			summary.Vested = big.Add(summary.Vested, fund.Amount)
+		} else {
+			summary.Unvested = big.Add(summary.Unvested, fund.Amount)
+		}
+	}
+	return summary, nil
+}
+
+// Projects the unlocked balance at each of some future epochs, assuming vested funds are unlocked and
+// there are no other changes to the balance, locked funds or pre-commit deposits.
+// Returns one amount for each epoch, in the order provided.
+func (st *State) ProjectUnlockedBalance(store adt.Store, actorBalance abi.TokenAmount, epochs ...abi.ChainEpoch) ([]abi.TokenAmount, error) {
+	funds, err := st.LoadVestingFunds(store)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cumulative sums of vested amounts, such that vestedBefore[i] is the sum of the first i entries.
+	vestedBefore := make([]abi.TokenAmount, len(funds)+1)
+	vestedBefore[0] = big.Zero()
+	for i, fund := range funds {
+		vestedBefore[i+1] = big.Add(vestedBefore[i], fund.Amount)
+	}
+
+	unlockedBalance := st.GetUnlockedBalance(actorBalance)
+	projections := make([]abi.TokenAmount, len(epochs))
+	for i, epoch := range epochs {
+		vestedCount := sort.Search(len(funds), func(j int) bool {
+			return funds[j].Epoch >= epoch
+		})
+		projections[i] = big.Add(unlockedBalance, vestedBefore[vestedCount])
+	}
+	return projections, nil
+}
+
+// Merges vesting table entries into coarser buckets, to reduce the size of the table.
+// Each entry's epoch is rounded up to the next multiple of the quantization unit, aligned with the proving
+// period start, so no funds vest earlier than previously scheduled. The total locked funds is unchanged.
+func (st *State) CompactVestingFunds(store adt.Store, quantization abi.ChainEpoch) error {
+	AssertMsg(quantization > 0, "non-positive vesting quantization %d", quantization)
+	funds, err := st.LoadVestingFunds(store)
+	if err != nil {
+		return err
+	}
+
+	vestingFunds := adt.MakeEmptyArray(store)
+	for _, fund := range funds {
+		key := EpochKey(quantizeUp(fund.Epoch, quantization, st.ProvingPeriodStart))
+		lockedFundEntry := big.Zero()
+		if _, err = vestingFunds.Get(key, &lockedFundEntry); err != nil {
+			return err
+		}
+		lockedFundEntry = big.Add(lockedFundEntry, fund.Amount)
+		if err = vestingFunds.Set(key, &lockedFundEntry); err != nil {
+			return err
+		}
+	}
+
+	st.VestingFunds, err = vestingFunds.Root()
+	return err
+}
+
 // Unclaimed funds that are not locked -- includes funds used to cover initial pledge requirement
 func (st *State) GetUnlockedBalance(actorBalance abi.TokenAmount) abi.TokenAmount {
 	unlockedBalance := big.Subtract(actorBalance, st.LockedFunds, st.PreCommitDeposits)
@@ -1098,11 +1464,11 @@ func (st *State) GetUnlockedBalance(actorBalance abi.TokenAmount) abi.TokenAmoun
 	return unlockedBalance
 }
 
-// Unclaimed funds.  Actor balance - (locked funds, precommit deposit, ip requirement)
-// Can go negative if the miner is in IP debt
+// Unclaimed funds.  Actor balance - (locked funds, precommit deposit, ip requirement, fee debt)
+// Can go negative if the miner is in IP or fee debt
 func (st *State) GetAvailableBalance(actorBalance abi.TokenAmount) abi.TokenAmount {
 	availableBalance := st.GetUnlockedBalance(actorBalance)
-	return big.Sub(availableBalance, st.InitialPledgeRequirement)
+	return big.Subtract(availableBalance, st.InitialPledgeRequirement, st.FeeDebt)
 }
 
 // Returns a quantization spec that quantizes values to the last epoch in each deadline.
@@ -1114,6 +1480,7 @@ func (st *State) QuantEndOfDeadline() QuantSpec {
 func (st *State) AssertBalanceInvariants(balance abi.TokenAmount) {
 	Assert(st.PreCommitDeposits.GreaterThanEqual(big.Zero()))
 	Assert(st.LockedFunds.GreaterThanEqual(big.Zero()))
+	Assert(st.FeeDebt.GreaterThanEqual(big.Zero()))
 	Assert(balance.GreaterThanEqual(big.Sum(st.PreCommitDeposits, st.LockedFunds)))
 }
 
@@ -1122,6 +1489,11 @@ func (st *State) MeetsInitialPledgeCondition(balance abi.TokenAmount) bool {
 	return available.GreaterThanEqual(st.InitialPledgeRequirement)
 }
 
+// Whether the miner has no unpaid penalties.
+func (st *State) IsDebtFree() bool {
+	return st.FeeDebt.LessThanEqual(big.Zero())
+}
+
 //
 // Misc helpers
 //
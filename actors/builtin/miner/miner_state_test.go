@@ -543,6 +543,178 @@ func TestVestingFunds_UnvestedFunds(t *testing.T) {
 
 }
 
+func TestFeeDebt(t *testing.T) {
+	vspec := &miner.VestSpec{
+		InitialDelay: 0,
+		VestPeriod:   5,
+		StepDuration: 1,
+		Quantization: 1,
+	}
+	vestStart := abi.ChainEpoch(100)
+
+	t.Run("penalty exceeding funds accrues as debt", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, abi.NewTokenAmount(100), vspec)
+
+		fromVesting, fromBalance, err := harness.s.PenalizeFundsInPriorityOrder(harness.store, vestStart, abi.NewTokenAmount(150), abi.NewTokenAmount(30))
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(100), fromVesting)
+		assert.Equal(t, abi.NewTokenAmount(30), fromBalance)
+		assert.Equal(t, abi.NewTokenAmount(20), harness.s.FeeDebt)
+		assert.False(t, harness.s.IsDebtFree())
+
+		// Debt reduces the available balance.
+		assert.Equal(t, abi.NewTokenAmount(80), harness.s.GetAvailableBalance(abi.NewTokenAmount(100)))
+	})
+
+	t.Run("penalty within funds accrues no debt", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, abi.NewTokenAmount(100), vspec)
+
+		fromVesting, fromBalance, err := harness.s.PenalizeFundsInPriorityOrder(harness.store, vestStart, abi.NewTokenAmount(120), abi.NewTokenAmount(30))
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(100), fromVesting)
+		assert.Equal(t, abi.NewTokenAmount(20), fromBalance)
+		assert.Equal(t, abi.NewTokenAmount(0), harness.s.FeeDebt)
+		assert.True(t, harness.s.IsDebtFree())
+	})
+
+	t.Run("debt is repaid from vesting funds then balance", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.s.FeeDebt = abi.NewTokenAmount(150)
+		harness.addLockedFunds(vestStart, abi.NewTokenAmount(100), vspec)
+
+		// Partial repayment.
+		fromVesting, fromBalance, err := harness.s.RepayDebtsInPriorityOrder(harness.store, vestStart, abi.NewTokenAmount(30))
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(100), fromVesting)
+		assert.Equal(t, abi.NewTokenAmount(30), fromBalance)
+		assert.Equal(t, abi.NewTokenAmount(20), harness.s.FeeDebt)
+		assert.True(t, harness.vestingFundsStoreEmpty())
+
+		// Full repayment takes no more than the debt.
+		fromVesting, fromBalance, err = harness.s.RepayDebtsInPriorityOrder(harness.store, vestStart, abi.NewTokenAmount(50))
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(0), fromVesting)
+		assert.Equal(t, abi.NewTokenAmount(20), fromBalance)
+		assert.True(t, harness.s.IsDebtFree())
+	})
+}
+
+func TestVestingFunds_Inspection(t *testing.T) {
+	vspec := &miner.VestSpec{
+		InitialDelay: 0,
+		VestPeriod:   5,
+		StepDuration: 1,
+		Quantization: 1,
+	}
+	vestStart := abi.ChainEpoch(100)
+	vestSum := abi.NewTokenAmount(100)
+
+	t.Run("loads and summarizes vesting table", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, vestSum, vspec)
+
+		funds, err := harness.s.LoadVestingFunds(harness.store)
+		require.NoError(t, err)
+		require.Len(t, funds, 5)
+		for i, fund := range funds {
+			assert.Equal(t, vestStart+abi.ChainEpoch(i+1), fund.Epoch)
+			assert.Equal(t, abi.NewTokenAmount(20), fund.Amount)
+		}
+
+		summary, err := harness.s.SummarizeVestingFunds(harness.store, 103)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), summary.Entries)
+		assert.Equal(t, abi.ChainEpoch(101), summary.FirstEpoch)
+		assert.Equal(t, abi.ChainEpoch(105), summary.LastEpoch)
+		assert.Equal(t, harness.s.LockedFunds, summary.Total)
+		assert.Equal(t, abi.NewTokenAmount(40), summary.Vested)
+		assert.Equal(t, abi.NewTokenAmount(60), summary.Unvested)
+	})
+
+	t.Run("projects unlocked balance", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, vestSum, vspec)
+
+		balance := abi.NewTokenAmount(200)
+		projections, err := harness.s.ProjectUnlockedBalance(harness.store, balance, 200, 100, 103)
+		require.NoError(t, err)
+		assert.Equal(t, []abi.TokenAmount{
+			abi.NewTokenAmount(200),
+			abi.NewTokenAmount(100),
+			abi.NewTokenAmount(140),
+		}, projections)
+
+		// Projection matches the amount actually unlocked.
+		unlocked := harness.unlockVestedFunds(103)
+		assert.Equal(t, abi.NewTokenAmount(40), unlocked)
+	})
+
+	t.Run("compaction merges entries without vesting early", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, vestSum, vspec)
+
+		require.NoError(t, harness.s.CompactVestingFunds(harness.store, 4))
+		funds, err := harness.s.LoadVestingFunds(harness.store)
+		require.NoError(t, err)
+		assert.Equal(t, []miner.VestingFund{
+			{Epoch: 104, Amount: abi.NewTokenAmount(80)},
+			{Epoch: 108, Amount: abi.NewTokenAmount(20)},
+		}, funds)
+		assert.Equal(t, vestSum, harness.s.LockedFunds)
+
+		assert.Equal(t, abi.NewTokenAmount(0), harness.unlockVestedFunds(104))
+		assert.Equal(t, abi.NewTokenAmount(80), harness.unlockVestedFunds(105))
+	})
+
+	t.Run("compaction at the vesting quantization leaves unlocked balance unchanged", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		qspec := &miner.VestSpec{
+			InitialDelay: 0,
+			VestPeriod:   9,
+			StepDuration: 1,
+			Quantization: 3,
+		}
+		harness.addLockedFunds(vestStart, vestSum, qspec)
+		harness.addLockedFunds(vestStart+4, vestSum, qspec)
+
+		balance := abi.NewTokenAmount(300)
+		var epochs []abi.ChainEpoch
+		for e := vestStart - 1; e <= vestStart+20; e++ {
+			epochs = append(epochs, e)
+		}
+		before, err := harness.s.ProjectUnlockedBalance(harness.store, balance, epochs...)
+		require.NoError(t, err)
+
+		require.NoError(t, harness.s.CompactVestingFunds(harness.store, qspec.Quantization))
+		after, err := harness.s.ProjectUnlockedBalance(harness.store, balance, epochs...)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("coarser compaction unlocks nothing earlier", func(t *testing.T) {
+		harness := constructStateHarness(t, abi.ChainEpoch(0))
+		harness.addLockedFunds(vestStart, vestSum, vspec)
+
+		balance := abi.NewTokenAmount(200)
+		var epochs []abi.ChainEpoch
+		for e := vestStart - 1; e <= vestStart+10; e++ {
+			epochs = append(epochs, e)
+		}
+		before, err := harness.s.ProjectUnlockedBalance(harness.store, balance, epochs...)
+		require.NoError(t, err)
+
+		require.NoError(t, harness.s.CompactVestingFunds(harness.store, 4))
+		after, err := harness.s.ProjectUnlockedBalance(harness.store, balance, epochs...)
+		require.NoError(t, err)
+		for i := range epochs {
+			assert.True(t, after[i].LessThanEqual(before[i]), "epoch %d: %v unlocked after compaction, %v before", epochs[i], after[i], before[i])
+		}
+		assert.Equal(t, before[len(before)-1], after[len(after)-1])
+	})
+}
+
 type stateHarness struct {
 	t testing.TB
 
@@ -663,23 +835,9 @@ func constructStateHarness(t *testing.T, periodBoundary abi.ChainEpoch) *stateHa
 
 	testSealProofType := abi.RegisteredSealProof_StackedDrg2KiBV1
 
-	sectorSize, err := testSealProofType.SectorSize()
+	info, err := miner.ConstructMinerInfo(owner, worker, nil, abi.PeerID("peer"), testMultiaddrs, testSealProofType)
 	require.NoError(t, err)
-
-	partitionSectors, err := testSealProofType.WindowPoStPartitionSectors()
-	require.NoError(t, err)
-
-	info := miner.MinerInfo{
-		Owner:                      owner,
-		Worker:                     worker,
-		PendingWorkerKey:           nil,
-		PeerId:                     abi.PeerID("peer"),
-		Multiaddrs:                 testMultiaddrs,
-		SealProofType:              testSealProofType,
-		SectorSize:                 sectorSize,
-		WindowPoStPartitionSectors: partitionSectors,
-	}
-	infoCid, err := store.Put(context.Background(), &info)
+	infoCid, err := store.Put(context.Background(), info)
 	require.NoError(t, err)
 
 	state, err := miner.ConstructState(infoCid, periodBoundary, emptyArray, emptyMap, emptyDeadlinesCid)
@@ -701,11 +859,12 @@ func constructStateHarness(t *testing.T, periodBoundary abi.ChainEpoch) *stateHa
 func newSectorPreCommitOnChainInfo(sectorNo abi.SectorNumber, sealed cid.Cid, deposit abi.TokenAmount, epoch abi.ChainEpoch) *miner.SectorPreCommitOnChainInfo {
 	info := newSectorPreCommitInfo(sectorNo, sealed)
 	return &miner.SectorPreCommitOnChainInfo{
-		Info:               *info,
-		PreCommitDeposit:   deposit,
-		PreCommitEpoch:     epoch,
-		DealWeight:         big.Zero(),
-		VerifiedDealWeight: big.Zero(),
+		Info:                *info,
+		PreCommitDeposit:    deposit,
+		PreCommitEpoch:      epoch,
+		DealWeight:          big.Zero(),
+		VerifiedDealWeight:  big.Zero(),
+		ProofSubmittedEpoch: miner.NoProofSubmittedEpoch,
 	}
 }
 
@@ -722,6 +881,7 @@ func newSectorOnChainInfo(sectorNo abi.SectorNumber, sealed cid.Cid, weight big.
 		SealedCID:          sealed,
 		DealIDs:            nil,
 		Activation:         activation,
+		PowerBaseEpoch:     activation,
 		Expiration:         sectorExpiration,
 		DealWeight:         weight,
 		VerifiedDealWeight: weight,
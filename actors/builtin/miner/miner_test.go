@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"strings"
 	"testing"
 
 	addr "github.com/filecoin-project/go-address"
@@ -58,10 +59,12 @@ func TestConstruction(t *testing.T) {
 	owner := tutil.NewIDAddr(t, 100)
 	worker := tutil.NewIDAddr(t, 101)
 	workerKey := tutil.NewBLSAddr(t, 0)
+	controlAddr := tutil.NewIDAddr(t, 999)
 	receiver := tutil.NewIDAddr(t, 1000)
 	builder := mock.NewBuilder(context.Background(), receiver).
 		WithActorType(owner, builtin.AccountActorCodeID).
 		WithActorType(worker, builtin.AccountActorCodeID).
+		WithActorType(controlAddr, builtin.AccountActorCodeID).
 		WithHasher(blake2b.Sum256).
 		WithCaller(builtin.InitActorAddr, builtin.InitActorCodeID)
 
@@ -70,6 +73,7 @@ func TestConstruction(t *testing.T) {
 		params := miner.ConstructorParams{
 			OwnerAddr:     owner,
 			WorkerAddr:    worker,
+			ControlAddrs:  []addr.Address{controlAddr},
 			SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
 			PeerId:        testPid,
 			Multiaddrs:    testMultiaddrs,
@@ -93,6 +97,7 @@ func TestConstruction(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, params.OwnerAddr, info.Owner)
 		assert.Equal(t, params.WorkerAddr, info.Worker)
+		assert.Equal(t, params.ControlAddrs, info.ControlAddresses)
 		assert.Equal(t, params.PeerId, info.PeerId)
 		assert.Equal(t, params.Multiaddrs, info.Multiaddrs)
 		assert.Equal(t, abi.RegisteredSealProof_StackedDrg32GiBV1, info.SealProofType)
@@ -134,15 +139,494 @@ func TestControlAddresses(t *testing.T) {
 		rt := builder.Build(t)
 		actor.constructAndVerify(rt)
 
-		o, w := actor.controlAddresses(rt)
+		o, w, c := actor.controlAddresses(rt)
 		assert.Equal(t, actor.owner, o)
 		assert.Equal(t, actor.worker, w)
+		assert.Equal(t, actor.controlAddrs, c)
+	})
+
+	t.Run("change control addresses", func(t *testing.T) {
+		newControl := tutil.NewIDAddr(t, 501)
+		rt := builder.WithActorType(newControl, builtin.AccountActorCodeID).Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeWorkerAddress(rt, actor.worker, []addr.Address{newControl})
+		_, _, c := actor.controlAddresses(rt)
+		assert.Equal(t, []addr.Address{newControl}, c)
+
+		// The new control address may act for the miner.
+		newPeerID := abi.PeerID("new peer")
+		rt.SetCaller(newControl, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.worker, newControl)
+		rt.Call(actor.a.ChangePeerID, &miner.ChangePeerIDParams{NewID: newPeerID})
+		rt.Verify()
+		assert.Equal(t, newPeerID, actor.getInfo(rt).PeerId)
+
+		// The replaced control addresses may not.
+		rt.SetCaller(actor.controlAddrs[0], builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.worker, newControl)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangePeerID, &miner.ChangePeerIDParams{NewID: testPid})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("change worker schedules key change", func(t *testing.T) {
+		newWorker := tutil.NewIDAddr(t, 503)
+		rt := builder.WithActorType(newWorker, builtin.AccountActorCodeID).Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeWorkerAddress(rt, newWorker, actor.controlAddrs)
+		info := actor.getInfo(rt)
+		assert.Equal(t, actor.worker, info.Worker)
+		require.NotNil(t, info.PendingWorkerKey)
+		assert.Equal(t, newWorker, info.PendingWorkerKey.NewWorker)
+		assert.Equal(t, rt.Epoch()+miner.WorkerKeyChangeDelay, info.PendingWorkerKey.EffectiveAt)
+		actor.checkState(rt)
+	})
+
+	t.Run("clear control addresses", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeWorkerAddress(rt, actor.worker, []addr.Address{})
+		_, _, c := actor.controlAddresses(rt)
+		assert.Empty(t, c)
+		actor.checkState(rt)
+	})
+
+	t.Run("omitting control addresses keeps existing ones", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeWorkerAddress(rt, actor.worker, nil)
+		_, _, c := actor.controlAddresses(rt)
+		assert.Equal(t, actor.controlAddrs, c)
+		actor.checkState(rt)
+	})
+
+	t.Run("fails to both clear and replace control addresses", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectSend(actor.worker, builtin.MethodsAccount.PubkeyAddress, nil, big.Zero(), &actor.key, exitcode.Ok)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "cannot both clear and replace control addresses", func() {
+			rt.Call(actor.a.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+				NewWorker:         actor.worker,
+				NewControlAddrs:   actor.controlAddrs,
+				ClearControlAddrs: true,
+			})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("owner may not act as worker", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangePeerID, &miner.ChangePeerIDParams{NewID: testPid})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails with too many control addresses", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		var controlAddrs []addr.Address
+		for i := 0; i <= miner.MaxControlAddresses; i++ {
+			controlAddrs = append(controlAddrs, actor.controlAddrs[0])
+		}
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectSend(actor.worker, builtin.MethodsAccount.PubkeyAddress, nil, big.Zero(), &actor.key, exitcode.Ok)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too many control addresses", func() {
+			rt.Call(actor.a.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+				NewWorker:       actor.worker,
+				NewControlAddrs: controlAddrs,
+			})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("fails if control address is not an account", func(t *testing.T) {
+		minerAddr := tutil.NewIDAddr(t, 502)
+		rt := builder.WithActorType(minerAddr, builtin.StorageMinerActorCodeID).Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectSend(actor.worker, builtin.MethodsAccount.PubkeyAddress, nil, big.Zero(), &actor.key, exitcode.Ok)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "must be an account", func() {
+			rt.Call(actor.a.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+				NewWorker:       actor.worker,
+				NewControlAddrs: []addr.Address{minerAddr},
+			})
+		})
+		actor.checkState(rt)
 	})
 
 	// TODO: test changing worker (with delay), changing peer id
 	// https://github.com/filecoin-project/specs-actors/issues/479
 }
 
+func TestChangeOwnerAddress(t *testing.T) {
+	actor := newHarness(t, 0)
+	newAddr := tutil.NewIDAddr(t, 102)
+	otherAddr := tutil.NewIDAddr(t, 103)
+	builder := builderForHarness(actor).
+		WithActorType(newAddr, builtin.AccountActorCodeID).
+		WithActorType(otherAddr, builtin.AccountActorCodeID)
+
+	t.Run("successful change", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		// Proposal doesn't change the owner.
+		actor.changeOwnerAddress(rt, actor.owner, newAddr)
+		info := actor.getInfo(rt)
+		assert.Equal(t, actor.owner, info.Owner)
+		assert.Equal(t, newAddr, info.PendingOwner.NewOwner)
+
+		// Confirmation by the new owner completes the change.
+		actor.changeOwnerAddress(rt, newAddr, newAddr)
+		info = actor.getInfo(rt)
+		assert.Equal(t, newAddr, info.Owner)
+		assert.Nil(t, info.PendingOwner)
+		actor.checkState(rt)
+	})
+
+	t.Run("proposed must be valid", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		nobody := tutil.NewIDAddr(t, 1234)
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &nobody)
+		})
+
+		minerAddr := tutil.NewIDAddr(t, 1235)
+		rt.SetAddressActorType(minerAddr, builtin.StorageMinerActorCodeID)
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &minerAddr)
+		})
+		assert.Nil(t, actor.getInfo(rt).PendingOwner)
+		actor.checkState(rt)
+	})
+
+	t.Run("withdraw proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeOwnerAddress(rt, actor.owner, newAddr)
+
+		// Revoke the proposal by proposing the current owner.
+		actor.changeOwnerAddress(rt, actor.owner, actor.owner)
+		assert.Nil(t, actor.getInfo(rt).PendingOwner)
+
+		// The previously proposed address can no longer confirm.
+		rt.SetCaller(newAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &newAddr)
+		})
+		assert.Equal(t, actor.owner, actor.getInfo(rt).Owner)
+		actor.checkState(rt)
+	})
+
+	t.Run("only owner can propose", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &newAddr)
+		})
+
+		// Nor can a third party confirm a pending proposal.
+		actor.changeOwnerAddress(rt, actor.owner, newAddr)
+		rt.SetCaller(otherAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(newAddr)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &newAddr)
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("confirmation must match proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeOwnerAddress(rt, actor.owner, newAddr)
+
+		rt.SetCaller(newAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(newAddr)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "expected confirmation", func() {
+			rt.Call(actor.a.ChangeOwnerAddress, &otherAddr)
+		})
+
+		info := actor.getInfo(rt)
+		assert.Equal(t, actor.owner, info.Owner)
+		assert.Equal(t, newAddr, info.PendingOwner.NewOwner)
+		actor.checkState(rt)
+	})
+
+	t.Run("owner can replace proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		actor.changeOwnerAddress(rt, actor.owner, newAddr)
+		actor.changeOwnerAddress(rt, actor.owner, otherAddr)
+		assert.Equal(t, otherAddr, actor.getInfo(rt).PendingOwner.NewOwner)
+
+		actor.changeOwnerAddress(rt, otherAddr, otherAddr)
+		assert.Equal(t, otherAddr, actor.getInfo(rt).Owner)
+		actor.checkState(rt)
+	})
+}
+
+func TestChangeBeneficiary(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	beneficiary := tutil.NewIDAddr(t, 102)
+	otherAddr := tutil.NewIDAddr(t, 103)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero()).
+		WithActorType(beneficiary, builtin.AccountActorCodeID).
+		WithActorType(otherAddr, builtin.AccountActorCodeID)
+	quota := abi.NewTokenAmount(1e18)
+	expiration := abi.ChainEpoch(1000)
+
+	t.Run("owner is initial beneficiary", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, actor.owner, ret.Active.Beneficiary)
+		assert.True(t, ret.Active.Term.Quota.IsZero())
+		assert.Nil(t, ret.Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("successful change", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		// Proposal by the owner needs approval by the nominee.
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, actor.owner, ret.Active.Beneficiary)
+		require.NotNil(t, ret.Proposed)
+		assert.Equal(t, beneficiary, ret.Proposed.NewBeneficiary)
+		assert.True(t, ret.Proposed.ApprovedByBeneficiary)
+		assert.False(t, ret.Proposed.ApprovedByNominee)
+
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+		ret = actor.getBeneficiary(rt)
+		assert.Equal(t, beneficiary, ret.Active.Beneficiary)
+		assert.Equal(t, quota, ret.Active.Term.Quota)
+		assert.True(t, ret.Active.Term.UsedQuota.IsZero())
+		assert.Equal(t, expiration, ret.Active.Term.Expiration)
+		assert.Nil(t, ret.Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("change requires approval of current beneficiary", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+
+		actor.changeBeneficiary(rt, actor.owner, otherAddr, quota, expiration)
+		actor.changeBeneficiary(rt, otherAddr, otherAddr, quota, expiration)
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, beneficiary, ret.Active.Beneficiary)
+		require.NotNil(t, ret.Proposed)
+		assert.False(t, ret.Proposed.ApprovedByBeneficiary)
+		assert.True(t, ret.Proposed.ApprovedByNominee)
+
+		actor.changeBeneficiary(rt, beneficiary, otherAddr, quota, expiration)
+		assert.Equal(t, otherAddr, actor.getBeneficiary(rt).Active.Beneficiary)
+		actor.checkState(rt)
+	})
+
+	t.Run("expired beneficiary need not approve", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+
+		rt.SetEpoch(expiration)
+		actor.changeBeneficiary(rt, actor.owner, actor.owner, big.Zero(), 0)
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, actor.owner, ret.Active.Beneficiary)
+		assert.Nil(t, ret.Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("approval must match proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+
+		rt.SetCaller(beneficiary, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(beneficiary, actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "expected approval", func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       big.Mul(quota, big.NewInt(2)),
+				NewExpiration:  expiration,
+			})
+		})
+		assert.Equal(t, actor.owner, actor.getBeneficiary(rt).Active.Beneficiary)
+		actor.checkState(rt)
+	})
+
+	t.Run("third party cannot approve", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(otherAddr, builtin.AccountActorCodeID)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: otherAddr,
+				NewQuota:       quota,
+				NewExpiration:  expiration,
+			})
+		})
+
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		rt.SetCaller(otherAddr, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(beneficiary, actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       quota,
+				NewExpiration:  expiration,
+			})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("non-owner beneficiary requires positive quota", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       big.Zero(),
+				NewExpiration:  expiration,
+			})
+		})
+		assert.Nil(t, actor.getBeneficiary(rt).Proposed)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects expiration not after current epoch", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		rt.SetEpoch(expiration)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "must be after current epoch", func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       quota,
+				NewExpiration:  expiration,
+			})
+		})
+		rt.Reset()
+		assert.Nil(t, actor.getBeneficiary(rt).Proposed)
+
+		// A proposal that expires before it is approved cannot be approved.
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration+1)
+		rt.SetEpoch(expiration + 1)
+		rt.SetCaller(beneficiary, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(beneficiary, actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "proposed beneficiary term expired", func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       quota,
+				NewExpiration:  expiration + 1,
+			})
+		})
+		rt.Reset()
+		assert.Equal(t, actor.owner, actor.getBeneficiary(rt).Active.Beneficiary)
+		actor.checkState(rt)
+	})
+
+	t.Run("change of owner abandons proposal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+
+		actor.changeOwnerAddress(rt, actor.owner, otherAddr)
+		actor.changeOwnerAddress(rt, otherAddr, otherAddr)
+		ret := actor.getBeneficiary(rt)
+		assert.Equal(t, otherAddr, ret.Active.Beneficiary)
+		assert.Nil(t, ret.Proposed)
+
+		// The nominee can no longer approve the previous owner's proposal.
+		rt.SetCaller(beneficiary, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "no beneficiary change proposed", func() {
+			rt.Call(actor.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+				NewBeneficiary: beneficiary,
+				NewQuota:       quota,
+				NewExpiration:  expiration,
+			})
+		})
+		rt.Reset()
+		assert.Equal(t, otherAddr, actor.getBeneficiary(rt).Active.Beneficiary)
+		actor.checkState(rt)
+	})
+
+	t.Run("beneficiary withdrawal is limited by quota", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+
+		// Withdrawals by either the owner or the beneficiary are paid to the beneficiary.
+		half := big.Div(quota, big.NewInt(2))
+		actor.withdrawFundsToBeneficiary(rt, actor.owner, half, half)
+		actor.withdrawFundsToBeneficiary(rt, beneficiary, quota, big.Sub(quota, half))
+		assert.Equal(t, quota, actor.getBeneficiary(rt).Active.Term.UsedQuota)
+
+		// Quota is used up.
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			actor.withdrawFundsToBeneficiary(rt, beneficiary, quota, big.Zero())
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("expired beneficiary cannot withdraw", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, quota, expiration)
+		actor.changeBeneficiary(rt, beneficiary, beneficiary, quota, expiration)
+
+		rt.SetEpoch(expiration)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			actor.withdrawFundsToBeneficiary(rt, beneficiary, quota, big.Zero())
+		})
+		actor.checkState(rt)
+	})
+}
+
 // Test for sector precommitment and proving.
 func TestCommitments(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
@@ -254,6 +738,7 @@ func TestCommitments(t *testing.T) {
 		assert.Equal(t, expectedInitialPledge, entry.OnTimePledge)
 		assert.Equal(t, sectorPower, entry.ActivePower)
 		assert.Equal(t, miner.NewPowerPairZero(), entry.FaultyPower)
+		actor.checkState(rt)
 	})
 
 	t.Run("invalid pre-commit rejected", func(t *testing.T) {
@@ -339,6 +824,7 @@ func TestCommitments(t *testing.T) {
 			actor.preCommitSector(rt, actor.makePreCommit(abi.MaxSectorNumber+1, challengeEpoch, expiration, nil))
 		})
 		rt.Reset()
+		actor.checkState(rt)
 	})
 
 	t.Run("valid committed capacity upgrade", func(t *testing.T) {
@@ -448,14 +934,17 @@ func TestCommitments(t *testing.T) {
 		assert.True(t, newSectorPower.Equals(partition.LivePower))
 		assert.True(t, newSectorPower.Equals(partition.FaultyPower))
 
+		// The partition is also registered with an expiry at the new sector's fault expiration.
 		dQueue = actor.collectDeadlineExpirations(rt, deadline)
 		assert.Equal(t, map[abi.ChainEpoch][]uint64{
+			dlInfo.NextNotElapsed().Last() + miner.FaultMaxAge: {uint64(0)},
 			newSector.Expiration: {uint64(0)},
 		}, dQueue)
 
 		// Old sector gone from pledge requirement and deposit
 		assert.Equal(t, st.InitialPledgeRequirement, newSector.InitialPledge)
 		assert.Equal(t, st.LockedFunds, big.Mul(big.NewInt(4), faultPenalty)) // from manual fund addition above - 1 fault penalty
+		actor.checkState(rt)
 	})
 
 	t.Run("invalid committed capacity upgrade rejected", func(t *testing.T) {
@@ -547,6 +1036,7 @@ func TestCommitments(t *testing.T) {
 		// Demonstrate that the params are otherwise ok
 		actor.preCommitSector(rt, upgradeParams)
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("faulty committed capacity sector not replaced", func(t *testing.T) {
@@ -688,6 +1178,7 @@ func TestCommitments(t *testing.T) {
 			actor.proveCommitSectorAndConfirm(rt, precommit, precommitEpoch, makeProveCommit(sectorNo), proveCommitConf{})
 		})
 		rt.Reset()
+		actor.checkState(rt)
 	})
 
 	t.Run("fails with too many deals", func(t *testing.T) {
@@ -738,81 +1229,825 @@ func TestCommitments(t *testing.T) {
 	})
 }
 
-func TestWindowPost(t *testing.T) {
+func TestPreCommitBatch(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
-	actor := newHarness(t, periodOffset)
-	actor.setProofType(abi.RegisteredSealProof_StackedDrg2KiBV1)
-	precommitEpoch := abi.ChainEpoch(1)
-	builder := builderForHarness(actor).
-		WithEpoch(precommitEpoch).
-		WithBalance(bigBalance, big.Zero())
 
-	t.Run("test proof", func(t *testing.T) {
-		rt := builder.Build(t)
+	setup := func(t *testing.T, balance abi.TokenAmount) (*mock.Runtime, *actorHarness, *miner.DeadlineInfo) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(balance, big.Zero()).
+			Build(t)
+		rt.SetEpoch(periodOffset + 1)
 		actor.constructAndVerify(rt)
-		store := rt.AdtStore()
-		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		return rt, actor, actor.deadline(rt)
+	}
 
-		st := getState(rt)
-		dlIdx, pIdx, err := st.FindSector(store, sector.SectorNumber)
-		require.NoError(t, err)
+	t.Run("valid batch", func(t *testing.T) {
+		rt, actor, dlInfo := setup(t, bigBalance)
+		expiration := dlInfo.PeriodEnd() + 181*miner.WPoStProvingPeriod
+		precommits := []*miner.SectorPreCommitInfo{
+			actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+			actor.makePreCommit(101, rt.Epoch()-1, expiration, []abi.DealID{1}),
+			actor.makePreCommit(102, rt.Epoch()-1, expiration, []abi.DealID{2, 3}),
+		}
+		onChain := actor.preCommitSectorBatch(rt, precommits...)
 
-		// Skip over deadlines until the beginning of the one with the new sector
-		dlinfo := actor.deadline(rt)
-		for dlinfo.Index != dlIdx {
-			advanceDeadline(rt, actor, &cronConfig{})
-			dlinfo = actor.deadline(rt)
+		sectorSize, err := precommits[0].SealProof.SectorSize()
+		require.NoError(t, err)
+		totalDeposit := big.Zero()
+		for i, pc := range onChain {
+			assert.Equal(t, *precommits[i], pc.Info)
+			assert.Equal(t, rt.Epoch(), pc.PreCommitEpoch)
+			assert.Equal(t, big.NewInt(int64(sectorSize/2)), pc.DealWeight)
+			assert.Equal(t, big.NewInt(int64(sectorSize/2)), pc.VerifiedDealWeight)
+
+			qaPower := miner.QAPowerForWeight(sectorSize, expiration-rt.Epoch(), pc.DealWeight, pc.VerifiedDealWeight)
+			expectedDeposit := miner.PreCommitDepositForPower(actor.epochReward, actor.networkQAPower, qaPower)
+			assert.Equal(t, expectedDeposit, pc.PreCommitDeposit)
+			totalDeposit = big.Add(totalDeposit, pc.PreCommitDeposit)
 		}
+		assert.Equal(t, totalDeposit, getState(rt).PreCommitDeposits)
+		actor.checkState(rt)
+	})
 
-		// Submit PoSt
-		partitions := []miner.PoStPartition{
-			{Index: pIdx, Skipped: abi.NewBitField()},
+	t.Run("batch size limits", func(t *testing.T) {
+		rt, actor, dlInfo := setup(t, big.Mul(bigBalance, big.NewInt(1000)))
+		expiration := dlInfo.PeriodEnd() + 181*miner.WPoStProvingPeriod
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "batch empty", func() {
+			rt.Call(actor.a.PreCommitSectorBatch, &miner.PreCommitSectorBatchParams{})
+		})
+
+		params := miner.PreCommitSectorBatchParams{}
+		for i := 0; i < miner.PreCommitSectorBatchMaxSize+1; i++ {
+			params.Sectors = append(params.Sectors, *actor.makePreCommit(abi.SectorNumber(100+i), rt.Epoch()-1, expiration, nil))
 		}
-		actor.submitWindowPoSt(rt, dlinfo, partitions, []*miner.SectorOnChainInfo{sector}, nil)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too large", func() {
+			rt.Call(actor.a.PreCommitSectorBatch, &params)
+		})
 
-		// Verify proof recorded
-		deadline := actor.getDeadline(rt, dlIdx)
-		empty, err := deadline.PostSubmissions.IsEmpty()
-		require.NoError(t, err)
-		assert.False(t, empty, "no post submission")
+		// A batch of the maximum size succeeds.
+		var precommits []*miner.SectorPreCommitInfo
+		for i := range params.Sectors[:miner.PreCommitSectorBatchMaxSize] {
+			precommits = append(precommits, &params.Sectors[i])
+		}
+		actor.preCommitSectorBatch(rt, precommits...)
+		actor.checkState(rt)
+	})
 
-		// Advance to end-of-deadline cron to verify no penalties.
-		advanceDeadline(rt, actor, &cronConfig{})
+	t.Run("fails with duplicate sector number", func(t *testing.T) {
+		rt, actor, dlInfo := setup(t, bigBalance)
+		expiration := dlInfo.PeriodEnd() + 181*miner.WPoStProvingPeriod
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		params := miner.PreCommitSectorBatchParams{Sectors: []miner.SectorPreCommitInfo{
+			*actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+			*actor.makePreCommit(101, rt.Epoch()-1, expiration, nil),
+			*actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+		}}
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "duplicate sector number 100", func() {
+			rt.Call(actor.a.PreCommitSectorBatch, &params)
+		})
+		actor.checkState(rt)
 	})
 
-	//runTillNextDeadline := func(rt *mock.Runtime) (*miner.DeadlineInfo, []*miner.SectorOnChainInfo, []uint64) {
-	//	st := getState(rt)
-	//	deadlines, err := st.LoadDeadlines(rt.AdtStore())
-	//	require.NoError(t, err)
-	//	deadline := actor.deadline(rt)
-	//
-	//	// advance to next deadline where we expect the first sectors to appear
-	//	rt.SetEpoch(deadline.NextOpen())
-	//	deadline = st.DeadlineInfo(rt.Epoch())
-	//
-	//	infos, partitions := actor.computePartitions(rt, deadlines, deadline.Index)
-	//	return deadline, infos, partitions
-	//}
+	t.Run("fails if any sector is already pre-committed", func(t *testing.T) {
+		rt, actor, dlInfo := setup(t, bigBalance)
+		expiration := dlInfo.PeriodEnd() + 181*miner.WPoStProvingPeriod
+		actor.preCommitSector(rt, actor.makePreCommit(101, rt.Epoch()-1, expiration, nil))
 
-	//runTillFirstDeadline := func(rt *mock.Runtime) (*miner.DeadlineInfo, []*miner.SectorOnChainInfo, []uint64) {
-	//	actor.constructAndVerify(rt)
-	//
-	//	_ = actor.commitAndProveSectors(rt, 6, 100, nil)
-	//
-	//	// Skip to end of proving period, cron adds sectors to proving set.
-	//	actor.advancePastProvingPeriodWithCron(rt)
-	//
-	//	return runTillNextDeadline(rt)
-	//}
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "sector 101 already pre-committed", func() {
+			actor.preCommitSectorBatch(rt,
+				actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+				actor.makePreCommit(101, rt.Epoch()-1, expiration, nil),
+			)
+		})
+		rt.Reset()
 
-	t.Run("successful recoveries recover power", func(t *testing.T) {
-		t.Skip("Disabled in miner state refactor #648, restore soon")
-		// TODO minerstate
-		//rt := builder.Build(t)
-		//deadline, infos, partitions := runTillFirstDeadline(rt)
-		//st := getState(rt)
+		// No part of the batch was committed.
+		_, found, err := getState(rt).GetPrecommittedSector(rt.AdtStore(), 100)
+		require.NoError(t, err)
+		assert.False(t, found)
+		actor.checkState(rt)
+	})
 
-		// mark all sectors as recovered faults
+	t.Run("fails with insufficient funds for whole batch", func(t *testing.T) {
+		actor := newHarness(t, periodOffset)
+		sectorSize, err := actor.sealProofType.SectorSize()
+		require.NoError(t, err)
+		// Enough for one sector, but not two.
+		dealWeight := big.NewInt(int64(sectorSize / 2))
+		expiration := abi.ChainEpoch(periodOffset + 181*miner.WPoStProvingPeriod - 1)
+		qaPower := miner.QAPowerForWeight(sectorSize, expiration-(periodOffset+1), dealWeight, dealWeight)
+		deposit := miner.PreCommitDepositForPower(actor.epochReward, actor.networkQAPower, qaPower)
+
+		rt, actor, _ := setup(t, deposit)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrInsufficientFunds, "insufficient funds", func() {
+			actor.preCommitSectorBatch(rt,
+				actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+				actor.makePreCommit(101, rt.Epoch()-1, expiration, nil),
+			)
+		})
+		rt.Reset()
+
+		actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+		assert.Equal(t, deposit, getState(rt).PreCommitDeposits)
+		actor.checkState(rt)
+	})
+}
+
+func TestWithdrawPreCommits(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T) (*mock.Runtime, *actorHarness, []*miner.SectorPreCommitOnChainInfo) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		rt.SetEpoch(periodOffset + 1)
+		actor.constructAndVerify(rt)
+
+		expiration := actor.deadline(rt).PeriodEnd() + 181*miner.WPoStProvingPeriod
+		onChain := actor.preCommitSectorBatch(rt,
+			actor.makePreCommit(100, rt.Epoch()-1, expiration, nil),
+			actor.makePreCommit(101, rt.Epoch()-1, expiration, nil),
+		)
+		return rt, actor, onChain
+	}
+
+	t.Run("withdraws pre-commit with partial refund", func(t *testing.T) {
+		rt, actor, precommits := setup(t)
+		availableBefore := getState(rt).GetAvailableBalance(rt.Balance())
+
+		penalty := miner.PreCommitWithdrawalPenalty(precommits[0].PreCommitDeposit)
+		assert.True(t, penalty.GreaterThan(big.Zero()))
+		assert.True(t, penalty.LessThan(precommits[0].PreCommitDeposit))
+		actor.withdrawPreCommits(rt, penalty, 100)
+
+		st := getState(rt)
+		_, found, err := st.GetPrecommittedSector(rt.AdtStore(), 100)
+		require.NoError(t, err)
+		assert.False(t, found)
+		actor.getPreCommit(rt, 101)
+		assert.Equal(t, precommits[1].PreCommitDeposit, st.PreCommitDeposits)
+
+		refund := big.Sub(precommits[0].PreCommitDeposit, penalty)
+		assert.Equal(t, big.Add(availableBefore, refund), st.GetAvailableBalance(rt.Balance()))
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects sector not pre-committed", func(t *testing.T) {
+		rt, actor, _ := setup(t)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrNotFound, "no pre-commitment for sector 102", func() {
+			rt.Call(actor.a.WithdrawPreCommits, &miner.WithdrawPreCommitsParams{Sectors: bf(100, 102)})
+		})
+		rt.Reset()
+
+		actor.getPreCommit(rt, 100)
+		actor.checkState(rt)
+	})
+
+	t.Run("withdraws unproven pre-commit at epoch zero", func(t *testing.T) {
+		rt, actor, precommits := setup(t)
+		assert.Equal(t, miner.NoProofSubmittedEpoch, precommits[0].ProofSubmittedEpoch)
+
+		// A pre-commit with no proof submitted is not mistaken for one proven in epoch zero.
+		rt.SetEpoch(0)
+		actor.withdrawPreCommits(rt, miner.PreCommitWithdrawalPenalty(precommits[0].PreCommitDeposit), 100)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects withdrawal of pre-commit with proof awaiting verification", func(t *testing.T) {
+		rt, actor, precommits := setup(t)
+		precommitEpoch := rt.Epoch()
+		advanceToEpochWithCron(rt, actor, precommitEpoch+miner.PreCommitChallengeDelay+1)
+		actor.proveCommitSector(rt, &precommits[0].Info, precommitEpoch, makeProveCommit(100))
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		assert.Equal(t, rt.Epoch(), actor.getPreCommit(rt, 100).ProofSubmittedEpoch)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "proof for sector 100 awaiting verification", func() {
+			rt.Call(actor.a.WithdrawPreCommits, &miner.WithdrawPreCommitsParams{Sectors: bf(100)})
+		})
+		rt.Reset()
+
+		// A proof that was not confirmed by the end of its epoch failed verification, so the pre-commit
+		// may then be withdrawn.
+		rt.SetEpoch(rt.Epoch() + 1)
+		actor.withdrawPreCommits(rt, miner.PreCommitWithdrawalPenalty(precommits[0].PreCommitDeposit), 100)
+		actor.checkState(rt)
+	})
+
+	t.Run("expiry of withdrawn pre-commit has no effect", func(t *testing.T) {
+		rt, actor, precommits := setup(t)
+		actor.withdrawPreCommits(rt, miner.PreCommitWithdrawalPenalty(precommits[0].PreCommitDeposit), 100)
+
+		// The expiry scheduled for the withdrawn pre-commit burns nothing.
+		rt.SetEpoch(rt.Epoch() + miner.MaxSealDuration[precommits[0].Info.SealProof] + 1)
+		rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
+		rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
+		rt.Call(actor.a.OnDeferredCronEvent, &miner.CronEventPayload{
+			EventType: miner.CronEventPreCommitExpiry,
+			Sectors:   bf(100),
+		})
+		rt.Verify()
+
+		actor.getPreCommit(rt, 101)
+		assert.Equal(t, precommits[1].PreCommitDeposit, getState(rt).PreCommitDeposits)
+		actor.checkState(rt)
+	})
+}
+
+func TestSectorNumberAllocation(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T) (*mock.Runtime, *actorHarness, abi.ChainEpoch) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		rt.SetEpoch(periodOffset + 1)
+		actor.constructAndVerify(rt)
+		return rt, actor, actor.deadline(rt).PeriodEnd() + 181*miner.WPoStProvingPeriod
+	}
+
+	t.Run("pre-commit allocates sector number", func(t *testing.T) {
+		rt, actor, expiration := setup(t)
+		actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+
+		assertBitfieldEquals(t, getState(rt).AllocatedSectors, 100)
+		actor.checkState(rt)
+	})
+
+	t.Run("withdrawn sector number stays allocated", func(t *testing.T) {
+		rt, actor, expiration := setup(t)
+		precommit := actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+		actor.withdrawPreCommits(rt, miner.PreCommitWithdrawalPenalty(precommit.PreCommitDeposit), 100)
+
+		// Withdrawal does not free the number: it is burned, like the number of a terminated sector.
+		assertBitfieldEquals(t, getState(rt).AllocatedSectors, 100)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "sector number 100 already allocated", func() {
+			actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects pre-commit of masked sector number", func(t *testing.T) {
+		rt, actor, expiration := setup(t)
+		actor.maskSectorNumbers(rt, bf(100, 101, 102))
+		assertBitfieldEquals(t, getState(rt).AllocatedSectors, 100, 101, 102)
+
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "sector number 101 already allocated", func() {
+			actor.preCommitSector(rt, actor.makePreCommit(101, rt.Epoch()-1, expiration, nil))
+		})
+		rt.Reset()
+
+		// Masking is idempotent, and numbers outside the mask remain available.
+		actor.maskSectorNumbers(rt, bf(101, 102, 103))
+		assertBitfieldEquals(t, getState(rt).AllocatedSectors, 100, 101, 102, 103)
+		actor.preCommitSector(rt, actor.makePreCommit(104, rt.Epoch()-1, expiration, nil))
+		actor.checkState(rt)
+	})
+
+	t.Run("only owner may mask sector numbers", func(t *testing.T) {
+		rt, actor, _ := setup(t)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.MaskSectorNumbers, &miner.MaskSectorNumbersParams{Mask: bf(100)})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects mask beyond max sector number", func(t *testing.T) {
+		rt, actor, _ := setup(t)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "invalid sector number mask", func() {
+			rt.Call(actor.a.MaskSectorNumbers, &miner.MaskSectorNumbersParams{Mask: bf(100, abi.MaxSectorNumber+1)})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects mask with too many sector numbers", func(t *testing.T) {
+		rt, actor, _ := setup(t)
+
+		// A single run may not exceed the limit.
+		mask := abi.NewBitField()
+		for i := uint64(0); i <= miner.AddressedSectorsMax; i++ {
+			mask.Set(i)
+		}
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too many sector numbers in mask", func() {
+			rt.Call(actor.a.MaskSectorNumbers, &miner.MaskSectorNumbersParams{Mask: mask})
+		})
+		rt.Reset()
+
+		// The limit itself is accepted.
+		mask.Unset(miner.AddressedSectorsMax)
+		actor.maskSectorNumbers(rt, mask)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects mask with too many runs", func(t *testing.T) {
+		rt, actor, _ := setup(t)
+
+		mask := abi.NewBitField()
+		for i := uint64(0); i <= miner.SectorNumberMaskRunsMax; i++ {
+			mask.Set(2 * i)
+		}
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too many runs in mask", func() {
+			rt.Call(actor.a.MaskSectorNumbers, &miner.MaskSectorNumbersParams{Mask: mask})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+}
+
+func TestProveCommitAggregate(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T, n int) (*mock.Runtime, *actorHarness, abi.ChainEpoch, []*miner.SectorPreCommitInfo) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		precommitEpoch := periodOffset + 1
+		rt.SetEpoch(precommitEpoch)
+		actor.constructAndVerify(rt)
+		dlInfo := actor.deadline(rt)
+
+		expiration := dlInfo.PeriodEnd() + 181*miner.WPoStProvingPeriod
+		var precommits []*miner.SectorPreCommitInfo
+		for i := 0; i < n; i++ {
+			precommits = append(precommits, actor.makePreCommit(abi.SectorNumber(100+i), precommitEpoch-1, expiration, nil))
+		}
+		actor.preCommitSectorBatch(rt, precommits...)
+		rt.SetEpoch(precommitEpoch + miner.PreCommitChallengeDelay + 1)
+		return rt, actor, precommitEpoch, precommits
+	}
+
+	t.Run("valid aggregate activates sectors", func(t *testing.T) {
+		rt, actor, precommitEpoch, precommits := setup(t, miner.MinAggregatedSectors)
+		precommitDeposits := getState(rt).PreCommitDeposits
+
+		actor.proveCommitAggregateSector(rt, proveCommitConf{}, precommitEpoch, precommits...)
+
+		st := getState(rt)
+		totalPledge := big.Zero()
+		for _, precommit := range precommits {
+			_, found, err := st.GetPrecommittedSector(rt.AdtStore(), precommit.SectorNumber)
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			sector := actor.getSector(rt, precommit.SectorNumber)
+			assert.Equal(t, rt.Epoch(), sector.Activation)
+			assert.Equal(t, precommit.SealedCID, sector.SealedCID)
+			totalPledge = big.Add(totalPledge, sector.InitialPledge)
+		}
+		assert.True(t, precommitDeposits.GreaterThan(big.Zero()))
+		assert.Equal(t, big.Zero(), st.PreCommitDeposits)
+		assert.Equal(t, totalPledge, st.InitialPledgeRequirement)
+		actor.checkState(rt)
+	})
+
+	t.Run("sector with failed deal activation is dropped", func(t *testing.T) {
+		rt, actor, precommitEpoch, precommits := setup(t, miner.MinAggregatedSectors)
+
+		conf := proveCommitConf{verifyDealsExit: map[abi.SectorNumber]exitcode.ExitCode{
+			precommits[1].SectorNumber: exitcode.ErrIllegalArgument,
+		}}
+		actor.proveCommitAggregateSector(rt, conf, precommitEpoch, precommits...)
+
+		st := getState(rt)
+		_, found, err := st.GetPrecommittedSector(rt.AdtStore(), precommits[1].SectorNumber)
+		require.NoError(t, err)
+		assert.True(t, found)
+		found, err = st.HasSectorNo(rt.AdtStore(), precommits[1].SectorNumber)
+		require.NoError(t, err)
+		assert.False(t, found)
+		actor.getSector(rt, precommits[0].SectorNumber)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects too few or too many sectors", func(t *testing.T) {
+		rt, actor, _, precommits := setup(t, miner.MinAggregatedSectors)
+
+		sectorNos := bitfield.New()
+		for _, precommit := range precommits[:miner.MinAggregatedSectors-1] {
+			sectorNos.Set(uint64(precommit.SectorNumber))
+		}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too few sectors", func() {
+			rt.Call(actor.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{SectorNumbers: &sectorNos})
+		})
+
+		tooMany := bitfield.NewFromSet(nil)
+		for i := uint64(0); i < miner.MaxAggregatedSectors+1; i++ {
+			tooMany.Set(100 + i)
+		}
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "too many sectors", func() {
+			rt.Call(actor.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{SectorNumbers: tooMany})
+		})
+
+		sectorNos.Set(uint64(precommits[miner.MinAggregatedSectors-1].SectorNumber))
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "exceeds max size", func() {
+			rt.Call(actor.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{
+				SectorNumbers:  &sectorNos,
+				AggregateProof: make([]byte, miner.MaxAggregateProofSize+1),
+			})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects missing pre-commit", func(t *testing.T) {
+		rt, actor, _, precommits := setup(t, miner.MinAggregatedSectors)
+
+		sectorNos := bitfield.New()
+		for _, precommit := range precommits[1:] {
+			sectorNos.Set(uint64(precommit.SectorNumber))
+		}
+		sectorNos.Set(999)
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrNotFound, "no pre-committed sector 999", func() {
+			rt.Call(actor.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{SectorNumbers: &sectorNos})
+		})
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects invalid proof", func(t *testing.T) {
+		rt, actor, precommitEpoch, precommits := setup(t, miner.MinAggregatedSectors)
+
+		proof := []byte{1, 2, 3}
+		sectorNos := actor.expectVerifyAggregateSeals(rt, precommitEpoch, proof, fmt.Errorf("invalid aggregate"), precommits...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "aggregate seal verify failed", func() {
+			rt.Call(actor.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{
+				SectorNumbers:  sectorNos,
+				AggregateProof: proof,
+			})
+		})
+		rt.Verify()
+
+		for _, precommit := range precommits {
+			actor.getPreCommit(rt, precommit.SectorNumber)
+		}
+		actor.checkState(rt)
+	})
+
+}
+
+func TestProveReplicaUpdates(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T) (*mock.Runtime, *actorHarness, *miner.SectorOnChainInfo, miner.ReplicaUpdate) {
+		actor := newHarness(t, periodOffset)
+		actor.ccWithoutDeals = true
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		require.True(t, sector.DealWeight.IsZero())
+
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		update := miner.ReplicaUpdate{
+			SectorNumber:       sector.SectorNumber,
+			Deadline:           dlIdx,
+			Partition:          pIdx,
+			NewSealedSectorCID: tutil.MakeCID("updated", &miner.SealedCIDPrefix),
+			Deals:              []abi.DealID{1, 2},
+			UpdateProofType:    abi.RegisteredUpdateProof_StackedDrg32GiBV1,
+			ReplicaProof:       []byte{1, 2, 3},
+		}
+		return rt, actor, sector, update
+	}
+
+	t.Run("updates committed-capacity sector in place", func(t *testing.T) {
+		rt, actor, oldSector, update := setup(t)
+		weights := market.SectorWeights{
+			DealWeight:         big.NewInt(int64(actor.sectorSize / 2)),
+			VerifiedDealWeight: big.NewInt(int64(actor.sectorSize / 2)),
+		}
+
+		actor.proveReplicaUpdates(rt, []*miner.SectorOnChainInfo{oldSector}, []market.SectorWeights{weights}, update)
+
+		sector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, update.NewSealedSectorCID, sector.SealedCID)
+		assert.Equal(t, update.Deals, sector.DealIDs)
+		assert.Equal(t, weights.DealWeight, sector.DealWeight)
+		assert.Equal(t, weights.VerifiedDealWeight, sector.VerifiedDealWeight)
+		assert.Equal(t, oldSector.Activation, sector.Activation)
+		assert.Equal(t, rt.Epoch(), sector.PowerBaseEpoch)
+		assert.Equal(t, oldSector.Expiration, sector.Expiration)
+		assert.True(t, sector.InitialPledge.GreaterThan(oldSector.InitialPledge))
+
+		// The sector remains in the same partition, with more power.
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		assert.Equal(t, update.Deadline, dlIdx)
+		assert.Equal(t, update.Partition, pIdx)
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		expectedPower := miner.PowerForSector(actor.sectorSize, sector)
+		assert.True(t, expectedPower.Equals(partition.LivePower))
+
+		assert.Equal(t, sector.InitialPledge, getState(rt).InitialPledgeRequirement)
+		actor.checkState(rt)
+	})
+
+	t.Run("termination fee of updated sector uses its original age", func(t *testing.T) {
+		rt, actor, oldSector, update := setup(t)
+		weights := market.SectorWeights{
+			DealWeight:         big.NewInt(int64(actor.sectorSize / 2)),
+			VerifiedDealWeight: big.NewInt(int64(actor.sectorSize / 2)),
+		}
+		rt.SetEpoch(rt.Epoch() + 1000)
+		actor.proveReplicaUpdates(rt, []*miner.SectorOnChainInfo{oldSector}, []market.SectorWeights{weights}, update)
+
+		sector := actor.getSector(rt, oldSector.SectorNumber)
+		rt.SetEpoch(rt.Epoch() + 10)
+		age := rt.Epoch() - oldSector.Activation
+		require.Equal(t, abi.ChainEpoch(1010), age)
+		sectorPower := miner.QAPowerForSector(actor.sectorSize, sector)
+		expectedFee := miner.PledgePenaltyForTermination(sector.InitialPledge, age, actor.epochReward, actor.networkQAPower, sectorPower)
+		actor.terminateSectors(rt, bf(uint64(sector.SectorNumber)), expectedFee)
+		actor.checkState(rt)
+	})
+
+	// Sets up a miner with 2KiB sectors, committing enough to fill one partition and start the next.
+	setupPartitions := func(t *testing.T) (*mock.Runtime, *actorHarness, []*miner.SectorOnChainInfo, []miner.ReplicaUpdate) {
+		actor := newHarness(t, periodOffset)
+		actor.setProofType(abi.RegisteredSealProof_StackedDrg2KiBV1)
+		actor.ccWithoutDeals = true
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		actor.constructAndVerify(rt)
+		sectors := actor.commitAndProveSectors(rt, int(actor.partitionSize)+1, 181, nil)
+
+		var updates []miner.ReplicaUpdate
+		for i, sector := range sectors {
+			dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+			require.NoError(t, err)
+			updates = append(updates, miner.ReplicaUpdate{
+				SectorNumber:       sector.SectorNumber,
+				Deadline:           dlIdx,
+				Partition:          pIdx,
+				NewSealedSectorCID: tutil.MakeCID(fmt.Sprintf("updated-%d", i), &miner.SealedCIDPrefix),
+				Deals:              []abi.DealID{abi.DealID(10 * (i + 1))},
+				UpdateProofType:    abi.RegisteredUpdateProof_StackedDrg2KiBV1,
+				ReplicaProof:       []byte{byte(i)},
+			})
+		}
+		// The updates span more than one partition.
+		first, last := updates[0], updates[len(updates)-1]
+		require.NotEqual(t, miner.PartitionKey{Deadline: first.Deadline, Partition: first.Partition},
+			miner.PartitionKey{Deadline: last.Deadline, Partition: last.Partition})
+		return rt, actor, sectors, updates
+	}
+
+	t.Run("updates sectors in several partitions", func(t *testing.T) {
+		rt, actor, oldSectors, updates := setupPartitions(t)
+		var weights []market.SectorWeights
+		for range updates {
+			weights = append(weights, market.SectorWeights{
+				DealWeight:         big.NewInt(int64(actor.sectorSize)),
+				VerifiedDealWeight: big.Zero(),
+			})
+		}
+
+		actor.proveReplicaUpdates(rt, oldSectors, weights, updates...)
+
+		pledgeTotal := big.Zero()
+		for i, update := range updates {
+			sector := actor.getSector(rt, update.SectorNumber)
+			assert.Equal(t, update.NewSealedSectorCID, sector.SealedCID)
+			assert.Equal(t, update.Deals, sector.DealIDs)
+			assert.Equal(t, weights[i].DealWeight, sector.DealWeight)
+			pledgeTotal = big.Add(pledgeTotal, sector.InitialPledge)
+		}
+		for _, update := range updates {
+			_, partition := actor.getDeadlineAndPartition(rt, update.Deadline, update.Partition)
+			expectedPower := miner.NewPowerPairZero()
+			for _, other := range updates {
+				if other.Deadline == update.Deadline && other.Partition == update.Partition {
+					expectedPower = expectedPower.Add(miner.PowerForSector(actor.sectorSize, actor.getSector(rt, other.SectorNumber)))
+				}
+			}
+			assert.True(t, expectedPower.Equals(partition.LivePower))
+		}
+		assert.Equal(t, pledgeTotal, getState(rt).InitialPledgeRequirement)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects all updates if a later one fails verification", func(t *testing.T) {
+		rt, actor, oldSectors, updates := setupPartitions(t)
+		var weights []market.SectorWeights
+		for range updates {
+			weights = append(weights, market.SectorWeights{
+				DealWeight:         big.NewInt(int64(actor.sectorSize)),
+				VerifiedDealWeight: big.Zero(),
+			})
+		}
+		results := make([]error, len(updates))
+		results[len(updates)-1] = fmt.Errorf("invalid replica update")
+
+		actor.expectVerifyReplicaUpdates(rt, oldSectors, weights, results, updates...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "failed to verify replica update", func() {
+			rt.Call(actor.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: updates})
+		})
+		rt.Verify()
+
+		for i, oldSector := range oldSectors {
+			sector := actor.getSector(rt, oldSector.SectorNumber)
+			assert.Equal(t, oldSector.SealedCID, sector.SealedCID, "sector %d", i)
+			assert.Empty(t, sector.DealIDs)
+		}
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects sector with deals", func(t *testing.T) {
+		rt, actor, oldSector, update := setup(t)
+		weights := market.SectorWeights{DealWeight: big.NewInt(int64(actor.sectorSize)), VerifiedDealWeight: big.Zero()}
+		actor.proveReplicaUpdates(rt, []*miner.SectorOnChainInfo{oldSector}, []market.SectorWeights{weights}, update)
+
+		update.Deals = []abi.DealID{3}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "cannot update sector", func() {
+			rt.Call(actor.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: []miner.ReplicaUpdate{update}})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects update of deadline that may be challenged", func(t *testing.T) {
+		rt, actor, _, update := setup(t)
+		dlInfo := miner.NewDeadlineInfo(getState(rt).ProvingPeriodStart, update.Deadline, rt.Epoch()).NextNotElapsed()
+		rt.SetEpoch(dlInfo.Challenge)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "cannot update sectors in deadline", func() {
+			rt.Call(actor.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: []miner.ReplicaUpdate{update}})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects invalid proof", func(t *testing.T) {
+		rt, actor, oldSector, update := setup(t)
+
+		actor.expectVerifyReplicaUpdates(rt, []*miner.SectorOnChainInfo{oldSector}, []market.SectorWeights{{
+			DealWeight:         big.NewInt(int64(actor.sectorSize)),
+			VerifiedDealWeight: big.Zero(),
+		}}, []error{fmt.Errorf("invalid replica update")}, update)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "failed to verify replica update", func() {
+			rt.Call(actor.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: []miner.ReplicaUpdate{update}})
+		})
+		rt.Reset()
+
+		sector := actor.getSector(rt, oldSector.SectorNumber)
+		assert.Equal(t, oldSector.SealedCID, sector.SealedCID)
+		assert.Empty(t, sector.DealIDs)
+		actor.checkState(rt)
+	})
+}
+
+func TestWindowPost(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	actor.setProofType(abi.RegisteredSealProof_StackedDrg2KiBV1)
+	precommitEpoch := abi.ChainEpoch(1)
+	builder := builderForHarness(actor).
+		WithEpoch(precommitEpoch).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("test proof", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		store := rt.AdtStore()
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(store, sector.SectorNumber)
+		require.NoError(t, err)
+
+		// Skip over deadlines until the beginning of the one with the new sector
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			advanceDeadline(rt, actor, &cronConfig{})
+			dlinfo = actor.deadline(rt)
+		}
+
+		// Submit PoSt
+		partitions := []miner.PoStPartition{
+			{Index: pIdx, Skipped: abi.NewBitField()},
+		}
+		actor.submitWindowPoSt(rt, dlinfo, partitions, []*miner.SectorOnChainInfo{sector}, nil)
+
+		// Verify proof recorded
+		deadline := actor.getDeadline(rt, dlIdx)
+		empty, err := deadline.PostSubmissions.IsEmpty()
+		require.NoError(t, err)
+		assert.False(t, empty, "no post submission")
+
+		// Advance to end-of-deadline cron to verify no penalties.
+		advanceDeadline(rt, actor, &cronConfig{})
+		actor.checkState(rt)
+	})
+
+	t.Run("gas trace", func(t *testing.T) {
+		rt := builder.WithPricelist(runtime.DefaultPricelist).Build(t)
+		actor.constructAndVerify(rt)
+		store := rt.AdtStore()
+		sectors := actor.commitAndProveSectors(rt, 2, 181, nil) // A full 2KiB partition.
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(store, sectors[0].SectorNumber)
+		require.NoError(t, err)
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			advanceDeadline(rt, actor, &cronConfig{})
+			dlinfo = actor.deadline(rt)
+		}
+
+		partitions := []miner.PoStPartition{
+			{Index: pIdx, Skipped: abi.NewBitField()},
+		}
+		actor.submitWindowPoSt(rt, dlinfo, partitions, sectors, nil)
+
+		trace := rt.LastGasTrace()
+		require.NotNil(t, trace)
+		assert.Equal(t, "SubmitWindowedPoSt", trace.Method)
+		counts := trace.CountsByName()
+		assert.Equal(t, 0, counts["OnVerifyPost"]) // Accepted optimistically.
+		assert.Greater(t, counts["OnIpldGet"], 0)
+		assert.Greater(t, counts["OnIpldPut"], 0)
+		assert.Greater(t, counts["OnAmtGet"], 0)
+		assert.Equal(t, 2, counts["OnMethodInvocation"]) // Network info queries.
+		assert.Contains(t, trace.String(), "OnIpldPut")
+
+		// The proof is verified when disputed.
+		advanceDeadline(rt, actor, &cronConfig{})
+		actor.disputeWindowPoSt(rt, dlinfo, 0, sectors, nil)
+		trace = rt.LastGasTrace()
+		assert.Equal(t, "DisputeWindowedPoSt", trace.Method)
+		postCost := runtime.DefaultPricelist.VerifyPost[abi.RegisteredPoStProof_StackedDrgWindow2KiBV1]
+		assert.Equal(t, postCost.Apply(len(sectors)), trace.TotalsByName()["OnVerifyPost"])
+		assert.Greater(t, trace.Total(), trace.TotalsByName()["OnVerifyPost"])
+		assert.Contains(t, trace.String(), "OnVerifyPost")
+
+		// Charges made while the test harness inspects state are not recorded.
+		traces := len(rt.GasTraces())
+		_ = getState(rt)
+		assert.Len(t, rt.GasTraces(), traces)
+		assert.Equal(t, trace, rt.LastGasTrace())
+	})
+
+	//runTillNextDeadline := func(rt *mock.Runtime) (*miner.DeadlineInfo, []*miner.SectorOnChainInfo, []uint64) {
+	//	st := getState(rt)
+	//	deadlines, err := st.LoadDeadlines(rt.AdtStore())
+	//	require.NoError(t, err)
+	//	deadline := actor.deadline(rt)
+	//
+	//	// advance to next deadline where we expect the first sectors to appear
+	//	rt.SetEpoch(deadline.NextOpen())
+	//	deadline = st.DeadlineInfo(rt.Epoch())
+	//
+	//	infos, partitions := actor.computePartitions(rt, deadlines, deadline.Index)
+	//	return deadline, infos, partitions
+	//}
+
+	//runTillFirstDeadline := func(rt *mock.Runtime) (*miner.DeadlineInfo, []*miner.SectorOnChainInfo, []uint64) {
+	//	actor.constructAndVerify(rt)
+	//
+	//	_ = actor.commitAndProveSectors(rt, 6, 100, nil)
+	//
+	//	// Skip to end of proving period, cron adds sectors to proving set.
+	//	actor.advancePastProvingPeriodWithCron(rt)
+	//
+	//	return runTillNextDeadline(rt)
+	//}
+
+	t.Run("successful recoveries recover power", func(t *testing.T) {
+		t.Skip("Disabled in miner state refactor #648, restore soon")
+		// TODO minerstate
+		//rt := builder.Build(t)
+		//deadline, infos, partitions := runTillFirstDeadline(rt)
+		//st := getState(rt)
+
+		// mark all sectors as recovered faults
 		//sectors := bitfield.New()
 		//for _, info := range infos {
 		//	sectors.Set(uint64(info.SectorNumber))
@@ -988,6 +2223,121 @@ func TestWindowPost(t *testing.T) {
 	//})
 }
 
+func TestWindowPostDispute(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	actor.setProofType(abi.RegisteredSealProof_StackedDrg2KiBV1)
+	precommitEpoch := abi.ChainEpoch(1)
+	builder := builderForHarness(actor).
+		WithEpoch(precommitEpoch).
+		WithBalance(bigBalance, big.Zero())
+
+	// Commits a full partition of sectors and submits an optimistic proof for them, returning the deadline
+	// at which they were proven.
+	setup := func(rt *mock.Runtime) ([]*miner.SectorOnChainInfo, *miner.DeadlineInfo, uint64) {
+		actor.constructAndVerify(rt)
+		sectors := actor.commitAndProveSectors(rt, 2, 181, nil)
+
+		st := getState(rt)
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sectors[0].SectorNumber)
+		require.NoError(t, err)
+		dlinfo := actor.deadline(rt)
+		for dlinfo.Index != dlIdx {
+			advanceDeadline(rt, actor, &cronConfig{})
+			dlinfo = actor.deadline(rt)
+		}
+
+		partitions := []miner.PoStPartition{
+			{Index: pIdx, Skipped: abi.NewBitField()},
+		}
+		actor.submitWindowPoSt(rt, dlinfo, partitions, sectors, nil)
+		return sectors, dlinfo, pIdx
+	}
+
+	t.Run("invalid proof is penalized and faults sectors", func(t *testing.T) {
+		rt := builder.Build(t)
+		sectors, dlinfo, pIdx := setup(rt)
+
+		// The proof may not be disputed while its challenge window is open.
+		params := miner.DisputeWindowedPoStParams{Deadline: dlinfo.Index, PoStIndex: 0}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.DisputeWindowedPoSt, &params)
+		})
+		rt.Reset()
+
+		advanceDeadline(rt, actor, &cronConfig{})
+
+		pwr := miner.PowerForSectors(actor.sectorSize, sectors)
+		actor.disputeWindowPoSt(rt, dlinfo, 0, sectors, &poStDisputeResult{
+			expectedPowerDelta: pwr.Neg(),
+			expectedPenalty:    miner.PledgePenaltyForInvalidWindowPoSt(actor.epochReward, actor.networkQAPower, pwr.QA),
+			expectedReward:     miner.BaseRewardForDisputedWindowPoSt,
+		})
+
+		// All sectors are now faulty.
+		_, partition := actor.getDeadlineAndPartition(rt, dlinfo.Index, pIdx)
+		assertBitfieldEquals(t, partition.Faults, uint64(sectors[0].SectorNumber), uint64(sectors[1].SectorNumber))
+		assert.True(t, pwr.Equals(partition.FaultyPower))
+		actor.checkState(rt)
+
+		// The proof cannot be disputed again.
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectAbort(exitcode.ErrNotFound, func() {
+			rt.Call(actor.a.DisputeWindowedPoSt, &params)
+		})
+		rt.Reset()
+	})
+
+	t.Run("valid proof cannot be disputed", func(t *testing.T) {
+		rt := builder.Build(t)
+		sectors, dlinfo, pIdx := setup(rt)
+		advanceDeadline(rt, actor, &cronConfig{})
+
+		actor.disputeWindowPoSt(rt, dlinfo, 0, sectors, nil)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlinfo.Index, pIdx)
+		empty, err := partition.Faults.IsEmpty()
+		require.NoError(t, err)
+		assert.True(t, empty)
+		actor.checkState(rt)
+	})
+
+	t.Run("cannot dispute after the dispute window", func(t *testing.T) {
+		rt := builder.Build(t)
+		_, dlinfo, _ := setup(rt)
+		advanceDeadline(rt, actor, &cronConfig{})
+		rt.SetEpoch(dlinfo.Close + miner.WPoStDisputeWindow)
+
+		params := miner.DisputeWindowedPoStParams{Deadline: dlinfo.Index, PoStIndex: 0}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+		expectQueryNetworkInfo(rt, actor)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.DisputeWindowedPoSt, &params)
+		})
+		rt.Reset()
+	})
+
+	t.Run("cannot compact partitions during the dispute window", func(t *testing.T) {
+		rt := builder.Build(t)
+		_, dlinfo, pIdx := setup(rt)
+		advanceDeadline(rt, actor, &cronConfig{})
+
+		params := miner.CompactPartitionsParams{Deadline: dlinfo.Index, Partitions: bitfield.NewFromSet([]uint64{pIdx})}
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "cannot compact deadline", func() {
+			rt.Call(actor.a.CompactPartitions, &params)
+		})
+		rt.Reset()
+	})
+}
+
 func TestProveCommit(t *testing.T) {
 	periodOffset := abi.ChainEpoch(100)
 	actor := newHarness(t, periodOffset)
@@ -1024,6 +2374,7 @@ func TestProveCommit(t *testing.T) {
 		// succeeds when pledge deposits satisfy initial pledge requirement
 		rt.SetBalance(bal)
 		actor.proveCommitSectorAndConfirm(rt, precommit, precommitEpoch, makeProveCommit(actor.nextSectorNo), proveCommitConf{})
+		actor.checkState(rt)
 	})
 
 	t.Run("drop invalid prove commit while processing valid one", func(t *testing.T) {
@@ -1055,6 +2406,7 @@ func TestProveCommit(t *testing.T) {
 			},
 		}
 		actor.confirmSectorProofsValid(rt, conf, precommitEpoch, precommitA, precommitB)
+		actor.checkState(rt)
 	})
 }
 
@@ -1235,10 +2587,188 @@ func TestDeclareFaults(t *testing.T) {
 		fee := miner.PledgePenaltyForDeclaredFault(actor.epochReward, totalQAPower, sectorQAPower)
 
 		actor.declareFaults(rt, fee, info)
+		actor.checkState(rt)
+	})
+}
+
+func TestDeclareFaultsPartial(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	setup := func(t *testing.T) (*mock.Runtime, *miner.SectorOnChainInfo, uint64, uint64) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		return rt, sector, dlIdx, pIdx
+	}
+
+	t.Run("ignores invalid fault declarations", func(t *testing.T) {
+		rt, sector, dlIdx, pIdx := setup(t)
+		sno := uint64(sector.SectorNumber)
+
+		applied := actor.declareFaultsPartial(rt, []*miner.SectorOnChainInfo{sector}, []miner.FaultDeclaration{
+			{Deadline: miner.WPoStPeriodDeadlines, Partition: pIdx, Sectors: bf(sno)}, // No such deadline
+			{Deadline: dlIdx, Partition: pIdx + 1, Sectors: bf(sno)},                  // No such partition
+			{Deadline: dlIdx, Partition: pIdx, Sectors: bf(sno, sno+1)},               // Sector not in partition
+			{Deadline: dlIdx, Partition: pIdx, Sectors: bf(sno)},
+		}...)
+		assertBitfieldEquals(t, applied, 3)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Faults, sno)
+		actor.checkState(rt)
+	})
+
+	t.Run("applies valid declarations around an invalid one", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sectors := actor.commitAndProveSectors(rt, 3, 181, nil)
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sectors[0].SectorNumber)
+		require.NoError(t, err)
+		for _, s := range sectors[1:] {
+			d, p, err := getState(rt).FindSector(rt.AdtStore(), s.SectorNumber)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{dlIdx, pIdx}, []uint64{d, p})
+		}
+		s0, s1, s2 := uint64(sectors[0].SectorNumber), uint64(sectors[1].SectorNumber), uint64(sectors[2].SectorNumber)
+
+		applied := actor.declareFaultsPartial(rt, sectors[:2], []miner.FaultDeclaration{
+			{Deadline: dlIdx, Partition: pIdx, Sectors: bf(s0)},
+			{Deadline: dlIdx, Partition: pIdx + 1, Sectors: bf(s2)}, // No such partition
+			{Deadline: dlIdx, Partition: pIdx, Sectors: bf(s1)},
+		}...)
+		assertBitfieldEquals(t, applied, 0, 2)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Faults, s0, s1)
+		actor.checkState(rt)
+	})
+
+	t.Run("ignores declarations for deadline past fault cutoff", func(t *testing.T) {
+		rt, sector, dlIdx, pIdx := setup(t)
+		dlInfo := miner.NewDeadlineInfo(getState(rt).ProvingPeriodStart, dlIdx, rt.Epoch()).NextNotElapsed()
+		rt.SetEpoch(dlInfo.FaultCutoff)
+
+		applied := actor.declareFaultsPartial(rt, nil, miner.FaultDeclaration{
+			Deadline: dlIdx, Partition: pIdx, Sectors: bf(uint64(sector.SectorNumber)),
+		})
+		assertBitfieldEquals(t, applied)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Faults)
+		actor.checkState(rt)
+	})
+
+	t.Run("aborts on invalid declaration without partial success", func(t *testing.T) {
+		rt, sector, dlIdx, pIdx := setup(t)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrNotFound, "no such partition", func() {
+			rt.Call(actor.a.DeclareFaults, &miner.DeclareFaultsParams{Faults: []miner.FaultDeclaration{
+				{Deadline: dlIdx, Partition: pIdx, Sectors: bf(uint64(sector.SectorNumber))},
+				{Deadline: dlIdx, Partition: pIdx + 1, Sectors: bf(uint64(sector.SectorNumber))},
+			}})
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("ignores invalid recovery declarations", func(t *testing.T) {
+		rt, sector, dlIdx, pIdx := setup(t)
+		sno := uint64(sector.SectorNumber)
+		actor.declareFaultsPartial(rt, []*miner.SectorOnChainInfo{sector}, miner.FaultDeclaration{
+			Deadline: dlIdx, Partition: pIdx, Sectors: bf(sno),
+		})
+
+		applied := actor.declareRecoveriesPartial(rt, []miner.RecoveryDeclaration{
+			{Deadline: dlIdx, Partition: pIdx, Sectors: bf(sno)},
+			{Deadline: dlIdx, Partition: pIdx + 1, Sectors: bf(sno)}, // No such partition
+		}...)
+		assertBitfieldEquals(t, applied, 0)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Recoveries, sno)
+		actor.checkState(rt)
 	})
 }
 
 func TestExtendSectorExpiration(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	precommitEpoch := abi.ChainEpoch(1)
+	builder := builderForHarness(actor).
+		WithEpoch(precommitEpoch).
+		WithBalance(bigBalance, big.Zero())
+
+	commitSector := func(t *testing.T, rt *mock.Runtime) (*miner.SectorOnChainInfo, uint64, uint64) {
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		return sector, dlIdx, pIdx
+	}
+
+	t.Run("defers extension of sector with deal weight", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector, dlIdx, pIdx := commitSector(t, rt)
+		require.False(t, sector.DealWeight.IsZero())
+
+		// The sector's power is unchanged, so no power update is expected.
+		newExpiration := sector.Expiration + 10*miner.WPoStProvingPeriod
+		actor.extendSectors(rt, &miner.ExtendSectorExpirationParams{
+			Extensions: []miner.ExpirationExtension{{
+				Deadline:      dlIdx,
+				Partition:     pIdx,
+				Sectors:       bf(uint64(sector.SectorNumber)),
+				NewExpiration: newExpiration,
+			}},
+		})
+
+		extended := actor.getSector(rt, sector.SectorNumber)
+		assert.Equal(t, sector.Expiration, extended.Expiration)
+		assert.Equal(t, newExpiration, extended.ExtendedExpiration)
+		assert.Equal(t, sector.DealWeight, extended.DealWeight)
+		assert.Equal(t, sector.VerifiedDealWeight, extended.VerifiedDealWeight)
+
+		// The sector is queued for renewal at its current expiration.
+		st := getState(rt)
+		deadline := actor.getDeadline(rt, dlIdx)
+		renewals, err := miner.LoadBitfieldQueue(rt.AdtStore(), deadline.Renewals, st.QuantEndOfDeadline())
+		require.NoError(t, err)
+		ExpectBQ().Add(st.QuantEndOfDeadline().QuantizeUp(sector.Expiration), uint64(sector.SectorNumber)).Equals(t, renewals)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects reduction of extended expiration", func(t *testing.T) {
+		rt := builder.Build(t)
+		sector, dlIdx, pIdx := commitSector(t, rt)
+
+		extend := func(newExpiration abi.ChainEpoch) *miner.ExtendSectorExpirationParams {
+			return &miner.ExtendSectorExpirationParams{
+				Extensions: []miner.ExpirationExtension{{
+					Deadline:      dlIdx,
+					Partition:     pIdx,
+					Sectors:       bf(uint64(sector.SectorNumber)),
+					NewExpiration: newExpiration,
+				}},
+			}
+		}
+		actor.extendSectors(rt, extend(sector.Expiration+10*miner.WPoStProvingPeriod))
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "cannot reduce sector expiration", func() {
+			rt.Call(actor.a.ExtendSectorExpiration, extend(sector.Expiration+5*miner.WPoStProvingPeriod))
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
 	//periodOffset := abi.ChainEpoch(100)
 	//actor := newHarness(t, periodOffset)
 	//precommitEpoch := abi.ChainEpoch(1)
@@ -1369,74 +2899,152 @@ func TestExtendSectorExpiration(t *testing.T) {
 	//})
 }
 
-func TestTerminateSectors(t *testing.T) {
-	//periodOffset := abi.ChainEpoch(100)
-	//actor := newHarness(t, periodOffset)
-	//builder := builderForHarness(actor).
-	//	WithBalance(bigBalance, big.Zero())
-	//
-	//commitSector := func(t *testing.T, rt *mock.Runtime) *miner.SectorOnChainInfo {
-	//	actor.constructAndVerify(rt)
-	//	precommitEpoch := abi.ChainEpoch(1)
-	//	rt.SetEpoch(precommitEpoch)
-	//	sectorInfo := actor.commitAndProveSectors(rt, 1, 100, nil)
-	//	return sectorInfo[0]
-	//}
+func TestProjectExpirations(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithEpoch(abi.ChainEpoch(1)).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("projects expirations of committed sectors", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		first := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		second := actor.commitAndProveSectors(rt, 1, 200, nil)[0]
+		quant := getState(rt).QuantEndOfDeadline()
+
+		projections, err := getState(rt).ProjectExpirations(rt.AdtStore(), 0, second.Expiration+miner.WPoStProvingPeriod)
+		require.NoError(t, err)
+		require.Len(t, projections, 2)
+		for i, sector := range []*miner.SectorOnChainInfo{first, second} {
+			proj := projections[i]
+			assert.Equal(t, quant.QuantizeUp(sector.Expiration), proj.Epoch)
+			assertBitfieldEquals(t, proj.OnTimeSectors, uint64(sector.SectorNumber))
+			assertEmptyBitfield(t, proj.EarlySectors)
+			assertEmptyBitfield(t, proj.RenewedSectors)
+			assert.Equal(t, sector.InitialPledge, proj.PledgeReleased)
+			sectorPower := miner.PowerForSector(actor.sectorSize, sector)
+			assert.True(t, sectorPower.Equals(proj.ActivePower))
+			assert.True(t, proj.FaultyPower.IsZero())
+		}
+
+		// A range covering only the first expiration excludes the second.
+		total, err := getState(rt).ProjectExpirationTotal(rt.AdtStore(), 0, first.Expiration+miner.WPoStChallengeWindow)
+		require.NoError(t, err)
+		assertBitfieldEquals(t, total.OnTimeSectors, uint64(first.SectorNumber))
+		assert.Equal(t, first.InitialPledge, total.PledgeReleased)
+	})
+
+	t.Run("projects renewal of extended sector", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		st := getState(rt)
+		quant := st.QuantEndOfDeadline()
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+
+		newExpiration := sector.Expiration + 10*miner.WPoStProvingPeriod
+		actor.extendSectors(rt, &miner.ExtendSectorExpirationParams{
+			Extensions: []miner.ExpirationExtension{{
+				Deadline:      dlIdx,
+				Partition:     pIdx,
+				Sectors:       bf(uint64(sector.SectorNumber)),
+				NewExpiration: newExpiration,
+			}},
+		})
+
+		projections, err := getState(rt).ProjectExpirations(rt.AdtStore(), 0, newExpiration+miner.WPoStProvingPeriod)
+		require.NoError(t, err)
+		require.Len(t, projections, 2)
+
+		// At its current expiration, the sector is renewed and loses the power derived from its deals.
+		renewal := projections[0]
+		sectorPower := miner.PowerForSector(actor.sectorSize, sector)
+		rawPower := miner.NewPowerPair(sectorPower.Raw, sectorPower.Raw)
+		assert.Equal(t, quant.QuantizeUp(sector.Expiration), renewal.Epoch)
+		assertEmptyBitfield(t, renewal.OnTimeSectors)
+		assertBitfieldEquals(t, renewal.RenewedSectors, uint64(sector.SectorNumber))
+		assert.True(t, renewal.PledgeReleased.IsZero())
+		expectedLoss := sectorPower.Sub(rawPower)
+		assert.True(t, expectedLoss.Equals(renewal.ActivePower))
+
+		// At the extended expiration, the sector expires with raw-byte power.
+		expiry := projections[1]
+		assert.Equal(t, quant.QuantizeUp(newExpiration), expiry.Epoch)
+		assertBitfieldEquals(t, expiry.OnTimeSectors, uint64(sector.SectorNumber))
+		assertEmptyBitfield(t, expiry.RenewedSectors)
+		assert.Equal(t, sector.InitialPledge, expiry.PledgeReleased)
+		assert.True(t, rawPower.Equals(expiry.ActivePower))
+	})
+
+	t.Run("does not project renewal of extended sector faulty for too long", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		st := getState(rt)
+		quant := st.QuantEndOfDeadline()
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+
+		newExpiration := sector.Expiration + 10*miner.WPoStProvingPeriod
+		actor.extendSectors(rt, &miner.ExtendSectorExpirationParams{
+			Extensions: []miner.ExpirationExtension{{
+				Deadline:      dlIdx,
+				Partition:     pIdx,
+				Sectors:       bf(uint64(sector.SectorNumber)),
+				NewExpiration: newExpiration,
+			}},
+		})
+
+		// The fault expires long before the sector's renewal is due.
+		sector = actor.getSector(rt, sector.SectorNumber)
+		actor.declareFaultsPartial(rt, []*miner.SectorOnChainInfo{sector}, miner.FaultDeclaration{
+			Deadline: dlIdx, Partition: pIdx, Sectors: bf(uint64(sector.SectorNumber)),
+		})
+
+		projections, err := getState(rt).ProjectExpirations(rt.AdtStore(), 0, newExpiration+miner.WPoStProvingPeriod)
+		require.NoError(t, err)
+		require.Len(t, projections, 1)
+		proj := projections[0]
+		assert.True(t, proj.Epoch < quant.QuantizeUp(sector.Expiration))
+		assertEmptyBitfield(t, proj.OnTimeSectors)
+		assertBitfieldEquals(t, proj.EarlySectors, uint64(sector.SectorNumber))
+		assertEmptyBitfield(t, proj.RenewedSectors)
+		sectorPower := miner.PowerForSector(actor.sectorSize, sector)
+		assert.True(t, sectorPower.Equals(proj.FaultyPower))
+	})
+}
+
+func TestTerminateSectors(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("removes sector with correct accounting", func(t *testing.T) {
+		rt := builder.Build(t)
+		rt.SetEpoch(periodOffset + 1)
+		actor.constructAndVerify(rt)
+		sector := actor.commitAndProveSectors(rt, 1, 181, [][]abi.DealID{{10}})[0]
 
-	// TODO minerstate
-	//t.Run("removes sector with correct accounting", func(t *testing.T) {
-	//	rt := builder.Build(t)
-	//	sector := commitSector(t, rt)
-	//	var initialLockedFunds abi.TokenAmount
-	//
-	//	// A miner will pay the minimum of termination fee and locked funds. Add some locked funds to ensure
-	//	// correct fee calculation is used.
-	//	actor.addLockedFund(rt, big.NewInt(1<<61))
-	//
-	//	{
-	//		// Verify that a sector expiration was registered.
-	//		st := getState(rt)
-	//		expiration, err := st.GetSectorExpirations(rt.AdtStore(), sector.Expiration)
-	//		require.NoError(t, err)
-	//		expiringSectorNos, err := expiration.All(1)
-	//		require.NoError(t, err)
-	//		assert.Len(t, expiringSectorNos, 1)
-	//		assert.Equal(t, sector.SectorNumber, abi.SectorNumber(expiringSectorNos[0]))
-	//		initialLockedFunds = st.LockedFunds
-	//	}
-	//
-	//	sectorSize, err := sector.SealProof.SectorSize()
-	//	require.NoError(t, err)
-	//	sectorPower := miner.QAPowerForSector(sectorSize, sector)
-	//	sectorAge := rt.Epoch() - sector.Activation
-	//	expectedFee := miner.PledgePenaltyForTermination(sector.InitialPledge, sectorAge, actor.epochReward, actor.networkQAPower, sectorPower)
-	//
-	//	sectors := bitfield.New()
-	//	sectors.Set(uint64(sector.SectorNumber))
-	//	actor.terminateSectors(rt, &sectors, expectedFee)
-	//
-	//	{
-	//		st := getState(rt)
-	//
-	//		// expect sector expiration to have been removed
-	//		err = st.ForEachSectorExpiration(rt.AdtStore(), func(expiry abi.ChainEpoch, sectors *abi.BitField) error {
-	//			assert.Fail(t, "did not expect to find a sector expiration, found expiration at %s", expiry)
-	//			return nil
-	//		})
-	//		assert.NoError(t, err)
-	//
-	//		// expect sector to have been removed
-	//		_, found, err := st.GetSector(rt.AdtStore(), sector.SectorNumber)
-	//		require.NoError(t, err)
-	//		assert.False(t, found)
-	//
-	//		// expect fee to have been unlocked and burnt
-	//		assert.Equal(t, big.Sub(initialLockedFunds, expectedFee), st.LockedFunds)
-	//
-	//		// expect pledge requirement to have been decremented
-	//		assert.Equal(t, big.Zero(), st.InitialPledgeRequirement)
-	//	}
-	//})
+		sectorPower := miner.QAPowerForSector(actor.sectorSize, sector)
+		expectedFee := miner.PledgePenaltyForTermination(sector.InitialPledge, rt.Epoch()-sector.Activation,
+			actor.epochReward, actor.networkQAPower, sectorPower)
+		actor.terminateSectors(rt, bf(uint64(sector.SectorNumber)), expectedFee)
+
+		// The termination is processed immediately, paying the fee and releasing the pledge requirement.
+		st := getState(rt)
+		assertBitfieldEmpty(t, st.EarlyTerminations)
+		assert.Equal(t, big.Zero(), st.InitialPledgeRequirement)
+
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		dl, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Terminated, uint64(sector.SectorNumber))
+		assertBitfieldEmpty(t, dl.EarlyTerminations)
+		actor.checkState(rt)
+	})
 }
 
 func TestWithdrawBalance(t *testing.T) {
@@ -1451,6 +3059,7 @@ func TestWithdrawBalance(t *testing.T) {
 
 		// withdraw 1% of balance
 		actor.withdrawFunds(rt, big.Mul(big.NewInt(10), big.NewInt(1e18)))
+		actor.checkState(rt)
 	})
 
 	t.Run("fails if miner is currently undercollateralized", func(t *testing.T) {
@@ -1469,6 +3078,88 @@ func TestWithdrawBalance(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrInsufficientFunds, func() {
 			actor.withdrawFunds(rt, big.Mul(big.NewInt(10), big.NewInt(1e18)))
 		})
+		actor.checkState(rt)
+	})
+}
+
+func TestRepayDebt(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	// Simulates a penalty that the miner could not pay.
+	addDebt := func(rt *mock.Runtime, debt abi.TokenAmount) {
+		st := getState(rt)
+		st.FeeDebt = debt
+		rt.ReplaceState(st)
+	}
+
+	t.Run("repays debt from balance", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		debt := big.Mul(big.NewInt(5), big.NewInt(1e18))
+		addDebt(rt, debt)
+
+		actor.repayDebt(rt, big.Zero(), debt)
+		assert.True(t, getState(rt).IsDebtFree())
+		actor.checkState(rt)
+
+		// With no debt, nothing is burnt.
+		actor.repayDebt(rt, big.Zero(), big.Zero())
+	})
+
+	t.Run("repays debt from funds sent and balance", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		debt := big.Add(bigBalance, big.NewInt(1e18))
+		addDebt(rt, debt)
+
+		// The balance alone is not sufficient.
+		actor.repayDebt(rt, big.Zero(), bigBalance)
+		assert.Equal(t, big.NewInt(1e18), getState(rt).FeeDebt)
+
+		actor.repayDebt(rt, big.NewInt(2e18), big.NewInt(1e18))
+		assert.True(t, getState(rt).IsDebtFree())
+		assert.Equal(t, big.NewInt(1e18), rt.Balance())
+		actor.checkState(rt)
+	})
+
+	t.Run("debt blocks withdrawal", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		addDebt(rt, big.NewInt(1))
+
+		rt.ExpectAbortConstainsMessage(exitcode.ErrInsufficientFunds, "unpaid fee debt", func() {
+			actor.withdrawFunds(rt, big.NewInt(1e18))
+		})
+		rt.Reset()
+	})
+
+	t.Run("debt blocks pre-commit", func(t *testing.T) {
+		rt := builder.Build(t)
+		precommitEpoch := periodOffset + 1
+		rt.SetEpoch(precommitEpoch)
+		actor.constructAndVerify(rt)
+		addDebt(rt, big.NewInt(1))
+
+		expiration := actor.deadline(rt).PeriodEnd() + 181*miner.WPoStProvingPeriod
+		rt.ExpectAbortConstainsMessage(exitcode.ErrInsufficientFunds, "unpaid fee debt", func() {
+			actor.preCommitSector(rt, actor.makePreCommit(100, precommitEpoch-1, expiration, nil))
+		})
+		rt.Reset()
+	})
+
+	t.Run("only owner, worker or control address may repay", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(tutil.NewIDAddr(t, 1234), builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs(actor.owner)...)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.RepayDebt, nil)
+		})
+		rt.Reset()
 	})
 }
 
@@ -1501,6 +3192,222 @@ func TestReportConsensusFault(t *testing.T) {
 	actor.reportConsensusFault(rt, addr.TestAddress, params, allDeals)
 }
 
+func TestExitMiner(t *testing.T) {
+	periodOffset := abi.ChainEpoch(100)
+
+	setup := func(t *testing.T) (*mock.Runtime, *actorHarness) {
+		actor := newHarness(t, periodOffset)
+		rt := builderForHarness(actor).
+			WithBalance(bigBalance, big.Zero()).
+			Build(t)
+		rt.SetEpoch(periodOffset + 1)
+		actor.constructAndVerify(rt)
+		return rt, actor
+	}
+
+	t.Run("terminates sectors and deletes miner", func(t *testing.T) {
+		rt, actor := setup(t)
+		sectors := actor.commitAndProveSectors(rt, 2, 181, nil)
+
+		actor.exitMiner(rt)
+		assert.True(t, getState(rt).Exiting)
+
+		rt.SetEpoch(rt.Epoch() + 1)
+		actor.onMinerExitCron(rt, &exitCronConfig{terminatedSectors: sectors})
+
+		st := getState(rt)
+		assert.Equal(t, big.Zero(), st.InitialPledgeRequirement)
+		assertBitfieldEmpty(t, st.EarlyTerminations)
+	})
+
+	t.Run("terminates deals of exited sectors", func(t *testing.T) {
+		rt, actor := setup(t)
+		sectors := actor.commitAndProveSectors(rt, 2, 181, [][]abi.DealID{{1, 2}, {3}})
+
+		actor.exitMiner(rt)
+		rt.SetEpoch(rt.Epoch() + 1)
+		// All deals are terminated with a single message.
+		actor.onMinerExitCron(rt, &exitCronConfig{terminatedSectors: sectors})
+	})
+
+	t.Run("waits for locked funds to vest", func(t *testing.T) {
+		rt, actor := setup(t)
+		amt := abi.NewTokenAmount(600_000)
+		actor.addLockedFund(rt, amt)
+
+		actor.exitMiner(rt)
+		rt.SetEpoch(rt.Epoch() + 1)
+		summary, err := getState(rt).SummarizeVestingFunds(rt.AdtStore(), rt.Epoch())
+		require.NoError(t, err)
+		actor.onMinerExitCron(rt, &exitCronConfig{expectedEnrollment: summary.LastEpoch + 1})
+
+		rt.SetEpoch(summary.LastEpoch + 1)
+		actor.onMinerExitCron(rt, &exitCronConfig{vestedFunds: amt})
+		assert.Equal(t, big.Zero(), getState(rt).LockedFunds)
+	})
+
+	t.Run("forgives fee debt exceeding balance", func(t *testing.T) {
+		rt, actor := setup(t)
+		actor.exitMiner(rt)
+
+		st := getState(rt)
+		st.FeeDebt = big.Add(bigBalance, abi.NewTokenAmount(1000))
+		rt.ReplaceState(st)
+
+		rt.SetEpoch(rt.Epoch() + 1)
+		actor.onMinerExitCron(rt, &exitCronConfig{repaidDebt: bigBalance})
+		assert.True(t, getState(rt).IsDebtFree())
+	})
+
+	t.Run("rejects pre-commit while exiting", func(t *testing.T) {
+		rt, actor := setup(t)
+		actor.exitMiner(rt)
+
+		expiration := actor.deadline(rt).PeriodEnd() + 181*miner.WPoStProvingPeriod
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "miner is exiting", func() {
+			actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("allows faults to be declared while exiting", func(t *testing.T) {
+		rt, actor := setup(t)
+		sector := actor.commitAndProveSectors(rt, 1, 181, nil)[0]
+		dlIdx, pIdx, err := getState(rt).FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(t, err)
+		actor.exitMiner(rt)
+
+		applied := actor.declareFaultsPartial(rt, []*miner.SectorOnChainInfo{sector}, miner.FaultDeclaration{
+			Deadline: dlIdx, Partition: pIdx, Sectors: bf(uint64(sector.SectorNumber)),
+		})
+		assertBitfieldEquals(t, applied, 0)
+
+		_, partition := actor.getDeadlineAndPartition(rt, dlIdx, pIdx)
+		assertBitfieldEquals(t, partition.Faults, uint64(sector.SectorNumber))
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects changes to sectors while exiting", func(t *testing.T) {
+		sectorNos := abi.NewBitField()
+		for i := uint64(0); i < miner.MinAggregatedSectors; i++ {
+			sectorNos.Set(100 + i)
+		}
+		workerMethods := []struct {
+			name   string
+			method interface{}
+			params runtime.CBORMarshaler
+		}{
+			{"ExtendSectorExpiration", miner.Actor{}.ExtendSectorExpiration, &miner.ExtendSectorExpirationParams{
+				Extensions: []miner.ExpirationExtension{{Sectors: abi.NewBitField(), NewExpiration: 1_000_000}},
+			}},
+			{"ProveReplicaUpdates", miner.Actor{}.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{
+				Updates: []miner.ReplicaUpdate{{SectorNumber: 100, Deals: []abi.DealID{1}}},
+			}},
+			{"ProveCommitAggregate", miner.Actor{}.ProveCommitAggregate, &miner.ProveCommitAggregateParams{
+				SectorNumbers: sectorNos,
+			}},
+			{"TerminateSectors", miner.Actor{}.TerminateSectors, &miner.TerminateSectorsParams{
+				Terminations: []miner.TerminationDeclaration{{Sectors: abi.NewBitField()}},
+			}},
+			{"DeclareFaultsRecovered", miner.Actor{}.DeclareFaultsRecovered, &miner.DeclareFaultsRecoveredParams{}},
+			{"DeclareFaultsRecoveredPartial", miner.Actor{}.DeclareFaultsRecoveredPartial, &miner.DeclareFaultsRecoveredParams{}},
+			{"CompactPartitions", miner.Actor{}.CompactPartitions, &miner.CompactPartitionsParams{
+				Partitions: abi.NewBitField(),
+			}},
+		}
+		for _, m := range workerMethods {
+			t.Run(m.name, func(t *testing.T) {
+				rt, actor := setup(t)
+				actor.exitMiner(rt)
+
+				rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+				rt.ExpectValidateCallerAddr(actor.workerOrControlAddrs()...)
+				rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "miner is exiting", func() {
+					rt.Call(m.method, m.params)
+				})
+				rt.Verify()
+				actor.checkState(rt)
+			})
+		}
+
+		t.Run("ProveCommitSector", func(t *testing.T) {
+			rt, actor := setup(t)
+			actor.exitMiner(rt)
+
+			rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+			rt.ExpectValidateCallerAny()
+			rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "miner is exiting", func() {
+				rt.Call(actor.a.ProveCommitSector, &miner.ProveCommitSectorParams{SectorNumber: 100})
+			})
+			rt.Verify()
+			actor.checkState(rt)
+		})
+
+		t.Run("ConfirmSectorProofsValid", func(t *testing.T) {
+			rt, actor := setup(t)
+			actor.exitMiner(rt)
+
+			rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
+			rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
+			rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "miner is exiting", func() {
+				rt.Call(actor.a.ConfirmSectorProofsValid, &builtin.ConfirmSectorProofsParams{Sectors: []abi.SectorNumber{100}})
+			})
+			rt.Verify()
+			actor.checkState(rt)
+		})
+	})
+
+	t.Run("rejects exit with pre-committed sectors", func(t *testing.T) {
+		rt, actor := setup(t)
+		expiration := actor.deadline(rt).PeriodEnd() + 181*miner.WPoStProvingPeriod
+		actor.preCommitSector(rt, actor.makePreCommit(100, rt.Epoch()-1, expiration, nil))
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "cannot exit with pre-committed sectors", func() {
+			rt.Call(actor.a.ExitMiner, nil)
+		})
+		rt.Reset()
+		assert.False(t, getState(rt).Exiting)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects exit with beneficiary other than owner", func(t *testing.T) {
+		rt, actor := setup(t)
+		beneficiary := tutil.NewIDAddr(t, 999)
+		actor.changeBeneficiary(rt, actor.owner, beneficiary, abi.NewTokenAmount(100), rt.Epoch()+200)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "cannot exit with beneficiary", func() {
+			rt.Call(actor.a.ExitMiner, nil)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+
+	t.Run("only owner may exit, once", func(t *testing.T) {
+		rt, actor := setup(t)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.ExitMiner, nil)
+		})
+		rt.Reset()
+
+		actor.exitMiner(rt)
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrForbidden, "miner is already exiting", func() {
+			rt.Call(actor.a.ExitMiner, nil)
+		})
+		rt.Reset()
+		actor.checkState(rt)
+	})
+}
+
 func TestAddLockedFund(t *testing.T) {
 	periodOffset := abi.ChainEpoch(1808)
 	actor := newHarness(t, periodOffset)
@@ -1537,7 +3444,7 @@ func TestAddLockedFund(t *testing.T) {
 		})
 		require.NoError(t, err)
 		assert.Equal(t, amt, st.LockedFunds)
-
+		actor.checkState(rt)
 	})
 
 	t.Run("funds vest when under collateralized", func(t *testing.T) {
@@ -1565,6 +3472,7 @@ func TestAddLockedFund(t *testing.T) {
 		assert.False(t, st.MeetsInitialPledgeCondition(newBalance))
 		// all funds locked in vesting table
 		assert.Equal(t, amt, st.LockedFunds)
+		actor.checkState(rt)
 	})
 
 	t.Run("unvested funds will recollateralize a miner", func(t *testing.T) {
@@ -1579,8 +3487,67 @@ func TestAddLockedFund(t *testing.T) {
 
 		st.InitialPledgeRequirement = balance
 		assert.True(t, st.MeetsInitialPledgeCondition(balance))
+		actor.checkState(rt)
+	})
+
+}
+
+func TestCompactVestingFunds(t *testing.T) {
+	periodOffset := abi.ChainEpoch(1808)
+	actor := newHarness(t, periodOffset)
+	builder := builderForHarness(actor).
+		WithBalance(bigBalance, big.Zero())
+
+	t.Run("owner compacts vesting table", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+		store := rt.AdtStore()
+
+		// Funds locked half a day apart vest on interleaved epochs.
+		amt := abi.NewTokenAmount(600_000)
+		actor.addLockedFund(rt, amt)
+		rt.SetEpoch(rt.Epoch() + miner.RewardVestingSpec.Quantization)
+		actor.addLockedFund(rt, amt)
+		before, err := getState(rt).LoadVestingFunds(store)
+		require.NoError(t, err)
+		require.Equal(t, 360, len(before))
+
+		quantization := 2 * miner.RewardVestingSpec.Quantization
+		actor.compactVestingFunds(rt, quantization)
+
+		st := getState(rt)
+		after, err := st.LoadVestingFunds(store)
+		require.NoError(t, err)
+		assert.Equal(t, 180, len(after))
+		for _, fund := range after {
+			assert.Equal(t, st.ProvingPeriodStart%quantization, fund.Epoch%quantization)
+		}
+		assert.Equal(t, big.Mul(amt, big.NewInt(2)), st.LockedFunds)
+		// No funds vest earlier than scheduled.
+		assert.True(t, after[0].Epoch >= before[0].Epoch)
+		actor.checkState(rt)
+	})
+
+	t.Run("rejects non-positive quantization", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.owner, builtin.AccountActorCodeID)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "non-positive vesting quantization", func() {
+			rt.Call(actor.a.CompactVestingFunds, &miner.CompactVestingFundsParams{Quantization: 0})
+		})
 	})
 
+	t.Run("only owner may compact", func(t *testing.T) {
+		rt := builder.Build(t)
+		actor.constructAndVerify(rt)
+
+		rt.SetCaller(actor.worker, builtin.AccountActorCodeID)
+		rt.ExpectValidateCallerAddr(actor.owner)
+		rt.ExpectAbort(exitcode.ErrForbidden, func() {
+			rt.Call(actor.a.CompactVestingFunds, &miner.CompactVestingFundsParams{Quantization: miner.WPoStProvingPeriod})
+		})
+	})
 }
 
 type actorHarness struct {
@@ -1592,11 +3559,16 @@ type actorHarness struct {
 	worker   addr.Address
 	key      addr.Address
 
+	controlAddrs []addr.Address
+
 	sealProofType abi.RegisteredSealProof
 	sectorSize    abi.SectorSize
 	partitionSize uint64
 	periodOffset  abi.ChainEpoch
 	nextSectorNo  abi.SectorNumber
+	// If set, the market reports no deal weight for sectors pre-committed without deals, which are then
+	// committed-capacity sectors.
+	ccWithoutDeals bool
 
 	epochReward     abi.TokenAmount
 	networkPledge   abi.TokenAmount
@@ -1614,6 +3586,7 @@ func newHarness(t testing.TB, provingPeriodOffset abi.ChainEpoch) *actorHarness
 	owner := tutil.NewIDAddr(t, 100)
 	worker := tutil.NewIDAddr(t, 101)
 	workerKey := tutil.NewBLSAddr(t, 0)
+	controlAddrs := []addr.Address{tutil.NewIDAddr(t, 999), tutil.NewIDAddr(t, 998)}
 	receiver := tutil.NewIDAddr(t, 1000)
 	reward := big.Mul(big.NewIntUnsigned(100), big.NewIntUnsigned(1e18))
 	return &actorHarness{
@@ -1623,6 +3596,8 @@ func newHarness(t testing.TB, provingPeriodOffset abi.ChainEpoch) *actorHarness
 		worker:   worker,
 		key:      workerKey,
 
+		controlAddrs: controlAddrs,
+
 		sealProofType: sealProofType,
 		sectorSize:    sectorSize,
 		partitionSize: partitionSectors,
@@ -1650,6 +3625,7 @@ func (h *actorHarness) constructAndVerify(rt *mock.Runtime) {
 	params := miner.ConstructorParams{
 		OwnerAddr:     h.owner,
 		WorkerAddr:    h.worker,
+		ControlAddrs:  h.controlAddrs,
 		SealProofType: h.sealProofType,
 		PeerId:        testPid,
 	}
@@ -1729,6 +3705,12 @@ func (h *actorHarness) getDeadlineAndPartition(rt *mock.Runtime, dlIdx, pIdx uin
 	return deadline, partition
 }
 
+func (h *actorHarness) checkState(rt *mock.Runtime) {
+	st := getState(rt)
+	_, msgs := miner.CheckStateInvariants(st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 // Collects all sector infos into a map.
 func (h *actorHarness) collectSectors(rt *mock.Runtime) map[abi.SectorNumber]*miner.SectorOnChainInfo {
 	sectors := map[abi.SectorNumber]*miner.SectorOnChainInfo{}
@@ -1774,56 +3756,181 @@ func (h *actorHarness) collectPartitionExpirations(rt *mock.Runtime, partition *
 // Actor method calls
 //
 
-func (h *actorHarness) controlAddresses(rt *mock.Runtime) (owner, worker addr.Address) {
+// Returns the addresses permitted to send worker messages, followed by any others, as validated by the actor.
+func (h *actorHarness) workerOrControlAddrs(others ...addr.Address) []addr.Address {
+	return append(append([]addr.Address{h.worker}, h.controlAddrs...), others...)
+}
+
+func (h *actorHarness) controlAddresses(rt *mock.Runtime) (owner, worker addr.Address, control []addr.Address) {
 	rt.ExpectValidateCallerAny()
 	ret := rt.Call(h.a.ControlAddresses, nil).(*miner.GetControlAddressesReturn)
 	require.NotNil(h.t, ret)
 	rt.Verify()
-	return ret.Owner, ret.Worker
+	return ret.Owner, ret.Worker, ret.ControlAddrs
+}
+
+// Changes the control addresses and, if different, the worker (with delay).
+// The new worker is expected to have the harness' worker key.
+func (h *actorHarness) changeWorkerAddress(rt *mock.Runtime, newWorker addr.Address, newControlAddrs []addr.Address) {
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.owner)
+	rt.ExpectSend(newWorker, builtin.MethodsAccount.PubkeyAddress, nil, big.Zero(), &h.key, exitcode.Ok)
+
+	if newWorker != h.getInfo(rt).Worker {
+		payload := miner.CronEventPayload{EventType: miner.CronEventWorkerKeyChange}
+		buf := bytes.Buffer{}
+		require.NoError(h.t, payload.MarshalCBOR(&buf))
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.EnrollCronEvent, &power.EnrollCronEventParams{
+			EventEpoch: rt.Epoch() + miner.WorkerKeyChangeDelay,
+			Payload:    buf.Bytes(),
+		}, big.Zero(), nil, exitcode.Ok)
+	}
+
+	ret := rt.Call(h.a.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+		NewWorker:         newWorker,
+		NewControlAddrs:   newControlAddrs,
+		ClearControlAddrs: newControlAddrs != nil && len(newControlAddrs) == 0,
+	})
+	assert.Nil(h.t, ret)
+	rt.Verify()
+}
+
+func (h *actorHarness) changeOwnerAddress(rt *mock.Runtime, caller, newAddr addr.Address) {
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	if caller == h.getInfo(rt).Owner {
+		rt.ExpectValidateCallerAddr(caller)
+	} else {
+		rt.ExpectValidateCallerAddr(h.getInfo(rt).PendingOwner.NewOwner)
+	}
+	ret := rt.Call(h.a.ChangeOwnerAddress, &newAddr)
+	assert.Nil(h.t, ret)
+	rt.Verify()
+}
+
+func (h *actorHarness) changeBeneficiary(rt *mock.Runtime, caller, beneficiary addr.Address, quota abi.TokenAmount, expiration abi.ChainEpoch) {
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	info := h.getInfo(rt)
+	if caller == info.Owner {
+		rt.ExpectValidateCallerAddr(caller)
+	} else {
+		rt.ExpectValidateCallerAddr(info.PendingBeneficiaryTerm.NewBeneficiary, info.Beneficiary)
+	}
+	ret := rt.Call(h.a.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+		NewBeneficiary: beneficiary,
+		NewQuota:       quota,
+		NewExpiration:  expiration,
+	})
+	assert.Nil(h.t, ret)
+	rt.Verify()
+}
+
+// Extends sectors without changing their power, as for sectors with deal weight.
+func (h *actorHarness) extendSectors(rt *mock.Runtime, params *miner.ExtendSectorExpirationParams) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+	ret := rt.Call(h.a.ExtendSectorExpiration, params)
+	assert.Nil(h.t, ret)
+	rt.Verify()
+}
+
+func (h *actorHarness) getBeneficiary(rt *mock.Runtime) *miner.GetBeneficiaryReturn {
+	rt.ExpectValidateCallerAny()
+	ret := rt.Call(h.a.GetBeneficiary, nil).(*miner.GetBeneficiaryReturn)
+	require.NotNil(h.t, ret)
+	rt.Verify()
+	return ret
 }
 
 func (h *actorHarness) preCommitSector(rt *mock.Runtime, params *miner.SectorPreCommitInfo) *miner.SectorPreCommitOnChainInfo {
+	h.expectPreCommitSectors(rt, params)
+	rt.Call(h.a.PreCommitSector, params)
+	rt.Verify()
+	return h.getPreCommit(rt, params.SectorNumber)
+}
+
+func (h *actorHarness) withdrawPreCommits(rt *mock.Runtime, penalty abi.TokenAmount, sectorNos ...uint64) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+	if penalty.GreaterThan(big.Zero()) {
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, penalty, nil, exitcode.Ok)
+	}
+
+	rt.Call(h.a.WithdrawPreCommits, &miner.WithdrawPreCommitsParams{Sectors: bf(sectorNos...)})
+	rt.Verify()
+}
+
+func (h *actorHarness) maskSectorNumbers(rt *mock.Runtime, mask *bitfield.BitField) {
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.owner)
+
+	rt.Call(h.a.MaskSectorNumbers, &miner.MaskSectorNumbersParams{Mask: mask})
+	rt.Verify()
+}
+
+func (h *actorHarness) preCommitSectorBatch(rt *mock.Runtime, sectors ...*miner.SectorPreCommitInfo) []*miner.SectorPreCommitOnChainInfo {
+	params := miner.PreCommitSectorBatchParams{}
+	for _, sector := range sectors {
+		params.Sectors = append(params.Sectors, *sector)
+	}
+	h.expectPreCommitSectors(rt, sectors...)
+	rt.Call(h.a.PreCommitSectorBatch, &params)
+	rt.Verify()
+
+	var precommits []*miner.SectorPreCommitOnChainInfo
+	for _, sector := range sectors {
+		precommits = append(precommits, h.getPreCommit(rt, sector.SectorNumber))
+	}
+	return precommits
+}
 
+// Sets up the expectations for a successful pre-commitment of sectors, in a single message.
+func (h *actorHarness) expectPreCommitSectors(rt *mock.Runtime, sectors ...*miner.SectorPreCommitInfo) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
 	{
 		expectQueryNetworkInfo(rt, h)
 	}
 	{
-		sectorSize, err := params.SealProof.SectorSize()
-		require.NoError(h.t, err)
-
-		vdParams := market.VerifyDealsForActivationParams{
-			DealIDs:      params.DealIDs,
-			SectorStart:  rt.Epoch(),
-			SectorExpiry: params.Expiration,
-		}
-
-		vdReturn := market.VerifyDealsForActivationReturn{
-			DealWeight:         big.NewInt(int64(sectorSize / 2)),
-			VerifiedDealWeight: big.NewInt(int64(sectorSize / 2)),
+		vdParams := market.VerifyDealsForActivationParams{}
+		vdReturn := market.VerifyDealsForActivationReturn{}
+		for _, sector := range sectors {
+			sectorSize, err := sector.SealProof.SectorSize()
+			require.NoError(h.t, err)
+
+			vdParams.Sectors = append(vdParams.Sectors, market.SectorDeals{
+				SectorExpiry: sector.Expiration,
+				DealIDs:      sector.DealIDs,
+			})
+			weights := market.SectorWeights{
+				DealWeight:         big.NewInt(int64(sectorSize / 2)),
+				VerifiedDealWeight: big.NewInt(int64(sectorSize / 2)),
+			}
+			if h.ccWithoutDeals && len(sector.DealIDs) == 0 {
+				weights = market.SectorWeights{DealWeight: big.Zero(), VerifiedDealWeight: big.Zero()}
+			}
+			vdReturn.Sectors = append(vdReturn.Sectors, weights)
 		}
 		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.VerifyDealsForActivation, &vdParams, big.Zero(), &vdReturn, exitcode.Ok)
 	}
 	{
+		sectorNos := bitfield.New()
+		for _, sector := range sectors {
+			sectorNos.Set(uint64(sector.SectorNumber))
+		}
 		eventPayload := miner.CronEventPayload{
 			EventType: miner.CronEventPreCommitExpiry,
-			Sectors:   bitfield.NewFromSet([]uint64{uint64(params.SectorNumber)}),
+			Sectors:   &sectorNos,
 		}
 		buf := bytes.Buffer{}
 		err := eventPayload.MarshalCBOR(&buf)
 		require.NoError(h.t, err)
 		cronParams := power.EnrollCronEventParams{
-			EventEpoch: rt.Epoch() + miner.MaxSealDuration[params.SealProof] + 1,
+			EventEpoch: rt.Epoch() + miner.MaxSealDuration[sectors[0].SealProof] + 1,
 			Payload:    buf.Bytes(),
 		}
 		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.EnrollCronEvent, &cronParams, big.Zero(), nil, exitcode.Ok)
 	}
-
-	rt.Call(h.a.PreCommitSector, params)
-	rt.Verify()
-	return h.getPreCommit(rt, params.SectorNumber)
 }
 
 // Options for proveCommitSector behaviour.
@@ -1879,15 +3986,27 @@ func (h *actorHarness) proveCommitSector(rt *mock.Runtime, precommit *miner.Sect
 }
 
 func (h *actorHarness) confirmSectorProofsValid(rt *mock.Runtime, conf proveCommitConf, precommitEpoch abi.ChainEpoch, precommits ...*miner.SectorPreCommitInfo) {
-	// expect calls to get network stats
-	expectQueryNetworkInfo(rt, h)
-
 	// Prepare for and receive call to ConfirmSectorProofsValid.
-	var validPrecommits []*miner.SectorPreCommitInfo
+	h.expectConfirmSectorProofsValid(rt, conf, precommits...)
+
 	var allSectorNumbers []abi.SectorNumber
 	for _, precommit := range precommits {
 		allSectorNumbers = append(allSectorNumbers, precommit.SectorNumber)
+	}
+
+	rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
+	rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
+	rt.Call(h.a.ConfirmSectorProofsValid, &builtin.ConfirmSectorProofsParams{Sectors: allSectorNumbers})
+	rt.Verify()
+}
 
+// Sets up the expectations for activation of sectors for which proofs have been verified.
+func (h *actorHarness) expectConfirmSectorProofsValid(rt *mock.Runtime, conf proveCommitConf, precommits ...*miner.SectorPreCommitInfo) {
+	// expect calls to get network stats
+	expectQueryNetworkInfo(rt, h)
+
+	var validPrecommits []*miner.SectorPreCommitInfo
+	for _, precommit := range precommits {
 		vdParams := market.ActivateDealsParams{
 			DealIDs:      precommit.DealIDs,
 			SectorExpiry: precommit.Expiration,
@@ -1924,13 +4043,149 @@ func (h *actorHarness) confirmSectorProofsValid(rt *mock.Runtime, conf proveComm
 		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &pcParams, big.Zero(), nil, exitcode.Ok)
 		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &expectPledge, big.Zero(), nil, exitcode.Ok)
 	}
+}
 
-	rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
-	rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
-	rt.Call(h.a.ConfirmSectorProofsValid, &builtin.ConfirmSectorProofsParams{Sectors: allSectorNumbers})
+// Proves pre-committed sectors with an aggregate proof, activating them immediately.
+// The pre-commits must be in order of sector number.
+func (h *actorHarness) proveCommitAggregateSector(rt *mock.Runtime, conf proveCommitConf, precommitEpoch abi.ChainEpoch,
+	precommits ...*miner.SectorPreCommitInfo) {
+	proof := []byte{9, 10, 11, 12}
+	sectorNos := h.expectVerifyAggregateSeals(rt, precommitEpoch, proof, nil, precommits...)
+	h.expectConfirmSectorProofsValid(rt, conf, precommits...)
+
+	rt.Call(h.a.ProveCommitAggregate, &miner.ProveCommitAggregateParams{
+		SectorNumbers:  sectorNos,
+		AggregateProof: proof,
+	})
+	rt.Verify()
+}
+
+// Sets up the expectations for verification of an aggregate proof for pre-committed sectors, called by the worker.
+// Returns the sector numbers to be aggregated.
+func (h *actorHarness) expectVerifyAggregateSeals(rt *mock.Runtime, precommitEpoch abi.ChainEpoch, proof []byte,
+	result error, precommits ...*miner.SectorPreCommitInfo) *bitfield.BitField {
+	commd := cbg.CborCid(tutil.MakeCID("commd", &market.PieceCIDPrefix))
+	sealRand := abi.SealRandomness([]byte{1, 2, 3, 4})
+	sealIntRand := abi.InteractiveSealRandomness([]byte{5, 6, 7, 8})
+	interactiveEpoch := precommitEpoch + miner.PreCommitChallengeDelay
+
+	actorId, err := addr.IDFromAddress(h.receiver)
+	require.NoError(h.t, err)
+	var buf bytes.Buffer
+	require.NoError(h.t, rt.Receiver().MarshalCBOR(&buf))
+
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+
+	sectorNos := bitfield.New()
+	var infos []abi.AggregateSealVerifyInfo
+	for _, precommit := range precommits {
+		sectorNos.Set(uint64(precommit.SectorNumber))
+
+		cdcParams := market.ComputeDataCommitmentParams{
+			DealIDs:    precommit.DealIDs,
+			SectorType: precommit.SealProof,
+		}
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ComputeDataCommitment, &cdcParams, big.Zero(), &commd, exitcode.Ok)
+		rt.ExpectGetRandomness(crypto.DomainSeparationTag_SealRandomness, precommit.SealRandEpoch, buf.Bytes(), abi.Randomness(sealRand))
+		rt.ExpectGetRandomness(crypto.DomainSeparationTag_InteractiveSealChallengeSeed, interactiveEpoch, buf.Bytes(), abi.Randomness(sealIntRand))
+
+		infos = append(infos, abi.AggregateSealVerifyInfo{
+			Number:                precommit.SectorNumber,
+			Randomness:            sealRand,
+			InteractiveRandomness: sealIntRand,
+			SealedCID:             precommit.SealedCID,
+			UnsealedCID:           cid.Cid(commd),
+		})
+	}
+	rt.ExpectVerifyAggregateSeals(abi.AggregateSealVerifyProofAndInfos{
+		Miner:          abi.ActorID(actorId),
+		SealProof:      h.sealProofType,
+		AggregateProof: abi.RegisteredAggregationProof_SnarkPackV1,
+		Proof:          proof,
+		Infos:          infos,
+	}, result)
+	return &sectorNos
+}
+
+// Updates committed-capacity sectors with deals, for which the market reports some weights.
+// The old sectors and weights are in the same order as the updates.
+func (h *actorHarness) proveReplicaUpdates(rt *mock.Runtime, oldSectors []*miner.SectorOnChainInfo, weights []market.SectorWeights,
+	updates ...miner.ReplicaUpdate) {
+	h.expectVerifyReplicaUpdates(rt, oldSectors, weights, nil, updates...)
+	expectQueryNetworkInfo(rt, h)
+
+	powerDelta := miner.NewPowerPairZero()
+	pledgeDelta := big.Zero()
+	for i := range updates {
+		oldPower := miner.PowerForSector(h.sectorSize, oldSectors[i])
+		qaPower := miner.QAPowerForWeight(h.sectorSize, oldSectors[i].Expiration-rt.Epoch(), weights[i].DealWeight, weights[i].VerifiedDealWeight)
+		powerDelta = powerDelta.Add(miner.NewPowerPair(oldPower.Raw, qaPower).Sub(oldPower))
+
+		pledge := miner.InitialPledgeForPower(qaPower, h.networkQAPower, h.baselinePower,
+			h.networkPledge, h.epochReward, rt.TotalFilCircSupply())
+		pledgeDelta = big.Add(pledgeDelta, big.Sub(big.Max(pledge, oldSectors[i].InitialPledge), oldSectors[i].InitialPledge))
+	}
+	if !powerDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &power.UpdateClaimedPowerParams{
+			RawByteDelta:         powerDelta.Raw,
+			QualityAdjustedDelta: powerDelta.QA,
+		}, big.Zero(), nil, exitcode.Ok)
+	}
+	if !pledgeDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+	}
+
+	rt.Call(h.a.ProveReplicaUpdates, &miner.ProveReplicaUpdatesParams{Updates: updates})
 	rt.Verify()
 }
 
+// Sets up the expectations for verification of replica updates, called by the worker, and activation of their
+// deals. Results are in the same order as the updates, and a nil slice means every verification succeeds.
+// Expectations stop at the first verification with a non-nil result.
+func (h *actorHarness) expectVerifyReplicaUpdates(rt *mock.Runtime, oldSectors []*miner.SectorOnChainInfo, weights []market.SectorWeights,
+	results []error, updates ...miner.ReplicaUpdate) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+
+	vdParams := market.VerifyDealsForActivationParams{}
+	for i, update := range updates {
+		vdParams.Sectors = append(vdParams.Sectors, market.SectorDeals{
+			SectorExpiry: oldSectors[i].Expiration,
+			DealIDs:      update.Deals,
+		})
+	}
+	vdReturn := market.VerifyDealsForActivationReturn{Sectors: weights}
+	rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.VerifyDealsForActivation, &vdParams, big.Zero(), &vdReturn, exitcode.Ok)
+
+	commd := cbg.CborCid(tutil.MakeCID("commd", &market.PieceCIDPrefix))
+	for i, update := range updates {
+		var result error
+		if results != nil {
+			result = results[i]
+		}
+		cdcParams := market.ComputeDataCommitmentParams{
+			DealIDs:    update.Deals,
+			SectorType: h.sealProofType,
+		}
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ComputeDataCommitment, &cdcParams, big.Zero(), &commd, exitcode.Ok)
+		rt.ExpectVerifyReplicaUpdate(abi.ReplicaUpdateInfo{
+			UpdateProofType:      update.UpdateProofType,
+			Proof:                update.ReplicaProof,
+			OldSealedSectorCID:   oldSectors[i].SealedCID,
+			NewSealedSectorCID:   update.NewSealedSectorCID,
+			NewUnsealedSectorCID: cid.Cid(commd),
+		}, result)
+		if result != nil {
+			return
+		}
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.ActivateDeals, &market.ActivateDealsParams{
+			DealIDs:      update.Deals,
+			SectorExpiry: oldSectors[i].Expiration,
+		}, big.Zero(), nil, exitcode.Ok)
+	}
+}
+
 func (h *actorHarness) proveCommitSectorAndConfirm(rt *mock.Runtime, precommit *miner.SectorPreCommitInfo, precommitEpoch abi.ChainEpoch,
 	params *miner.ProveCommitSectorParams, conf proveCommitConf) *miner.SectorOnChainInfo {
 	h.proveCommitSector(rt, precommit, precommitEpoch, params)
@@ -2009,7 +4264,7 @@ type poStConfig struct {
 
 func (h *actorHarness) submitWindowPoSt(rt *mock.Runtime, deadline *miner.DeadlineInfo, partitions []miner.PoStPartition, infos []*miner.SectorOnChainInfo, poStCfg *poStConfig) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
 	expectQueryNetworkInfo(rt, h)
 
@@ -2021,7 +4276,6 @@ func (h *actorHarness) submitWindowPoSt(rt *mock.Runtime, deadline *miner.Deadli
 		proofs[i].PoStProof = registeredPoStProof
 		proofs[i].ProofBytes = []byte(fmt.Sprintf("proof%d", i))
 	}
-	challengeRand := abi.SealRandomness([]byte{10, 11, 12, 13})
 
 	allSkipped := map[abi.SectorNumber]struct{}{}
 	for _, p := range partitions {
@@ -2031,48 +4285,23 @@ func (h *actorHarness) submitWindowPoSt(rt *mock.Runtime, deadline *miner.Deadli
 		})
 	}
 
-	// find the first non-faulty sector in poSt to replace all faulty sectors.
-	var goodInfo *miner.SectorOnChainInfo
-	for _, ci := range infos {
-		if _, contains := allSkipped[ci.SectorNumber]; !contains {
-			goodInfo = ci
-			break
+	// The proof is verified immediately only if it recovers faulty sectors, otherwise it is accepted optimistically.
+	dl := h.getDeadline(rt, deadline.Index)
+	recovering := false
+	for _, p := range partitions {
+		proven, err := dl.PostSubmissions.IsSet(p.Index)
+		require.NoError(h.t, err)
+		if proven {
+			continue
 		}
-	}
-
-	// goodInfo == nil indicates all the sectors have been skipped and should PoSt verification should not occur
-	if goodInfo != nil {
-		var buf bytes.Buffer
-		err := rt.Receiver().MarshalCBOR(&buf)
+		recovered, err := bitfield.SubtractBitField(h.getPartition(rt, dl, p.Index).Recoveries, p.Skipped)
 		require.NoError(h.t, err)
-
-		rt.ExpectGetRandomness(crypto.DomainSeparationTag_WindowedPoStChallengeSeed, deadline.Challenge, buf.Bytes(), abi.Randomness(challengeRand))
-
-		actorId, err := addr.IDFromAddress(h.receiver)
+		empty, err := recovered.IsEmpty()
 		require.NoError(h.t, err)
-
-		// if not all sectors are skipped
-		proofInfos := make([]abi.SectorInfo, len(infos))
-		for i, ci := range infos {
-			si := ci
-			_, contains := allSkipped[ci.SectorNumber]
-			if contains {
-				si = goodInfo
-			}
-			proofInfos[i] = abi.SectorInfo{
-				SealProof:    si.SealProof,
-				SectorNumber: si.SectorNumber,
-				SealedCID:    si.SealedCID,
-			}
-		}
-
-		vi := abi.WindowPoStVerifyInfo{
-			Randomness:        abi.PoStRandomness(challengeRand),
-			Proofs:            proofs,
-			ChallengedSectors: proofInfos,
-			Prover:            abi.ActorID(actorId),
-		}
-		rt.ExpectVerifyPoSt(vi, nil)
+		recovering = recovering || !empty
+	}
+	if recovering {
+		h.expectVerifyWindowPoSt(rt, deadline.Challenge, infos, allSkipped, proofs, nil)
 	}
 	if poStCfg != nil {
 		// expect power update
@@ -2105,6 +4334,107 @@ func (h *actorHarness) submitWindowPoSt(rt *mock.Runtime, deadline *miner.Deadli
 	rt.Verify()
 }
 
+// Expects verification of a window PoSt, substituting the first non-skipped sector for skipped ones.
+// Verification does not occur if all the sectors are skipped.
+func (h *actorHarness) expectVerifyWindowPoSt(rt *mock.Runtime, challenge abi.ChainEpoch, infos []*miner.SectorOnChainInfo,
+	skipped map[abi.SectorNumber]struct{}, proofs []abi.PoStProof, result error) {
+	challengeRand := abi.SealRandomness([]byte{10, 11, 12, 13})
+
+	// find the first non-faulty sector in poSt to replace all faulty sectors.
+	var goodInfo *miner.SectorOnChainInfo
+	for _, ci := range infos {
+		if _, contains := skipped[ci.SectorNumber]; !contains {
+			goodInfo = ci
+			break
+		}
+	}
+	if goodInfo == nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	err := rt.Receiver().MarshalCBOR(&buf)
+	require.NoError(h.t, err)
+
+	rt.ExpectGetRandomness(crypto.DomainSeparationTag_WindowedPoStChallengeSeed, challenge, buf.Bytes(), abi.Randomness(challengeRand))
+
+	actorId, err := addr.IDFromAddress(h.receiver)
+	require.NoError(h.t, err)
+
+	proofInfos := make([]abi.SectorInfo, len(infos))
+	for i, ci := range infos {
+		si := ci
+		if _, contains := skipped[ci.SectorNumber]; contains {
+			si = goodInfo
+		}
+		proofInfos[i] = abi.SectorInfo{
+			SealProof:    si.SealProof,
+			SectorNumber: si.SectorNumber,
+			SealedCID:    si.SealedCID,
+		}
+	}
+
+	vi := abi.WindowPoStVerifyInfo{
+		Randomness:        abi.PoStRandomness(challengeRand),
+		Proofs:            proofs,
+		ChallengedSectors: proofInfos,
+		Prover:            abi.ActorID(actorId),
+	}
+	rt.ExpectVerifyPoSt(vi, result)
+}
+
+type poStDisputeResult struct {
+	expectedPowerDelta miner.PowerPair
+	expectedPenalty    abi.TokenAmount
+	expectedReward     abi.TokenAmount
+}
+
+// Disputes an optimistically accepted proof of the sectors in infos, none of which were skipped.
+// The dispute is expected to succeed if a result is provided, and otherwise to fail because the proof is valid.
+func (h *actorHarness) disputeWindowPoSt(rt *mock.Runtime, deadline *miner.DeadlineInfo, proofIndex uint64, infos []*miner.SectorOnChainInfo, expectSuccess *poStDisputeResult) {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerType(builtin.CallerTypesSignable...)
+
+	expectQueryNetworkInfo(rt, h)
+
+	registeredPoStProof, err := h.sealProofType.RegisteredWindowPoStProof()
+	require.NoError(h.t, err)
+	proofs := []abi.PoStProof{{PoStProof: registeredPoStProof, ProofBytes: []byte("proof0")}}
+
+	params := miner.DisputeWindowedPoStParams{
+		Deadline:  deadline.Index,
+		PoStIndex: proofIndex,
+	}
+	if expectSuccess == nil {
+		h.expectVerifyWindowPoSt(rt, deadline.Challenge, infos, nil, proofs, nil)
+		rt.ExpectAbortConstainsMessage(exitcode.ErrIllegalArgument, "failed to dispute valid post", func() {
+			rt.Call(h.a.DisputeWindowedPoSt, &params)
+		})
+		rt.Verify()
+		return
+	}
+
+	h.expectVerifyWindowPoSt(rt, deadline.Challenge, infos, nil, proofs, fmt.Errorf("invalid post"))
+	if !expectSuccess.expectedReward.IsZero() {
+		rt.ExpectSend(h.worker, builtin.MethodSend, nil, expectSuccess.expectedReward, nil, exitcode.Ok)
+	}
+	if !expectSuccess.expectedPowerDelta.IsZero() {
+		claim := &power.UpdateClaimedPowerParams{
+			RawByteDelta:         expectSuccess.expectedPowerDelta.Raw,
+			QualityAdjustedDelta: expectSuccess.expectedPowerDelta.QA,
+		}
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, claim, abi.NewTokenAmount(0),
+			nil, exitcode.Ok)
+	}
+	toBurn := big.Sub(expectSuccess.expectedPenalty, expectSuccess.expectedReward)
+	if !toBurn.IsZero() {
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, toBurn, nil, exitcode.Ok)
+	}
+
+	rt.Call(h.a.DisputeWindowedPoSt, &params)
+	rt.Verify()
+}
+
 func (h *actorHarness) computePartitions(rt *mock.Runtime, deadlines *miner.Deadlines, deadlineIdx uint64) ([]*miner.SectorOnChainInfo, []uint64) {
 	panic("todo")
 	// TODO minerstate
@@ -2134,7 +4464,7 @@ func (h *actorHarness) computePartitions(rt *mock.Runtime, deadlines *miner.Dead
 
 func (h *actorHarness) declareFaults(rt *mock.Runtime, fee abi.TokenAmount, faultSectorInfos ...*miner.SectorOnChainInfo) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
 	ss, err := faultSectorInfos[0].SealProof.SectorSize()
 	require.NoError(h.t, err)
@@ -2188,7 +4518,7 @@ func (h *actorHarness) declareFaults(rt *mock.Runtime, fee abi.TokenAmount, faul
 
 func (h *actorHarness) declareRecoveries(rt *mock.Runtime, deadlineIdx uint64, recoverySectors *bitfield.BitField) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
 	expectQueryNetworkInfo(rt, h)
 
@@ -2202,6 +4532,35 @@ func (h *actorHarness) declareRecoveries(rt *mock.Runtime, deadlineIdx uint64, r
 	rt.Verify()
 }
 
+// Declares faults allowing partial success, expecting the loss of power for the newly faulty sectors.
+// Returns the indices of the declarations applied.
+func (h *actorHarness) declareFaultsPartial(rt *mock.Runtime, newFaults []*miner.SectorOnChainInfo, decls ...miner.FaultDeclaration) *bitfield.BitField {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+
+	if len(newFaults) > 0 {
+		rawDelta, qaDelta := powerForSectors(h.sectorSize, newFaults)
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &power.UpdateClaimedPowerParams{
+			RawByteDelta:         rawDelta.Neg(),
+			QualityAdjustedDelta: qaDelta.Neg(),
+		}, big.Zero(), nil, exitcode.Ok)
+	}
+
+	ret := rt.Call(h.a.DeclareFaultsPartial, &miner.DeclareFaultsParams{Faults: decls}).(*miner.DeclarationsReturn)
+	rt.Verify()
+	return ret.Applied
+}
+
+// Declares recoveries allowing partial success, returning the indices of the declarations applied.
+func (h *actorHarness) declareRecoveriesPartial(rt *mock.Runtime, decls ...miner.RecoveryDeclaration) *bitfield.BitField {
+	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
+
+	ret := rt.Call(h.a.DeclareFaultsRecoveredPartial, &miner.DeclareFaultsRecoveredParams{Recoveries: decls}).(*miner.DeclarationsReturn)
+	rt.Verify()
+	return ret.Applied
+}
+
 func (h *actorHarness) advanceProvingPeriodWithoutFaults(rt *mock.Runtime) {
 
 	// Iterate deadlines in the proving period, setting epoch to the first in each deadline.
@@ -2245,7 +4604,7 @@ func (h *actorHarness) advanceProvingPeriodWithoutFaults(rt *mock.Runtime) {
 
 func (h *actorHarness) extendSector(rt *mock.Runtime, sector *miner.SectorOnChainInfo, extension abi.ChainEpoch, params *miner.ExtendSectorExpirationParams) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
 	newSector := *sector
 	newSector.Expiration += extension
@@ -2267,41 +4626,61 @@ func (h *actorHarness) extendSector(rt *mock.Runtime, sector *miner.SectorOnChai
 
 func (h *actorHarness) terminateSectors(rt *mock.Runtime, sectors *abi.BitField, expectedFee abi.TokenAmount) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs()...)
 
+	st := getState(rt)
 	dealIDs := []abi.DealID{}
 	sectorInfos := []*miner.SectorOnChainInfo{}
+	pledgeDelta := big.Zero()
+	byPartition := map[[2]uint64][]uint64{}
+	var partitionKeys [][2]uint64
 	err := sectors.ForEach(func(secNum uint64) error {
 		sector := h.getSector(rt, abi.SectorNumber(secNum))
 		dealIDs = append(dealIDs, sector.DealIDs...)
-
 		sectorInfos = append(sectorInfos, sector)
+		pledgeDelta = big.Sub(pledgeDelta, sector.InitialPledge)
+
+		dlIdx, pIdx, err := st.FindSector(rt.AdtStore(), sector.SectorNumber)
+		require.NoError(h.t, err)
+		key := [2]uint64{dlIdx, pIdx}
+		if _, ok := byPartition[key]; !ok {
+			partitionKeys = append(partitionKeys, key)
+		}
+		byPartition[key] = append(byPartition[key], secNum)
 		return nil
 	})
 	require.NoError(h.t, err)
 
-	{
-		expectQueryNetworkInfo(rt, h)
+	params := &miner.TerminateSectorsParams{}
+	for _, key := range partitionKeys {
+		params.Terminations = append(params.Terminations, miner.TerminationDeclaration{
+			Deadline:  key[0],
+			Partition: key[1],
+			Sectors:   bf(byPartition[key]...),
+		})
 	}
 
-	{
-		// TODO minerstate
-		//rawPower, qaPower := miner.PowerForSectors(h.sectorSize, sectorInfos)
-		//rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &power.UpdateClaimedPowerParams{
-		//	RawByteDelta:         rawPower.Neg(),
-		//	QualityAdjustedDelta: qaPower.Neg(),
-		//}, abi.NewTokenAmount(0), nil, exitcode.Ok)
-	}
+	expectQueryNetworkInfo(rt, h)
 	if big.Zero().LessThan(expectedFee) {
 		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, expectedFee, nil, exitcode.Ok)
-		pledgeDelta := expectedFee.Neg()
-		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+	}
+	rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+	if len(dealIDs) > 0 {
+		rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.OnMinerSectorsTerminate, &market.OnMinerSectorsTerminateParams{
+			Epoch:   rt.Epoch(),
+			DealIDs: dealIDs,
+		}, big.Zero(), nil, exitcode.Ok)
 	}
 
-	// TODO minerstate
-	//params := &miner.TerminateSectorsParams{Sectors: sectors}
-	//rt.Call(h.a.TerminateSectors, params)
-	//rt.Verify()
+	powerDelta := h.powerPairForSectors(sectorInfos).Neg()
+	rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &power.UpdateClaimedPowerParams{
+		RawByteDelta:         powerDelta.Raw,
+		QualityAdjustedDelta: powerDelta.QA,
+	}, big.Zero(), nil, exitcode.Ok)
+
+	ret := rt.Call(h.a.TerminateSectors, params).(*miner.TerminateSectorsReturn)
+	assert.True(h.t, ret.Done)
+	rt.Verify()
 }
 
 func (h *actorHarness) reportConsensusFault(rt *mock.Runtime, from addr.Address, params *miner.ReportConsensusFaultParams, dealIDs []abi.DealID) {
@@ -2334,9 +4713,84 @@ func (h *actorHarness) reportConsensusFault(rt *mock.Runtime, from addr.Address,
 	rt.Verify()
 }
 
+func (h *actorHarness) exitMiner(rt *mock.Runtime) {
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.owner)
+	rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.EnrollCronEvent,
+		makeExitCronEventParams(h.t, rt.Epoch()+1), big.Zero(), nil, exitcode.Ok)
+
+	rt.Call(h.a.ExitMiner, nil)
+	rt.Verify()
+}
+
+type exitCronConfig struct {
+	terminatedSectors  []*miner.SectorOnChainInfo // Sectors expected to be terminated and have their fees paid
+	vestedFunds        abi.TokenAmount
+	repaidDebt         abi.TokenAmount // Fee debt burnt from the miner's funds as the exit completes
+	expectedEnrollment abi.ChainEpoch  // Epoch at which the exit is rescheduled, or zero if it is expected to complete
+}
+
+func (h *actorHarness) onMinerExitCron(rt *mock.Runtime, config *exitCronConfig) {
+	rt.SetCaller(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID)
+	rt.ExpectValidateCallerAddr(builtin.StoragePowerActorAddr)
+
+	powerDelta := h.powerPairForSectors(config.terminatedSectors).Neg()
+	if !powerDelta.IsZero() {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdateClaimedPower, &power.UpdateClaimedPowerParams{
+			RawByteDelta:         powerDelta.Raw,
+			QualityAdjustedDelta: powerDelta.QA,
+		}, big.Zero(), nil, exitcode.Ok)
+	}
+	if !config.vestedFunds.Nil() && !config.vestedFunds.IsZero() {
+		pledgeDelta := config.vestedFunds.Neg()
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+	}
+
+	expectQueryNetworkInfo(rt, h)
+	if len(config.terminatedSectors) > 0 {
+		penalty := big.Zero()
+		pledgeDelta := big.Zero()
+		dealIDs := []abi.DealID{}
+		for _, sector := range config.terminatedSectors {
+			sectorPower := miner.QAPowerForSector(h.sectorSize, sector)
+			fee := miner.PledgePenaltyForTermination(sector.InitialPledge, rt.Epoch()-sector.Activation, h.epochReward, h.networkQAPower, sectorPower)
+			penalty = big.Add(penalty, fee)
+			pledgeDelta = big.Sub(pledgeDelta, sector.InitialPledge)
+			dealIDs = append(dealIDs, sector.DealIDs...)
+		}
+		if !penalty.IsZero() {
+			rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, penalty, nil, exitcode.Ok)
+		}
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.UpdatePledgeTotal, &pledgeDelta, big.Zero(), nil, exitcode.Ok)
+		if len(dealIDs) > 0 {
+			rt.ExpectSend(builtin.StorageMarketActorAddr, builtin.MethodsMarket.OnMinerSectorsTerminate, &market.OnMinerSectorsTerminateParams{
+				Epoch:   rt.Epoch(),
+				DealIDs: dealIDs,
+			}, big.Zero(), nil, exitcode.Ok)
+		}
+	}
+
+	if !config.repaidDebt.Nil() && !config.repaidDebt.IsZero() {
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, config.repaidDebt, nil, exitcode.Ok)
+	}
+
+	if config.expectedEnrollment != 0 {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.EnrollCronEvent,
+			makeExitCronEventParams(h.t, config.expectedEnrollment), big.Zero(), nil, exitcode.Ok)
+	} else {
+		rt.ExpectSend(builtin.StoragePowerActorAddr, builtin.MethodsPower.OnMinerExit, nil, big.Zero(), nil, exitcode.Ok)
+		rt.ExpectDeleteActor(h.owner)
+	}
+
+	rt.Call(h.a.OnDeferredCronEvent, &miner.CronEventPayload{
+		EventType: miner.CronEventExitMiner,
+	})
+	rt.Verify()
+}
+
 func (h *actorHarness) addLockedFund(rt *mock.Runtime, amt abi.TokenAmount) {
 	rt.SetCaller(h.worker, builtin.AccountActorCodeID)
-	rt.ExpectValidateCallerAddr(h.worker, h.owner, builtin.RewardActorAddr)
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs(h.owner, builtin.RewardActorAddr)...)
 	// expect pledge update
 	rt.ExpectSend(
 		builtin.StoragePowerActorAddr,
@@ -2438,6 +4892,43 @@ func (h *actorHarness) withdrawFunds(rt *mock.Runtime, amount abi.TokenAmount) {
 	rt.Verify()
 }
 
+// Withdraws funds on behalf of a beneficiary other than the owner, expecting the amount sent to the beneficiary.
+func (h *actorHarness) withdrawFundsToBeneficiary(rt *mock.Runtime, caller addr.Address, requested, expected abi.TokenAmount) {
+	info := h.getInfo(rt)
+	rt.SetCaller(caller, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(info.Owner, info.Beneficiary)
+
+	rt.ExpectSend(info.Beneficiary, builtin.MethodSend, nil, expected, nil, exitcode.Ok)
+
+	rt.Call(h.a.WithdrawBalance, &miner.WithdrawBalanceParams{
+		AmountRequested: requested,
+	})
+	rt.Verify()
+}
+
+// Repays fee debt with funds sent by the owner, expecting an amount to be burnt.
+func (h *actorHarness) repayDebt(rt *mock.Runtime, value, expectedBurn abi.TokenAmount) {
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.SetReceived(value)
+	rt.SetBalance(big.Add(rt.Balance(), value))
+	rt.ExpectValidateCallerAddr(h.workerOrControlAddrs(h.owner)...)
+
+	if !expectedBurn.IsZero() {
+		rt.ExpectSend(builtin.BurntFundsActorAddr, builtin.MethodSend, nil, expectedBurn, nil, exitcode.Ok)
+	}
+
+	rt.Call(h.a.RepayDebt, nil)
+	rt.Verify()
+	rt.SetReceived(big.Zero())
+}
+
+func (h *actorHarness) compactVestingFunds(rt *mock.Runtime, quantization abi.ChainEpoch) {
+	rt.SetCaller(h.owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(h.owner)
+	rt.Call(h.a.CompactVestingFunds, &miner.CompactVestingFundsParams{Quantization: quantization})
+	rt.Verify()
+}
+
 func (h *actorHarness) declaredFaultPenalty(sectors []*miner.SectorOnChainInfo) abi.TokenAmount {
 	_, qa := powerForSectors(h.sectorSize, sectors)
 	return miner.PledgePenaltyForDeclaredFault(h.epochReward, h.networkQAPower, qa)
@@ -2502,10 +4993,14 @@ func advanceToEpochWithCron(rt *mock.Runtime, h *actorHarness, e abi.ChainEpoch)
 //
 
 func builderForHarness(actor *actorHarness) *mock.RuntimeBuilder {
-	return mock.NewBuilder(context.Background(), actor.receiver).
+	rb := mock.NewBuilder(context.Background(), actor.receiver).
 		WithActorType(actor.owner, builtin.AccountActorCodeID).
 		WithActorType(actor.worker, builtin.AccountActorCodeID).
 		WithHasher(fixedHasher(uint64(actor.periodOffset)))
+	for _, ca := range actor.controlAddrs {
+		rb = rb.WithActorType(ca, builtin.AccountActorCodeID)
+	}
+	return rb
 }
 
 func getState(rt *mock.Runtime) *miner.State {
@@ -2525,6 +5020,17 @@ func makeDeadlineCronEventParams(t testing.TB, epoch abi.ChainEpoch) *power.Enro
 	}
 }
 
+func makeExitCronEventParams(t testing.TB, epoch abi.ChainEpoch) *power.EnrollCronEventParams {
+	eventPayload := miner.CronEventPayload{EventType: miner.CronEventExitMiner}
+	buf := bytes.Buffer{}
+	err := eventPayload.MarshalCBOR(&buf)
+	require.NoError(t, err)
+	return &power.EnrollCronEventParams{
+		EventEpoch: epoch,
+		Payload:    buf.Bytes(),
+	}
+}
+
 func makeProveCommit(sectorNo abi.SectorNumber) *miner.ProveCommitSectorParams {
 	return &miner.ProveCommitSectorParams{
 		SectorNumber: sectorNo,
@@ -53,6 +53,37 @@ func PledgePenaltyForUndeclaredFault(epochReward abi.TokenAmount, networkQAPower
 		UndeclaredFaultFactorDenom)
 }
 
+// Flat penalty for an invalid window PoSt that is successfully disputed, in addition to the undeclared fault
+// penalty for the power it claimed to prove. Part of this penalty pays the disputer's reward.
+var BasePenaltyForDisputedWindowPoSt = big.Mul(big.NewInt(20), abi.TokenPrecision)
+
+// Flat reward for successfully disputing an invalid window PoSt.
+// This must exceed the cost of the dispute message, but not the base penalty from which it is paid.
+var BaseRewardForDisputedWindowPoSt = big.Mul(big.NewInt(4), abi.TokenPrecision)
+
+// This is the penalty for an invalid window PoSt, charged when it is successfully disputed.
+// InvalidPoStPenalty(t) = SP(t) + BasePenaltyForDisputedWindowPoSt
+func PledgePenaltyForInvalidWindowPoSt(epochReward abi.TokenAmount, networkQAPower abi.StoragePower, qaSectorPower abi.StoragePower) abi.TokenAmount {
+	return big.Add(PledgePenaltyForUndeclaredFault(epochReward, networkQAPower, qaSectorPower), BasePenaltyForDisputedWindowPoSt)
+}
+
+// The reward paid to the disputer of an invalid window PoSt.
+func RewardForDisputedWindowPoSt() abi.TokenAmount {
+	return BaseRewardForDisputedWindowPoSt
+}
+
+// Fraction of a pre-commit deposit burnt when the pre-commitment is withdrawn before it is proven or expires.
+// The remainder of the deposit is returned to the miner's available balance.
+var PreCommitWithdrawalFactorNum = big.NewInt(1)
+var PreCommitWithdrawalFactorDenom = big.NewInt(4)
+
+// This is the penalty for withdrawing a pre-commitment, paid from its deposit.
+// It is less than the deposit burnt when the pre-commitment expires, so that a miner that cannot prove a
+// sector has reason to withdraw it promptly, but enough to discourage speculative pre-commitment.
+func PreCommitWithdrawalPenalty(preCommitDeposit abi.TokenAmount) abi.TokenAmount {
+	return big.Div(big.Mul(preCommitDeposit, PreCommitWithdrawalFactorNum), PreCommitWithdrawalFactorDenom)
+}
+
 // Penalty to locked pledge collateral for the termination of a sector before scheduled expiry.
 // SectorAge is the time between the sector's activation and termination.
 func PledgePenaltyForTermination(initialPledge abi.TokenAmount, sectorAge abi.ChainEpoch, epochTargetReward abi.TokenAmount, networkQAPower, qaSectorPower abi.StoragePower) abi.TokenAmount {
@@ -290,6 +290,61 @@ func (p *Partition) ReplaceSectors(store adt.Store, oldSectors, newSectors []*Se
 	return powerDelta, pledgeDelta, nil
 }
 
+// Replaces a number of faulty "old" sectors with new ones of the same numbers.
+// The new sectors remain faulty, and recovering if the old ones were. They are scheduled to expire early at the
+// fault expiration epoch, unless they are due to expire on-time before then.
+// Returns the delta to faulty power, which is also the delta to live power.
+func (p *Partition) ReplaceFaultySectors(store adt.Store, oldSectors, newSectors []*SectorOnChainInfo,
+	faultExpiration abi.ChainEpoch, ssize abi.SectorSize, quant QuantSpec) (PowerPair, error) {
+	oldSnos := make([]uint64, len(oldSectors))
+	for i, sector := range oldSectors {
+		oldSnos[i] = uint64(sector.SectorNumber)
+	}
+	allFaulty, err := abi.BitFieldContainsAll(p.Faults, bitfield.NewFromSet(oldSnos))
+	if err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to check for faulty sectors: %w", err)
+	} else if !allFaulty {
+		return NewPowerPairZero(), xerrors.Errorf("refusing to replace non-faulty sectors in %v (faults: %v)", oldSnos, p.Faults)
+	}
+
+	expirations, err := LoadExpirationQueue(store, p.ExpirationsEpochs, quant)
+	if err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to load sector expirations: %w", err)
+	}
+	removed, oldRecoveringPower, err := expirations.RemoveSectors(oldSectors, p.Faults, p.Recoveries, ssize)
+	if err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to remove replaced sectors: %w", err)
+	}
+	if _, _, _, err = expirations.AddActiveSectors(newSectors, ssize); err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to add replacement sectors: %w", err)
+	}
+	newPower, err := expirations.RescheduleAsFaults(faultExpiration, newSectors, ssize)
+	if err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to reschedule replacement sectors as faults: %w", err)
+	}
+	if p.ExpirationsEpochs, err = expirations.Root(); err != nil {
+		return NewPowerPairZero(), xerrors.Errorf("failed to save sector expirations: %w", err)
+	}
+
+	newRecoveringPower := NewPowerPairZero()
+	for _, sector := range newSectors {
+		recovering, err := p.Recoveries.IsSet(uint64(sector.SectorNumber))
+		if err != nil {
+			return NewPowerPairZero(), xerrors.Errorf("failed to check recovery of sector %d: %w", sector.SectorNumber, err)
+		}
+		if recovering {
+			newRecoveringPower = newRecoveringPower.Add(PowerForSector(ssize, sector))
+		}
+	}
+
+	// No change to sectors, faults, recoveries, or terminations.
+	powerDelta := newPower.Sub(removed.FaultyPower)
+	p.LivePower = p.LivePower.Add(powerDelta)
+	p.FaultyPower = p.FaultyPower.Add(powerDelta)
+	p.RecoveringPower = p.RecoveringPower.Add(newRecoveringPower.Sub(oldRecoveringPower))
+	return powerDelta, nil
+}
+
 // Record the epoch of any sectors expiring early, for termination fee calculation later.
 func (p *Partition) recordEarlyTermination(store adt.Store, epoch abi.ChainEpoch, sectors *bitfield.BitField) error {
 	etQueue, err := LoadBitfieldQueue(store, p.EarlyTerminated, NoQuantization)
@@ -46,12 +46,35 @@ const AddressedPartitionsMax = 200
 // The maximum number of sector infos that may be required to be loaded in a single invocation.
 const AddressedSectorsMax = 10_000
 
+// The maximum number of runs of set bits in a sector number mask.
+// This bounds the growth of the allocated sector numbers bitfield from a single invocation.
+const SectorNumberMaskRunsMax = 1000
+
 // The maximum number of partitions that may be required to be loaded in a single invocation,
 // when all the sector infos for the partitions will be loaded.
 func loadPartitionsSectorsMax(partitionSectorCount uint64) uint64 {
 	return min64(AddressedSectorsMax/partitionSectorCount, AddressedPartitionsMax)
 }
 
+// The maximum number of sectors that may be pre-committed in a single batch.
+const PreCommitSectorBatchMaxSize = 256
+
+// The maximum number of sector pre-commitments that may be proven with a single aggregate proof.
+const MaxAggregatedSectors = 819
+
+// The minimum number of sector pre-commitments that may be proven with a single aggregate proof.
+// Below this, it is cheaper to prove sectors individually.
+const MinAggregatedSectors = 4
+
+// The maximum number of bytes in an aggregate proof.
+const MaxAggregateProofSize = 81960
+
+// The maximum number of sectors that may be updated with a single ProveReplicaUpdates message.
+const ProveReplicaUpdatesMaxSize = 25
+
+// The maximum number of bytes in a replica update proof.
+const MaxReplicaUpdateProofSize = 4096
+
 // The maximum number of new sectors that may be staged by a miner during a single proving period.
 const NewSectorsPerPeriodMax = 128 << 10
 
@@ -99,6 +122,12 @@ const WPoStChallengeLookback = abi.ChainEpoch(20)
 // faults after learning the challenge value.
 const FaultDeclarationCutoff = WPoStChallengeLookback + 50
 
+// The period after a deadline's challenge window closes during which a PoSt accepted optimistically for that
+// deadline may be disputed.
+// The deadline's partitions may not be compacted during this period, since a successful dispute records faults
+// against them.
+var WPoStDisputeWindow = 2 * ChainFinality
+
 // The maximum age of a fault before the sector is terminated.
 var FaultMaxAge = WPoStProvingPeriod * 14
 
@@ -108,6 +137,9 @@ var FaultMaxAge = WPoStProvingPeriod * 14
 // key or allowing the owner account to submit PoSts while a key change is pending.
 const WorkerKeyChangeDelay = ChainFinality
 
+// Maximum number of control addresses a miner may register.
+const MaxControlAddresses = 10
+
 // Minimum number of epochs past the current epoch a sector may be set to expire.
 const MinSectorExpiration = 180 * builtin.EpochsInDay
 
@@ -155,7 +187,7 @@ func QAPowerForWeight(size abi.SectorSize, duration abi.ChainEpoch, dealWeight,
 
 // Returns the quality-adjusted power for a sector.
 func QAPowerForSector(size abi.SectorSize, sector *SectorOnChainInfo) abi.StoragePower {
-	duration := sector.Expiration - sector.Activation
+	duration := sector.Expiration - sector.PowerBaseEpoch
 	return QAPowerForWeight(size, duration, sector.DealWeight, sector.VerifiedDealWeight)
 }
 
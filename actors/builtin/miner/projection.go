@@ -0,0 +1,231 @@
+package miner
+
+import (
+	"sort"
+
+	"github.com/filecoin-project/go-bitfield"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// The projected expiration of a miner's sectors at some epoch, aggregated over all deadlines and partitions.
+type ExpirationProjection struct {
+	Epoch          abi.ChainEpoch  // A quantized epoch, the last epoch of the deadline processing the expirations
+	OnTimeSectors  *abi.BitField   // Sectors expiring at the end of their committed life
+	EarlySectors   *abi.BitField   // Sectors terminating early due to being faulty for too long
+	RenewedSectors *abi.BitField   // Sectors with deal weight renewed until their extended expiration
+	PledgeReleased abi.TokenAmount // Initial pledge of the on-time sectors
+	ActivePower    PowerPair       // Active power lost, including power lost by renewed sectors
+	FaultyPower    PowerPair       // Faulty power lost
+}
+
+func newExpirationProjection(epoch abi.ChainEpoch) *ExpirationProjection {
+	return &ExpirationProjection{
+		Epoch:          epoch,
+		OnTimeSectors:  abi.NewBitField(),
+		EarlySectors:   abi.NewBitField(),
+		RenewedSectors: abi.NewBitField(),
+		PledgeReleased: big.Zero(),
+		ActivePower:    NewPowerPairZero(),
+		FaultyPower:    NewPowerPairZero(),
+	}
+}
+
+// Projects the expiration of sectors at epochs in the range [from, until], assuming no further faults,
+// recoveries, terminations or extensions.
+// Sectors with an extended expiration are projected to be renewed, rather than expire, at their current
+// expiration. Those renewed while faulty are projected to expire early at their new fault expiration.
+// The state is not modified.
+// Returns one projection for each epoch at which any sectors expire or are renewed, in order of increasing epoch.
+func (st *State) ProjectExpirations(store adt.Store, from, until abi.ChainEpoch) ([]*ExpirationProjection, error) {
+	info, err := st.GetInfo(store)
+	if err != nil {
+		return nil, err
+	}
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		return nil, err
+	}
+	quant := st.QuantEndOfDeadline()
+
+	byEpoch := map[abi.ChainEpoch]*ExpirationProjection{}
+	projectionAt := func(epoch abi.ChainEpoch) *ExpirationProjection {
+		proj, ok := byEpoch[epoch]
+		if !ok {
+			proj = newExpirationProjection(epoch)
+			byEpoch[epoch] = proj
+		}
+		return proj
+	}
+	inRange := func(epoch abi.ChainEpoch) bool {
+		return epoch >= from && epoch <= until
+	}
+
+	if err = deadlines.ForEach(store, func(dlIdx uint64, dl *Deadline) error {
+		// Extended sectors renewed after the end of the range also expire after it, so can be ignored.
+		renewals, err := LoadBitfieldQueue(store, dl.Renewals, quant)
+		if err != nil {
+			return xerrors.Errorf("failed to load renewals for deadline %d: %w", dlIdx, err)
+		}
+		var dueRenewals []*abi.BitField
+		if err = renewals.ForEach(func(epoch abi.ChainEpoch, bf *abi.BitField) error {
+			if epoch <= until {
+				dueRenewals = append(dueRenewals, bf)
+			}
+			return nil
+		}); err != nil {
+			return xerrors.Errorf("failed to iterate renewals for deadline %d: %w", dlIdx, err)
+		}
+		extended, err := bitfield.MultiMerge(dueRenewals...)
+		if err != nil {
+			return err
+		}
+
+		partitions, err := dl.PartitionsArray(store)
+		if err != nil {
+			return err
+		}
+		var partition Partition
+		return partitions.ForEach(&partition, func(partIdx int64) error {
+			queue, err := LoadExpirationQueue(store, partition.ExpirationsEpochs, quant)
+			if err != nil {
+				return xerrors.Errorf("failed to load expiration queue for deadline %d partition %d: %w", dlIdx, partIdx, err)
+			}
+			// Faulty sectors are renewed only if they expire on time before their fault expiration.
+			onTime := abi.NewBitField()
+			if err = queue.ForEachUntil(until, func(epoch abi.ChainEpoch, es *ExpirationSet) error {
+				if onTime, err = bitfield.MergeBitFields(onTime, es.OnTimeSectors); err != nil {
+					return err
+				}
+				if epoch < from {
+					return nil
+				}
+				proj := projectionAt(epoch)
+				if proj.OnTimeSectors, err = bitfield.MergeBitFields(proj.OnTimeSectors, es.OnTimeSectors); err != nil {
+					return err
+				}
+				if proj.EarlySectors, err = bitfield.MergeBitFields(proj.EarlySectors, es.EarlySectors); err != nil {
+					return err
+				}
+				proj.PledgeReleased = big.Add(proj.PledgeReleased, es.OnTimePledge)
+				proj.ActivePower = proj.ActivePower.Add(es.ActivePower)
+				proj.FaultyPower = proj.FaultyPower.Add(es.FaultyPower)
+				return nil
+			}); err != nil {
+				return xerrors.Errorf("failed to project expirations for deadline %d partition %d: %w", dlIdx, partIdx, err)
+			}
+
+			active, err := partition.ActiveSectors()
+			if err != nil {
+				return err
+			}
+			activeExtended, err := bitfield.IntersectBitField(active, extended)
+			if err != nil {
+				return err
+			}
+			faultyExtended, err := bitfield.IntersectBitField(partition.Faults, extended)
+			if err != nil {
+				return err
+			}
+			if faultyExtended, err = bitfield.IntersectBitField(faultyExtended, onTime); err != nil {
+				return err
+			}
+			activeInfos, err := st.LoadSectorInfos(store, activeExtended)
+			if err != nil {
+				return xerrors.Errorf("failed to load extended sectors for deadline %d partition %d: %w", dlIdx, partIdx, err)
+			}
+			faultyInfos, err := st.LoadSectorInfos(store, faultyExtended)
+			if err != nil {
+				return xerrors.Errorf("failed to load faulty extended sectors for deadline %d partition %d: %w", dlIdx, partIdx, err)
+			}
+			for _, sector := range activeInfos {
+				sno := uint64(sector.SectorNumber)
+				renewed := renewedSector(sector)
+
+				// At the current expiration, the sector remains with reduced power and the same pledge.
+				if epoch := quant.QuantizeUp(sector.Expiration); inRange(epoch) {
+					proj := projectionAt(epoch)
+					proj.OnTimeSectors.Unset(sno)
+					proj.RenewedSectors.Set(sno)
+					proj.PledgeReleased = big.Sub(proj.PledgeReleased, sector.InitialPledge)
+					proj.ActivePower = proj.ActivePower.Sub(PowerForSector(info.SectorSize, renewed))
+				}
+				// At the extended expiration, the renewed sector expires.
+				if epoch := quant.QuantizeUp(renewed.Expiration); inRange(epoch) {
+					proj := projectionAt(epoch)
+					proj.OnTimeSectors.Set(sno)
+					proj.PledgeReleased = big.Add(proj.PledgeReleased, renewed.InitialPledge)
+					proj.ActivePower = proj.ActivePower.Add(PowerForSector(info.SectorSize, renewed))
+				}
+			}
+			for _, sector := range faultyInfos {
+				sno := uint64(sector.SectorNumber)
+				renewed := renewedSector(sector)
+				renewalEpoch := quant.QuantizeUp(sector.Expiration)
+
+				// At the current expiration, the sector remains faulty with reduced power and the same pledge.
+				if inRange(renewalEpoch) {
+					proj := projectionAt(renewalEpoch)
+					proj.OnTimeSectors.Unset(sno)
+					proj.RenewedSectors.Set(sno)
+					proj.PledgeReleased = big.Sub(proj.PledgeReleased, sector.InitialPledge)
+					proj.FaultyPower = proj.FaultyPower.Sub(PowerForSector(info.SectorSize, renewed))
+				}
+				// The renewed sector expires early at its fault expiration, unless it expires on time before then.
+				faultExpiration := quant.QuantizeUp(renewalEpoch + FaultMaxAge)
+				if epoch := quant.QuantizeUp(renewed.Expiration); epoch > faultExpiration {
+					if inRange(faultExpiration) {
+						proj := projectionAt(faultExpiration)
+						proj.EarlySectors.Set(sno)
+						proj.FaultyPower = proj.FaultyPower.Add(PowerForSector(info.SectorSize, renewed))
+					}
+				} else if inRange(epoch) {
+					proj := projectionAt(epoch)
+					proj.OnTimeSectors.Set(sno)
+					proj.PledgeReleased = big.Add(proj.PledgeReleased, renewed.InitialPledge)
+					proj.FaultyPower = proj.FaultyPower.Add(PowerForSector(info.SectorSize, renewed))
+				}
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	projections := make([]*ExpirationProjection, 0, len(byEpoch))
+	for _, proj := range byEpoch { //nolint:nomaprange
+		projections = append(projections, proj)
+	}
+	sort.Slice(projections, func(i, j int) bool {
+		return projections[i].Epoch < projections[j].Epoch
+	})
+	return projections, nil
+}
+
+// Sums the projected expirations at epochs in the range [from, until].
+// The total's epoch is the end of the range.
+func (st *State) ProjectExpirationTotal(store adt.Store, from, until abi.ChainEpoch) (*ExpirationProjection, error) {
+	projections, err := st.ProjectExpirations(store, from, until)
+	if err != nil {
+		return nil, err
+	}
+	total := newExpirationProjection(until)
+	for _, proj := range projections {
+		if total.OnTimeSectors, err = bitfield.MergeBitFields(total.OnTimeSectors, proj.OnTimeSectors); err != nil {
+			return nil, err
+		}
+		if total.EarlySectors, err = bitfield.MergeBitFields(total.EarlySectors, proj.EarlySectors); err != nil {
+			return nil, err
+		}
+		if total.RenewedSectors, err = bitfield.MergeBitFields(total.RenewedSectors, proj.RenewedSectors); err != nil {
+			return nil, err
+		}
+		total.PledgeReleased = big.Add(total.PledgeReleased, proj.PledgeReleased)
+		total.ActivePower = total.ActivePower.Add(proj.ActivePower)
+		total.FaultyPower = total.FaultyPower.Add(proj.FaultyPower)
+	}
+	return total, nil
+}
@@ -0,0 +1,834 @@
+package miner
+
+import (
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type DealSummary struct {
+	SectorStart      abi.ChainEpoch
+	SectorExpiration abi.ChainEpoch
+}
+
+type StateSummary struct {
+	LivePower     PowerPair
+	ActivePower   PowerPair
+	FaultyPower   PowerPair
+	SealProofType abi.RegisteredSealProof
+	Deals         map[abi.DealID]DealSummary
+}
+
+// Checks internal invariants of miner state.
+func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+	sectorSize := abi.SectorSize(0)
+	minerSummary := &StateSummary{
+		LivePower:   NewPowerPairZero(),
+		ActivePower: NewPowerPairZero(),
+		FaultyPower: NewPowerPairZero(),
+		Deals:       map[abi.DealID]DealSummary{},
+	}
+
+	// Load data from linked structures.
+	if info, err := st.GetInfo(store); err != nil {
+		acc.Addf("error loading miner info: %v", err)
+		// Stop here, it's too hard to make other useful checks.
+		return minerSummary, acc
+	} else {
+		minerSummary.SealProofType = info.SealProofType
+		sectorSize = info.SectorSize
+		CheckMinerInfo(info, acc)
+	}
+
+	CheckMinerBalances(st, store, balance, acc)
+
+	allSectors := map[abi.SectorNumber]*SectorOnChainInfo{}
+	if sectorsArr, err := adt.AsArray(store, st.Sectors); err != nil {
+		acc.Addf("error loading sectors: %v", err)
+	} else {
+		var sector SectorOnChainInfo
+		err = sectorsArr.ForEach(&sector, func(sno int64) error {
+			cpy := sector
+			allSectors[abi.SectorNumber(sno)] = &cpy
+
+			acc.Require(sector.SectorNumber == abi.SectorNumber(sno), "sector %d stored under key %d", sector.SectorNumber, sno)
+			allocated, err := st.IsSectorNumberAllocated(abi.SectorNumber(sno))
+			acc.RequireNoError(err, "error checking sector number allocation")
+			acc.Require(allocated, "sector number %d is not allocated", sno)
+			acc.Require(sector.SealProof == minerSummary.SealProofType, "sector %d seal proof %d does not match miner %d",
+				sno, sector.SealProof, minerSummary.SealProofType)
+			acc.Require(sector.Activation <= sector.Expiration, "sector %d activation %d is after expiration %d",
+				sno, sector.Activation, sector.Expiration)
+			acc.Require(sector.Activation <= sector.PowerBaseEpoch && sector.PowerBaseEpoch <= sector.Expiration,
+				"sector %d power base epoch %d is not between activation %d and expiration %d",
+				sno, sector.PowerBaseEpoch, sector.Activation, sector.Expiration)
+			acc.Require(sector.InitialPledge.GreaterThanEqual(big.Zero()), "sector %d has negative initial pledge %v",
+				sno, sector.InitialPledge)
+
+			for _, dealID := range sector.DealIDs {
+				if _, found := minerSummary.Deals[dealID]; found {
+					acc.Addf("deal %d is in more than one sector", dealID)
+				}
+				minerSummary.Deals[dealID] = DealSummary{
+					SectorStart:      sector.PowerBaseEpoch,
+					SectorExpiration: sector.Expiration,
+				}
+			}
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating sectors")
+	}
+
+	CheckPreCommits(st, store, allSectors, acc)
+
+	// Check deadlines
+	acc.Require(st.CurrentDeadline < WPoStPeriodDeadlines,
+		"current deadline index is greater than deadlines per period(%d): %d", WPoStPeriodDeadlines, st.CurrentDeadline)
+
+	deadlines, err := st.LoadDeadlines(store)
+	if err != nil {
+		acc.Addf("error loading deadlines: %v", err)
+	} else {
+		quant := st.QuantEndOfDeadline()
+		deadlinesSectors := bitfield.New()
+		err = deadlines.ForEach(store, func(dlIdx uint64, dl *Deadline) error {
+			acc := acc.WithPrefix("deadline %d: ", dlIdx) // Shadow
+			summary := CheckDeadlineStateInvariants(dl, store, quant, sectorSize, allSectors, acc)
+
+			minerSummary.LivePower = minerSummary.LivePower.Add(summary.LivePower)
+			minerSummary.ActivePower = minerSummary.ActivePower.Add(summary.ActivePower)
+			minerSummary.FaultyPower = minerSummary.FaultyPower.Add(summary.FaultyPower)
+
+			if contains, err := abi.BitFieldContainsAny(&deadlinesSectors, summary.AllSectors); err != nil {
+				acc.Addf("error checking deadline sector overlap: %v", err)
+			} else {
+				acc.Require(!contains, "deadline contains sectors assigned to another deadline")
+			}
+			if merged, err := bitfield.MergeBitFields(&deadlinesSectors, summary.AllSectors); err != nil {
+				acc.Addf("error merging deadline sectors: %v", err)
+			} else {
+				deadlinesSectors = *merged
+			}
+
+			if noEarly, err := dl.EarlyTerminations.IsEmpty(); err != nil {
+				acc.Addf("error checking early terminations: %v", err)
+			} else if !noEarly {
+				isSet, err := st.EarlyTerminations.IsSet(dlIdx)
+				acc.RequireNoError(err, "error checking miner early terminations")
+				acc.Require(isSet, "deadline has early terminations but is not recorded in miner early terminations")
+			}
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating deadlines")
+
+		// Every sector in the sectors array is assigned to some deadline.
+		for sno := range allSectors { //nolint:nomaprange
+			isSet, err := deadlinesSectors.IsSet(uint64(sno))
+			acc.RequireNoError(err, "error checking sector assignment")
+			acc.Require(isSet, "sector %d is not assigned to any deadline", sno)
+		}
+
+		acc.Require(st.FaultyPower.Equals(minerSummary.FaultyPower),
+			"miner faulty power %v does not match sum over partitions %v", st.FaultyPower, minerSummary.FaultyPower)
+	}
+
+	return minerSummary, acc
+}
+
+type DeadlineStateSummary struct {
+	AllSectors        *bitfield.BitField
+	LiveSectors       *bitfield.BitField
+	FaultySectors     *bitfield.BitField
+	RecoveringSectors *bitfield.BitField
+	TerminatedSectors *bitfield.BitField
+	LivePower         PowerPair
+	ActivePower       PowerPair
+	FaultyPower       PowerPair
+}
+
+func CheckDeadlineStateInvariants(deadline *Deadline, store adt.Store, quant QuantSpec, ssize abi.SectorSize, sectors map[abi.SectorNumber]*SectorOnChainInfo, acc *builtin.MessageAccumulator) *DeadlineStateSummary {
+	// Load linked structures.
+	partitions, err := deadline.PartitionsArray(store)
+	if err != nil {
+		acc.Addf("error loading partitions: %v", err)
+		// Hard to do any useful checks.
+		return &DeadlineStateSummary{
+			AllSectors:        bitfield.NewFromSet(nil),
+			LiveSectors:       bitfield.NewFromSet(nil),
+			FaultySectors:     bitfield.NewFromSet(nil),
+			RecoveringSectors: bitfield.NewFromSet(nil),
+			TerminatedSectors: bitfield.NewFromSet(nil),
+			LivePower:         NewPowerPairZero(),
+			ActivePower:       NewPowerPairZero(),
+			FaultyPower:       NewPowerPairZero(),
+		}
+	}
+
+	allSectors := bitfield.NewFromSet(nil)
+	var allLiveSectors []*bitfield.BitField
+	var allFaultySectors []*bitfield.BitField
+	var allRecoveringSectors []*bitfield.BitField
+	var allTerminatedSectors []*bitfield.BitField
+	allLivePower := NewPowerPairZero()
+	allActivePower := NewPowerPairZero()
+	allFaultyPower := NewPowerPairZero()
+
+	// Check partitions.
+	partitionsWithExpirations := map[abi.ChainEpoch][]uint64{}
+	partitionsWithEarlyTerminations := bitfield.New()
+	partitionCount := uint64(0)
+	var partition Partition
+	err = partitions.ForEach(&partition, func(i int64) error {
+		pIdx := uint64(i)
+		// Check sequential partitions.
+		acc.Require(pIdx == partitionCount, "Non-sequential partitions, expected index %d, found %d", partitionCount, pIdx)
+		partitionCount++
+
+		acc := acc.WithPrefix("partition %d: ", pIdx) // Shadow
+		summary := CheckPartitionStateInvariants(&partition, store, quant, ssize, sectors, acc)
+
+		if contains, err := abi.BitFieldContainsAny(allSectors, summary.AllSectors); err != nil {
+			acc.Addf("error checking bitfield contains: %v", err)
+		} else {
+			acc.Require(!contains, "duplicate sector in partition %d", pIdx)
+		}
+
+		for _, e := range summary.ExpirationEpochs {
+			partitionsWithExpirations[e] = append(partitionsWithExpirations[e], pIdx)
+		}
+		if summary.EarlyTerminationCount > 0 {
+			partitionsWithEarlyTerminations.Set(pIdx)
+		}
+
+		allSectors, err = bitfield.MergeBitFields(allSectors, summary.AllSectors)
+		if err != nil {
+			acc.Addf("error merging partition sector numbers with all: %v", err)
+			allSectors = bitfield.NewFromSet(nil)
+		}
+		allLiveSectors = append(allLiveSectors, summary.LiveSectors)
+		allFaultySectors = append(allFaultySectors, summary.FaultySectors)
+		allRecoveringSectors = append(allRecoveringSectors, summary.RecoveringSectors)
+		allTerminatedSectors = append(allTerminatedSectors, summary.TerminatedSectors)
+		allLivePower = allLivePower.Add(summary.LivePower)
+		allActivePower = allActivePower.Add(summary.ActivePower)
+		allFaultyPower = allFaultyPower.Add(summary.FaultyPower)
+		return nil
+	})
+	acc.RequireNoError(err, "error iterating partitions")
+
+	// Check PoSt submissions
+	if postSubmissions, err := deadline.PostSubmissions.All(1 << 20); err != nil {
+		acc.Addf("error expanding post submissions: %v", err)
+	} else {
+		for _, p := range postSubmissions {
+			acc.Require(p < partitionCount, "invalid PoSt submission for partition %d of %d", p, partitionCount)
+		}
+	}
+
+	// Check optimistic PoSt submissions are for partitions with submissions.
+	if proofs, err := adt.AsArray(store, deadline.OptimisticPoStSubmissions); err != nil {
+		acc.Addf("error loading optimistic PoSt submissions: %v", err)
+	} else {
+		var post WindowedPoSt
+		err = proofs.ForEach(&post, func(i int64) error {
+			contains, err := abi.BitFieldContainsAll(deadline.PostSubmissions, post.Partitions)
+			if err != nil {
+				return err
+			}
+			acc.Require(contains, "optimistic PoSt submission %d proves partitions without a PoSt submission", i)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating optimistic PoSt submissions")
+	}
+
+	// Check memoized sector and power values.
+	live, err := bitfield.MultiMerge(allLiveSectors...)
+	if err != nil {
+		acc.Addf("error merging live sector numbers: %v", err)
+		live = bitfield.NewFromSet(nil)
+	} else {
+		if liveCount, err := live.Count(); err != nil {
+			acc.Addf("error counting live sectors: %v", err)
+		} else {
+			acc.Require(deadline.LiveSectors == liveCount, "deadline live sectors %d != partitions count %d", deadline.LiveSectors, liveCount)
+		}
+	}
+
+	if allCount, err := allSectors.Count(); err != nil {
+		acc.Addf("error counting all sectors: %v", err)
+	} else {
+		acc.Require(deadline.TotalSectors == allCount, "deadline total sectors %d != partitions count %d", deadline.TotalSectors, allCount)
+	}
+
+	faulty, err := bitfield.MultiMerge(allFaultySectors...)
+	if err != nil {
+		acc.Addf("error merging faulty sector numbers: %v", err)
+		faulty = bitfield.NewFromSet(nil)
+	}
+	recovering, err := bitfield.MultiMerge(allRecoveringSectors...)
+	if err != nil {
+		acc.Addf("error merging recovering sector numbers: %v", err)
+		recovering = bitfield.NewFromSet(nil)
+	}
+	terminated, err := bitfield.MultiMerge(allTerminatedSectors...)
+	if err != nil {
+		acc.Addf("error merging terminated sector numbers: %v", err)
+		terminated = bitfield.NewFromSet(nil)
+	}
+
+	// Validate partition expiration queue contains an entry for each partition and epoch with an expiration.
+	// The queue may be a superset of the partitions that have expirations because we never remove from it.
+	if expirationEpochs, err := LoadBitfieldQueue(store, deadline.ExpirationsEpochs, quant); err != nil {
+		acc.Addf("error loading expiration queue: %v", err)
+	} else {
+		// Record the earliest epoch in the deadline queue for each partition.
+		earliestQueued := map[uint64]abi.ChainEpoch{}
+		err = expirationEpochs.ForEach(func(epoch abi.ChainEpoch, bf *bitfield.BitField) error {
+			return bf.ForEach(func(pIdx uint64) error {
+				acc.Require(pIdx < partitionCount, "expiration queue at epoch %d references partition %d of %d", epoch, pIdx, partitionCount)
+				if prev, found := earliestQueued[pIdx]; !found || epoch < prev {
+					earliestQueued[pIdx] = epoch
+				}
+				return nil
+			})
+		})
+		acc.RequireNoError(err, "error iterating deadline expiration queue")
+
+		for epoch, expiringPIdxs := range partitionsWithExpirations { //nolint:nomaprange
+			for _, pIdx := range expiringPIdxs {
+				queued, found := earliestQueued[pIdx]
+				acc.Require(found && queued <= epoch,
+					"partition %d has expirations at epoch %d but is not in the deadline expiration queue at or before it", pIdx, epoch)
+			}
+		}
+	}
+
+	// Validate live extended sectors are scheduled for renewal at their current expiration.
+	if renewals, err := LoadBitfieldQueue(store, deadline.Renewals, quant); err != nil {
+		acc.Addf("error loading renewal queue: %v", err)
+	} else {
+		queuedRenewals := map[abi.SectorNumber]abi.ChainEpoch{}
+		err = renewals.ForEach(func(epoch abi.ChainEpoch, bf *bitfield.BitField) error {
+			acc.Require(quant.QuantizeUp(epoch) == epoch, "renewal queue key %d is not quantized", epoch)
+			return bf.ForEach(func(sno uint64) error {
+				if isLive, err := live.IsSet(sno); err != nil {
+					return err
+				} else if !isLive {
+					return nil // Terminated sectors remain queued.
+				}
+				sector, found := sectors[abi.SectorNumber(sno)]
+				if !found {
+					return nil
+				}
+				acc.Require(sector.ExtendedExpiration > sector.Expiration, "queued renewal of sector %d expiration %d not after %d",
+					sno, sector.ExtendedExpiration, sector.Expiration)
+				queuedRenewals[sector.SectorNumber] = epoch
+				return nil
+			})
+		})
+		acc.RequireNoError(err, "error iterating renewal queue")
+
+		err = live.ForEach(func(sno uint64) error {
+			sector, found := sectors[abi.SectorNumber(sno)]
+			if !found || sector.ExtendedExpiration == 0 {
+				return nil
+			}
+			epoch, queued := queuedRenewals[sector.SectorNumber]
+			acc.Require(queued && epoch == quant.QuantizeUp(sector.Expiration),
+				"extended sector %d is not queued for renewal at its expiration %d", sno, sector.Expiration)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating live sectors")
+	}
+
+	// Validate the early termination queue contains exactly the partitions with early terminations.
+	requireEqual(&partitionsWithEarlyTerminations, deadline.EarlyTerminations, acc, "deadline early terminations doesn't match expected partitions")
+
+	return &DeadlineStateSummary{
+		AllSectors:        allSectors,
+		LiveSectors:       live,
+		FaultySectors:     faulty,
+		RecoveringSectors: recovering,
+		TerminatedSectors: terminated,
+		LivePower:         allLivePower,
+		ActivePower:       allActivePower,
+		FaultyPower:       allFaultyPower,
+	}
+}
+
+type PartitionStateSummary struct {
+	AllSectors            *bitfield.BitField
+	LiveSectors           *bitfield.BitField
+	FaultySectors         *bitfield.BitField
+	RecoveringSectors     *bitfield.BitField
+	TerminatedSectors     *bitfield.BitField
+	LivePower             PowerPair
+	ActivePower           PowerPair
+	FaultyPower           PowerPair
+	RecoveringPower       PowerPair
+	ExpirationEpochs      []abi.ChainEpoch // Epochs at which some sector is scheduled to expire.
+	EarlyTerminationCount int
+}
+
+func CheckPartitionStateInvariants(
+	partition *Partition,
+	store adt.Store,
+	quant QuantSpec,
+	sectorSize abi.SectorSize,
+	sectors map[abi.SectorNumber]*SectorOnChainInfo,
+	acc *builtin.MessageAccumulator,
+) *PartitionStateSummary {
+	live, err := partition.LiveSectors()
+	if err != nil {
+		acc.Addf("error computing live sectors: %v", err)
+		live = bitfield.NewFromSet(nil)
+	}
+	active, err := partition.ActiveSectors()
+	if err != nil {
+		acc.Addf("error computing active sectors: %v", err)
+		active = bitfield.NewFromSet(nil)
+	}
+
+	// Live contains all active sectors.
+	requireContainsAll(live, active, acc, "live does not contain active")
+
+	// Live contains all faults.
+	requireContainsAll(live, partition.Faults, acc, "live does not contain faults")
+
+	// Live contains all recoveries.
+	requireContainsAll(live, partition.Recoveries, acc, "live does not contain recoveries")
+
+	// Active contains no faults
+	requireContainsNone(active, partition.Faults, acc, "active includes faults")
+
+	// Sectors contains all terminated
+	requireContainsAll(partition.Sectors, partition.Terminated, acc, "sectors do not contain terminations")
+
+	// Live contains no terminated sectors
+	requireContainsNone(live, partition.Terminated, acc, "live includes terminations")
+
+	// Faults contains all recoveries.
+	requireContainsAll(partition.Faults, partition.Recoveries, acc, "faults do not contain recoveries")
+
+	// Validate power
+	var liveSectors map[abi.SectorNumber]*SectorOnChainInfo
+	var missing []abi.SectorNumber
+	livePower := NewPowerPairZero()
+	faultyPower := NewPowerPairZero()
+	recoveringPower := NewPowerPairZero()
+
+	if liveSectors, missing, err = selectSectorsMap(sectors, live); err != nil {
+		acc.Addf("error selecting live sectors: %v", err)
+	} else if len(missing) > 0 {
+		acc.Addf("live sectors missing from all sectors: %v", missing)
+	} else {
+		livePower = powerForSectors(liveSectors, sectorSize)
+		acc.Require(partition.LivePower.Equals(livePower), "live power was %v, expected %v", partition.LivePower, livePower)
+	}
+
+	if _, missing, err = selectSectorsMap(sectors, partition.Terminated); err != nil {
+		acc.Addf("error selecting terminated sectors: %v", err)
+	} else {
+		acc.Require(len(missing) == 0, "terminated sectors missing from all sectors: %v", missing)
+	}
+
+	if faultySectors, missing, err := selectSectorsMap(sectors, partition.Faults); err != nil {
+		acc.Addf("error selecting faulty sectors: %v", err)
+	} else if len(missing) > 0 {
+		acc.Addf("faulty sectors missing from all sectors: %v", missing)
+	} else {
+		faultyPower = powerForSectors(faultySectors, sectorSize)
+		acc.Require(partition.FaultyPower.Equals(faultyPower), "faulty power was %v, expected %v", partition.FaultyPower, faultyPower)
+	}
+
+	if recoveringSectors, missing, err := selectSectorsMap(sectors, partition.Recoveries); err != nil {
+		acc.Addf("error selecting recovering sectors: %v", err)
+	} else if len(missing) > 0 {
+		acc.Addf("recovering sectors missing from all sectors: %v", missing)
+	} else {
+		recoveringPower = powerForSectors(recoveringSectors, sectorSize)
+		acc.Require(partition.RecoveringPower.Equals(recoveringPower), "recovering power was %v, expected %v", partition.RecoveringPower, recoveringPower)
+	}
+
+	activePower := livePower.Sub(faultyPower)
+	partitionActivePower := partition.ActivePower()
+	acc.Require(partitionActivePower.Equals(activePower), "active power was %v, expected %v", partitionActivePower, activePower)
+
+	// Validate the expiration queue.
+	var expirationEpochs []abi.ChainEpoch
+	if expQ, err := LoadExpirationQueue(store, partition.ExpirationsEpochs, quant); err != nil {
+		acc.Addf("error loading expiration queue: %v", err)
+	} else if liveSectors != nil {
+		qsummary := CheckExpirationQueue(expQ, liveSectors, partition.Faults, quant, sectorSize, acc)
+		expirationEpochs = qsummary.ExpirationEpochs
+
+		// Check the queue is compatible with partition fields
+		if qSectors, err := bitfield.MergeBitFields(qsummary.OnTimeSectors, qsummary.EarlySectors); err != nil {
+			acc.Addf("error merging summary on-time and early sectors: %v", err)
+		} else {
+			requireEqual(live, qSectors, acc, "live does not equal all expirations")
+		}
+	}
+
+	// Validate the early termination queue.
+	earlyTerminationCount := 0
+	if earlyQ, err := LoadBitfieldQueue(store, partition.EarlyTerminated, NoQuantization); err != nil {
+		acc.Addf("error loading early termination queue: %v", err)
+	} else {
+		earlyTerminationCount = CheckEarlyTerminationQueue(earlyQ, partition.Terminated, acc)
+	}
+
+	return &PartitionStateSummary{
+		AllSectors:            partition.Sectors,
+		LiveSectors:           live,
+		FaultySectors:         partition.Faults,
+		RecoveringSectors:     partition.Recoveries,
+		TerminatedSectors:     partition.Terminated,
+		LivePower:             livePower,
+		ActivePower:           activePower,
+		FaultyPower:           partition.FaultyPower,
+		RecoveringPower:       partition.RecoveringPower,
+		ExpirationEpochs:      expirationEpochs,
+		EarlyTerminationCount: earlyTerminationCount,
+	}
+}
+
+type ExpirationQueueStateSummary struct {
+	OnTimeSectors    *bitfield.BitField
+	EarlySectors     *bitfield.BitField
+	ActivePower      PowerPair
+	FaultyPower      PowerPair
+	OnTimePledge     abi.TokenAmount
+	ExpirationEpochs []abi.ChainEpoch
+}
+
+// Checks the expiration queue for consistency.
+func CheckExpirationQueue(expQ ExpirationQueue, liveSectors map[abi.SectorNumber]*SectorOnChainInfo,
+	partitionFaults *bitfield.BitField, quant QuantSpec, sectorSize abi.SectorSize, acc *builtin.MessageAccumulator) *ExpirationQueueStateSummary {
+	partitionFaultsMap, err := partitionFaults.AllMap(1 << 30)
+	if err != nil {
+		acc.Addf("error loading partition faults map: %v", err)
+		partitionFaultsMap = nil
+	}
+
+	seenSectors := make(map[abi.SectorNumber]bool)
+	var allOnTime []*bitfield.BitField
+	var allEarly []*bitfield.BitField
+	var expirationEpochs []abi.ChainEpoch
+	allActivePower := NewPowerPairZero()
+	allFaultyPower := NewPowerPairZero()
+	allOnTimePledge := big.Zero()
+	var exp ExpirationSet
+	err = expQ.ForEach(&exp, func(e int64) error {
+		epoch := abi.ChainEpoch(e)
+		acc := acc.WithPrefix("expiration epoch %d: ", epoch)
+		expirationEpochs = append(expirationEpochs, epoch)
+
+		onTimeSectorsPledge := big.Zero()
+		err := exp.OnTimeSectors.ForEach(func(n uint64) error {
+			sno := abi.SectorNumber(n)
+			// Check sectors are present only once.
+			acc.Require(!seenSectors[sno], "sector %d in expiration queue twice", sno)
+			seenSectors[sno] = true
+
+			// Check expiring sectors are still alive.
+			if sector, ok := liveSectors[sno]; ok {
+				// The sector may be "on time" at an epoch earlier than its target expiration if it was
+				// rescheduled after being replaced by a committed-capacity upgrade.
+				target := quant.QuantizeUp(sector.Expiration)
+				acc.Require(epoch <= target, "invalid expiration %d for sector %d, expected at most %d",
+					epoch, sector.SectorNumber, target)
+
+				onTimeSectorsPledge = big.Add(onTimeSectorsPledge, sector.InitialPledge)
+			} else {
+				acc.Addf("on-time expiration sector %d isn't live", n)
+			}
+
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating on-time sectors")
+
+		err = exp.EarlySectors.ForEach(func(n uint64) error {
+			sno := abi.SectorNumber(n)
+			// Check sectors are present only once.
+			acc.Require(!seenSectors[sno], "sector %d in expiration queue twice", sno)
+			seenSectors[sno] = true
+
+			// Check early sectors are faulty
+			acc.Require(partitionFaultsMap == nil || partitionFaultsMap[n], "sector %d expiring early but not faulty", sno)
+
+			// Check expiring sectors are still alive.
+			if sector, ok := liveSectors[sno]; ok {
+				target := quant.QuantizeUp(sector.Expiration)
+				acc.Require(epoch < target, "invalid early expiration %d for sector %d, expected < %d",
+					epoch, sector.SectorNumber, target)
+			} else {
+				acc.Addf("early expiration sector %d isn't live", n)
+			}
+
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating early sectors")
+
+		// Validate power and pledge.
+		var activeSectors, faultySectors map[abi.SectorNumber]*SectorOnChainInfo
+		var missing []abi.SectorNumber
+
+		all, err := bitfield.MergeBitFields(exp.OnTimeSectors, exp.EarlySectors)
+		if err != nil {
+			acc.Addf("error merging all on-time and early bitfields: %v", err)
+		} else {
+			if allActive, err := bitfield.SubtractBitField(all, partitionFaults); err != nil {
+				acc.Addf("error computing active sectors: %v", err)
+			} else {
+				activeSectors, missing, err = selectSectorsMap(liveSectors, allActive)
+				if err != nil {
+					acc.Addf("error selecting active sectors: %v", err)
+					activeSectors = nil
+				} else if len(missing) > 0 {
+					acc.Addf("active sectors missing from live: %v", missing)
+				}
+			}
+
+			if allFaulty, err := bitfield.IntersectBitField(all, partitionFaults); err != nil {
+				acc.Addf("error computing faulty sectors: %v", err)
+			} else {
+				faultySectors, missing, err = selectSectorsMap(liveSectors, allFaulty)
+				if err != nil {
+					acc.Addf("error selecting faulty sectors: %v", err)
+					faultySectors = nil
+				} else if len(missing) > 0 {
+					acc.Addf("faulty sectors missing from live: %v", missing)
+				}
+			}
+		}
+
+		if activeSectors != nil && faultySectors != nil {
+			activeSectorsPower := powerForSectors(activeSectors, sectorSize)
+			acc.Require(exp.ActivePower.Equals(activeSectorsPower), "active power recorded %v doesn't match computed %v", exp.ActivePower, activeSectorsPower)
+
+			faultySectorsPower := powerForSectors(faultySectors, sectorSize)
+			acc.Require(exp.FaultyPower.Equals(faultySectorsPower), "faulty power recorded %v doesn't match computed %v", exp.FaultyPower, faultySectorsPower)
+		}
+
+		acc.Require(exp.OnTimePledge.Equals(onTimeSectorsPledge), "on time pledge recorded %v doesn't match computed %v", exp.OnTimePledge, onTimeSectorsPledge)
+
+		allOnTime = append(allOnTime, exp.OnTimeSectors)
+		allEarly = append(allEarly, exp.EarlySectors)
+		allActivePower = allActivePower.Add(exp.ActivePower)
+		allFaultyPower = allFaultyPower.Add(exp.FaultyPower)
+		allOnTimePledge = big.Add(allOnTimePledge, exp.OnTimePledge)
+		return nil
+	})
+	acc.RequireNoError(err, "error iterating expiration queue")
+
+	unionOnTime, err := bitfield.MultiMerge(allOnTime...)
+	if err != nil {
+		acc.Addf("error merging on-time sector numbers: %v", err)
+		unionOnTime = bitfield.NewFromSet(nil)
+	}
+	unionEarly, err := bitfield.MultiMerge(allEarly...)
+	if err != nil {
+		acc.Addf("error merging early sector numbers: %v", err)
+		unionEarly = bitfield.NewFromSet(nil)
+	}
+	return &ExpirationQueueStateSummary{
+		OnTimeSectors:    unionOnTime,
+		EarlySectors:     unionEarly,
+		ActivePower:      allActivePower,
+		FaultyPower:      allFaultyPower,
+		OnTimePledge:     allOnTimePledge,
+		ExpirationEpochs: expirationEpochs,
+	}
+}
+
+// Checks the early termination queue for consistency.
+// Returns the number of sectors in the queue.
+func CheckEarlyTerminationQueue(earlyQ BitfieldQueue, terminated *bitfield.BitField, acc *builtin.MessageAccumulator) int {
+	seenMap := make(map[uint64]bool)
+	seenBf := bitfield.NewFromSet(nil)
+	err := earlyQ.ForEach(func(epoch abi.ChainEpoch, bf *bitfield.BitField) error {
+		acc := acc.WithPrefix("early termination epoch %d: ", epoch)
+		err := bf.ForEach(func(i uint64) error {
+			acc.Require(!seenMap[i], "sector %v in early termination queue twice", i)
+			seenMap[i] = true
+			seenBf.Set(i)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating early termination bitfield")
+		return nil
+	})
+	acc.RequireNoError(err, "error iterating early termination queue")
+
+	requireContainsAll(terminated, seenBf, acc, "terminated sectors missing early termination entry")
+	return len(seenMap)
+}
+
+func CheckMinerInfo(info *MinerInfo, acc *builtin.MessageAccumulator) {
+	acc.Require(info.Owner.Protocol() == addr.ID, "owner %v is not an ID address", info.Owner)
+	acc.Require(info.Worker.Protocol() == addr.ID, "worker %v is not an ID address", info.Worker)
+	if info.PendingWorkerKey != nil {
+		acc.Require(info.PendingWorkerKey.NewWorker.Protocol() == addr.ID,
+			"pending worker %v is not an ID address", info.PendingWorkerKey.NewWorker)
+		acc.Require(info.PendingWorkerKey.NewWorker != info.Worker,
+			"pending worker key %v is same as existing worker %v", info.PendingWorkerKey.NewWorker, info.Worker)
+	}
+	if info.PendingOwner != nil {
+		acc.Require(info.PendingOwner.NewOwner.Protocol() == addr.ID,
+			"pending owner %v is not an ID address", info.PendingOwner.NewOwner)
+		acc.Require(info.PendingOwner.NewOwner != info.Owner,
+			"pending owner %v is same as existing owner %v", info.PendingOwner.NewOwner, info.Owner)
+	}
+
+	acc.Require(len(info.ControlAddresses) <= MaxControlAddresses, "too many control addresses %d, max %d",
+		len(info.ControlAddresses), MaxControlAddresses)
+	for _, ca := range info.ControlAddresses {
+		acc.Require(ca.Protocol() == addr.ID, "control address %v is not an ID address", ca)
+	}
+
+	acc.Require(info.Beneficiary.Protocol() == addr.ID, "beneficiary %v is not an ID address", info.Beneficiary)
+	if info.Beneficiary != info.Owner {
+		acc.Require(info.BeneficiaryTerm.Quota.GreaterThan(big.Zero()), "beneficiary %v has non-positive quota %v",
+			info.Beneficiary, info.BeneficiaryTerm.Quota)
+		acc.Require(info.BeneficiaryTerm.UsedQuota.LessThanEqual(info.BeneficiaryTerm.Quota),
+			"beneficiary used quota %v exceeds quota %v", info.BeneficiaryTerm.UsedQuota, info.BeneficiaryTerm.Quota)
+	}
+	if info.PendingBeneficiaryTerm != nil {
+		acc.Require(info.PendingBeneficiaryTerm.NewBeneficiary.Protocol() == addr.ID,
+			"pending beneficiary %v is not an ID address", info.PendingBeneficiaryTerm.NewBeneficiary)
+		acc.Require(!(info.PendingBeneficiaryTerm.ApprovedByBeneficiary && info.PendingBeneficiaryTerm.ApprovedByNominee),
+			"pending beneficiary change to %v is fully approved but not applied", info.PendingBeneficiaryTerm.NewBeneficiary)
+	}
+
+	sectorSize, err := info.SealProofType.SectorSize()
+	if err != nil {
+		acc.Addf("invalid seal proof type %d: %v", info.SealProofType, err)
+	} else {
+		acc.Require(sectorSize == info.SectorSize, "sector size %d is wrong for seal proof type %d: %d",
+			info.SectorSize, info.SealProofType, sectorSize)
+	}
+
+	partitionSectors, err := info.SealProofType.WindowPoStPartitionSectors()
+	if err != nil {
+		acc.Addf("invalid seal proof type %d: %v", info.SealProofType, err)
+	} else {
+		acc.Require(partitionSectors == info.WindowPoStPartitionSectors, "miner partition sectors %d does not match partition sectors %d for seal proof type %d",
+			info.WindowPoStPartitionSectors, partitionSectors, info.SealProofType)
+	}
+}
+
+func CheckMinerBalances(st *State, store adt.Store, balance abi.TokenAmount, acc *builtin.MessageAccumulator) {
+	acc.Require(balance.GreaterThanEqual(big.Zero()), "miner actor balance is less than zero: %v", balance)
+	acc.Require(st.LockedFunds.GreaterThanEqual(big.Zero()), "miner locked funds is less than zero: %v", st.LockedFunds)
+	acc.Require(st.PreCommitDeposits.GreaterThanEqual(big.Zero()), "miner precommit deposit is less than zero: %v", st.PreCommitDeposits)
+	acc.Require(st.FeeDebt.GreaterThanEqual(big.Zero()), "miner fee debt is less than zero: %v", st.FeeDebt)
+	acc.Require(st.InitialPledgeRequirement.GreaterThanEqual(big.Zero()), "miner initial pledge is less than zero: %v", st.InitialPledgeRequirement)
+
+	// Locked funds must be the sum of the vesting table.
+	vestingSum := big.Zero()
+	if funds, err := adt.AsArray(store, st.VestingFunds); err != nil {
+		acc.Addf("error loading vesting funds: %v", err)
+	} else {
+		var amount abi.TokenAmount
+		err = funds.ForEach(&amount, func(epoch int64) error {
+			acc.Require(amount.GreaterThan(big.Zero()), "non-positive amount %v vesting at epoch %d", amount, epoch)
+			vestingSum = big.Add(vestingSum, amount)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating vesting funds")
+	}
+
+	acc.Require(st.LockedFunds.Equals(vestingSum), "locked funds %v is not sum of vesting table entries %v", st.LockedFunds, vestingSum)
+
+	acc.Require(balance.GreaterThanEqual(big.Add(st.PreCommitDeposits, st.LockedFunds)),
+		"miner balance %v is less than pre-commit deposits %v plus locked funds %v", balance, st.PreCommitDeposits, st.LockedFunds)
+}
+
+func CheckPreCommits(st *State, store adt.Store, allSectors map[abi.SectorNumber]*SectorOnChainInfo, acc *builtin.MessageAccumulator) {
+	precommitTotal := big.Zero()
+	if precommitted, err := adt.AsMap(store, st.PreCommittedSectors); err != nil {
+		acc.Addf("error loading precommitted sectors: %v", err)
+	} else {
+		var precommit SectorPreCommitOnChainInfo
+		err = precommitted.ForEach(&precommit, func(key string) error {
+			sectorNo, err := adt.ParseUIntKey(key)
+			if err != nil {
+				return err
+			}
+			acc.Require(precommit.Info.SectorNumber == abi.SectorNumber(sectorNo),
+				"precommitted sector %d stored under key %d", precommit.Info.SectorNumber, sectorNo)
+
+			_, found := allSectors[abi.SectorNumber(sectorNo)]
+			acc.Require(!found, "precommitted sector number %d has already been committed", sectorNo)
+
+			allocated, err := st.IsSectorNumberAllocated(abi.SectorNumber(sectorNo))
+			acc.RequireNoError(err, "error checking sector number allocation")
+			acc.Require(allocated, "precommitted sector number %d is not allocated", sectorNo)
+			acc.Require(precommit.ProofSubmittedEpoch == NoProofSubmittedEpoch || precommit.ProofSubmittedEpoch >= precommit.PreCommitEpoch,
+				"precommitted sector %d proof submitted at %d before pre-commit at %d", sectorNo, precommit.ProofSubmittedEpoch, precommit.PreCommitEpoch)
+
+			precommitTotal = big.Add(precommitTotal, precommit.PreCommitDeposit)
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating pre-committed sectors")
+	}
+
+	acc.Require(st.PreCommitDeposits.Equals(precommitTotal),
+		"sum of precommit deposits %v does not equal recorded precommit deposit %v", precommitTotal, st.PreCommitDeposits)
+}
+
+// Selects a subset of sectors from a map by sector number.
+// Returns the selected sectors, and a slice of any sector numbers not found.
+func selectSectorsMap(sectors map[abi.SectorNumber]*SectorOnChainInfo, include *bitfield.BitField) (map[abi.SectorNumber]*SectorOnChainInfo, []abi.SectorNumber, error) {
+	included := map[abi.SectorNumber]*SectorOnChainInfo{}
+	var missing []abi.SectorNumber
+	if err := include.ForEach(func(n uint64) error {
+		if s, ok := sectors[abi.SectorNumber(n)]; ok {
+			included[abi.SectorNumber(n)] = s
+		} else {
+			missing = append(missing, abi.SectorNumber(n))
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return included, missing, nil
+}
+
+func powerForSectors(sectors map[abi.SectorNumber]*SectorOnChainInfo, ssize abi.SectorSize) PowerPair {
+	qa := big.Zero()
+	for _, s := range sectors { //nolint:nomaprange
+		qa = big.Add(qa, QAPowerForSector(ssize, s))
+	}
+
+	return PowerPair{
+		Raw: big.Mul(big.NewIntUnsigned(uint64(ssize)), big.NewIntUnsigned(uint64(len(sectors)))),
+		QA:  qa,
+	}
+}
+
+func requireContainsAll(superset, subset *bitfield.BitField, acc *builtin.MessageAccumulator, msg string) {
+	if contains, err := abi.BitFieldContainsAll(superset, subset); err != nil {
+		acc.Addf("error in BitfieldContainsAll(): %v", err)
+	} else if !contains {
+		acc.Add(msg)
+	}
+}
+
+func requireContainsNone(superset, subset *bitfield.BitField, acc *builtin.MessageAccumulator, msg string) {
+	if contains, err := abi.BitFieldContainsAny(superset, subset); err != nil {
+		acc.Addf("error in BitfieldContainsAny(): %v", err)
+	} else if contains {
+		acc.Add(msg)
+	}
+}
+
+func requireEqual(a, b *bitfield.BitField, acc *builtin.MessageAccumulator, msg string) {
+	requireContainsAll(a, b, acc, msg)
+	requireContainsAll(b, a, acc, msg)
+}
@@ -0,0 +1,19 @@
+package multisig
+
+import (
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one multisig state to another.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "PendingTxns")
+	err := d.WithField("PendingTxns").Map(store, pre.PendingTxns, post.PendingTxns, func(key string) (interface{}, error) {
+		return ParseTxnIDKey(key)
+	}, func() runtime.CBORUnmarshaler {
+		return new(Transaction)
+	})
+	return d, err
+}
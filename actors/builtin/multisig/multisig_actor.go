@@ -22,6 +22,15 @@ func (t TxnID) Key() string {
 	return string(txnKey[:n])
 }
 
+// ParseTxnIDKey converts a HAMT key back to the TxnID it encodes.
+func ParseTxnIDKey(key string) (TxnID, error) {
+	id, n := binary.Varint([]byte(key))
+	if n <= 0 || n != len(key) {
+		return 0, fmt.Errorf("invalid transaction ID key %x", key)
+	}
+	return TxnID(id), nil
+}
+
 type Transaction struct {
 	To     addr.Address
 	Value  abi.TokenAmount
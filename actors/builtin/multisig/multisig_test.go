@@ -3,6 +3,7 @@ package multisig_test
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 
 	addr "github.com/filecoin-project/go-address"
@@ -196,6 +197,7 @@ func TestVesting(t *testing.T) {
 			Approved: []addr.Address{anne},
 		})
 		actor.approveOK(rt, 0, proposalHashData, nil)
+		actor.checkState(rt)
 	})
 
 	t.Run("partial vesting propose to send half the actor balance when the epoch is hald the unlock duration", func(t *testing.T) {
@@ -224,6 +226,7 @@ func TestVesting(t *testing.T) {
 		})
 
 		actor.approveOK(rt, 0, proposalHashData, nil)
+		actor.checkState(rt)
 	})
 
 	t.Run("propose and autoapprove transaction above locked amount fails", func(t *testing.T) {
@@ -247,6 +250,7 @@ func TestVesting(t *testing.T) {
 		rt.ExpectSend(darlene, builtin.MethodSend, fakeParams, abi.NewTokenAmount(10), nil, 0)
 		actor.proposeOK(rt, darlene, abi.NewTokenAmount(10), builtin.MethodSend, fakeParams, nil)
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("fail to vest more than locked amount", func(t *testing.T) {
@@ -275,6 +279,7 @@ func TestVesting(t *testing.T) {
 			_ = actor.approve(rt, 0, proposalHashData, nil)
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 }
@@ -311,6 +316,7 @@ func TestPropose(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("propose with threshold met", func(t *testing.T) {
@@ -329,6 +335,7 @@ func TestPropose(t *testing.T) {
 		// the transaction has been sent and cleaned up
 		actor.assertTransactions(rt)
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("propose with threshold and non-empty return value", func(t *testing.T) {
@@ -355,7 +362,7 @@ func TestPropose(t *testing.T) {
 		// the transaction has been sent and cleaned up
 		actor.assertTransactions(rt)
 		rt.Verify()
-
+		actor.checkState(rt)
 	})
 
 	t.Run("fail propose with threshold met and insufficient balance", func(t *testing.T) {
@@ -372,6 +379,7 @@ func TestPropose(t *testing.T) {
 
 		// proposal failed since it should have but failed to immediately execute.
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("fail propose from non-signer", func(t *testing.T) {
@@ -392,6 +400,7 @@ func TestPropose(t *testing.T) {
 
 		// the transaction is not persisted
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 }
 
@@ -450,6 +459,7 @@ func TestApprove(t *testing.T) {
 
 		// Transaction should be removed from actor state after send
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("approve with non-empty return value", func(t *testing.T) {
@@ -487,6 +497,7 @@ func TestApprove(t *testing.T) {
 
 		// the transaction has been sent and cleaned up
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("fail approval with bad proposal hash", func(t *testing.T) {
@@ -522,6 +533,7 @@ func TestApprove(t *testing.T) {
 			})
 			_ = actor.approve(rt, txnID, proposalHashData, nil)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail approve transaction more than once", func(t *testing.T) {
@@ -557,6 +569,7 @@ func TestApprove(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail approve transaction that does not exist", func(t *testing.T) {
@@ -593,6 +606,7 @@ func TestApprove(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail to approve transaction by non-signer", func(t *testing.T) {
@@ -629,6 +643,7 @@ func TestApprove(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("proposed transaction is approved by proposer if number of approvers has already crossed threshold", func(t *testing.T) {
@@ -658,6 +673,7 @@ func TestApprove(t *testing.T) {
 
 		// Transaction should be removed from actor state after send
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("approve transaction if number of approvers has already crossed threshold even if we attempt a duplicate approval", func(t *testing.T) {
@@ -694,6 +710,7 @@ func TestApprove(t *testing.T) {
 
 		// Transaction should be removed from actor state after send
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("approve transaction if number of approvers has already crossed threshold and ensure non-signatory cannot approve a transaction", func(t *testing.T) {
@@ -734,6 +751,7 @@ func TestApprove(t *testing.T) {
 
 		// Transaction should be removed from actor state after send
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 }
 
@@ -785,6 +803,7 @@ func TestCancel(t *testing.T) {
 
 		// Transaction should be removed from actor state after cancel
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 
 	t.Run("fail cancel with bad proposal hash", func(t *testing.T) {
@@ -812,6 +831,7 @@ func TestCancel(t *testing.T) {
 			})
 			actor.cancel(rt, txnID, proposalHashData)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("signer fails to cancel transaction from another signer", func(t *testing.T) {
@@ -848,6 +868,7 @@ func TestCancel(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail to cancel transaction when not signer", func(t *testing.T) {
@@ -884,6 +905,7 @@ func TestCancel(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("fail to cancel a transaction that does not exist", func(t *testing.T) {
@@ -920,6 +942,7 @@ func TestCancel(t *testing.T) {
 			Params:   fakeParams,
 			Approved: []addr.Address{anne},
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("transaction can ONLY be cancelled by a proposer who is still the signer", func(t *testing.T) {
@@ -978,6 +1001,7 @@ func TestCancel(t *testing.T) {
 		rt.ExpectValidateCallerType(builtin.AccountActorCodeID, builtin.MultisigActorCodeID)
 		actor.cancel(rt, txnID, proposalHash)
 		actor.assertTransactions(rt)
+		actor.checkState(rt)
 	})
 }
 
@@ -1490,6 +1514,13 @@ func (h *msActorHarness) constructAndVerify(rt *mock.Runtime, numApprovalsThresh
 	rt.Verify()
 }
 
+func (h *msActorHarness) checkState(rt *mock.Runtime) {
+	var st multisig.State
+	rt.GetState(&st)
+	_, msgs := multisig.CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func (h *msActorHarness) propose(rt *mock.Runtime, to addr.Address, value abi.TokenAmount, method abi.MethodNum, params []byte, out runtime.CBORUnmarshaler) exitcode.ExitCode {
 	proposeParams := &multisig.ProposeParams{
 		To:     to,
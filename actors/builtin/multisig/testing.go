@@ -0,0 +1,76 @@
+package multisig
+
+import (
+	addr "github.com/filecoin-project/go-address"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type StateSummary struct {
+	PendingTxnCount       uint64
+	NumApprovalsThreshold uint64
+	SignerCount           int
+}
+
+// Checks internal invariants of multisig state.
+func CheckStateInvariants(st *State, store adt.Store, _ abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	// assert invariants involving signers
+	acc.Require(len(st.Signers) > 0, "multisig has no signers")
+	acc.Require(st.NumApprovalsThreshold >= 1, "approval threshold %d is less than one", st.NumApprovalsThreshold)
+	acc.Require(st.NumApprovalsThreshold <= uint64(len(st.Signers)),
+		"approval threshold %d exceeds signer count %d", st.NumApprovalsThreshold, len(st.Signers))
+
+	signers := make(map[addr.Address]struct{}, len(st.Signers))
+	for _, s := range st.Signers {
+		_, found := signers[s]
+		acc.Require(!found, "duplicate signer %v", s)
+		signers[s] = struct{}{}
+	}
+
+	// assert invariants involving vesting
+	acc.Require(st.UnlockDuration >= 0, "negative unlock duration %d", st.UnlockDuration)
+	acc.Require(st.InitialBalance.GreaterThanEqual(big.Zero()), "negative initial balance %v", st.InitialBalance)
+	if st.UnlockDuration == 0 {
+		acc.Require(st.InitialBalance.IsZero(), "initial balance %v with no unlock duration", st.InitialBalance)
+	}
+
+	// assert invariants involving pending transactions
+	pendingTxnCount := uint64(0)
+	if transactions, err := adt.AsMap(store, st.PendingTxns); err != nil {
+		acc.Addf("error loading transactions: %v", err)
+	} else {
+		var txn Transaction
+		err = transactions.ForEach(&txn, func(key string) error {
+			txnID, err := ParseTxnIDKey(key)
+			if err != nil {
+				return err
+			}
+			acc.Require(txnID >= 0, "transaction ID %d is negative", txnID)
+			acc.Require(txnID < st.NextTxnID, "transaction ID %d should be less than next transaction %d", txnID, st.NextTxnID)
+			acc.Require(txn.Value.GreaterThanEqual(big.Zero()), "transaction %d value %v is negative", txnID, txn.Value)
+			acc.Require(len(txn.Approved) > 0, "transaction %d has no approvals", txnID)
+
+			approvers := make(map[addr.Address]struct{}, len(txn.Approved))
+			for _, approver := range txn.Approved {
+				_, found := approvers[approver]
+				acc.Require(!found, "duplicate approver %v for transaction %d", approver, txnID)
+				approvers[approver] = struct{}{}
+			}
+
+			pendingTxnCount++
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating transactions")
+	}
+
+	return &StateSummary{
+		PendingTxnCount:       pendingTxnCount,
+		NumApprovalsThreshold: st.NumApprovalsThreshold,
+		SignerCount:           len(st.Signers),
+	}, acc
+}
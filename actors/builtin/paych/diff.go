@@ -0,0 +1,12 @@
+package paych
+
+import (
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+)
+
+// Describes the changes from one payment channel state to another.
+func DiffStates(pre, post *State) *builtin.DiffAccumulator {
+	d := &builtin.DiffAccumulator{}
+	d.Compare(pre, post)
+	return d
+}
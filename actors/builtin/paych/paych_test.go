@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	addr "github.com/filecoin-project/go-address"
@@ -43,6 +44,7 @@ func TestPaymentChannelActor_Constructor(t *testing.T) {
 			WithActorType(payeeAddr, builtin.AccountActorCodeID)
 		rt := builder.Build(t)
 		actor.constructAndVerify(t, rt, payerAddr, payeeAddr)
+		actor.checkState(rt)
 	})
 
 	testCases := []struct {
@@ -263,6 +265,7 @@ func TestActor_UpdateChannelStateRedeem(t *testing.T) {
 			LaneStates:      []*LaneState{&expLs},
 		}
 		verifyState(t, rt, 1, expState)
+		actor.checkState(rt)
 	})
 
 	t.Run("redeems voucher for correct lane", func(t *testing.T) {
@@ -294,6 +297,7 @@ func TestActor_UpdateChannelStateRedeem(t *testing.T) {
 		assert.Equal(t, expToSend, st2.ToSend)
 		assert.Equal(t, ucp.Sv.Amount, lUpdated.Redeemed)
 		assert.Equal(t, ucp.Sv.Nonce, lUpdated.Nonce)
+		actor.checkState(rt)
 	})
 
 	t.Run("redeeming voucher fails on nonce reuse", func(t *testing.T) {
@@ -316,6 +320,7 @@ func TestActor_UpdateChannelStateRedeem(t *testing.T) {
 		})
 
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -438,6 +443,7 @@ func TestActor_UpdateChannelStateMergeFailure(t *testing.T) {
 			rt.Call(actor.UpdateChannelState, ucp)
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("Too many lanes, fails with: lane limit exceeded", func(t *testing.T) {
@@ -456,6 +462,7 @@ func TestActor_UpdateChannelStateMergeFailure(t *testing.T) {
 			rt.Call(actor.UpdateChannelState, ucp)
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -579,6 +586,7 @@ func TestActor_UpdateChannelStateSecretPreimage(t *testing.T) {
 		rt.ExpectVerifySignature(*ucp.Sv.Signature, st.To, voucherBytes(t, &ucp.Sv), nil)
 		rt.Call(actor.UpdateChannelState, ucp)
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("If bad secret preimage, fails with: incorrect secret!", func(t *testing.T) {
@@ -597,6 +605,7 @@ func TestActor_UpdateChannelStateSecretPreimage(t *testing.T) {
 			rt.Call(actor.UpdateChannelState, ucp)
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -617,6 +626,7 @@ func TestActor_Settle(t *testing.T) {
 		rt.GetState(&st)
 		assert.Equal(t, expSettlingAt, st.SettlingAt)
 		assert.Equal(t, abi.ChainEpoch(0), st.MinSettleHeight)
+		actor.checkState(rt)
 	})
 
 	t.Run("settle fails if called twice: channel already settling", func(t *testing.T) {
@@ -633,6 +643,7 @@ func TestActor_Settle(t *testing.T) {
 		rt.ExpectAbort(exitcode.ErrIllegalState, func() {
 			rt.Call(actor.Settle, nil)
 		})
+		actor.checkState(rt)
 	})
 
 	t.Run("Settle changes SettleHeight again if MinSettleHeight is less", func(t *testing.T) {
@@ -663,6 +674,7 @@ func TestActor_Settle(t *testing.T) {
 		// SettlingAt should = MinSettleHeight, not epoch + SettleDelay.
 		rt.GetState(&newSt)
 		assert.Equal(t, ucp.Sv.MinSettleHeight, newSt.SettlingAt)
+		actor.checkState(rt)
 	})
 }
 
@@ -694,6 +706,7 @@ func TestActor_Collect(t *testing.T) {
 		rt.ExpectDeleteActor(st.From)
 		res := rt.Call(actor.Collect, nil)
 		assert.Nil(t, res)
+		actor.checkState(rt)
 	})
 
 	testCases := []struct {
@@ -815,6 +828,13 @@ func (h *pcActorHarness) constructAndVerify(t *testing.T, rt *mock.Runtime, send
 	verifyInitialState(t, rt, sender, receiver)
 }
 
+func (h *pcActorHarness) checkState(rt *mock.Runtime) {
+	var st State
+	rt.GetState(&st)
+	_, msgs := CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func verifyInitialState(t *testing.T, rt *mock.Runtime, sender, receiver addr.Address) {
 	var st State
 	rt.GetState(&st)
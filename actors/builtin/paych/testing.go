@@ -0,0 +1,44 @@
+package paych
+
+import (
+	addr "github.com/filecoin-project/go-address"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type StateSummary struct {
+	From     addr.Address
+	To       addr.Address
+	Redeemed abi.TokenAmount
+}
+
+// Checks internal invariants of paych state.
+func CheckStateInvariants(st *State, _ adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	acc.Require(st.From.Protocol() == addr.ID, "from address is not ID address %v", st.From)
+	acc.Require(st.To.Protocol() == addr.ID, "to address is not ID address %v", st.To)
+	acc.Require(st.SettlingAt == 0 || st.SettlingAt >= st.MinSettleHeight,
+		"channel is settling at epoch %d before min settle height %d", st.SettlingAt, st.MinSettleHeight)
+	acc.Require(st.ToSend.GreaterThanEqual(big.Zero()), "to send %v is negative", st.ToSend)
+	acc.Require(balance.GreaterThanEqual(st.ToSend), "balance %v is less than to send %v", balance, st.ToSend)
+	acc.Require(len(st.LaneStates) <= LaneLimit, "lane count %d exceeds limit %d", len(st.LaneStates), LaneLimit)
+
+	redeemed := big.Zero()
+	for i, ls := range st.LaneStates {
+		if i > 0 {
+			prev := st.LaneStates[i-1]
+			acc.Require(prev.ID < ls.ID, "lane %d out of order after lane %d", ls.ID, prev.ID)
+		}
+		redeemed = big.Add(redeemed, ls.Redeemed)
+	}
+
+	return &StateSummary{
+		From:     st.From,
+		To:       st.To,
+		Redeemed: redeemed,
+	}, acc
+}
@@ -0,0 +1,27 @@
+package power
+
+import (
+	cid "github.com/ipfs/go-cid"
+
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one power state to another.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "Claims", "CronEventQueue")
+	if err := d.WithField("Claims").Map(store, pre.Claims, post.Claims, builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler {
+		return new(Claim)
+	}); err != nil {
+		return d, err
+	}
+	err := d.WithField("CronEventQueue").MapOfRoots(store, pre.CronEventQueue, post.CronEventQueue, builtin.IntKeyDecoder,
+		func(entry *builtin.DiffAccumulator, from, to cid.Cid) error {
+			return entry.Array(store, from, to, func() runtime.CBORUnmarshaler {
+				return new(CronEvent)
+			})
+		})
+	return d, err
+}
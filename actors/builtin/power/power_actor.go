@@ -9,12 +9,10 @@ import (
 	xerrors "golang.org/x/xerrors"
 
 	abi "github.com/filecoin-project/specs-actors/actors/abi"
-	big "github.com/filecoin-project/specs-actors/actors/abi/big"
 	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
 	initact "github.com/filecoin-project/specs-actors/actors/builtin/init"
 	vmr "github.com/filecoin-project/specs-actors/actors/runtime"
 	exitcode "github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
-	. "github.com/filecoin-project/specs-actors/actors/util"
 	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
 )
 
@@ -45,6 +43,7 @@ func (a Actor) Exports() []interface{} {
 		7:                         a.OnConsensusFault,
 		8:                         a.SubmitPoRepForBulkVerify,
 		9:                         a.CurrentTotalPower,
+		10:                        a.OnMinerExit,
 	}
 }
 
@@ -55,6 +54,7 @@ var _ abi.Invokee = Actor{}
 type MinerConstructorParams struct {
 	OwnerAddr     addr.Address
 	WorkerAddr    addr.Address
+	ControlAddrs  []addr.Address
 	SealProofType abi.RegisteredSealProof
 	PeerId        abi.PeerID
 	Multiaddrs    []abi.Multiaddrs
@@ -278,18 +278,10 @@ func (a Actor) OnConsensusFault(rt Runtime, pledgeAmount *abi.TokenAmount) *adt.
 		if !powerOk {
 			rt.Abortf(exitcode.ErrIllegalArgument, "miner %v not registered (already slashed?)", minerAddr)
 		}
-		Assert(claim.RawBytePower.GreaterThanEqual(big.Zero()))
-		Assert(claim.QualityAdjPower.GreaterThanEqual(big.Zero()))
-		err = st.addToClaim(claims, minerAddr, claim.RawBytePower.Neg(), claim.QualityAdjPower.Neg())
-		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "could not add to claim for %s after loading existing claim for this address", minerAddr)
-
-		st.addPledgeTotal(pledgeAmount.Neg())
-
-		// delete miner actor claims
-		err = claims.Delete(AddrKey(minerAddr))
+		err = st.deleteClaim(claims, minerAddr, claim)
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove miner %v", minerAddr)
 
-		st.MinerCount -= 1
+		st.addPledgeTotal(pledgeAmount.Neg())
 
 		st.Claims, err = claims.Root()
 		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush claims")
@@ -300,6 +292,34 @@ func (a Actor) OnConsensusFault(rt Runtime, pledgeAmount *abi.TokenAmount) *adt.
 	return nil
 }
 
+// Removes the claim of a miner that is exiting. The miner is expected to have already removed its power
+// and pledge by terminating its sectors, but any power remaining in the claim is also removed.
+// Cron events the miner has enrolled are left in the queue, and dropped when their callbacks fail.
+func (a Actor) OnMinerExit(rt Runtime, _ *adt.EmptyValue) *adt.EmptyValue {
+	rt.ValidateImmediateCallerType(builtin.StorageMinerActorCodeID)
+	minerAddr := rt.Message().Caller()
+
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		claims, err := adt.AsMap(adt.AsStore(rt), st.Claims)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to load claims")
+
+		claim, found, err := getClaim(claims, minerAddr)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to read claim for %v", minerAddr)
+		if !found {
+			rt.Abortf(exitcode.ErrIllegalArgument, "miner %v not registered", minerAddr)
+		}
+
+		err = st.deleteClaim(claims, minerAddr, claim)
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to remove miner %v", minerAddr)
+
+		st.Claims, err = claims.Root()
+		builtin.RequireNoErr(rt, err, exitcode.ErrIllegalState, "failed to flush claims")
+		return nil
+	})
+	return nil
+}
+
 // GasOnSubmitVerifySeal is amount of gas charged for SubmitPoRepForBulkVerify
 // This number is empirically determined
 const GasOnSubmitVerifySeal = 132166313
@@ -176,6 +176,21 @@ func (st *State) addToClaim(claims *adt.Map, miner addr.Address, power abi.Stora
 	return setClaim(claims, miner, &newClaim)
 }
 
+// Removes a miner's claim, first deducting any remaining power from the totals.
+func (st *State) deleteClaim(claims *adt.Map, miner addr.Address, claim *Claim) error {
+	Assert(claim.RawBytePower.GreaterThanEqual(big.Zero()))
+	Assert(claim.QualityAdjPower.GreaterThanEqual(big.Zero()))
+	if err := st.addToClaim(claims, miner, claim.RawBytePower.Neg(), claim.QualityAdjPower.Neg()); err != nil {
+		return xerrors.Errorf("failed to remove power from claim: %w", err)
+	}
+
+	if err := claims.Delete(AddrKey(miner)); err != nil {
+		return xerrors.Errorf("failed to delete claim: %w", err)
+	}
+	st.MinerCount -= 1
+	return nil
+}
+
 func getClaim(claims *adt.Map, a addr.Address) (*Claim, bool, error) {
 	var out Claim
 	found, err := claims.Get(AddrKey(a), &out)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 
 	addr "github.com/filecoin-project/go-address"
@@ -69,6 +70,7 @@ func TestConstruction(t *testing.T) {
 		assert.Equal(t, power.Claim{big.Zero(), big.Zero()}, actualClaim) // miner has not proven anything
 
 		verifyEmptyMap(t, rt, st.CronEventQueue)
+		actor.checkState(rt)
 	})
 }
 
@@ -195,6 +197,7 @@ func TestEnrollCronEpoch(t *testing.T) {
 		evt = events[0]
 		require.EqualValues(t, p3, evt.CallbackPayload)
 		require.EqualValues(t, miner2, evt.MinerAddr)
+		ac.checkState(rt)
 	})
 
 	t.Run("enroll for an epoch before the current epoch", func(t *testing.T) {
@@ -289,6 +292,7 @@ func TestOnConsensusFault(t *testing.T) {
 		require.True(t, st.TotalQABytesCommitted.IsZero())
 		require.True(t, st.TotalBytesCommitted.IsZero())
 		require.EqualValues(t, big.Sub(delta, slash), st.TotalPledgeCollateral)
+		ac.checkState(rt)
 	})
 
 	t.Run("fails if total pledged amount goes below zero after fault", func(t *testing.T) {
@@ -326,6 +330,82 @@ func TestOnConsensusFault(t *testing.T) {
 	})
 }
 
+func TestOnMinerExit(t *testing.T) {
+	miner := tutil.NewIDAddr(t, 101)
+	owner := tutil.NewIDAddr(t, 102)
+	powerUnit := power.ConsensusMinerMinPower
+
+	t.Run("removes claim without power", func(t *testing.T) {
+		rt, ac := basicPowerSetup(t)
+		ac.createMinerBasic(rt, owner, owner, miner)
+		ac.updatePledgeTotal(rt, miner, abi.NewTokenAmount(100))
+
+		ac.onMinerExit(rt, miner)
+
+		// Pledge is released by the miner separately.
+		st := getState(rt)
+		require.EqualValues(t, abi.NewTokenAmount(100), st.TotalPledgeCollateral)
+		ac.checkState(rt)
+	})
+
+	t.Run("removes remaining power", func(t *testing.T) {
+		rt, ac := basicPowerSetup(t)
+		ac.createMinerBasic(rt, owner, owner, miner)
+		ac.updateClaimedPower(rt, miner, powerUnit, powerUnit)
+
+		ac.onMinerExit(rt, miner)
+
+		st := getState(rt)
+		require.True(t, st.TotalRawBytePower.IsZero())
+		require.True(t, st.TotalQualityAdjPower.IsZero())
+		require.EqualValues(t, 0, st.MinerAboveMinPowerCount)
+		require.True(t, st.TotalBytesCommitted.IsZero())
+		require.True(t, st.TotalQABytesCommitted.IsZero())
+		ac.checkState(rt)
+	})
+
+	t.Run("drops cron events of exited miner when they fire", func(t *testing.T) {
+		rt, ac := basicPowerSetup(t)
+		miner2 := tutil.NewIDAddr(t, 103)
+		ac.createMinerBasic(rt, owner, owner, miner)
+		ac.createMinerBasic(rt, owner, owner, miner2)
+		ac.enrollCronEvent(rt, miner, 2, []byte("a"))
+		ac.enrollCronEvent(rt, miner2, 2, []byte("b"))
+
+		ac.onMinerExit(rt, miner)
+
+		// The exited miner's callback fails, but the other miner's callback is still invoked.
+		expectedPower := big.NewInt(0)
+		rt.SetEpoch(2)
+		rt.ExpectValidateCallerAddr(builtin.CronActorAddr)
+		rt.ExpectSend(miner, builtin.MethodsMiner.OnDeferredCronEvent, vmr.CBORBytes([]byte("a")), big.Zero(), nil, exitcode.SysErrInvalidReceiver)
+		rt.ExpectSend(miner2, builtin.MethodsMiner.OnDeferredCronEvent, vmr.CBORBytes([]byte("b")), big.Zero(), nil, exitcode.Ok)
+		rt.ExpectSend(builtin.RewardActorAddr, builtin.MethodsReward.UpdateNetworkKPI, &expectedPower, big.Zero(), nil, exitcode.Ok)
+		rt.SetCaller(builtin.CronActorAddr, builtin.CronActorCodeID)
+		rt.Call(ac.Actor.OnEpochTickEnd, nil)
+		rt.Verify()
+
+		st := getState(rt)
+		mmap, err := adt.AsMultimap(adt.AsStore(rt), st.CronEventQueue)
+		require.NoError(t, err)
+		_, found, err := mmap.Get(adt.IntKey(2))
+		require.NoError(t, err)
+		require.False(t, found)
+		ac.checkState(rt)
+	})
+
+	t.Run("fails if claim does not exist for caller", func(t *testing.T) {
+		rt, ac := basicPowerSetup(t)
+
+		rt.SetCaller(miner, builtin.StorageMinerActorCodeID)
+		rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
+		rt.ExpectAbort(exitcode.ErrIllegalArgument, func() {
+			rt.Call(ac.OnMinerExit, nil)
+		})
+		rt.Verify()
+	})
+}
+
 func TestPowerAndPledgeAccounting(t *testing.T) {
 	actor := newHarness(t)
 	owner := tutil.NewIDAddr(t, 101)
@@ -398,6 +478,7 @@ func TestPowerAndPledgeAccounting(t *testing.T) {
 		claim2 = actor.getClaim(rt, miner2)
 		require.Equal(t, big.Zero(), claim2.RawBytePower)
 		require.Equal(t, big.Zero(), claim2.QualityAdjPower)
+		actor.checkState(rt)
 	})
 
 	t.Run("power accounting crossing threshold", func(t *testing.T) {
@@ -434,6 +515,7 @@ func TestPowerAndPledgeAccounting(t *testing.T) {
 
 		actor.updateClaimedPower(rt, miner3, div(delta.Neg(), 2), delta.Neg())
 		actor.expectTotalPowerEager(rt, div(expectedTotalBelow, 2), expectedTotalBelow)
+		actor.checkState(rt)
 	})
 
 	t.Run("all of one miner's power disappears when that miner dips below min power threshold", func(t *testing.T) {
@@ -459,6 +541,7 @@ func TestPowerAndPledgeAccounting(t *testing.T) {
 
 		expectedTotal = mul(powerUnit, 3)
 		actor.expectTotalPowerEager(rt, expectedTotal, expectedTotal)
+		actor.checkState(rt)
 	})
 
 	t.Run("threshold only depends on qa power, not raw byte", func(t *testing.T) {
@@ -551,6 +634,7 @@ func TestPowerAndPledgeAccounting(t *testing.T) {
 
 		// power of the fourth miner is removed
 		actor.expectTotalPowerEager(rt, mul(powerUnit, 3), mul(powerUnit, 3))
+		actor.checkState(rt)
 	})
 }
 
@@ -637,6 +721,7 @@ func TestCron(t *testing.T) {
 			return nil
 		})
 		require.NoError(t, err)
+		actor.checkState(rt)
 	})
 
 	t.Run("fails to enroll if epoch is negative", func(t *testing.T) {
@@ -692,6 +777,7 @@ func TestCron(t *testing.T) {
 		rt.SetCaller(builtin.CronActorAddr, builtin.CronActorCodeID)
 		rt.Call(actor.Actor.OnEpochTickEnd, nil)
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -985,6 +1071,23 @@ func (h *spActorHarness) onConsensusFault(rt *mock.Runtime, minerAddr addr.Addre
 	require.EqualValues(h.t, big.Sub(prevPledged, *pledgeAmount), st.TotalPledgeCollateral)
 }
 
+func (h *spActorHarness) onMinerExit(rt *mock.Runtime, minerAddr addr.Address) {
+	prevMinerCount := getState(rt).MinerCount
+
+	rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
+	rt.SetCaller(minerAddr, builtin.StorageMinerActorCodeID)
+	rt.Call(h.Actor.OnMinerExit, nil)
+	rt.Verify()
+
+	st := getState(rt)
+	claims, err := adt.AsMap(adt.AsStore(rt), st.Claims)
+	require.NoError(h.t, err)
+	found, err := claims.Get(power.AddrKey(minerAddr), nil)
+	require.NoError(h.t, err)
+	require.False(h.t, found)
+	require.EqualValues(h.t, prevMinerCount-1, st.MinerCount)
+}
+
 func (h *spActorHarness) submitPoRepForBulkVerify(rt *mock.Runtime, minerAddr addr.Address, sealInfo *abi.SealVerifyInfo) {
 	rt.ExpectValidateCallerType(builtin.StorageMinerActorCodeID)
 	rt.SetCaller(minerAddr, builtin.StorageMinerActorCodeID)
@@ -1023,6 +1126,12 @@ func (s key) Key() string {
 	return string(s)
 }
 
+func (h *spActorHarness) checkState(rt *mock.Runtime) {
+	st := getState(rt)
+	_, msgs := power.CheckStateInvariants(st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func getState(rt *mock.Runtime) *power.State {
 	var st power.State
 	rt.GetState(&st)
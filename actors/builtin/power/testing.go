@@ -0,0 +1,184 @@
+package power
+
+import (
+	addr "github.com/filecoin-project/go-address"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type MinerCronEvent struct {
+	Epoch   abi.ChainEpoch
+	Payload []byte
+}
+
+type CronEventsByAddress map[addr.Address][]MinerCronEvent
+type ClaimsByAddress map[addr.Address]Claim
+type ProofsByAddress map[addr.Address][]abi.SealVerifyInfo
+
+type StateSummary struct {
+	Crons                 CronEventsByAddress
+	Claims                ClaimsByAddress
+	Proofs                ProofsByAddress
+	TotalPledgeCollateral abi.TokenAmount
+}
+
+// Checks internal invariants of power state.
+func CheckStateInvariants(st *State, store adt.Store, _ abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	// basic invariants around recorded power
+	acc.Require(st.TotalRawBytePower.GreaterThanEqual(big.Zero()), "total raw power is negative %v", st.TotalRawBytePower)
+	acc.Require(st.TotalQualityAdjPower.GreaterThanEqual(st.TotalRawBytePower),
+		"total qa power %v is less than total raw power %v", st.TotalQualityAdjPower, st.TotalRawBytePower)
+	acc.Require(st.TotalBytesCommitted.GreaterThanEqual(st.TotalRawBytePower),
+		"total raw committed %v is less than total raw power %v", st.TotalBytesCommitted, st.TotalRawBytePower)
+	acc.Require(st.TotalQABytesCommitted.GreaterThanEqual(st.TotalQualityAdjPower),
+		"total qa committed %v is less than total qa power %v", st.TotalQABytesCommitted, st.TotalQualityAdjPower)
+	acc.Require(st.TotalPledgeCollateral.GreaterThanEqual(big.Zero()), "total pledge collateral is negative %v", st.TotalPledgeCollateral)
+	acc.Require(st.MinerCount >= 0, "miner count is negative %d", st.MinerCount)
+	acc.Require(st.MinerAboveMinPowerCount >= 0, "miner above min power count is negative %d", st.MinerAboveMinPowerCount)
+	acc.Require(st.MinerAboveMinPowerCount <= st.MinerCount,
+		"miners above min power %d exceeds miner count %d", st.MinerAboveMinPowerCount, st.MinerCount)
+
+	crons := checkCronInvariants(st, store, acc)
+	claims := checkClaimInvariants(st, store, acc)
+	proofs := checkProofValidationInvariants(st, store, claims, acc)
+
+	return &StateSummary{
+		Crons:                 crons,
+		Claims:                claims,
+		Proofs:                proofs,
+		TotalPledgeCollateral: st.TotalPledgeCollateral,
+	}, acc
+}
+
+func checkCronInvariants(st *State, store adt.Store, acc *builtin.MessageAccumulator) CronEventsByAddress {
+	byAddress := make(CronEventsByAddress)
+	queue, err := adt.AsMultimap(store, st.CronEventQueue)
+	if err != nil {
+		acc.Addf("error loading cron event queue: %v", err)
+		return byAddress
+	}
+
+	err = queue.ForAll(func(ekey string, arr *adt.Array) error {
+		epoch, err := adt.ParseIntKey(ekey)
+		acc.Require(err == nil, "non-int key in cron array")
+		if err != nil {
+			return nil // error noted above
+		}
+
+		acc.Require(abi.ChainEpoch(epoch) >= st.FirstCronEpoch, "cron event at epoch %d before FirstCronEpoch %d",
+			epoch, st.FirstCronEpoch)
+
+		var event CronEvent
+		return arr.ForEach(&event, func(i int64) error {
+			byAddress[event.MinerAddr] = append(byAddress[event.MinerAddr], MinerCronEvent{
+				Epoch:   abi.ChainEpoch(epoch),
+				Payload: event.CallbackPayload,
+			})
+
+			return nil
+		})
+	})
+	acc.RequireNoError(err, "error iterating cron tasks")
+	return byAddress
+}
+
+func checkClaimInvariants(st *State, store adt.Store, acc *builtin.MessageAccumulator) ClaimsByAddress {
+	byAddress := make(ClaimsByAddress)
+	claims, err := adt.AsMap(store, st.Claims)
+	if err != nil {
+		acc.Addf("error loading power claims: %v", err)
+		return byAddress
+	}
+
+	committedRawPower := abi.NewStoragePower(0)
+	committedQAPower := abi.NewStoragePower(0)
+	rawPower := abi.NewStoragePower(0)
+	qaPower := abi.NewStoragePower(0)
+	claimsWithSufficientPowerCount := int64(0)
+	var claim Claim
+	err = claims.ForEach(&claim, func(key string) error {
+		a, err := addr.NewFromBytes([]byte(key))
+		if err != nil {
+			return err
+		}
+		byAddress[a] = claim
+
+		acc.Require(claim.RawBytePower.GreaterThanEqual(big.Zero()), "miner %v claimed negative raw power %v", a, claim.RawBytePower)
+		acc.Require(claim.QualityAdjPower.GreaterThanEqual(claim.RawBytePower),
+			"miner %v claimed qa power %v less than raw power %v", a, claim.QualityAdjPower, claim.RawBytePower)
+
+		committedRawPower = big.Add(committedRawPower, claim.RawBytePower)
+		committedQAPower = big.Add(committedQAPower, claim.QualityAdjPower)
+
+		if claim.QualityAdjPower.GreaterThanEqual(ConsensusMinerMinPower) {
+			claimsWithSufficientPowerCount++
+			rawPower = big.Add(rawPower, claim.RawBytePower)
+			qaPower = big.Add(qaPower, claim.QualityAdjPower)
+		}
+		return nil
+	})
+	acc.RequireNoError(err, "error iterating power claims")
+
+	acc.Require(committedRawPower.Equals(st.TotalBytesCommitted),
+		"sum of raw power in claims %v does not match recorded bytes committed %v",
+		committedRawPower, st.TotalBytesCommitted)
+	acc.Require(committedQAPower.Equals(st.TotalQABytesCommitted),
+		"sum of qa power in claims %v does not match recorded qa power %v",
+		committedQAPower, st.TotalQABytesCommitted)
+
+	acc.Require(int64(len(byAddress)) == st.MinerCount,
+		"claim count %d does not match miner count %d", len(byAddress), st.MinerCount)
+	acc.Require(claimsWithSufficientPowerCount == st.MinerAboveMinPowerCount,
+		"claims with sufficient power %d does not match MinerAboveMinPowerCount %d",
+		claimsWithSufficientPowerCount, st.MinerAboveMinPowerCount)
+
+	acc.Require(st.TotalRawBytePower.Equals(rawPower),
+		"recorded raw power %v does not match raw power in claims %v", st.TotalRawBytePower, rawPower)
+	acc.Require(st.TotalQualityAdjPower.Equals(qaPower),
+		"recorded qa power %v does not match qa power in claims %v", st.TotalQualityAdjPower, qaPower)
+
+	return byAddress
+}
+
+func checkProofValidationInvariants(st *State, store adt.Store, claims ClaimsByAddress, acc *builtin.MessageAccumulator) ProofsByAddress {
+	if st.ProofValidationBatch == nil {
+		return nil
+	}
+
+	proofs := make(ProofsByAddress)
+	queue, err := adt.AsMultimap(store, *st.ProofValidationBatch)
+	if err != nil {
+		acc.Addf("error loading proof validation queue: %v", err)
+		return proofs
+	}
+
+	err = queue.ForAll(func(key string, arr *adt.Array) error {
+		a, err := addr.NewFromBytes([]byte(key))
+		if err != nil {
+			return err
+		}
+
+		_, exists := claims[a]
+		acc.Require(exists, "miner %v has proofs awaiting validation but no claim", a)
+
+		var info abi.SealVerifyInfo
+		err = arr.ForEach(&info, func(i int64) error {
+			proofs[a] = append(proofs[a], info)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		acc.Require(len(proofs[a]) <= MaxMinerProveCommitsPerEpoch,
+			"miner %v has submitted too many proofs (%d) for batch verification", a, len(proofs[a]))
+		return nil
+	})
+	acc.RequireNoError(err, "error iterating proof validation queue")
+	return proofs
+}
@@ -2,6 +2,7 @@ package reward_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -34,6 +35,7 @@ func TestConstructor(t *testing.T) {
 		epochZeroBaseline := big.Sub(reward.BaselineInitialValue, big.NewInt(1)) // account for rounding error of one byte during construction
 		assert.Equal(t, epochZeroBaseline, st.ThisEpochBaselinePower)
 		assert.Equal(t, reward.BaselineInitialValue, st.EffectiveBaselinePower)
+		actor.checkState(rt)
 	})
 	t.Run("construct with less power than baseline", func(t *testing.T) {
 		rt := mock.NewBuilder(context.Background(), builtin.RewardActorAddr).
@@ -46,6 +48,7 @@ func TestConstructor(t *testing.T) {
 		assert.Equal(t, startRealizedPower, st.CumsumRealized)
 
 		assert.NotEqual(t, big.Zero(), st.ThisEpochReward)
+		actor.checkState(rt)
 	})
 	t.Run("construct with more power than baseline", func(t *testing.T) {
 		rt := mock.NewBuilder(context.Background(), builtin.RewardActorAddr).
@@ -65,6 +68,7 @@ func TestConstructor(t *testing.T) {
 		newSt := getState(rt)
 		// Reward value is the same; realized power impact on reward is capped at baseline
 		assert.Equal(t, rwrd, newSt.ThisEpochReward)
+		actor.checkState(rt)
 	})
 
 }
@@ -93,6 +97,7 @@ func TestAwardBlockReward(t *testing.T) {
 			})
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 
 	t.Run("pays out current balance when reward exceeds total balance", func(t *testing.T) {
@@ -113,6 +118,7 @@ func TestAwardBlockReward(t *testing.T) {
 			WinCount:  1,
 		})
 		rt.Verify()
+		actor.checkState(rt)
 	})
 }
 
@@ -129,6 +135,13 @@ func (h *rewardHarness) constructAndVerify(rt *mock.Runtime, currRawPower *abi.S
 
 }
 
+func (h *rewardHarness) checkState(rt *mock.Runtime) {
+	var st reward.State
+	rt.GetState(&st)
+	_, msgs := reward.CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func getState(rt *mock.Runtime) *reward.State {
 	var st reward.State
 	rt.GetState(&st)
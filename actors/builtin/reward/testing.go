@@ -0,0 +1,24 @@
+package reward
+
+import (
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type StateSummary struct{}
+
+// Checks internal invariants of reward state.
+func CheckStateInvariants(st *State, _ adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+
+	acc.Require(st.Epoch >= 0, "reward state epoch %d is negative", st.Epoch)
+	acc.Require(st.EffectiveNetworkTime <= st.Epoch+1, "effective network time %d is ahead of epoch %d", st.EffectiveNetworkTime, st.Epoch)
+	acc.Require(st.CumsumRealized.LessThanEqual(st.CumsumBaseline), "cumsum realized %v exceeds cumsum baseline %v", st.CumsumRealized, st.CumsumBaseline)
+	acc.Require(st.ThisEpochReward.GreaterThanEqual(big.Zero()), "negative epoch reward %v", st.ThisEpochReward)
+	acc.Require(st.ThisEpochBaselinePower.GreaterThan(big.Zero()), "non-positive baseline power %v", st.ThisEpochBaselinePower)
+	acc.Require(balance.GreaterThanEqual(big.Zero()), "negative balance %v", balance)
+
+	return &StateSummary{}, acc
+}
@@ -0,0 +1,22 @@
+package verifreg
+
+import (
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Describes the changes from one verified registry state to another.
+func DiffStates(pre, post *State, store adt.Store) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	d.CompareExcept(pre, post, "Verifiers", "VerifiedClients")
+	if err := d.WithField("Verifiers").Map(store, pre.Verifiers, post.Verifiers, builtin.AddrKeyDecoder, newDataCap); err != nil {
+		return d, err
+	}
+	err := d.WithField("VerifiedClients").Map(store, pre.VerifiedClients, post.VerifiedClients, builtin.AddrKeyDecoder, newDataCap)
+	return d, err
+}
+
+func newDataCap() runtime.CBORUnmarshaler {
+	return new(DataCap)
+}
@@ -0,0 +1,66 @@
+package verifreg
+
+import (
+	addr "github.com/filecoin-project/go-address"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	adt "github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+type StateSummary struct {
+	Verifiers map[addr.Address]DataCap
+	Clients   map[addr.Address]DataCap
+}
+
+// Checks internal invariants of verified registry state.
+func CheckStateInvariants(st *State, store adt.Store, balance abi.TokenAmount) (*StateSummary, *builtin.MessageAccumulator) {
+	acc := &builtin.MessageAccumulator{}
+	acc.Require(st.RootKey.Protocol() == addr.ID, "root key %v should have ID protocol", st.RootKey)
+
+	// Check verifiers
+	allVerifiers := map[addr.Address]DataCap{}
+	if verifiers, err := adt.AsMap(store, st.Verifiers); err != nil {
+		acc.Addf("error loading verifiers: %v", err)
+	} else {
+		var vcap abi.StoragePower
+		err = verifiers.ForEach(&vcap, func(key string) error {
+			verifier, err := addr.NewFromBytes([]byte(key))
+			if err != nil {
+				return err
+			}
+			acc.Require(verifier != st.RootKey, "root key %v is a verifier", verifier)
+			acc.Require(vcap.GreaterThanEqual(big.Zero()), "verifier %v cap %v is negative", verifier, vcap)
+			allVerifiers[verifier] = vcap.Copy()
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating verifiers")
+	}
+
+	// Check clients
+	allClients := map[addr.Address]DataCap{}
+	if clients, err := adt.AsMap(store, st.VerifiedClients); err != nil {
+		acc.Addf("error loading clients: %v", err)
+	} else {
+		var ccap abi.StoragePower
+		err = clients.ForEach(&ccap, func(key string) error {
+			client, err := addr.NewFromBytes([]byte(key))
+			if err != nil {
+				return err
+			}
+			acc.Require(client != st.RootKey, "root key %v is a verified client", client)
+			acc.Require(ccap.GreaterThanEqual(MinVerifiedDealSize), "client %v cap %v is below minimum verified deal size", client, ccap)
+			_, isVerifier := allVerifiers[client]
+			acc.Require(!isVerifier, "client %v is also a verifier", client)
+			allClients[client] = ccap.Copy()
+			return nil
+		})
+		acc.RequireNoError(err, "error iterating clients")
+	}
+
+	return &StateSummary{
+		Verifiers: allVerifiers,
+		Clients:   allClients,
+	}, acc
+}
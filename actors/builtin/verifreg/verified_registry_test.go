@@ -2,6 +2,7 @@ package verifreg_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/filecoin-project/go-address"
@@ -12,6 +13,7 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	"github.com/filecoin-project/specs-actors/support/mock"
 	tutil "github.com/filecoin-project/specs-actors/support/testing"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -677,6 +679,13 @@ func (h *verifRegActorTestHarness) constructAndVerify(rt *mock.Runtime) {
 	rt.Verify()
 }
 
+func (h *verifRegActorTestHarness) checkState(rt *mock.Runtime) {
+	var st verifreg.State
+	rt.GetState(&st)
+	_, msgs := verifreg.CheckStateInvariants(&st, rt.AdtStore(), rt.Balance())
+	assert.True(h.t, msgs.IsEmpty(), strings.Join(msgs.Messages(), "\n"))
+}
+
 func (h *verifRegActorTestHarness) mkVerifierParams(a address.Address, allowance verifreg.DataCap) *verifreg.AddVerifierParams {
 	return &verifreg.AddVerifierParams{Address: a, Allowance: allowance}
 }
@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"fmt"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	crypto "github.com/filecoin-project/specs-actors/actors/crypto"
+)
+
+// GasCharge is an amount of gas charged for an operation, split into computation and storage components.
+type GasCharge struct {
+	Name       string
+	ComputeGas int64
+	StorageGas int64
+
+	// Virtual amounts are not counted toward execution cost. See Runtime.ChargeGas.
+	VirtualCompute int64
+	VirtualStorage int64
+}
+
+func NewGasCharge(name string, computeGas int64, storageGas int64) GasCharge {
+	return GasCharge{
+		Name:       name,
+		ComputeGas: computeGas,
+		StorageGas: storageGas,
+	}
+}
+
+// Returns a copy of the charge with virtual amounts added.
+func (g GasCharge) WithVirtual(compute, storage int64) GasCharge {
+	out := g
+	out.VirtualCompute = compute
+	out.VirtualStorage = storage
+	return out
+}
+
+func (g GasCharge) Total() int64 {
+	return g.ComputeGas + g.StorageGas
+}
+
+func (g GasCharge) VirtualTotal() int64 {
+	return g.VirtualCompute + g.VirtualStorage
+}
+
+func (g GasCharge) String() string {
+	return fmt.Sprintf("%s: %d (compute %d, storage %d)", g.Name, g.Total(), g.ComputeGas, g.StorageGas)
+}
+
+// An operation on a HAMT or AMT collection in actor state.
+type CollectionOp int
+
+const (
+	HamtGet CollectionOp = iota
+	HamtPut
+	HamtDelete
+	HamtIterate // Charged per entry visited.
+	AmtGet
+	AmtSet
+	AmtDelete
+	AmtIterate // Charged per entry visited.
+)
+
+func (op CollectionOp) String() string {
+	switch op {
+	case HamtGet:
+		return "HamtGet"
+	case HamtPut:
+		return "HamtPut"
+	case HamtDelete:
+		return "HamtDelete"
+	case HamtIterate:
+		return "HamtIterate"
+	case AmtGet:
+		return "AmtGet"
+	case AmtSet:
+		return "AmtSet"
+	case AmtDelete:
+		return "AmtDelete"
+	case AmtIterate:
+		return "AmtIterate"
+	default:
+		return fmt.Sprintf("CollectionOp(%d)", int(op))
+	}
+}
+
+// Pricelist provides prices for the operations a runtime performs on behalf of actor code.
+// Runtime implementations are expected to charge these amounts as the operations are performed.
+type Pricelist interface {
+	// Charged for sending a message to another actor, whether or not it invokes a method.
+	OnMethodInvocation(value abi.TokenAmount, methodNum abi.MethodNum) GasCharge
+
+	// Charged for loading and storing blocks in the state store, by data size in bytes.
+	OnIpldGet(dataSize int) GasCharge
+	OnIpldPut(dataSize int) GasCharge
+
+	// Charged for operations on HAMT and AMT collections, in addition to the store operations they perform.
+	OnCollectionOp(op CollectionOp) GasCharge
+
+	OnCreateActor() GasCharge
+	OnDeleteActor() GasCharge
+
+	// Syscalls.
+	OnVerifySignature(sigType crypto.SigType, plaintextSize int) GasCharge
+	OnHashing(dataSize int) GasCharge
+	OnComputeUnsealedSectorCid(proofType abi.RegisteredSealProof, pieces []abi.PieceInfo) GasCharge
+	OnVerifySeal(info abi.SealVerifyInfo) GasCharge
+	OnVerifyAggregateSeals(aggregate abi.AggregateSealVerifyProofAndInfos) GasCharge
+	OnVerifyReplicaUpdate(update abi.ReplicaUpdateInfo) GasCharge
+	OnVerifyPost(info abi.WindowPoStVerifyInfo) GasCharge
+	OnVerifyConsensusFault() GasCharge
+}
+
+// CollectionOpCharger may be implemented by a Runtime in order to meter operations on HAMT and AMT collections,
+// which are otherwise visible to the runtime only as the store operations they perform.
+type CollectionOpCharger interface {
+	ChargeCollectionOp(op CollectionOp)
+}
@@ -0,0 +1,181 @@
+package runtime
+
+import (
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	crypto "github.com/filecoin-project/specs-actors/actors/crypto"
+)
+
+// A price with a flat component and a component scaling with some count (bytes, sectors, etc).
+type ScalingCost struct {
+	Flat  int64
+	Scale int64
+}
+
+func (c ScalingCost) Apply(n int) int64 {
+	return c.Flat + c.Scale*int64(n)
+}
+
+// PricelistV0 is a Pricelist with a fixed price for each operation.
+// Storage prices are expressed in bytes and multiplied by StorageGasMulti.
+type PricelistV0 struct {
+	ComputeGasMulti int64
+	StorageGasMulti int64
+
+	// Charged for every send. Transferring funds and invoking a method (i.e. a non-zero method number) add to this.
+	SendBase          int64
+	SendTransferFunds int64
+	SendInvokeMethod  int64
+
+	IpldGetBase    int64
+	IpldPutBase    int64
+	IpldPutPerByte int64 // Storage cost.
+
+	// Compute cost of each operation on a collection, in addition to the store operations.
+	CollectionOps map[CollectionOp]int64
+
+	CreateActorCompute int64
+	CreateActorStorage int64 // Bytes of a new actor record.
+	DeleteActor        int64 // Bytes of the actor record released, refunded as negative storage.
+
+	VerifySignature              map[crypto.SigType]ScalingCost // Scaling by plaintext bytes.
+	Hashing                      ScalingCost                    // Scaling by data bytes.
+	ComputeUnsealedSectorCidBase int64
+	VerifySealBase               int64
+	VerifyAggregateSeal          map[abi.RegisteredSealProof]ScalingCost // Scaling by aggregated seals.
+	VerifyReplicaUpdate          int64
+	VerifyPost                   map[abi.RegisteredPoStProof]ScalingCost // Scaling by challenged sectors.
+	VerifyConsensusFault         int64
+}
+
+var _ Pricelist = (*PricelistV0)(nil)
+
+// DefaultPricelist prices operations at the values measured for the first network version.
+// Collection operation prices are nominal, pending benchmarks of the HAMT and AMT implementations.
+var DefaultPricelist = &PricelistV0{
+	ComputeGasMulti: 1,
+	StorageGasMulti: 1000,
+
+	SendBase:          29233,
+	SendTransferFunds: 27500,
+	SendInvokeMethod:  -5377,
+
+	IpldGetBase:    75242,
+	IpldPutBase:    84070,
+	IpldPutPerByte: 1,
+
+	CollectionOps: map[CollectionOp]int64{
+		HamtGet:     5000,
+		HamtPut:     10000,
+		HamtDelete:  10000,
+		HamtIterate: 1000,
+		AmtGet:      3000,
+		AmtSet:      6000,
+		AmtDelete:   6000,
+		AmtIterate:  600,
+	},
+
+	CreateActorCompute: 1108454,
+	CreateActorStorage: 36 + 40,
+	DeleteActor:        -(36 + 40),
+
+	VerifySignature: map[crypto.SigType]ScalingCost{
+		crypto.SigTypeBLS:       {Flat: 16598605},
+		crypto.SigTypeSecp256k1: {Flat: 1637292},
+	},
+	Hashing:                      ScalingCost{Flat: 31355},
+	ComputeUnsealedSectorCidBase: 98647,
+	VerifySealBase:               2000, // Batch verification is charged separately by the power actor.
+	VerifyAggregateSeal: map[abi.RegisteredSealProof]ScalingCost{
+		abi.RegisteredSealProof_StackedDrg2KiBV1:   {Flat: 103994170, Scale: 449900},
+		abi.RegisteredSealProof_StackedDrg8MiBV1:   {Flat: 103994170, Scale: 449900},
+		abi.RegisteredSealProof_StackedDrg512MiBV1: {Flat: 103994170, Scale: 449900},
+		abi.RegisteredSealProof_StackedDrg32GiBV1:  {Flat: 103994170, Scale: 449900},
+		abi.RegisteredSealProof_StackedDrg64GiBV1:  {Flat: 103994170, Scale: 359272},
+	},
+	VerifyReplicaUpdate: 36316136,
+	VerifyPost: map[abi.RegisteredPoStProof]ScalingCost{
+		abi.RegisteredPoStProof_StackedDrgWindow2KiBV1:   {Flat: 123861062, Scale: 9226981},
+		abi.RegisteredPoStProof_StackedDrgWindow8MiBV1:   {Flat: 123861062, Scale: 9226981},
+		abi.RegisteredPoStProof_StackedDrgWindow512MiBV1: {Flat: 123861062, Scale: 9226981},
+		abi.RegisteredPoStProof_StackedDrgWindow32GiBV1:  {Flat: 123861062, Scale: 9226981},
+		abi.RegisteredPoStProof_StackedDrgWindow64GiBV1:  {Flat: 748593537, Scale: 85639},
+	},
+	VerifyConsensusFault: 495422,
+}
+
+func (pl *PricelistV0) OnMethodInvocation(value abi.TokenAmount, methodNum abi.MethodNum) GasCharge {
+	ret := pl.SendBase
+	if !value.Nil() && value.GreaterThan(big.Zero()) {
+		ret += pl.SendTransferFunds
+	}
+	if methodNum != 0 { // Method 0 is a plain value transfer.
+		ret += pl.SendInvokeMethod
+	}
+	return pl.compute("OnMethodInvocation", ret)
+}
+
+func (pl *PricelistV0) OnIpldGet(_ int) GasCharge {
+	return pl.compute("OnIpldGet", pl.IpldGetBase)
+}
+
+func (pl *PricelistV0) OnIpldPut(dataSize int) GasCharge {
+	return pl.charge("OnIpldPut", pl.IpldPutBase, int64(dataSize)*pl.IpldPutPerByte)
+}
+
+func (pl *PricelistV0) OnCollectionOp(op CollectionOp) GasCharge {
+	return pl.compute("On"+op.String(), pl.CollectionOps[op])
+}
+
+func (pl *PricelistV0) OnCreateActor() GasCharge {
+	return pl.charge("OnCreateActor", pl.CreateActorCompute, pl.CreateActorStorage)
+}
+
+func (pl *PricelistV0) OnDeleteActor() GasCharge {
+	return pl.charge("OnDeleteActor", 0, pl.DeleteActor)
+}
+
+func (pl *PricelistV0) OnVerifySignature(sigType crypto.SigType, plaintextSize int) GasCharge {
+	return pl.compute("OnVerifySignature", pl.VerifySignature[sigType].Apply(plaintextSize))
+}
+
+func (pl *PricelistV0) OnHashing(dataSize int) GasCharge {
+	return pl.compute("OnHashing", pl.Hashing.Apply(dataSize))
+}
+
+func (pl *PricelistV0) OnComputeUnsealedSectorCid(_ abi.RegisteredSealProof, _ []abi.PieceInfo) GasCharge {
+	return pl.compute("OnComputeUnsealedSectorCid", pl.ComputeUnsealedSectorCidBase)
+}
+
+func (pl *PricelistV0) OnVerifySeal(_ abi.SealVerifyInfo) GasCharge {
+	return pl.compute("OnVerifySeal", pl.VerifySealBase)
+}
+
+func (pl *PricelistV0) OnVerifyAggregateSeals(aggregate abi.AggregateSealVerifyProofAndInfos) GasCharge {
+	return pl.compute("OnVerifyAggregateSeals", pl.VerifyAggregateSeal[aggregate.SealProof].Apply(len(aggregate.Infos)))
+}
+
+func (pl *PricelistV0) OnVerifyReplicaUpdate(_ abi.ReplicaUpdateInfo) GasCharge {
+	return pl.compute("OnVerifyReplicaUpdate", pl.VerifyReplicaUpdate)
+}
+
+func (pl *PricelistV0) OnVerifyPost(info abi.WindowPoStVerifyInfo) GasCharge {
+	// All proofs in a verification are expected to have the same type.
+	var cost ScalingCost
+	if len(info.Proofs) > 0 {
+		cost = pl.VerifyPost[info.Proofs[0].PoStProof]
+	}
+	return pl.compute("OnVerifyPost", cost.Apply(len(info.ChallengedSectors)))
+}
+
+func (pl *PricelistV0) OnVerifyConsensusFault() GasCharge {
+	return pl.compute("OnVerifyConsensusFault", pl.VerifyConsensusFault)
+}
+
+func (pl *PricelistV0) compute(name string, compute int64) GasCharge {
+	return pl.charge(name, compute, 0)
+}
+
+func (pl *PricelistV0) charge(name string, compute, storageBytes int64) GasCharge {
+	return NewGasCharge(name, compute*pl.ComputeGasMulti, storageBytes*pl.StorageGasMulti)
+}
@@ -0,0 +1,312 @@
+package states
+
+import (
+	"bytes"
+	"sort"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+)
+
+// Report is the result of checking the invariants of a whole state tree.
+// It carries the summary computed for each actor alongside any invariant violations.
+type Report struct {
+	// Number of actors of each type, keyed by code CID.
+	ActorCounts map[cid.Cid]int
+	// Sum of the balances of all actors.
+	TotalBalance abi.TokenAmount
+
+	Init            *init_.StateSummary
+	Reward          *reward.StateSummary
+	Power           *power.StateSummary
+	Market          *market.StateSummary
+	Verifreg        *verifreg.StateSummary
+	Miners          map[addr.Address]*MinerReport
+	Multisigs       map[addr.Address]*multisig.StateSummary
+	PaymentChannels map[addr.Address]*paych.StateSummary
+
+	// Violated invariants. Messages about a single actor are prefixed with its address.
+	Messages *builtin.MessageAccumulator
+}
+
+// MinerReport is the summary of a single miner actor's state.
+type MinerReport struct {
+	*miner.StateSummary
+	InitialPledgeRequirement abi.TokenAmount
+	LockedFunds              abi.TokenAmount
+}
+
+// OK returns whether no invariant was violated.
+func (r *Report) OK() bool {
+	return r.Messages.IsEmpty()
+}
+
+// CheckStateInvariants checks the invariants of each actor in the tree, then the invariants that
+// relate the states of different actors.
+// An error is returned only if the tree itself cannot be traversed; violations are recorded in the report.
+func CheckStateInvariants(tree *Tree) (*Report, error) {
+	acc := &builtin.MessageAccumulator{}
+	report := &Report{
+		ActorCounts:     map[cid.Cid]int{},
+		TotalBalance:    big.Zero(),
+		Miners:          map[addr.Address]*MinerReport{},
+		Multisigs:       map[addr.Address]*multisig.StateSummary{},
+		PaymentChannels: map[addr.Address]*paych.StateSummary{},
+		Messages:        acc,
+	}
+	actorCodes := map[addr.Address]cid.Cid{}
+
+	if err := tree.ForEach(func(key addr.Address, act *Actor) error {
+		acc := acc.WithPrefix("%v ", key) // Intentional shadow here and below.
+
+		if key.Protocol() != addr.ID {
+			acc.Add("actor address is not an ID address")
+		}
+		if act.Balance.LessThan(big.Zero()) {
+			acc.Addf("negative balance %v", act.Balance)
+		}
+		report.TotalBalance = big.Add(report.TotalBalance, act.Balance)
+		report.ActorCounts[act.Code]++
+		actorCodes[key] = act.Code
+
+		switch act.Code {
+		case builtin.SystemActorCodeID, builtin.CronActorCodeID:
+			// No state invariants.
+		case builtin.AccountActorCodeID:
+			var st account.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			// The burnt funds actor is an account without a key.
+			acc.Require(key == builtin.BurntFundsActorAddr || st.Address.Protocol() != addr.ID,
+				"account address %v is an ID address", st.Address)
+		case builtin.InitActorCodeID:
+			var st init_.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := init_.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("init: ").AddAll(msgs)
+			report.Init = summary
+		case builtin.RewardActorCodeID:
+			var st reward.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := reward.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("reward: ").AddAll(msgs)
+			report.Reward = summary
+		case builtin.StoragePowerActorCodeID:
+			var st power.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := power.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("power: ").AddAll(msgs)
+			report.Power = summary
+		case builtin.StorageMarketActorCodeID:
+			var st market.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := market.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("market: ").AddAll(msgs)
+			report.Market = summary
+		case builtin.VerifiedRegistryActorCodeID:
+			var st verifreg.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := verifreg.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("verifreg: ").AddAll(msgs)
+			report.Verifreg = summary
+		case builtin.StorageMinerActorCodeID:
+			var st miner.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := miner.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("miner: ").AddAll(msgs)
+			report.Miners[key] = &MinerReport{
+				StateSummary:             summary,
+				InitialPledgeRequirement: st.InitialPledgeRequirement,
+				LockedFunds:              st.LockedFunds,
+			}
+		case builtin.MultisigActorCodeID:
+			var st multisig.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := multisig.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("multisig: ").AddAll(msgs)
+			report.Multisigs[key] = summary
+		case builtin.PaymentChannelActorCodeID:
+			var st paych.State
+			if err := tree.LoadActorState(act, &st); err != nil {
+				return err
+			}
+			summary, msgs := paych.CheckStateInvariants(&st, tree.Store, act.Balance)
+			acc.WithPrefix("paych: ").AddAll(msgs)
+			report.PaymentChannels[key] = summary
+		default:
+			acc.Addf("unexpected actor code CID %v", act.Code)
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to traverse state tree")
+	}
+
+	acc.Require(report.TotalBalance.LessThanEqual(abi.TotalFilecoin),
+		"total balance %v exceeds total filecoin %v", report.TotalBalance, abi.TotalFilecoin)
+
+	for _, singleton := range []addr.Address{
+		builtin.SystemActorAddr,
+		builtin.InitActorAddr,
+		builtin.RewardActorAddr,
+		builtin.CronActorAddr,
+		builtin.StoragePowerActorAddr,
+		builtin.StorageMarketActorAddr,
+		builtin.VerifiedRegistryActorAddr,
+		builtin.BurntFundsActorAddr,
+	} {
+		_, found := actorCodes[singleton]
+		acc.Require(found, "singleton actor %v is missing", singleton)
+	}
+
+	if report.Power != nil {
+		CheckMinersAgainstPower(report, actorCodes, acc)
+	}
+	if report.Market != nil {
+		CheckDealsAgainstMiners(report, actorCodes, acc)
+	}
+	CheckPaymentChannelParties(report, actorCodes, acc)
+
+	return report, nil
+}
+
+// CheckMinersAgainstPower checks that the power actor's claims, cron events and pledge total agree with the
+// states of the miner actors.
+func CheckMinersAgainstPower(report *Report, actorCodes map[addr.Address]cid.Cid, acc *builtin.MessageAccumulator) {
+	totalPledge := big.Zero()
+	for _, maddr := range sortedMinerAddrs(report.Miners) {
+		minerReport := report.Miners[maddr]
+		acc := acc.WithPrefix("miner %v: ", maddr)
+
+		// The miner reports changes to both its initial pledge requirement and its vesting funds to the power actor.
+		totalPledge = big.Sum(totalPledge, minerReport.InitialPledgeRequirement, minerReport.LockedFunds)
+
+		claim, found := report.Power.Claims[maddr]
+		if !found {
+			acc.Add("miner has no power claim")
+			continue
+		}
+		acc.Require(claim.RawBytePower.Equals(minerReport.ActivePower.Raw),
+			"power claim raw %v does not match active raw power %v", claim.RawBytePower, minerReport.ActivePower.Raw)
+		acc.Require(claim.QualityAdjPower.Equals(minerReport.ActivePower.QA),
+			"power claim qa %v does not match active qa power %v", claim.QualityAdjPower, minerReport.ActivePower.QA)
+	}
+
+	var claimAddrs, cronAddrs []addr.Address
+	for maddr := range report.Power.Claims { //nolint:nomaprange
+		claimAddrs = append(claimAddrs, maddr)
+	}
+	for maddr := range report.Power.Crons { //nolint:nomaprange
+		cronAddrs = append(cronAddrs, maddr)
+	}
+	for _, maddr := range sortAddrs(claimAddrs) {
+		_, found := report.Miners[maddr]
+		acc.Require(found, "power claim for %v which is not a miner", maddr)
+	}
+	for _, maddr := range sortAddrs(cronAddrs) {
+		// Events of a miner that has exited remain queued until they fire, so only a live non-miner is invalid.
+		code, live := actorCodes[maddr]
+		acc.Require(!live || code == builtin.StorageMinerActorCodeID, "power cron events for %v which is not a miner", maddr)
+	}
+
+	acc.Require(report.Power.TotalPledgeCollateral.Equals(totalPledge),
+		"power total pledge collateral %v does not match sum of miner pledge and locked funds %v",
+		report.Power.TotalPledgeCollateral, totalPledge)
+}
+
+// CheckDealsAgainstMiners checks that each market deal's provider is a miner and each activated deal is
+// in one of that miner's sectors.
+func CheckDealsAgainstMiners(report *Report, actorCodes map[addr.Address]cid.Cid, acc *builtin.MessageAccumulator) {
+	dealIDs := make([]abi.DealID, 0, len(report.Market.Deals))
+	for dealID := range report.Market.Deals { //nolint:nomaprange
+		dealIDs = append(dealIDs, dealID)
+	}
+	sort.Slice(dealIDs, func(i, j int) bool { return dealIDs[i] < dealIDs[j] })
+
+	for _, dealID := range dealIDs {
+		deal := report.Market.Deals[dealID]
+		acc := acc.WithPrefix("deal %d: ", dealID)
+
+		minerReport, found := report.Miners[deal.Provider]
+		if !found {
+			acc.Addf("provider %v is not a miner", deal.Provider)
+			continue
+		}
+		_, found = actorCodes[deal.Client]
+		acc.Require(found, "client %v does not exist", deal.Client)
+
+		// A deal that is activated and not slashed must be in a sector of its provider.
+		if deal.SectorStartEpoch == abi.ChainEpoch(-1) || deal.SlashEpoch != abi.ChainEpoch(-1) {
+			continue
+		}
+		sectorDeal, found := minerReport.Deals[dealID]
+		if !found {
+			acc.Addf("active deal is not in any sector of provider %v", deal.Provider)
+			continue
+		}
+		acc.Require(sectorDeal.SectorStart == deal.SectorStartEpoch,
+			"deal sector start %d does not match sector activation %d", deal.SectorStartEpoch, sectorDeal.SectorStart)
+		acc.Require(deal.EndEpoch <= sectorDeal.SectorExpiration,
+			"deal end %d is after sector expiration %d", deal.EndEpoch, sectorDeal.SectorExpiration)
+	}
+}
+
+// CheckPaymentChannelParties checks that both parties to each payment channel exist.
+func CheckPaymentChannelParties(report *Report, actorCodes map[addr.Address]cid.Cid, acc *builtin.MessageAccumulator) {
+	var chAddrs []addr.Address
+	for chAddr := range report.PaymentChannels { //nolint:nomaprange
+		chAddrs = append(chAddrs, chAddr)
+	}
+	for _, chAddr := range sortAddrs(chAddrs) {
+		summary := report.PaymentChannels[chAddr]
+		acc := acc.WithPrefix("paych %v: ", chAddr)
+		_, found := actorCodes[summary.From]
+		acc.Require(found, "from address %v does not exist", summary.From)
+		_, found = actorCodes[summary.To]
+		acc.Require(found, "to address %v does not exist", summary.To)
+	}
+}
+
+func sortedMinerAddrs(miners map[addr.Address]*MinerReport) []addr.Address {
+	addrs := make([]addr.Address, 0, len(miners))
+	for a := range miners { //nolint:nomaprange
+		addrs = append(addrs, a)
+	}
+	return sortAddrs(addrs)
+}
+
+// Sorts addresses in place by their byte representation (the state tree key order), and returns them.
+func sortAddrs(addrs []addr.Address) []addr.Address {
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i].Bytes(), addrs[j].Bytes()) < 0
+	})
+	return addrs
+}
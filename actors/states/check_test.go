@@ -0,0 +1,164 @@
+package states_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/states"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/genesis"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+	"github.com/filecoin-project/specs-actors/support/vm"
+)
+
+func TestCheckStateInvariants(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("singletons", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assert.True(t, report.OK(), strings.Join(report.Messages.Messages(), "\n"))
+
+		assert.Equal(t, 2, report.ActorCounts[builtin.AccountActorCodeID]) // burnt funds and verifreg root
+		assert.Equal(t, 1, report.ActorCounts[builtin.StoragePowerActorCodeID])
+		totalBalance, err := v.GetTotalActorBalance()
+		require.NoError(t, err)
+		assert.Equal(t, totalBalance, report.TotalBalance)
+		assert.NotNil(t, report.Init)
+		assert.NotNil(t, report.Power)
+		assert.NotNil(t, report.Market)
+		assert.Empty(t, report.Miners)
+	})
+
+	t.Run("miner with power", func(t *testing.T) {
+		v, maddr := genesisWithMiner(ctx, t)
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assert.True(t, report.OK(), strings.Join(report.Messages.Messages(), "\n"))
+
+		require.Contains(t, report.Miners, maddr)
+		assert.False(t, report.Miners[maddr].ActivePower.IsZero())
+		assert.Equal(t, report.Miners[maddr].ActivePower.Raw, report.Power.Claims[maddr].RawBytePower)
+	})
+
+	t.Run("claim disagrees with miner power", func(t *testing.T) {
+		v, maddr := genesisWithMiner(ctx, t)
+		mutatePowerState(t, v, func(st *power.State) {
+			claims, err := adt.AsMap(v.Store(), st.Claims)
+			require.NoError(t, err)
+			var claim power.Claim
+			found, err := claims.Get(adt.AddrKey(maddr), &claim)
+			require.NoError(t, err)
+			require.True(t, found)
+
+			// Adjust the totals along with the claim, so the power actor is internally consistent.
+			claim.RawBytePower = big.Add(claim.RawBytePower, big.NewInt(1))
+			claim.QualityAdjPower = big.Add(claim.QualityAdjPower, big.NewInt(1))
+			st.TotalBytesCommitted = big.Add(st.TotalBytesCommitted, big.NewInt(1))
+			st.TotalQABytesCommitted = big.Add(st.TotalQABytesCommitted, big.NewInt(1))
+			require.NoError(t, claims.Put(adt.AddrKey(maddr), &claim))
+			st.Claims, err = claims.Root()
+			require.NoError(t, err)
+		})
+
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assertMessage(t, report, "power claim raw")
+		assertMessage(t, report, "power claim qa")
+	})
+
+	t.Run("pledge total drifts from miners", func(t *testing.T) {
+		v, _ := genesisWithMiner(ctx, t)
+		mutatePowerState(t, v, func(st *power.State) {
+			st.TotalPledgeCollateral = big.Add(st.TotalPledgeCollateral, big.NewInt(1))
+		})
+
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assertMessage(t, report, "power total pledge collateral")
+	})
+
+	t.Run("unknown actor code", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		code := tutil.MakeCID("not an actor", nil)
+		require.NoError(t, v.SetActor(tutil.NewIDAddr(t, 5000), &states.Actor{Code: code, Head: code, Balance: big.Zero()}))
+
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assertMessage(t, report, "unexpected actor code CID")
+		assert.Equal(t, 1, report.ActorCounts[code])
+	})
+
+	t.Run("balances exceed total filecoin", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		burnt, found, err := v.GetActor(builtin.BurntFundsActorAddr)
+		require.NoError(t, err)
+		require.True(t, found)
+		burnt.Balance = abi.TotalFilecoin
+		require.NoError(t, v.SetActor(builtin.BurntFundsActorAddr, burnt))
+
+		report, err := v.CheckStateInvariants()
+		require.NoError(t, err)
+		assertMessage(t, report, "exceeds total filecoin")
+	})
+}
+
+func genesisWithMiner(ctx context.Context, t *testing.T) (*vm.VM, addr.Address) {
+	store := ipld.NewADTStore(ctx)
+	owner := tutil.NewBLSAddr(t, 1)
+	tmpl := genesis.Template{
+		NetworkName:     "check-test",
+		VerifregRootKey: tutil.NewBLSAddr(t, 2),
+		Accounts: []genesis.Account{
+			{Address: owner, Balance: abi.NewTokenAmount(1_000_000)},
+		},
+		Miners: []genesis.Miner{{
+			Owner:         owner,
+			Worker:        owner,
+			PeerId:        abi.PeerID("peer"),
+			SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+			Balance:       abi.NewTokenAmount(10_000),
+			Sectors: []genesis.PreSealedSector{{
+				SectorNumber: 0,
+				SealedCID:    tutil.MakeCID("0", &miner.SealedCIDPrefix),
+				Expiration:   abi.ChainEpoch(miner.MinSectorExpiration),
+			}},
+		}},
+	}
+	result, err := genesis.Build(ctx, store, &tmpl)
+	require.NoError(t, err)
+	v, err := vm.NewVMAtRoot(ctx, store, result.StateRoot)
+	require.NoError(t, err)
+	return v, result.Miners[0]
+}
+
+func mutatePowerState(t *testing.T, v *vm.VM, f func(st *power.State)) {
+	act, found, err := v.GetActor(builtin.StoragePowerActorAddr)
+	require.NoError(t, err)
+	require.True(t, found)
+	var st power.State
+	vm.GetState(t, v, builtin.StoragePowerActorAddr, &st)
+	f(&st)
+	require.NoError(t, v.SetActorState(builtin.StoragePowerActorAddr, act.Code, act.Balance, &st))
+}
+
+func assertMessage(t *testing.T, report *states.Report, substr string) {
+	for _, msg := range report.Messages.Messages() {
+		if strings.Contains(msg, substr) {
+			return
+		}
+	}
+	assert.Fail(t, "expected message not found", "%q not in %v", substr, report.Messages.Messages())
+}
@@ -0,0 +1,177 @@
+package states
+
+import (
+	"bytes"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/cron"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// DiffTrees describes the changes from one state tree to another.
+// Actors that were added or removed are reported whole. For actors present in both trees, changes to the
+// actor record are reported, and changes in state are described by the diff for the actor's type.
+// Change paths begin with the actor's address, e.g. "t01000.Sectors[17].Expiration".
+// Actors and collection entries with equal CIDs in both trees are not loaded.
+func DiffTrees(pre, post *Tree) (*builtin.DiffAccumulator, error) {
+	d := &builtin.DiffAccumulator{}
+	if err := adt.DiffMaps(pre.Map, post.Map, &treeDiffer{d: d, store: post.Store}); err != nil {
+		return d, errors.Wrap(err, "failed to diff state trees")
+	}
+	return d, nil
+}
+
+// DiffActorStates describes the changes from one state of an actor to another.
+// The actor records must have the same code CID.
+func DiffActorStates(pre, post *Actor, store adt.Store) (*builtin.DiffAccumulator, error) {
+	if !pre.Code.Equals(post.Code) {
+		return nil, errors.Errorf("cannot diff states of different actor types %v and %v", pre.Code, post.Code)
+	}
+	if pre.Head.Equals(post.Head) {
+		return &builtin.DiffAccumulator{}, nil
+	}
+
+	switch pre.Code {
+	case builtin.AccountActorCodeID:
+		d := &builtin.DiffAccumulator{}
+		return d, d.Object(store, pre.Head, post.Head, new(account.State), new(account.State))
+	case builtin.CronActorCodeID:
+		d := &builtin.DiffAccumulator{}
+		return d, d.Object(store, pre.Head, post.Head, new(cron.State), new(cron.State))
+	case builtin.InitActorCodeID:
+		var preSt, postSt init_.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return init_.DiffStates(&preSt, &postSt, store)
+	case builtin.RewardActorCodeID:
+		var preSt, postSt reward.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return reward.DiffStates(&preSt, &postSt), nil
+	case builtin.StoragePowerActorCodeID:
+		var preSt, postSt power.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return power.DiffStates(&preSt, &postSt, store)
+	case builtin.StorageMarketActorCodeID:
+		var preSt, postSt market.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return market.DiffStates(&preSt, &postSt, store)
+	case builtin.VerifiedRegistryActorCodeID:
+		var preSt, postSt verifreg.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return verifreg.DiffStates(&preSt, &postSt, store)
+	case builtin.StorageMinerActorCodeID:
+		var preSt, postSt miner.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return miner.DiffStates(&preSt, &postSt, store)
+	case builtin.MultisigActorCodeID:
+		var preSt, postSt multisig.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return multisig.DiffStates(&preSt, &postSt, store)
+	case builtin.PaymentChannelActorCodeID:
+		var preSt, postSt paych.State
+		if err := loadStates(store, pre, post, &preSt, &postSt); err != nil {
+			return nil, err
+		}
+		return paych.DiffStates(&preSt, &postSt), nil
+	default:
+		// The system actor has no state to describe, and unknown actors can only be compared by head.
+		d := &builtin.DiffAccumulator{}
+		d.WithField("Head").Compare(pre.Head, post.Head)
+		return d, nil
+	}
+}
+
+func loadStates(store adt.Store, pre, post *Actor, preSt, postSt cbg.CBORUnmarshaler) error {
+	if err := store.Get(store.Context(), pre.Head, preSt); err != nil {
+		return errors.Wrapf(err, "failed to load actor state at %v", pre.Head)
+	}
+	if err := store.Get(store.Context(), post.Head, postSt); err != nil {
+		return errors.Wrapf(err, "failed to load actor state at %v", post.Head)
+	}
+	return nil
+}
+
+type treeDiffer struct {
+	d     *builtin.DiffAccumulator
+	store adt.Store
+}
+
+func (t *treeDiffer) Add(key string, val *cbg.Deferred) error {
+	a, act, err := decodeActor(key, val)
+	if err != nil {
+		return err
+	}
+	t.d.WithField(a.String()).Added(*act)
+	return nil
+}
+
+func (t *treeDiffer) Modify(key string, from, to *cbg.Deferred) error {
+	a, pre, err := decodeActor(key, from)
+	if err != nil {
+		return err
+	}
+	_, post, err := decodeActor(key, to)
+	if err != nil {
+		return err
+	}
+	d := t.d.WithField(a.String())
+	if !pre.Code.Equals(post.Code) {
+		// The actor has been replaced, so there's no meaningful diff of its state.
+		d.Compare(pre, post)
+		return nil
+	}
+	d.CompareExcept(pre, post, "Head")
+	stateDiff, err := DiffActorStates(pre, post, t.store)
+	if err != nil {
+		return errors.Wrapf(err, "failed to diff state of actor %v", a)
+	}
+	d.AddAll(stateDiff)
+	return nil
+}
+
+func (t *treeDiffer) Remove(key string, val *cbg.Deferred) error {
+	a, act, err := decodeActor(key, val)
+	if err != nil {
+		return err
+	}
+	t.d.WithField(a.String()).Removed(*act)
+	return nil
+}
+
+func decodeActor(key string, val *cbg.Deferred) (addr.Address, *Actor, error) {
+	a, err := addr.NewFromBytes([]byte(key))
+	if err != nil {
+		return addr.Undef, nil, errors.Wrapf(err, "invalid address key in state tree")
+	}
+	var act Actor
+	if err := act.UnmarshalCBOR(bytes.NewReader(val.Raw)); err != nil {
+		return addr.Undef, nil, errors.Wrapf(err, "failed to decode actor %v", a)
+	}
+	return a, &act, nil
+}
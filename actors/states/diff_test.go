@@ -0,0 +1,143 @@
+package states_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/support/vm"
+)
+
+func TestDiffTrees(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no changes", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		diff, err := v.DiffSince(v.StateRoot())
+		require.NoError(t, err)
+		assert.True(t, diff.IsEmpty(), diff.Strings())
+	})
+
+	t.Run("transfer creates account", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		pre := v.StateRoot()
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(100), 93837778)
+
+		diff, err := v.DiffSince(pre)
+		require.NoError(t, err)
+		changes := changesByPath(diff)
+
+		added, ok := changes[addrs[0].String()]
+		require.True(t, ok, diff.Strings())
+		assert.Equal(t, builtin.Added, added.Kind)
+
+		balance, ok := changes[builtin.RewardActorAddr.String()+".Balance"]
+		require.True(t, ok, diff.Strings())
+		assert.Equal(t, big.Sub(balance.From.(big.Int), abi.NewTokenAmount(100)), balance.To)
+
+		nextID, ok := changes[builtin.InitActorAddr.String()+".NextID"]
+		require.True(t, ok, diff.Strings())
+		expectedID, err := addr.NewIDAddress(uint64(nextID.From.(abi.ActorID)))
+		require.NoError(t, err)
+		assert.Equal(t, expectedID, addrs[0])
+		assertPathPrefix(t, diff, builtin.InitActorAddr.String()+".AddressMap[")
+	})
+
+	t.Run("miner sector modified", func(t *testing.T) {
+		v, maddr := genesisWithMiner(ctx, t)
+		pre := v.StateRoot()
+
+		var st miner.State
+		vm.GetState(t, v, maddr, &st)
+		sector, found, err := st.GetSector(v.Store(), 0)
+		require.NoError(t, err)
+		require.True(t, found)
+		oldExpiration := sector.Expiration
+		sector.Expiration += 1000
+		require.NoError(t, st.PutSectors(v.Store(), sector))
+		act, found, err := v.GetActor(maddr)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.NoError(t, v.SetActorState(maddr, act.Code, act.Balance, &st))
+
+		diff, err := v.DiffSince(pre)
+		require.NoError(t, err)
+		require.Len(t, diff.Changes(), 1, diff.Strings())
+		change := diff.Changes()[0]
+		assert.Equal(t, maddr.String()+".Sectors[0].Expiration", change.Path)
+		assert.Equal(t, oldExpiration, change.From)
+		assert.Equal(t, oldExpiration+1000, change.To)
+		assert.Equal(t, fmt.Sprintf("%v.Sectors[0].Expiration changed %d → %d", maddr, oldExpiration, oldExpiration+1000),
+			diff.Strings()[0])
+	})
+
+	t.Run("miner deadlines advance with cron", func(t *testing.T) {
+		v, maddr := genesisWithMiner(ctx, t)
+		pre := v.StateRoot()
+
+		var st miner.State
+		vm.GetState(t, v, maddr, &st)
+		dlInfo := st.DeadlineInfo(v.GetEpoch())
+		vm.AdvanceToEpochWithCron(t, v, dlInfo.Last())
+
+		diff, err := v.DiffSince(pre)
+		require.NoError(t, err)
+		assertPathPrefix(t, diff, maddr.String()+".CurrentDeadline")
+		assertPathPrefix(t, diff, maddr.String()+".Deadlines[0].Partitions[0].Faults")
+		assertPathPrefix(t, diff, builtin.RewardActorAddr.String()+".Epoch")
+	})
+}
+
+func TestDiffMarketStates(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	var pre market.State
+	vm.GetState(t, v, builtin.StorageMarketActorAddr, &pre)
+
+	setDealState := func(st market.State, dealID abi.DealID, ds *market.DealState) market.State {
+		states, err := market.AsDealStateArray(v.Store(), st.States)
+		require.NoError(t, err)
+		require.NoError(t, states.Set(dealID, ds))
+		st.States, err = states.Root()
+		require.NoError(t, err)
+		return st
+	}
+	pre = setDealState(pre, 5, &market.DealState{SectorStartEpoch: 10, LastUpdatedEpoch: -1, SlashEpoch: -1})
+	post := setDealState(pre, 5, &market.DealState{SectorStartEpoch: 10, LastUpdatedEpoch: -1, SlashEpoch: 200})
+	post = setDealState(post, 6, &market.DealState{SectorStartEpoch: 20, LastUpdatedEpoch: -1, SlashEpoch: -1})
+	post.NextID = 7
+
+	diff, err := market.DiffStates(&pre, &post, v.Store())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"NextID changed 0 → 7",
+		"States[5].SlashEpoch changed -1 → 200",
+		"States[6] added: {SectorStartEpoch:20 LastUpdatedEpoch:-1 SlashEpoch:-1}",
+	}, diff.Strings())
+}
+
+func changesByPath(diff *builtin.DiffAccumulator) map[string]builtin.Change {
+	out := map[string]builtin.Change{}
+	for _, c := range diff.Changes() {
+		out[c.Path] = c
+	}
+	return out
+}
+
+func assertPathPrefix(t *testing.T, diff *builtin.DiffAccumulator, prefix string) {
+	for _, c := range diff.Changes() {
+		if len(c.Path) >= len(prefix) && c.Path[:len(prefix)] == prefix {
+			return
+		}
+	}
+	assert.Fail(t, "expected change not found", "no change at %q in %v", prefix, diff.Strings())
+}
@@ -0,0 +1,529 @@
+package states
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/cron"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/builtin/system"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// The canonical JSON encoding of actor types is:
+// - structs as objects with a member for each exported field, in declaration order
+//   (the fields of embedded structs are inlined, as with encoding/json);
+// - big integers (including token amounts and storage power) as decimal strings;
+// - bitfields as RLE run lists, i.e. the lengths of alternating runs of unset and set bits, starting with unset;
+// - addresses as strings and CIDs as {"/": "<cid>"} objects;
+// - byte slices as base64 strings, and other slices as arrays (with nil slices empty, as in CBOR).
+// When a store is available, the CIDs of known collections and objects in actor state are expanded:
+// - HAMTs as objects keyed by the rendered key, in key order (numerically for integer keys);
+// - AMTs as objects keyed by decimal index, in index order;
+// - sets as arrays of keys, and other objects (e.g. miner info, deadlines) as their encoding.
+
+// EncodeJSON renders a value in canonical JSON, without expanding collection roots.
+// This is suitable for method parameters and return values, and for states when a store is not available.
+func EncodeJSON(v interface{}) ([]byte, error) {
+	e := jsonEncoder{}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// EncodeStateJSON renders a value in canonical JSON, expanding known collection roots from the store.
+func EncodeStateJSON(store adt.Store, v interface{}) ([]byte, error) {
+	e := jsonEncoder{store: store}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// EncodeTreeJSON renders a whole state tree in canonical JSON, as an object keyed by actor address.
+// Each actor's record is rendered with an additional "State" member holding its expanded state.
+func EncodeTreeJSON(tree *Tree) ([]byte, error) {
+	e := jsonEncoder{store: tree.Store}
+	var entries []jsonEntry
+	if err := tree.ForEach(func(a addr.Address, act *Actor) error {
+		members, err := e.encodeFields(reflect.ValueOf(*act))
+		if err != nil {
+			return err
+		}
+		state := jsonEncoder{store: tree.Store}
+		if newState, ok := actorStateTypes[act.Code]; ok {
+			st := newState()
+			if err := tree.LoadActorState(act, st); err != nil {
+				return errors.Wrapf(err, "failed to load state of actor %v", a)
+			}
+			if err := state.encode(reflect.ValueOf(st)); err != nil {
+				return errors.Wrapf(err, "failed to encode state of actor %v", a)
+			}
+		} else {
+			state.buf.WriteString("null")
+		}
+		members = append(members, jsonEntry{name: "State", value: state.buf.Bytes()})
+
+		sub := jsonEncoder{}
+		sub.writeMembers(members)
+		entries = append(entries, jsonEntry{key: a, name: a.String(), value: sub.buf.Bytes()})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	e.writeEntries(entries)
+	return e.buf.Bytes(), nil
+}
+
+// The state type for each actor code.
+var actorStateTypes = map[cid.Cid]func() runtime.CBORUnmarshaler{
+	builtin.SystemActorCodeID:           func() runtime.CBORUnmarshaler { return new(system.State) },
+	builtin.InitActorCodeID:             func() runtime.CBORUnmarshaler { return new(init_.State) },
+	builtin.CronActorCodeID:             func() runtime.CBORUnmarshaler { return new(cron.State) },
+	builtin.AccountActorCodeID:          func() runtime.CBORUnmarshaler { return new(account.State) },
+	builtin.RewardActorCodeID:           func() runtime.CBORUnmarshaler { return new(reward.State) },
+	builtin.StoragePowerActorCodeID:     func() runtime.CBORUnmarshaler { return new(power.State) },
+	builtin.StorageMarketActorCodeID:    func() runtime.CBORUnmarshaler { return new(market.State) },
+	builtin.StorageMinerActorCodeID:     func() runtime.CBORUnmarshaler { return new(miner.State) },
+	builtin.MultisigActorCodeID:         func() runtime.CBORUnmarshaler { return new(multisig.State) },
+	builtin.PaymentChannelActorCodeID:   func() runtime.CBORUnmarshaler { return new(paych.State) },
+	builtin.VerifiedRegistryActorCodeID: func() runtime.CBORUnmarshaler { return new(verifreg.State) },
+}
+
+type collectionKind int
+
+const (
+	objectCollection collectionKind = iota
+	hamtCollection
+	amtCollection
+	setCollection
+)
+
+// Describes the structure referenced by a CID field.
+type collection struct {
+	kind collectionKind
+	// Decodes HAMT and set keys.
+	key builtin.KeyDecoder
+	// Returns a new object or collection element, if the collection's values are not themselves collection roots.
+	value func() runtime.CBORUnmarshaler
+	// The collection whose roots are the values of this one, e.g. for multimaps.
+	nested *collection
+}
+
+func object(value func() runtime.CBORUnmarshaler) *collection {
+	return &collection{kind: objectCollection, value: value}
+}
+
+func hamtOf(key builtin.KeyDecoder, value func() runtime.CBORUnmarshaler) *collection {
+	return &collection{kind: hamtCollection, key: key, value: value}
+}
+
+func amtOf(value func() runtime.CBORUnmarshaler) *collection {
+	return &collection{kind: amtCollection, value: value}
+}
+
+func hamtOfCollections(key builtin.KeyDecoder, nested *collection) *collection {
+	return &collection{kind: hamtCollection, key: key, nested: nested}
+}
+
+func newTokenAmount() runtime.CBORUnmarshaler {
+	return new(abi.TokenAmount)
+}
+
+func newBitField() runtime.CBORUnmarshaler {
+	return new(abi.BitField)
+}
+
+func newDealProposal() runtime.CBORUnmarshaler {
+	return new(market.DealProposal)
+}
+
+// The collections referenced by CID fields of actor state types, by type and field name.
+var stateCollections = map[reflect.Type]map[string]*collection{
+	reflect.TypeOf(init_.State{}): {
+		"AddressMap": hamtOf(builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler { return new(cbg.CborInt) }),
+	},
+	reflect.TypeOf(verifreg.State{}): {
+		"Verifiers":       hamtOf(builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler { return new(verifreg.DataCap) }),
+		"VerifiedClients": hamtOf(builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler { return new(verifreg.DataCap) }),
+	},
+	reflect.TypeOf(multisig.State{}): {
+		"PendingTxns": hamtOf(func(key string) (interface{}, error) {
+			return multisig.ParseTxnIDKey(key)
+		}, func() runtime.CBORUnmarshaler { return new(multisig.Transaction) }),
+	},
+	reflect.TypeOf(power.State{}): {
+		"Claims": hamtOf(builtin.AddrKeyDecoder, func() runtime.CBORUnmarshaler { return new(power.Claim) }),
+		"CronEventQueue": hamtOfCollections(builtin.IntKeyDecoder,
+			amtOf(func() runtime.CBORUnmarshaler { return new(power.CronEvent) })),
+		"ProofValidationBatch": hamtOfCollections(builtin.AddrKeyDecoder,
+			amtOf(func() runtime.CBORUnmarshaler { return new(abi.SealVerifyInfo) })),
+	},
+	reflect.TypeOf(market.State{}): {
+		"Proposals":        amtOf(newDealProposal),
+		"States":           amtOf(func() runtime.CBORUnmarshaler { return new(market.DealState) }),
+		"PendingProposals": hamtOf(builtin.CidKeyDecoder, newDealProposal),
+		"EscrowTable":      hamtOf(builtin.AddrKeyDecoder, newTokenAmount),
+		"LockedTable":      hamtOf(builtin.AddrKeyDecoder, newTokenAmount),
+		"DealOpsByEpoch": hamtOfCollections(builtin.UIntKeyDecoder,
+			&collection{kind: setCollection, key: builtin.UIntKeyDecoder}),
+	},
+	reflect.TypeOf(miner.State{}): {
+		"Info":                object(func() runtime.CBORUnmarshaler { return new(miner.MinerInfo) }),
+		"VestingFunds":        amtOf(newTokenAmount),
+		"PreCommittedSectors": hamtOf(builtin.UIntKeyDecoder, func() runtime.CBORUnmarshaler { return new(miner.SectorPreCommitOnChainInfo) }),
+		"Sectors":             amtOf(func() runtime.CBORUnmarshaler { return new(miner.SectorOnChainInfo) }),
+		"Deadlines":           object(func() runtime.CBORUnmarshaler { return new(miner.Deadlines) }),
+	},
+	reflect.TypeOf(miner.Deadlines{}): {
+		"Due": object(func() runtime.CBORUnmarshaler { return new(miner.Deadline) }),
+	},
+	reflect.TypeOf(miner.Deadline{}): {
+		"Partitions":        amtOf(func() runtime.CBORUnmarshaler { return new(miner.Partition) }),
+		"ExpirationsEpochs": amtOf(newBitField),
+	},
+	reflect.TypeOf(miner.Partition{}): {
+		"ExpirationsEpochs": amtOf(func() runtime.CBORUnmarshaler { return new(miner.ExpirationSet) }),
+		"EarlyTerminated":   amtOf(newBitField),
+	},
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bitFieldType = reflect.TypeOf(bitfield.BitField{})
+	cidType      = reflect.TypeOf(cid.Cid{})
+	addressType  = reflect.TypeOf(addr.Address{})
+)
+
+type jsonEncoder struct {
+	// Store from which to expand collections, or nil.
+	store adt.Store
+	buf   bytes.Buffer
+}
+
+// A rendered member of a JSON object, with the key by which it is sorted.
+type jsonEntry struct {
+	key   interface{}
+	name  string
+	value []byte
+}
+
+func (e *jsonEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+	switch v.Type() {
+	case bigIntType:
+		i := v.Interface().(big.Int)
+		if i.Nil() {
+			return e.writeJSON("0")
+		}
+		return e.writeJSON(i.String())
+	case bitFieldType:
+		bf := v.Interface().(bitfield.BitField)
+		b, err := bf.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		e.buf.Write(b)
+		return nil
+	case cidType:
+		c := v.Interface().(cid.Cid)
+		if !c.Defined() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.writeJSON(map[string]string{"/": c.String()})
+	case addressType:
+		a := v.Interface().(addr.Address)
+		if a == addr.Undef {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.writeJSON(a.String())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		members, err := e.encodeFields(v)
+		if err != nil {
+			return err
+		}
+		e.writeMembers(members)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() == 0 {
+				return e.writeJSON("")
+			}
+			return e.writeJSON(v.Interface())
+		}
+		e.buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteString(",")
+			}
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteString("]")
+	case reflect.Map:
+		var entries []jsonEntry
+		iter := v.MapRange()
+		for iter.Next() {
+			sub := jsonEncoder{store: e.store}
+			if err := sub.encode(iter.Value()); err != nil {
+				return err
+			}
+			entries = append(entries, jsonEntry{key: iter.Key().Interface(), name: fmt.Sprint(iter.Key().Interface()), value: sub.buf.Bytes()})
+		}
+		e.writeEntries(entries)
+	default:
+		return e.writeJSON(v.Interface())
+	}
+	return nil
+}
+
+// Renders the members for the exported fields of a struct, in order, expanding those with known collections.
+func (e *jsonEncoder) encodeFields(v reflect.Value) ([]jsonEntry, error) {
+	collections := stateCollections[v.Type()]
+	var members []jsonEntry
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue // Unexported.
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			inlined, err := e.encodeFields(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, inlined...)
+			continue
+		}
+
+		sub := jsonEncoder{store: e.store}
+		if coll, ok := collections[field.Name]; ok && e.store != nil {
+			if err := sub.encodeRoots(v.Field(i), coll); err != nil {
+				return nil, errors.Wrapf(err, "failed to expand %s.%s", v.Type().Name(), field.Name)
+			}
+		} else if err := sub.encode(v.Field(i)); err != nil {
+			return nil, err
+		}
+		members = append(members, jsonEntry{name: field.Name, value: sub.buf.Bytes()})
+	}
+	return members, nil
+}
+
+// Expands a CID field, which may be a CID, a pointer to one, or an array of them.
+func (e *jsonEncoder) encodeRoots(v reflect.Value, coll *collection) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.encodeRoots(v.Elem(), coll)
+	case reflect.Slice, reflect.Array:
+		e.buf.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.buf.WriteString(",")
+			}
+			if err := e.encodeRoots(v.Index(i), coll); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteString("]")
+		return nil
+	default:
+		return e.encodeCollection(v.Interface().(cid.Cid), coll)
+	}
+}
+
+func (e *jsonEncoder) encodeCollection(root cid.Cid, coll *collection) error {
+	switch coll.kind {
+	case objectCollection:
+		obj := coll.value()
+		if err := e.store.Get(e.store.Context(), root, obj); err != nil {
+			return errors.Wrapf(err, "failed to load %v", root)
+		}
+		return e.encode(reflect.ValueOf(obj))
+	case hamtCollection:
+		m, err := adt.AsMap(e.store, root)
+		if err != nil {
+			return err
+		}
+		var entries []jsonEntry
+		var out runtime.CBORUnmarshaler
+		var nestedRoot cbg.CborCid
+		if coll.nested != nil {
+			out = &nestedRoot
+		} else {
+			out = coll.value()
+		}
+		if err := m.ForEach(out, func(k string) error {
+			key, err := coll.key(k)
+			if err != nil {
+				return err
+			}
+			sub := jsonEncoder{store: e.store}
+			if coll.nested != nil {
+				err = sub.encodeCollection(cid.Cid(nestedRoot), coll.nested)
+			} else {
+				err = sub.encode(reflect.ValueOf(out))
+			}
+			if err != nil {
+				return err
+			}
+			entries = append(entries, jsonEntry{key: key, name: fmt.Sprint(key), value: sub.buf.Bytes()})
+			return nil
+		}); err != nil {
+			return err
+		}
+		e.writeEntries(entries)
+		return nil
+	case amtCollection:
+		arr, err := adt.AsArray(e.store, root)
+		if err != nil {
+			return err
+		}
+		out := coll.value()
+		e.buf.WriteString("{")
+		first := true
+		err = arr.ForEach(out, func(i int64) error {
+			if !first {
+				e.buf.WriteString(",")
+			}
+			first = false
+			if err := e.writeJSON(fmt.Sprint(i)); err != nil {
+				return err
+			}
+			e.buf.WriteString(":")
+			return e.encode(reflect.ValueOf(out))
+		})
+		e.buf.WriteString("}")
+		return err
+	case setCollection:
+		set, err := adt.AsSet(e.store, root)
+		if err != nil {
+			return err
+		}
+		var entries []jsonEntry
+		if err := set.ForEach(func(k string) error {
+			key, err := coll.key(k)
+			if err != nil {
+				return err
+			}
+			b, err := json.Marshal(key)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, jsonEntry{key: key, value: b})
+			return nil
+		}); err != nil {
+			return err
+		}
+		sortEntries(entries)
+		e.buf.WriteString("[")
+		for i, entry := range entries {
+			if i > 0 {
+				e.buf.WriteString(",")
+			}
+			e.buf.Write(entry.value)
+		}
+		e.buf.WriteString("]")
+		return nil
+	default:
+		return errors.Errorf("unknown collection kind %d", coll.kind)
+	}
+}
+
+// Writes entries as the members of an object, in key order.
+func (e *jsonEncoder) writeEntries(entries []jsonEntry) {
+	sortEntries(entries)
+	e.writeMembers(entries)
+}
+
+// Writes entries as the members of an object, in the order given.
+func (e *jsonEncoder) writeMembers(entries []jsonEntry) {
+	e.buf.WriteString("{")
+	for i, entry := range entries {
+		if i > 0 {
+			e.buf.WriteString(",")
+		}
+		_ = e.writeJSON(entry.name)
+		e.buf.WriteString(":")
+		e.buf.Write(entry.value)
+	}
+	e.buf.WriteString("}")
+}
+
+func (e *jsonEncoder) writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+// Sorts entries by key, numerically for integer keys and otherwise by rendered name.
+func sortEntries(entries []jsonEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ki, kj := reflect.ValueOf(entries[i].key), reflect.ValueOf(entries[j].key)
+		switch {
+		case isInt(ki) && isInt(kj):
+			return ki.Int() < kj.Int()
+		case isUint(ki) && isUint(kj):
+			return ki.Uint() < kj.Uint()
+		default:
+			return fmt.Sprint(entries[i].key) < fmt.Sprint(entries[j].key)
+		}
+	})
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
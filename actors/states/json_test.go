@@ -0,0 +1,140 @@
+package states_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/states"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	t.Run("params", func(t *testing.T) {
+		sv := paych.SignedVoucher{
+			ChannelAddr: tutil.NewIDAddr(t, 100),
+			TimeLockMin: 10,
+			Lane:        2,
+			Amount:      big.MustFromString("123456789012345678901234567890"),
+			Merges:      []paych.Merge{{Lane: 1, Nonce: 3}},
+		}
+		out, err := states.EncodeJSON(&sv)
+		require.NoError(t, err)
+		assert.Equal(t, `{"ChannelAddr":"t0100","TimeLockMin":10,"TimeLockMax":0,"SecretPreimage":"","Extra":null,`+
+			`"Lane":2,"Nonce":0,"Amount":"123456789012345678901234567890","MinSettleHeight":0,`+
+			`"Merges":[{"Lane":1,"Nonce":3}],"Signature":null}`, string(out))
+	})
+
+	t.Run("bitfields as runs", func(t *testing.T) {
+		out, err := states.EncodeJSON(&miner.Partition{
+			Sectors:    abi.NewBitField(),
+			Faults:     bitfieldOf(3, 4, 5, 9),
+			Recoveries: abi.NewBitField(),
+			Terminated: abi.NewBitField(),
+			LivePower:  miner.NewPowerPairZero(),
+		})
+		require.NoError(t, err)
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(out, &decoded))
+		assert.Equal(t, []interface{}{3.0, 3.0, 3.0, 1.0}, decoded["Faults"])
+		assert.Equal(t, map[string]interface{}{"Raw": "0", "QA": "0"}, decoded["LivePower"])
+		// Collection roots are not expanded without a store.
+		assert.Nil(t, decoded["ExpirationsEpochs"])
+	})
+}
+
+func TestEncodeStateJSON(t *testing.T) {
+	ctx := context.Background()
+	v, maddr := genesisWithMiner(ctx, t)
+
+	var st miner.State
+	require.NoError(t, v.GetState(maddr, &st))
+	out, err := states.EncodeStateJSON(v.Store(), &st)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Info struct {
+			Owner      string
+			SectorSize float64
+		}
+		Sectors map[string]struct {
+			SectorNumber float64
+			SealedCID    map[string]string
+			DealWeight   string
+		}
+		Deadlines struct {
+			Due []struct {
+				Partitions map[string]struct {
+					Sectors []float64
+				}
+			}
+		}
+		PreCommittedSectors map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(out, &decoded), string(out))
+
+	info, err := st.GetInfo(v.Store())
+	require.NoError(t, err)
+	assert.Equal(t, info.Owner.String(), decoded.Info.Owner)
+	assert.Equal(t, float64(info.SectorSize), decoded.Info.SectorSize)
+
+	require.Contains(t, decoded.Sectors, "0")
+	assert.Equal(t, 0.0, decoded.Sectors["0"].SectorNumber)
+	assert.Equal(t, "0", decoded.Sectors["0"].DealWeight)
+	assert.NotEmpty(t, decoded.Sectors["0"].SealedCID["/"])
+	assert.Empty(t, decoded.PreCommittedSectors)
+
+	require.Len(t, decoded.Deadlines.Due, int(miner.WPoStPeriodDeadlines))
+	partitions := 0
+	for _, dl := range decoded.Deadlines.Due {
+		for _, p := range dl.Partitions {
+			assert.Equal(t, []float64{0, 1}, p.Sectors) // Sector 0 is the first set bit.
+			partitions++
+		}
+	}
+	assert.Equal(t, 1, partitions)
+}
+
+func TestEncodeTreeJSON(t *testing.T) {
+	ctx := context.Background()
+	v, maddr := genesisWithMiner(ctx, t)
+	tree, err := states.LoadTree(v.Store(), v.StateRoot())
+	require.NoError(t, err)
+
+	out, err := states.EncodeTreeJSON(tree)
+	require.NoError(t, err)
+	var decoded map[string]struct {
+		Balance string
+		State   map[string]interface{}
+	}
+	require.NoError(t, json.Unmarshal(out, &decoded))
+
+	require.Contains(t, decoded, maddr.String())
+	assert.Contains(t, decoded[maddr.String()].State, "Sectors")
+
+	power := decoded[builtin.StoragePowerActorAddr.String()].State
+	claims, ok := power["Claims"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Contains(t, claims, maddr.String())
+
+	// Rendering is deterministic.
+	again, err := states.EncodeTreeJSON(tree)
+	require.NoError(t, err)
+	assert.Equal(t, out, again)
+}
+
+func bitfieldOf(bits ...uint64) *abi.BitField {
+	bf := abi.NewBitField()
+	for _, b := range bits {
+		bf.Set(b)
+	}
+	return bf
+}
@@ -0,0 +1,65 @@
+package states
+
+import (
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Actor is the state tree's record of a single actor.
+type Actor struct {
+	Code       cid.Cid
+	Head       cid.Cid
+	CallSeqNum uint64
+	Balance    big.Int
+}
+
+// Tree is a read-only view of a state tree: a HAMT of actors keyed by ID address.
+type Tree struct {
+	Map   *adt.Map
+	Store adt.Store
+}
+
+// LoadTree loads the state tree with the given root.
+func LoadTree(s adt.Store, r cid.Cid) (*Tree, error) {
+	m, err := adt.AsMap(s, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load state tree at %v", r)
+	}
+	return &Tree{Map: m, Store: s}, nil
+}
+
+// GetActor returns the actor with the given ID address, if it exists.
+func (t *Tree) GetActor(a addr.Address) (*Actor, bool, error) {
+	if a.Protocol() != addr.ID {
+		return nil, false, errors.Errorf("actor address %v must be an ID address", a)
+	}
+	var act Actor
+	found, err := t.Map.Get(adt.AddrKey(a), &act)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to load actor %v", a)
+	}
+	return &act, found, nil
+}
+
+// ForEach calls fn for each actor in the tree, in key order.
+// The actor passed to fn is reused between calls and must not be retained.
+func (t *Tree) ForEach(fn func(a addr.Address, act *Actor) error) error {
+	var act Actor
+	return t.Map.ForEach(&act, func(k string) error {
+		a, err := addr.NewFromBytes([]byte(k))
+		if err != nil {
+			return errors.Wrapf(err, "invalid address key in state tree")
+		}
+		return fn(a, &act)
+	})
+}
+
+// LoadActorState loads the state of an actor into the argument.
+func (t *Tree) LoadActorState(act *Actor, out runtime.CBORUnmarshaler) error {
+	return t.Store.Get(t.Store.Context(), act.Head, out)
+}
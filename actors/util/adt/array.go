@@ -48,6 +48,7 @@ func (a *Array) Root() (cid.Cid, error) {
 // Appends a value to the end of the array. Assumes continuous array.
 // If the array isn't continuous use Set and a separate counter
 func (a *Array) AppendContinuous(value runtime.CBORMarshaler) error {
+	chargeCollectionOp(a.store, runtime.AmtSet)
 	if err := a.root.Set(a.store.Context(), a.root.Count, value); err != nil {
 		return errors.Wrapf(err, "array append failed to set index %v value %v in root %v, ", a.root.Count, value, a.root)
 	}
@@ -55,6 +56,7 @@ func (a *Array) AppendContinuous(value runtime.CBORMarshaler) error {
 }
 
 func (a *Array) Set(i uint64, value runtime.CBORMarshaler) error {
+	chargeCollectionOp(a.store, runtime.AmtSet)
 	if err := a.root.Set(a.store.Context(), i, value); err != nil {
 		return xerrors.Errorf("array set failed to set index %v in root %v: %w", i, a.root, err)
 	}
@@ -62,6 +64,7 @@ func (a *Array) Set(i uint64, value runtime.CBORMarshaler) error {
 }
 
 func (a *Array) Delete(i uint64) error {
+	chargeCollectionOp(a.store, runtime.AmtDelete)
 	if err := a.root.Delete(a.store.Context(), i); err != nil {
 		return xerrors.Errorf("array delete failed to delete index %v in root %v: %w", i, a.root, err)
 	}
@@ -69,6 +72,9 @@ func (a *Array) Delete(i uint64) error {
 }
 
 func (a *Array) BatchDelete(ix []uint64) error {
+	for range ix {
+		chargeCollectionOp(a.store, runtime.AmtDelete)
+	}
 	if err := a.root.BatchDelete(a.store.Context(), ix); err != nil {
 		return xerrors.Errorf("array delete failed to batchdelete: %w", err)
 	}
@@ -80,6 +86,7 @@ func (a *Array) BatchDelete(ix []uint64) error {
 // If the output parameter is nil, deserialization is skipped.
 func (a *Array) ForEach(out runtime.CBORUnmarshaler, fn func(i int64) error) error {
 	return a.root.ForEach(a.store.Context(), func(k uint64, val *cbg.Deferred) error {
+		chargeCollectionOp(a.store, runtime.AmtIterate)
 		if out != nil {
 			if deferred, ok := out.(*cbg.Deferred); ok {
 				// fast-path deferred -> deferred to avoid re-decoding.
@@ -99,6 +106,7 @@ func (a *Array) Length() uint64 {
 // Get retrieves array element into the 'out' unmarshaler, returning a boolean
 //  indicating whether the element was found in the array
 func (a *Array) Get(k uint64, out runtime.CBORUnmarshaler) (bool, error) {
+	chargeCollectionOp(a.store, runtime.AmtGet)
 
 	if err := a.root.Get(a.store.Context(), k, out); err == nil {
 		return true, nil
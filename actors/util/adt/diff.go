@@ -0,0 +1,352 @@
+package adt
+
+import (
+	"bytes"
+	"context"
+	"math/bits"
+	"sort"
+
+	amt "github.com/filecoin-project/go-amt-ipld/v2"
+	cid "github.com/ipfs/go-cid"
+	hamt "github.com/ipfs/go-hamt-ipld"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+)
+
+// MapDiffer receives the entries that differ between two maps.
+// Values are passed undecoded; implementations decode them as appropriate for the map's value type.
+// Iteration halts if a method returns an error.
+type MapDiffer interface {
+	Add(key string, val *cbg.Deferred) error
+	Modify(key string, from, to *cbg.Deferred) error
+	Remove(key string, val *cbg.Deferred) error
+}
+
+// ArrayDiffer receives the entries that differ between two arrays.
+type ArrayDiffer interface {
+	Add(idx uint64, val *cbg.Deferred) error
+	Modify(idx uint64, from, to *cbg.Deferred) error
+	Remove(idx uint64, val *cbg.Deferred) error
+}
+
+// DiffMaps reports each key that is added, removed, or modified in post relative to pre.
+// Subtrees with equal CIDs in both maps are skipped without being loaded, so the cost of
+// the diff is proportional to the size of the change rather than the size of the maps.
+// Both maps are flushed before comparison.
+func DiffMaps(pre, post *Map, differ MapDiffer) error {
+	preRoot, err := pre.Root()
+	if err != nil {
+		return err
+	}
+	postRoot, err := post.Root()
+	if err != nil {
+		return err
+	}
+	if preRoot.Equals(postRoot) {
+		return nil
+	}
+	return diffHamtNodes(pre.store.Context(), pre.store, pre.root, post.root, differ)
+}
+
+// DiffArrays reports each index that is added, removed, or modified in post relative to pre.
+// Like DiffMaps, subtrees with equal CIDs are skipped. Both arrays are flushed before comparison.
+func DiffArrays(pre, post *Array, differ ArrayDiffer) error {
+	preRoot, err := pre.Root()
+	if err != nil {
+		return err
+	}
+	postRoot, err := post.Root()
+	if err != nil {
+		return err
+	}
+	if preRoot.Equals(postRoot) {
+		return nil
+	}
+
+	ctx := pre.store.Context()
+	preNode, postNode := &pre.root.Node, &post.root.Node
+	preHeight, postHeight := int(pre.root.Height), int(post.root.Height)
+	// A taller AMT holds the shorter one's entire index range in its first slot at each extra level.
+	// Everything outside that first slot exists only on one side.
+	for preHeight > postHeight {
+		if err := forEachAmtSlot(ctx, pre.store, preNode, preHeight, 1, amtWidth, differ.Remove); err != nil {
+			return err
+		}
+		if preNode, err = loadAmtChild(ctx, pre.store, preNode, 0); err != nil {
+			return err
+		}
+		preHeight--
+	}
+	for postHeight > preHeight {
+		if err := forEachAmtSlot(ctx, post.store, postNode, postHeight, 1, amtWidth, differ.Add); err != nil {
+			return err
+		}
+		if postNode, err = loadAmtChild(ctx, post.store, postNode, 0); err != nil {
+			return err
+		}
+		postHeight--
+	}
+	return diffAmtNodes(ctx, pre.store, preNode, postNode, preHeight, 0, differ)
+}
+
+//
+// HAMT
+//
+
+func diffHamtNodes(ctx context.Context, store Store, pre, post *hamt.Node, differ MapDiffer) error {
+	width := pre.Bitfield.BitLen()
+	if w := post.Bitfield.BitLen(); w > width {
+		width = w
+	}
+	for i := 0; i < width; i++ {
+		prePtr := hamtPointer(pre, i)
+		postPtr := hamtPointer(post, i)
+		if prePtr == nil && postPtr == nil {
+			continue
+		}
+		if prePtr != nil && postPtr != nil && prePtr.Link.Defined() && postPtr.Link.Defined() {
+			if prePtr.Link.Equals(postPtr.Link) {
+				continue
+			}
+			preChild, err := hamt.LoadNode(ctx, store, prePtr.Link, HamtOptions...)
+			if err != nil {
+				return xerrors.Errorf("failed to load hamt node %v: %w", prePtr.Link, err)
+			}
+			postChild, err := hamt.LoadNode(ctx, store, postPtr.Link, HamtOptions...)
+			if err != nil {
+				return xerrors.Errorf("failed to load hamt node %v: %w", postPtr.Link, err)
+			}
+			if err := diffHamtNodes(ctx, store, preChild, postChild, differ); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// At least one side is a bucket of values (or absent), so the two sides have different shapes.
+		// Compare their entries directly.
+		preKVs, err := collectHamtPointer(ctx, store, prePtr)
+		if err != nil {
+			return err
+		}
+		postKVs, err := collectHamtPointer(ctx, store, postPtr)
+		if err != nil {
+			return err
+		}
+		if err := diffKVs(preKVs, postKVs, differ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the pointer at bit index i of a node, or nil if there is none.
+func hamtPointer(n *hamt.Node, i int) *hamt.Pointer {
+	if n.Bitfield.Bit(i) == 0 {
+		return nil
+	}
+	idx := 0
+	for j := 0; j < i; j++ {
+		idx += int(n.Bitfield.Bit(j))
+	}
+	return n.Pointers[idx]
+}
+
+// Collects all key-value pairs reachable from a pointer, keyed by string key.
+func collectHamtPointer(ctx context.Context, store Store, p *hamt.Pointer) (map[string]*cbg.Deferred, error) {
+	out := map[string]*cbg.Deferred{}
+	if p == nil {
+		return out, nil
+	}
+	if !p.Link.Defined() {
+		for _, kv := range p.KVs {
+			out[string(kv.Key)] = kv.Value
+		}
+		return out, nil
+	}
+	child, err := hamt.LoadNode(ctx, store, p.Link, HamtOptions...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load hamt node %v: %w", p.Link, err)
+	}
+	err = child.ForEach(ctx, func(k string, val interface{}) error {
+		out[k] = val.(*cbg.Deferred)
+		return nil
+	})
+	return out, err
+}
+
+func diffKVs(pre, post map[string]*cbg.Deferred, differ MapDiffer) error {
+	keys := make([]string, 0, len(pre)+len(post))
+	for k := range pre { //nolint:nomaprange
+		keys = append(keys, k)
+	}
+	for k := range post { //nolint:nomaprange
+		if _, ok := pre[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		preVal, inPre := pre[k]
+		postVal, inPost := post[k]
+		var err error
+		switch {
+		case inPre && !inPost:
+			err = differ.Remove(k, preVal)
+		case !inPre && inPost:
+			err = differ.Add(k, postVal)
+		case !bytes.Equal(preVal.Raw, postVal.Raw):
+			err = differ.Modify(k, preVal, postVal)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//
+// AMT
+//
+
+// Width of each AMT node, fixed by the AMT implementation.
+const amtWidth = 8
+
+func diffAmtNodes(ctx context.Context, store Store, pre, post *amt.Node, height int, offset uint64, differ ArrayDiffer) error {
+	if height == 0 {
+		for i := 0; i < amtWidth; i++ {
+			preVal := amtValue(pre, i)
+			postVal := amtValue(post, i)
+			idx := offset + uint64(i)
+			var err error
+			switch {
+			case preVal == nil && postVal == nil:
+			case postVal == nil:
+				err = differ.Remove(idx, preVal)
+			case preVal == nil:
+				err = differ.Add(idx, postVal)
+			case !bytes.Equal(preVal.Raw, postVal.Raw):
+				err = differ.Modify(idx, preVal, postVal)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	span := amtNodeSpan(height - 1)
+	for i := 0; i < amtWidth; i++ {
+		preLink, inPre := amtLink(pre, i)
+		postLink, inPost := amtLink(post, i)
+		childOffset := offset + uint64(i)*span
+		switch {
+		case !inPre && !inPost:
+			continue
+		case inPre && inPost && preLink.Equals(postLink):
+			continue
+		case !inPost:
+			if err := forEachAmtSlot(ctx, store, pre, height, i, i+1, func(idx uint64, val *cbg.Deferred) error {
+				return differ.Remove(offset+idx, val)
+			}); err != nil {
+				return err
+			}
+		case !inPre:
+			if err := forEachAmtSlot(ctx, store, post, height, i, i+1, func(idx uint64, val *cbg.Deferred) error {
+				return differ.Add(offset+idx, val)
+			}); err != nil {
+				return err
+			}
+		default:
+			preChild, err := loadAmtChild(ctx, store, pre, i)
+			if err != nil {
+				return err
+			}
+			postChild, err := loadAmtChild(ctx, store, post, i)
+			if err != nil {
+				return err
+			}
+			if err := diffAmtNodes(ctx, store, preChild, postChild, height-1, childOffset, differ); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Calls fn for every value beneath slots [from, to) of a node, with indexes relative to the node's offset.
+func forEachAmtSlot(ctx context.Context, store Store, n *amt.Node, height int, from, to int, fn func(uint64, *cbg.Deferred) error) error {
+	if height == 0 {
+		for i := from; i < to; i++ {
+			if val := amtValue(n, i); val != nil {
+				if err := fn(uint64(i), val); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	span := amtNodeSpan(height - 1)
+	for i := from; i < to; i++ {
+		if _, ok := amtLink(n, i); !ok {
+			continue
+		}
+		child, err := loadAmtChild(ctx, store, n, i)
+		if err != nil {
+			return err
+		}
+		base := uint64(i) * span
+		if err := forEachAmtSlot(ctx, store, child, height-1, 0, amtWidth, func(idx uint64, val *cbg.Deferred) error {
+			return fn(base+idx, val)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Number of indexes covered by a node at the given height.
+func amtNodeSpan(height int) uint64 {
+	span := uint64(1)
+	for h := 0; h <= height; h++ {
+		span *= amtWidth
+	}
+	return span
+}
+
+// Returns the position in a node's packed Links or Values of slot i, and whether the slot is set.
+func amtSlot(n *amt.Node, i int) (int, bool) {
+	if n == nil || n.Bmap[0]&byte(1<<uint(i)) == 0 {
+		return 0, false
+	}
+	return bits.OnesCount8(n.Bmap[0] & byte((1<<uint(i))-1)), true
+}
+
+func amtValue(n *amt.Node, i int) *cbg.Deferred {
+	pos, ok := amtSlot(n, i)
+	if !ok {
+		return nil
+	}
+	return n.Values[pos]
+}
+
+func amtLink(n *amt.Node, i int) (cid.Cid, bool) {
+	pos, ok := amtSlot(n, i)
+	if !ok {
+		return cid.Undef, false
+	}
+	return n.Links[pos], true
+}
+
+// Loads the child at slot i of an interior node, or returns nil if the slot is empty.
+func loadAmtChild(ctx context.Context, store Store, n *amt.Node, i int) (*amt.Node, error) {
+	link, ok := amtLink(n, i)
+	if !ok {
+		return nil, nil
+	}
+	var child amt.Node
+	if err := store.Get(ctx, link, &child); err != nil {
+		return nil, xerrors.Errorf("failed to load amt node %v: %w", link, err)
+	}
+	return &child, nil
+}
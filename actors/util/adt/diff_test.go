@@ -0,0 +1,245 @@
+package adt_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+)
+
+func TestDiffMaps(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("identical maps", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := makeMap(t, store, 100)
+		post := makeMap(t, store, 100)
+		d := diffMaps(t, pre, post)
+		assert.Empty(t, d.changes)
+	})
+
+	t.Run("add modify remove", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := makeMap(t, store, 1000)
+		post := makeMap(t, store, 1000)
+		require.NoError(t, post.Put(adt.UIntKey(1000), intVal(1000)))
+		require.NoError(t, post.Put(adt.UIntKey(17), intVal(-17)))
+		require.NoError(t, post.Delete(adt.UIntKey(500)))
+
+		d := diffMaps(t, pre, post)
+		assert.ElementsMatch(t, []string{
+			"add 1000: 1000",
+			"modify 17: 17 -> -17",
+			"remove 500: 500",
+		}, d.changes)
+	})
+
+	t.Run("from empty", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := adt.MakeEmptyMap(store)
+		post := makeMap(t, store, 50)
+		d := diffMaps(t, pre, post)
+		assert.Len(t, d.changes, 50)
+
+		// And the reverse.
+		d = diffMaps(t, post, pre)
+		assert.Len(t, d.changes, 50)
+		assert.Contains(t, d.changes, "remove 0: 0")
+		assert.Contains(t, d.changes, "remove 49: 49")
+	})
+
+	t.Run("skips unchanged subtrees", func(t *testing.T) {
+		store := &countingStore{Store: ipld.NewADTStore(ctx)}
+		pre := makeMap(t, store, 5000)
+		post := makeMap(t, store, 5000)
+		require.NoError(t, post.Put(adt.UIntKey(1234), intVal(0)))
+		preRoot, err := pre.Root()
+		require.NoError(t, err)
+		postRoot, err := post.Root()
+		require.NoError(t, err)
+
+		// Reload so no nodes are cached.
+		pre, err = adt.AsMap(store, preRoot)
+		require.NoError(t, err)
+		post, err = adt.AsMap(store, postRoot)
+		require.NoError(t, err)
+
+		store.gets = 0
+		d := diffMaps(t, pre, post)
+		assert.Equal(t, []string{"modify 1234: 1234 -> 0"}, d.changes)
+		assert.Less(t, store.gets, 10)
+	})
+}
+
+func TestDiffArrays(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("identical arrays", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := makeArray(t, store, 100)
+		post := makeArray(t, store, 100)
+		d := diffArrays(t, pre, post)
+		assert.Empty(t, d.changes)
+	})
+
+	t.Run("add modify remove", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := makeArray(t, store, 1000)
+		post := makeArray(t, store, 1000)
+		require.NoError(t, post.Set(17, intVal(-17)))
+		require.NoError(t, post.Delete(500))
+		require.NoError(t, post.Set(1500, intVal(1500)))
+
+		d := diffArrays(t, pre, post)
+		assert.Equal(t, []string{
+			"modify 17: 17 -> -17",
+			"remove 500: 500",
+			"add 1500: 1500",
+		}, d.changes)
+	})
+
+	t.Run("different heights", func(t *testing.T) {
+		store := ipld.NewADTStore(ctx)
+		pre := makeArray(t, store, 5)
+		post := makeArray(t, store, 5)
+		require.NoError(t, post.Set(3, intVal(33)))
+		require.NoError(t, post.Set(100, intVal(100)))
+		require.NoError(t, post.Set(10_000, intVal(10_000)))
+
+		d := diffArrays(t, pre, post)
+		assert.ElementsMatch(t, []string{
+			"modify 3: 3 -> 33",
+			"add 100: 100",
+			"add 10000: 10000",
+		}, d.changes)
+
+		d = diffArrays(t, post, pre)
+		assert.ElementsMatch(t, []string{
+			"modify 3: 33 -> 3",
+			"remove 100: 100",
+			"remove 10000: 10000",
+		}, d.changes)
+	})
+
+	t.Run("skips unchanged subtrees", func(t *testing.T) {
+		store := &countingStore{Store: ipld.NewADTStore(ctx)}
+		pre := makeArray(t, store, 5000)
+		post := makeArray(t, store, 5000)
+		require.NoError(t, post.Set(4321, intVal(0)))
+		preRoot, err := pre.Root()
+		require.NoError(t, err)
+		postRoot, err := post.Root()
+		require.NoError(t, err)
+
+		pre, err = adt.AsArray(store, preRoot)
+		require.NoError(t, err)
+		post, err = adt.AsArray(store, postRoot)
+		require.NoError(t, err)
+
+		store.gets = 0
+		d := diffArrays(t, pre, post)
+		assert.Equal(t, []string{"modify 4321: 4321 -> 0"}, d.changes)
+		assert.Less(t, store.gets, 10)
+	})
+}
+
+func makeMap(t *testing.T, store adt.Store, n int) *adt.Map {
+	m := adt.MakeEmptyMap(store)
+	for i := 0; i < n; i++ {
+		require.NoError(t, m.Put(adt.UIntKey(uint64(i)), intVal(int64(i))))
+	}
+	return m
+}
+
+func makeArray(t *testing.T, store adt.Store, n int) *adt.Array {
+	a := adt.MakeEmptyArray(store)
+	for i := 0; i < n; i++ {
+		require.NoError(t, a.Set(uint64(i), intVal(int64(i))))
+	}
+	return a
+}
+
+func intVal(i int64) *cbg.CborInt {
+	v := cbg.CborInt(i)
+	return &v
+}
+
+func diffMaps(t *testing.T, pre, post *adt.Map) *recordingDiffer {
+	d := &recordingDiffer{t: t}
+	require.NoError(t, adt.DiffMaps(pre, post, d))
+	return d
+}
+
+func diffArrays(t *testing.T, pre, post *adt.Array) *recordingDiffer {
+	d := &recordingDiffer{t: t}
+	require.NoError(t, adt.DiffArrays(pre, post, &arrayDiffer{d}))
+	return d
+}
+
+// Records changes as strings of the form "<op> <key>: <value(s)>", with map keys decoded as uint keys.
+type recordingDiffer struct {
+	t       *testing.T
+	changes []string
+}
+
+func (d *recordingDiffer) Add(key string, val *cbg.Deferred) error {
+	d.changes = append(d.changes, fmt.Sprintf("add %d: %d", d.key(key), d.val(val)))
+	return nil
+}
+
+func (d *recordingDiffer) Modify(key string, from, to *cbg.Deferred) error {
+	d.changes = append(d.changes, fmt.Sprintf("modify %d: %d -> %d", d.key(key), d.val(from), d.val(to)))
+	return nil
+}
+
+func (d *recordingDiffer) Remove(key string, val *cbg.Deferred) error {
+	d.changes = append(d.changes, fmt.Sprintf("remove %d: %d", d.key(key), d.val(val)))
+	return nil
+}
+
+func (d *recordingDiffer) key(k string) uint64 {
+	i, err := adt.ParseUIntKey(k)
+	require.NoError(d.t, err)
+	return i
+}
+
+func (d *recordingDiffer) val(v *cbg.Deferred) int64 {
+	var i cbg.CborInt
+	require.NoError(d.t, i.UnmarshalCBOR(bytes.NewReader(v.Raw)))
+	return int64(i)
+}
+
+type arrayDiffer struct {
+	*recordingDiffer
+}
+
+func (d *arrayDiffer) Add(idx uint64, val *cbg.Deferred) error {
+	return d.recordingDiffer.Add(adt.UIntKey(idx).Key(), val)
+}
+
+func (d *arrayDiffer) Modify(idx uint64, from, to *cbg.Deferred) error {
+	return d.recordingDiffer.Modify(adt.UIntKey(idx).Key(), from, to)
+}
+
+func (d *arrayDiffer) Remove(idx uint64, val *cbg.Deferred) error {
+	return d.recordingDiffer.Remove(adt.UIntKey(idx).Key(), val)
+}
+
+// Counts block reads.
+type countingStore struct {
+	adt.Store
+	gets int
+}
+
+func (s *countingStore) Get(ctx context.Context, c cid.Cid, out interface{}) error {
+	s.gets++
+	return s.Store.Get(ctx, c, out)
+}
@@ -75,6 +75,7 @@ func (m *Map) Root() (cid.Cid, error) {
 
 // Put adds value `v` with key `k` to the hamt store.
 func (m *Map) Put(k Keyer, v runtime.CBORMarshaler) error {
+	chargeCollectionOp(m.store, runtime.HamtPut)
 	if err := m.root.Set(m.store.Context(), k.Key(), v); err != nil {
 		return errors.Wrapf(err, "map put failed set in node %v with key %v value %v", m.lastCid, k.Key(), v)
 	}
@@ -83,6 +84,7 @@ func (m *Map) Put(k Keyer, v runtime.CBORMarshaler) error {
 
 // Get puts the value at `k` into `out`.
 func (m *Map) Get(k Keyer, out runtime.CBORUnmarshaler) (bool, error) {
+	chargeCollectionOp(m.store, runtime.HamtGet)
 	if err := m.root.Find(m.store.Context(), k.Key(), out); err != nil {
 		if err == hamt.ErrNotFound {
 			return false, nil
@@ -94,6 +96,7 @@ func (m *Map) Get(k Keyer, out runtime.CBORUnmarshaler) (bool, error) {
 
 // Delete removes the value at `k` from the hamt store.
 func (m *Map) Delete(k Keyer) error {
+	chargeCollectionOp(m.store, runtime.HamtDelete)
 	if err := m.root.Delete(m.store.Context(), k.Key()); err != nil {
 		return errors.Wrapf(err, "map delete failed in node %v key %v", m.root, k.Key())
 	}
@@ -107,6 +110,7 @@ func (m *Map) Delete(k Keyer) error {
 // If the output parameter is nil, deserialization is skipped.
 func (m *Map) ForEach(out runtime.CBORUnmarshaler, fn func(key string) error) error {
 	return m.root.ForEach(m.store.Context(), func(k string, val interface{}) error {
+		chargeCollectionOp(m.store, runtime.HamtIterate)
 		if out != nil {
 			// Why doesn't hamt.ForEach() just return the value as bytes?
 			err := out.UnmarshalCBOR(bytes.NewReader(val.(*cbg.Deferred).Raw))
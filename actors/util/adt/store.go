@@ -70,6 +70,15 @@ func (r rtStore) Put(_ context.Context, v interface{}) (cid.Cid, error) {
 	return r.Store().Put(v.(vmr.CBORMarshaler)), nil
 }
 
+// Charges for a collection operation if the store is backed by a runtime that meters them.
+func chargeCollectionOp(s Store, op vmr.CollectionOp) {
+	if r, ok := s.(rtStore); ok {
+		if charger, ok := r.Runtime.(vmr.CollectionOpCharger); ok {
+			charger.ChargeCollectionOp(op)
+		}
+	}
+}
+
 // Keyer defines an interface required to put values in mapping.
 type Keyer interface {
 	Key() string
@@ -0,0 +1,408 @@
+// Command actorstate inspects a state tree loaded from a CAR file, decoding actor states by code CID.
+//
+// Usage:
+//
+//	actorstate -car <file> [-root <cid>] <command> [arguments]
+//
+// The state tree root defaults to the CAR file's first root. Addresses may be ID or robust addresses.
+//
+// Commands:
+//
+//	actors                           list all actors with their type and balance
+//	actor <addr>                     print an actor's state as JSON, with collections expanded
+//	miner info <addr>                print a miner's info
+//	miner sectors <addr>             list a miner's sectors
+//	miner deadlines <addr>           list a miner's deadlines and partitions
+//	market deals [-provider <addr>]  list storage deals, optionally for a single provider
+//	power claims                     list miners' power claims
+//	multisig pending <addr>          list a multisig's pending transactions
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/states"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "actorstate: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("actorstate", flag.ContinueOnError)
+	carPath := flags.String("car", "", "path to a CAR file containing the state tree")
+	rootFlag := flags.String("root", "", "state tree root CID (default: the CAR file's first root)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *carPath == "" {
+		return errors.New("a CAR file must be specified with -car")
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no command given")
+	}
+
+	f, err := os.Open(*carPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	store, roots, err := ipld.LoadCARStore(ctx, f)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load %s", *carPath)
+	}
+
+	var root cid.Cid
+	if *rootFlag != "" {
+		if root, err = cid.Decode(*rootFlag); err != nil {
+			return errors.Wrapf(err, "invalid root %s", *rootFlag)
+		}
+	} else if len(roots) > 0 {
+		root = roots[0]
+	} else {
+		return errors.New("CAR file has no roots, specify one with -root")
+	}
+	tree, err := states.LoadTree(store, root)
+	if err != nil {
+		return err
+	}
+
+	insp := &inspector{tree: tree, store: store, out: out}
+	cmd, rest := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "actors":
+		return insp.actors()
+	case "actor":
+		return withAddr(rest, insp.actor)
+	case "miner", "market", "power", "multisig":
+		if len(rest) == 0 {
+			return errors.Errorf("no %s subcommand given", cmd)
+		}
+		sub, subArgs := rest[0], rest[1:]
+		switch cmd + " " + sub {
+		case "miner info":
+			return withAddr(subArgs, insp.minerInfo)
+		case "miner sectors":
+			return withAddr(subArgs, insp.minerSectors)
+		case "miner deadlines":
+			return withAddr(subArgs, insp.minerDeadlines)
+		case "market deals":
+			return insp.marketDeals(subArgs)
+		case "power claims":
+			return insp.powerClaims()
+		case "multisig pending":
+			return withAddr(subArgs, insp.multisigPending)
+		}
+		return errors.Errorf("unknown command %s %s", cmd, sub)
+	default:
+		return errors.Errorf("unknown command %s", cmd)
+	}
+}
+
+func withAddr(args []string, fn func(addr.Address) error) error {
+	if len(args) != 1 {
+		return errors.New("expected a single address argument")
+	}
+	a, err := addr.NewFromString(args[0])
+	if err != nil {
+		return errors.Wrapf(err, "invalid address %s", args[0])
+	}
+	return fn(a)
+}
+
+type inspector struct {
+	tree  *states.Tree
+	store adt.Store
+	out   io.Writer
+}
+
+func (i *inspector) actors() error {
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tTYPE\tBALANCE\tNONCE")
+	if err := i.tree.ForEach(func(a addr.Address, act *states.Actor) error {
+		fmt.Fprintf(w, "%v\t%s\t%v\t%d\n", a, builtin.ActorNameByCode(act.Code), act.Balance, act.CallSeqNum)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (i *inspector) actor(a addr.Address) error {
+	idAddr, err := i.resolve(a)
+	if err != nil {
+		return err
+	}
+	act, err := i.loadActor(idAddr)
+	if err != nil {
+		return err
+	}
+	tree, err := states.EncodeTreeJSON(&states.Tree{Map: singleActorMap(i.store, idAddr, act), Store: i.store})
+	if err != nil {
+		return err
+	}
+	return i.printJSON(tree)
+}
+
+func (i *inspector) minerInfo(a addr.Address) error {
+	var st miner.State
+	if err := i.loadState(a, builtin.StorageMinerActorCodeID, &st); err != nil {
+		return err
+	}
+	info, err := st.GetInfo(i.store)
+	if err != nil {
+		return err
+	}
+	out, err := states.EncodeJSON(info)
+	if err != nil {
+		return err
+	}
+	return i.printJSON(out)
+}
+
+func (i *inspector) minerSectors(a addr.Address) error {
+	var st miner.State
+	if err := i.loadState(a, builtin.StorageMinerActorCodeID, &st); err != nil {
+		return err
+	}
+	sectors, err := miner.LoadSectors(i.store, st.Sectors)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SECTOR\tACTIVATION\tEXPIRATION\tSEALED CID\tDEALS\tDEAL WEIGHT\tVERIFIED WEIGHT\tPLEDGE")
+	var info miner.SectorOnChainInfo
+	if err := sectors.ForEach(&info, func(_ int64) error {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%v\t%v\t%v\t%v\t%v\n", info.SectorNumber, info.Activation, info.Expiration,
+			info.SealedCID, info.DealIDs, info.DealWeight, info.VerifiedDealWeight, info.InitialPledge)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (i *inspector) minerDeadlines(a addr.Address) error {
+	var st miner.State
+	if err := i.loadState(a, builtin.StorageMinerActorCodeID, &st); err != nil {
+		return err
+	}
+	deadlines, err := st.LoadDeadlines(i.store)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "proving period start %d, current deadline %d\n", st.ProvingPeriodStart, st.CurrentDeadline)
+	fmt.Fprintln(w, "DEADLINE\tPARTITION\tSECTORS\tFAULTS\tRECOVERIES\tTERMINATED\tLIVE POWER (QA)\tFAULTY POWER (QA)")
+	if err := deadlines.ForEach(i.store, func(dlIdx uint64, dl *miner.Deadline) error {
+		partitions, err := dl.PartitionsArray(i.store)
+		if err != nil {
+			return err
+		}
+		var partition miner.Partition
+		return partitions.ForEach(&partition, func(partIdx int64) error {
+			counts := make([]uint64, 4)
+			for j, bf := range []*abi.BitField{partition.Sectors, partition.Faults, partition.Recoveries, partition.Terminated} {
+				if counts[j], err = bf.Count(); err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t%v\t%v\n", dlIdx, partIdx, counts[0], counts[1], counts[2], counts[3],
+				partition.LivePower.QA, partition.FaultyPower.QA)
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (i *inspector) marketDeals(args []string) error {
+	flags := flag.NewFlagSet("market deals", flag.ContinueOnError)
+	providerFlag := flags.String("provider", "", "only list deals with this provider")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var provider addr.Address
+	if *providerFlag != "" {
+		a, err := addr.NewFromString(*providerFlag)
+		if err != nil {
+			return errors.Wrapf(err, "invalid provider address %s", *providerFlag)
+		}
+		if provider, err = i.resolve(a); err != nil {
+			return err
+		}
+	}
+
+	var st market.State
+	if err := i.loadState(builtin.StorageMarketActorAddr, builtin.StorageMarketActorCodeID, &st); err != nil {
+		return err
+	}
+	proposals, err := market.AsDealProposalArray(i.store, st.Proposals)
+	if err != nil {
+		return err
+	}
+	dealStates, err := market.AsDealStateArray(i.store, st.States)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DEAL\tPROVIDER\tCLIENT\tPIECE SIZE\tVERIFIED\tSTART\tEND\tPRICE/EPOCH\tSECTOR START\tLAST UPDATED\tSLASHED")
+	var proposal market.DealProposal
+	if err := proposals.ForEach(&proposal, func(id int64) error {
+		if provider != addr.Undef && proposal.Provider != provider {
+			return nil
+		}
+		ds, _, err := dealStates.Get(abi.DealID(id))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d\t%v\t%v\t%d\t%t\t%d\t%d\t%v\t%d\t%d\t%d\n", id, proposal.Provider, proposal.Client,
+			proposal.PieceSize, proposal.VerifiedDeal, proposal.StartEpoch, proposal.EndEpoch,
+			proposal.StoragePricePerEpoch, ds.SectorStartEpoch, ds.LastUpdatedEpoch, ds.SlashEpoch)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (i *inspector) powerClaims() error {
+	var st power.State
+	if err := i.loadState(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, &st); err != nil {
+		return err
+	}
+	claims, err := adt.AsMap(i.store, st.Claims)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "total raw %v, total qa %v, miners %d (above minimum %d)\n",
+		st.TotalRawBytePower, st.TotalQualityAdjPower, st.MinerCount, st.MinerAboveMinPowerCount)
+	fmt.Fprintln(w, "MINER\tRAW BYTE POWER\tQUALITY ADJ POWER")
+	var claim power.Claim
+	if err := claims.ForEach(&claim, func(k string) error {
+		a, err := addr.NewFromBytes([]byte(k))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", a, claim.RawBytePower, claim.QualityAdjPower)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (i *inspector) multisigPending(a addr.Address) error {
+	var st multisig.State
+	if err := i.loadState(a, builtin.MultisigActorCodeID, &st); err != nil {
+		return err
+	}
+	txns, err := adt.AsMap(i.store, st.PendingTxns)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(i.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "signers %v, threshold %d\n", st.Signers, st.NumApprovalsThreshold)
+	fmt.Fprintln(w, "TXN\tTO\tVALUE\tMETHOD\tPARAMS\tAPPROVED")
+	var txn multisig.Transaction
+	if err := txns.ForEach(&txn, func(k string) error {
+		id, err := multisig.ParseTxnIDKey(k)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d\t%v\t%v\t%d\t%x\t%v\n", id, txn.To, txn.Value, txn.Method, txn.Params, txn.Approved)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Resolves an address to an ID address via the init actor.
+func (i *inspector) resolve(a addr.Address) (addr.Address, error) {
+	if a.Protocol() == addr.ID {
+		return a, nil
+	}
+	var st init_.State
+	if err := i.loadState(builtin.InitActorAddr, builtin.InitActorCodeID, &st); err != nil {
+		return addr.Undef, err
+	}
+	idAddr, err := st.ResolveAddress(i.store, a)
+	if err != nil {
+		return addr.Undef, errors.Wrapf(err, "failed to resolve address %v", a)
+	}
+	return idAddr, nil
+}
+
+func (i *inspector) loadActor(a addr.Address) (*states.Actor, error) {
+	idAddr, err := i.resolve(a)
+	if err != nil {
+		return nil, err
+	}
+	act, found, err := i.tree.GetActor(idAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("no actor at address %v", a)
+	}
+	return act, nil
+}
+
+// Loads the state of an actor, which must have the expected code.
+func (i *inspector) loadState(a addr.Address, code cid.Cid, out runtime.CBORUnmarshaler) error {
+	act, err := i.loadActor(a)
+	if err != nil {
+		return err
+	}
+	if !act.Code.Equals(code) {
+		return errors.Errorf("actor %v is a %s, not a %s", a, builtin.ActorNameByCode(act.Code), builtin.ActorNameByCode(code))
+	}
+	return i.tree.LoadActorState(act, out)
+}
+
+func (i *inspector) printJSON(raw []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	_, err := buf.WriteTo(i.out)
+	return err
+}
+
+// Builds a one-actor state tree, for rendering a single actor with its state.
+func singleActorMap(store adt.Store, a addr.Address, act *states.Actor) *adt.Map {
+	m := adt.MakeEmptyMap(store)
+	if err := m.Put(adt.AddrKey(a), act); err != nil {
+		panic(err) // An in-memory put cannot fail.
+	}
+	return m
+}
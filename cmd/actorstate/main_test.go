@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/genesis"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+)
+
+func TestActorState(t *testing.T) {
+	ctx := context.Background()
+	carPath, res, owner := writeGenesisCAR(ctx, t)
+	maddr := res.Miners[0]
+
+	runCmd := func(args ...string) string {
+		var out bytes.Buffer
+		require.NoError(t, run(ctx, append([]string{"-car", carPath}, args...), &out))
+		return out.String()
+	}
+
+	t.Run("actors", func(t *testing.T) {
+		out := runCmd("actors")
+		assert.Contains(t, out, builtin.StoragePowerActorAddr.String())
+		assert.Regexp(t, maddr.String()+`\s+fil/1/storageminer\s+10000`, out)
+		assert.Regexp(t, res.Multisigs[0].String()+`\s+fil/1/multisig`, out)
+	})
+
+	t.Run("actor by robust address", func(t *testing.T) {
+		out := runCmd("actor", owner.String())
+		assert.Contains(t, out, `"`+res.Accounts[0].String()+`"`)
+		assert.Contains(t, out, `"Address": "`+owner.String()+`"`)
+	})
+
+	t.Run("miner info", func(t *testing.T) {
+		out := runCmd("miner", "info", maddr.String())
+		assert.Contains(t, out, `"Owner": "`+res.Accounts[0].String()+`"`)
+		assert.Contains(t, out, `"PeerId"`)
+	})
+
+	t.Run("miner sectors", func(t *testing.T) {
+		out := runCmd("miner", "sectors", maddr.String())
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		require.Len(t, lines, 3)
+		assert.Regexp(t, `^0\s+0\s+`, lines[1])
+		assert.Regexp(t, `^1\s+0\s+`, lines[2])
+	})
+
+	t.Run("miner deadlines", func(t *testing.T) {
+		out := runCmd("miner", "deadlines", maddr.String())
+		assert.Contains(t, out, "proving period start")
+		// Both sectors fill a single partition in the first deadline.
+		assert.Regexp(t, `\n0\s+0\s+2\s+0\s+0\s+0\s`, out)
+	})
+
+	t.Run("market deals", func(t *testing.T) {
+		out := runCmd("market", "deals", "-provider", maddr.String())
+		assert.Equal(t, 1, strings.Count(out, "\n"), "expected only a header: %s", out)
+	})
+
+	t.Run("power claims", func(t *testing.T) {
+		out := runCmd("power", "claims")
+		assert.Contains(t, out, "miners 1")
+		assert.Regexp(t, maddr.String()+`\s+\d+\s+\d+`, out)
+	})
+
+	t.Run("multisig pending", func(t *testing.T) {
+		out := runCmd("multisig", "pending", res.Multisigs[0].String())
+		assert.Contains(t, out, "threshold 1")
+		assert.Contains(t, out, "TXN")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var out bytes.Buffer
+		err := run(ctx, []string{"-car", carPath, "multisig", "pending", maddr.String()}, &out)
+		assert.EqualError(t, err, "actor "+maddr.String()+" is a fil/1/storageminer, not a fil/1/multisig")
+
+		err = run(ctx, []string{"-car", carPath, "miner", "bogus", maddr.String()}, &out)
+		assert.EqualError(t, err, "unknown command miner bogus")
+
+		missing := tutil.NewIDAddr(t, 9999)
+		err = run(ctx, []string{"-car", carPath, "actor", missing.String()}, &out)
+		assert.EqualError(t, err, "no actor at address "+missing.String())
+	})
+}
+
+// Builds a genesis state with an account, a multisig and a miner, and writes it to a CAR file.
+func writeGenesisCAR(ctx context.Context, t *testing.T) (string, *genesis.Result, addr.Address) {
+	bs := ipld.NewBlockStoreInMemory()
+	store := adt.WrapStore(ctx, cbor.NewCborStore(bs))
+	owner := tutil.NewBLSAddr(t, 1)
+	tmpl := genesis.Template{
+		NetworkName:     "actorstate-test",
+		VerifregRootKey: tutil.NewBLSAddr(t, 2),
+		Accounts: []genesis.Account{
+			{Address: owner, Balance: abi.NewTokenAmount(1_000_000)},
+		},
+		Multisigs: []genesis.Multisig{{
+			Signers:               []addr.Address{owner},
+			NumApprovalsThreshold: 1,
+			Balance:               abi.NewTokenAmount(500),
+		}},
+		Miners: []genesis.Miner{{
+			Owner:         owner,
+			Worker:        owner,
+			PeerId:        abi.PeerID("peer"),
+			SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+			Balance:       abi.NewTokenAmount(10_000),
+			Sectors: []genesis.PreSealedSector{{
+				SectorNumber: 0,
+				SealedCID:    tutil.MakeCID("0", &miner.SealedCIDPrefix),
+				Expiration:   abi.ChainEpoch(miner.MinSectorExpiration),
+			}, {
+				SectorNumber: 1,
+				SealedCID:    tutil.MakeCID("1", &miner.SealedCIDPrefix),
+				Expiration:   abi.ChainEpoch(miner.MinSectorExpiration),
+			}},
+		}},
+	}
+	res, err := genesis.Build(ctx, store, &tmpl)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "actorstate")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "state.car")
+	var buf bytes.Buffer
+	require.NoError(t, bs.WriteCAR(&buf, res.StateRoot))
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+	return path, res, owner
+}
@@ -17,6 +17,7 @@ import (
 	system "github.com/filecoin-project/specs-actors/actors/builtin/system"
 	verifreg "github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
 	puppet "github.com/filecoin-project/specs-actors/actors/puppet"
+	states "github.com/filecoin-project/specs-actors/actors/states"
 )
 
 func main() {
@@ -26,6 +27,9 @@ func main() {
 		abi.SectorID{},
 		abi.SectorInfo{},
 		abi.SealVerifyInfo{},
+		abi.AggregateSealVerifyInfo{},
+		abi.AggregateSealVerifyProofAndInfos{},
+		abi.ReplicaUpdateInfo{},
 		abi.PoStProof{},
 		abi.WindowPoStVerifyInfo{},
 		abi.WinningPoStVerifyInfo{},
@@ -159,6 +163,8 @@ func main() {
 		market.DealProposal{},
 		market.ClientDealProposal{},
 		market.DealState{},
+		market.SectorDeals{},
+		market.SectorWeights{},
 	); err != nil {
 		panic(err)
 	}
@@ -176,6 +182,10 @@ func main() {
 		miner.SectorPreCommitInfo{},
 		miner.SectorOnChainInfo{},
 		miner.WorkerKeyChange{},
+		miner.PendingOwnerChange{},
+		miner.WindowedPoSt{},
+		miner.BeneficiaryTerm{},
+		miner.PendingBeneficiaryChange{},
 		// method params
 		// miner.ConstructorParams{},
 		miner.SubmitWindowedPoStParams{},
@@ -193,6 +203,18 @@ func main() {
 		miner.CheckSectorProvenParams{},
 		miner.WithdrawBalanceParams{},
 		miner.CompactPartitionsParams{},
+		miner.PreCommitSectorBatchParams{},
+		miner.ProveCommitAggregateParams{},
+		miner.ProveReplicaUpdatesParams{},
+		miner.DeclarationsReturn{},
+		miner.WithdrawPreCommitsParams{},
+		miner.MaskSectorNumbersParams{},
+		miner.CompactVestingFundsParams{},
+		miner.ReplicaUpdate{},
+		miner.DisputeWindowedPoStParams{},
+		miner.ChangeBeneficiaryParams{},
+		miner.ActiveBeneficiary{},
+		miner.GetBeneficiaryReturn{},
 		// other types
 		miner.CronEventPayload{},
 		miner.FaultDeclaration{},
@@ -227,4 +249,10 @@ func main() {
 		panic(err)
 	}
 
+	if err := gen.WriteTupleEncodersToFile("./actors/states/cbor_gen.go", "states",
+		states.Actor{},
+	); err != nil {
+		panic(err)
+	}
+
 }
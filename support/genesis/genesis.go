@@ -0,0 +1,423 @@
+package genesis
+
+import (
+	"context"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	xerrors "golang.org/x/xerrors"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/cron"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/builtin/system"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/vm"
+)
+
+// Template is a declarative description of the initial state of a network.
+type Template struct {
+	NetworkName string
+	// Public key address of the verified registry root key holder.
+	// An account is created for it if it is not also listed in Accounts.
+	VerifregRootKey addr.Address
+	// Balance of the reward actor, from which all block rewards are paid.
+	// Defaults to the full mining reward allocation if nil.
+	RewardBalance *abi.TokenAmount
+	Accounts      []Account
+	Multisigs     []Multisig
+	Miners        []Miner
+}
+
+// Account is an initial account actor.
+type Account struct {
+	Address addr.Address // Public key (SECP256K1 or BLS) address.
+	Balance abi.TokenAmount
+}
+
+// Multisig is an initial multisig actor, optionally with a linear vesting schedule over its balance.
+type Multisig struct {
+	Signers               []addr.Address
+	NumApprovalsThreshold uint64
+	Balance               abi.TokenAmount
+	// Funds vest linearly from VestingStart over UnlockDuration epochs. Zero means the balance is unlocked.
+	VestingStart   abi.ChainEpoch
+	UnlockDuration abi.ChainEpoch
+}
+
+// Miner is an initial storage miner with sectors that are already sealed and assigned to deadlines.
+// Owner and worker must be listed in Accounts, and the worker must be a BLS address.
+// The miner's balance is transferred from the owner at creation.
+type Miner struct {
+	Owner         addr.Address
+	Worker        addr.Address
+	PeerId        abi.PeerID
+	SealProofType abi.RegisteredSealProof
+	Balance       abi.TokenAmount
+	Sectors       []PreSealedSector
+}
+
+// PreSealedSector is a sector committed at genesis.
+// Pre-sealed sectors carry no deals and require no initial pledge; their power is claimed at genesis.
+type PreSealedSector struct {
+	SectorNumber abi.SectorNumber
+	SealedCID    cid.Cid
+	Expiration   abi.ChainEpoch
+}
+
+// Result describes a genesis state tree, and the ID addresses of the actors created from the template.
+type Result struct {
+	StateRoot    cid.Cid
+	VerifregRoot addr.Address
+	Accounts     []addr.Address // In template order.
+	Multisigs    []addr.Address // In template order.
+	Miners       []addr.Address // In template order.
+}
+
+// Build constructs the genesis state tree described by a template, returning its root in the store.
+// The state tree is a HAMT of states.Actor keyed by ID address, loadable with vm.NewVMAtRoot.
+//
+// The singleton actors are placed at their reserved addresses and all other actors are allocated sequential ID
+// addresses by the Init actor, starting at builtin.FirstNonSingletonActorId, in the order: accounts, the verified
+// registry root key (if not an account), multisigs, then miners.
+func Build(ctx context.Context, store adt.Store, tmpl *Template) (*Result, error) {
+	v := vm.NewVM(ctx, store)
+	b := &builder{v: v, store: store}
+	result := &Result{}
+
+	if err := b.setupSingletons(tmpl); err != nil {
+		return nil, err
+	}
+
+	for _, acct := range tmpl.Accounts {
+		idAddr, err := b.createAccount(acct.Address, acct.Balance)
+		if err != nil {
+			return nil, err
+		}
+		result.Accounts = append(result.Accounts, idAddr)
+	}
+
+	rootID, found := v.NormalizeAddress(tmpl.VerifregRootKey)
+	if !found {
+		var err error
+		if rootID, err = b.createAccount(tmpl.VerifregRootKey, big.Zero()); err != nil {
+			return nil, err
+		}
+	}
+	result.VerifregRoot = rootID
+	emptyMap, err := adt.MakeEmptyMap(store).Root()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.SetActorState(builtin.VerifiedRegistryActorAddr, builtin.VerifiedRegistryActorCodeID, big.Zero(),
+		verifreg.ConstructState(emptyMap, rootID)); err != nil {
+		return nil, err
+	}
+
+	for i, msig := range tmpl.Multisigs {
+		idAddr, err := b.createMultisig(&msig)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to create multisig %d: %w", i, err)
+		}
+		result.Multisigs = append(result.Multisigs, idAddr)
+	}
+
+	for i, m := range tmpl.Miners {
+		idAddr, err := b.createMiner(&m)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to create miner %d: %w", i, err)
+		}
+		result.Miners = append(result.Miners, idAddr)
+	}
+
+	if err := b.initializeNetworkPower(); err != nil {
+		return nil, err
+	}
+
+	if result.StateRoot, err = v.Checkpoint(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type builder struct {
+	v     *vm.VM
+	store adt.Store
+}
+
+func (b *builder) setupSingletons(tmpl *Template) error {
+	emptyMap, err := adt.MakeEmptyMap(b.store).Root()
+	if err != nil {
+		return err
+	}
+	emptyArray, err := adt.MakeEmptyArray(b.store).Root()
+	if err != nil {
+		return err
+	}
+	emptyMultimap, err := adt.MakeEmptyMultimap(b.store).Root()
+	if err != nil {
+		return err
+	}
+	emptySetMultimap, err := market.MakeEmptySetMultimap(b.store).Root()
+	if err != nil {
+		return err
+	}
+
+	rewardBalance := big.Add(reward.SimpleTotal, reward.BaselineTotal)
+	if tmpl.RewardBalance != nil {
+		rewardBalance = *tmpl.RewardBalance
+	}
+
+	singletons := []struct {
+		addr    addr.Address
+		code    cid.Cid
+		balance abi.TokenAmount
+		state   runtime.CBORMarshaler
+	}{
+		{builtin.SystemActorAddr, builtin.SystemActorCodeID, big.Zero(), &system.State{}},
+		{builtin.InitActorAddr, builtin.InitActorCodeID, big.Zero(), init_.ConstructState(emptyMap, tmpl.NetworkName)},
+		{builtin.RewardActorAddr, builtin.RewardActorCodeID, rewardBalance, reward.ConstructState(big.Zero())},
+		{builtin.CronActorAddr, builtin.CronActorCodeID, big.Zero(), cron.ConstructState(cron.BuiltInEntries())},
+		{builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, big.Zero(), power.ConstructState(emptyMap, emptyMultimap)},
+		{builtin.StorageMarketActorAddr, builtin.StorageMarketActorCodeID, big.Zero(), market.ConstructState(emptyArray, emptyMap, emptySetMultimap)},
+		{builtin.BurntFundsActorAddr, builtin.AccountActorCodeID, big.Zero(), &account.State{Address: builtin.BurntFundsActorAddr}},
+	}
+	for _, s := range singletons {
+		if err := b.v.SetActorState(s.addr, s.code, s.balance, s.state); err != nil {
+			return xerrors.Errorf("failed to create singleton %v: %w", s.addr, err)
+		}
+	}
+	return nil
+}
+
+// Allocates the next ID address from the Init actor, mapping the given address to it if not undefined.
+func (b *builder) allocateID(a addr.Address) (addr.Address, error) {
+	var st init_.State
+	if err := b.v.GetState(builtin.InitActorAddr, &st); err != nil {
+		return addr.Undef, err
+	}
+
+	var idAddr addr.Address
+	var err error
+	if a == addr.Undef {
+		idAddr, err = addr.NewIDAddress(uint64(st.NextID))
+		st.NextID++
+	} else {
+		idAddr, err = st.MapAddressToNewID(b.store, a)
+	}
+	if err != nil {
+		return addr.Undef, err
+	}
+
+	if err := b.v.SetActorState(builtin.InitActorAddr, builtin.InitActorCodeID, big.Zero(), &st); err != nil {
+		return addr.Undef, err
+	}
+	return idAddr, nil
+}
+
+func (b *builder) createAccount(a addr.Address, balance abi.TokenAmount) (addr.Address, error) {
+	if a.Protocol() != addr.SECP256K1 && a.Protocol() != addr.BLS {
+		return addr.Undef, xerrors.Errorf("account address %v must be a public key address", a)
+	}
+	if _, found := b.v.NormalizeAddress(a); found {
+		return addr.Undef, xerrors.Errorf("duplicate account %v", a)
+	}
+	if balance.LessThan(big.Zero()) {
+		return addr.Undef, xerrors.Errorf("negative balance %v for account %v", balance, a)
+	}
+
+	idAddr, err := b.allocateID(a)
+	if err != nil {
+		return addr.Undef, xerrors.Errorf("failed to allocate ID for account %v: %w", a, err)
+	}
+	if err := b.v.SetActorState(idAddr, builtin.AccountActorCodeID, balance, &account.State{Address: a}); err != nil {
+		return addr.Undef, err
+	}
+	return idAddr, nil
+}
+
+func (b *builder) createMultisig(msig *Multisig) (addr.Address, error) {
+	if len(msig.Signers) < 1 {
+		return addr.Undef, xerrors.Errorf("must have at least one signer")
+	}
+	if msig.NumApprovalsThreshold < 1 || msig.NumApprovalsThreshold > uint64(len(msig.Signers)) {
+		return addr.Undef, xerrors.Errorf("invalid approval threshold %d for %d signers", msig.NumApprovalsThreshold, len(msig.Signers))
+	}
+	if msig.UnlockDuration < 0 {
+		return addr.Undef, xerrors.Errorf("negative unlock duration %d", msig.UnlockDuration)
+	}
+	if msig.Balance.LessThan(big.Zero()) {
+		return addr.Undef, xerrors.Errorf("negative balance %v", msig.Balance)
+	}
+
+	// Signers are stored by ID address where the account already exists.
+	signers := make([]addr.Address, len(msig.Signers))
+	seen := make(map[addr.Address]struct{}, len(msig.Signers))
+	for i, signer := range msig.Signers {
+		if idAddr, found := b.v.NormalizeAddress(signer); found {
+			signer = idAddr
+		}
+		if _, ok := seen[signer]; ok {
+			return addr.Undef, xerrors.Errorf("duplicate signer %v", msig.Signers[i])
+		}
+		seen[signer] = struct{}{}
+		signers[i] = signer
+	}
+
+	pending, err := adt.MakeEmptyMap(b.store).Root()
+	if err != nil {
+		return addr.Undef, err
+	}
+	st := multisig.State{
+		Signers:               signers,
+		NumApprovalsThreshold: msig.NumApprovalsThreshold,
+		NextTxnID:             0,
+		InitialBalance:        big.Zero(),
+		PendingTxns:           pending,
+	}
+	if msig.UnlockDuration != 0 {
+		st.InitialBalance = msig.Balance
+		st.StartEpoch = msig.VestingStart
+		st.UnlockDuration = msig.UnlockDuration
+	}
+
+	idAddr, err := b.allocateID(addr.Undef)
+	if err != nil {
+		return addr.Undef, err
+	}
+	if err := b.v.SetActorState(idAddr, builtin.MultisigActorCodeID, msig.Balance, &st); err != nil {
+		return addr.Undef, err
+	}
+	return idAddr, nil
+}
+
+func (b *builder) createMiner(m *Miner) (addr.Address, error) {
+	owner, found := b.v.NormalizeAddress(m.Owner)
+	if !found {
+		return addr.Undef, xerrors.Errorf("owner %v is not an account", m.Owner)
+	}
+
+	// Create the miner by executing the real constructor, which validates the control addresses and
+	// enrolls the first proving period cron event.
+	ret, code := b.v.ApplyMessage(owner, builtin.StoragePowerActorAddr, m.Balance, builtin.MethodsPower.CreateMiner,
+		&power.CreateMinerParams{
+			Owner:         m.Owner,
+			Worker:        m.Worker,
+			SealProofType: m.SealProofType,
+			Peer:          m.PeerId,
+		})
+	if code != exitcode.Ok {
+		return addr.Undef, xerrors.Errorf("failed to create miner with owner %v: exit code %v", m.Owner, code)
+	}
+	minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+
+	if len(m.Sectors) == 0 {
+		return minerAddr, nil
+	}
+
+	act, _, err := b.v.GetActor(minerAddr)
+	if err != nil {
+		return addr.Undef, err
+	}
+	var st miner.State
+	if err := b.v.GetState(minerAddr, &st); err != nil {
+		return addr.Undef, err
+	}
+	info, err := st.GetInfo(b.store)
+	if err != nil {
+		return addr.Undef, err
+	}
+
+	sectors := make([]*miner.SectorOnChainInfo, len(m.Sectors))
+	allocated := abi.NewBitField()
+	for i, s := range m.Sectors {
+		if s.SealedCID.Prefix() != miner.SealedCIDPrefix {
+			return addr.Undef, xerrors.Errorf("sector %d sealed CID had wrong prefix", s.SectorNumber)
+		}
+		if s.Expiration <= 0 {
+			return addr.Undef, xerrors.Errorf("sector %d expiration %d must be after genesis", s.SectorNumber, s.Expiration)
+		}
+		if found, err := st.HasSectorNo(b.store, s.SectorNumber); err != nil {
+			return addr.Undef, err
+		} else if found {
+			return addr.Undef, xerrors.Errorf("duplicate sector %d", s.SectorNumber)
+		}
+		sectors[i] = &miner.SectorOnChainInfo{
+			SectorNumber:       s.SectorNumber,
+			SealProof:          info.SealProofType,
+			SealedCID:          s.SealedCID,
+			DealIDs:            nil,
+			Activation:         0,
+			PowerBaseEpoch:     0,
+			Expiration:         s.Expiration,
+			DealWeight:         big.Zero(),
+			VerifiedDealWeight: big.Zero(),
+			InitialPledge:      big.Zero(),
+		}
+		if err := st.PutSectors(b.store, sectors[i]); err != nil {
+			return addr.Undef, err
+		}
+		allocated.Set(uint64(s.SectorNumber))
+	}
+	if err := st.MaskSectorNumbers(allocated); err != nil {
+		return addr.Undef, err
+	}
+
+	newPower, err := st.AssignSectorsToDeadlines(b.store, 0, sectors, info.WindowPoStPartitionSectors, info.SectorSize, st.QuantEndOfDeadline())
+	if err != nil {
+		return addr.Undef, xerrors.Errorf("failed to assign sectors to deadlines: %w", err)
+	}
+	if err := b.v.SetActorState(minerAddr, builtin.StorageMinerActorCodeID, act.Balance, &st); err != nil {
+		return addr.Undef, err
+	}
+
+	var powerSt power.State
+	if err := b.v.GetState(builtin.StoragePowerActorAddr, &powerSt); err != nil {
+		return addr.Undef, err
+	}
+	if err := powerSt.AddToClaim(b.store, minerAddr, newPower.Raw, newPower.QA); err != nil {
+		return addr.Undef, err
+	}
+	powerAct, _, err := b.v.GetActor(builtin.StoragePowerActorAddr)
+	if err != nil {
+		return addr.Undef, err
+	}
+	if err := b.v.SetActorState(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, powerAct.Balance, &powerSt); err != nil {
+		return addr.Undef, err
+	}
+	return minerAddr, nil
+}
+
+// Records the genesis power as the power for the first epoch, and computes the first epoch's reward from it.
+func (b *builder) initializeNetworkPower() error {
+	var powerSt power.State
+	if err := b.v.GetState(builtin.StoragePowerActorAddr, &powerSt); err != nil {
+		return err
+	}
+	powerAct, _, err := b.v.GetActor(builtin.StoragePowerActorAddr)
+	if err != nil {
+		return err
+	}
+	powerSt.ThisEpochRawBytePower, powerSt.ThisEpochQualityAdjPower = power.CurrentTotalPower(&powerSt)
+	if err := b.v.SetActorState(builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, powerAct.Balance, &powerSt); err != nil {
+		return err
+	}
+
+	rewardAct, _, err := b.v.GetActor(builtin.RewardActorAddr)
+	if err != nil {
+		return err
+	}
+	return b.v.SetActorState(builtin.RewardActorAddr, builtin.RewardActorCodeID, rewardAct.Balance,
+		reward.ConstructState(powerSt.ThisEpochRawBytePower))
+}
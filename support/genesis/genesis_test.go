@@ -0,0 +1,228 @@
+package genesis_test
+
+import (
+	"context"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/genesis"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+	"github.com/filecoin-project/specs-actors/support/vm"
+)
+
+func TestBuild(t *testing.T) {
+	ctx := context.Background()
+	store := ipld.NewADTStore(ctx)
+
+	alice := tutil.NewBLSAddr(t, 1)
+	bob := tutil.NewSECP256K1Addr(t, "bob")
+	rootKey := tutil.NewBLSAddr(t, 2)
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1
+	expiration := abi.ChainEpoch(miner.MinSectorExpiration)
+
+	tmpl := genesis.Template{
+		NetworkName:     "genesis-test",
+		VerifregRootKey: rootKey,
+		Accounts: []genesis.Account{
+			{Address: alice, Balance: abi.NewTokenAmount(1_000_000)},
+			{Address: bob, Balance: abi.NewTokenAmount(2_000)},
+		},
+		Multisigs: []genesis.Multisig{{
+			Signers:               []addr.Address{alice, bob},
+			NumApprovalsThreshold: 2,
+			Balance:               abi.NewTokenAmount(5_000),
+			VestingStart:          0,
+			UnlockDuration:        1_000,
+		}},
+		Miners: []genesis.Miner{{
+			Owner:         alice,
+			Worker:        alice,
+			PeerId:        abi.PeerID("peer"),
+			SealProofType: sealProof,
+			Balance:       abi.NewTokenAmount(10_000),
+			Sectors: []genesis.PreSealedSector{
+				{SectorNumber: 0, SealedCID: tutil.MakeCID("0", &miner.SealedCIDPrefix), Expiration: expiration},
+				{SectorNumber: 1, SealedCID: tutil.MakeCID("1", &miner.SealedCIDPrefix), Expiration: expiration},
+			},
+		}},
+	}
+
+	result, err := genesis.Build(ctx, store, &tmpl)
+	require.NoError(t, err)
+
+	// IDs are allocated sequentially from the first non-singleton ID
+	id := func(i uint64) addr.Address { return tutil.NewIDAddr(t, builtin.FirstNonSingletonActorId+i) }
+	assert.Equal(t, []addr.Address{id(0), id(1)}, result.Accounts)
+	assert.Equal(t, id(2), result.VerifregRoot)
+	assert.Equal(t, []addr.Address{id(3)}, result.Multisigs)
+	assert.Equal(t, []addr.Address{id(4)}, result.Miners)
+
+	v, err := vm.NewVMAtRoot(ctx, store, result.StateRoot)
+	require.NoError(t, err)
+
+	t.Run("init", func(t *testing.T) {
+		var st init_.State
+		vm.GetState(t, v, builtin.InitActorAddr, &st)
+		assert.Equal(t, "genesis-test", st.NetworkName)
+		assert.Equal(t, abi.ActorID(builtin.FirstNonSingletonActorId+5), st.NextID)
+
+		resolved, found := v.NormalizeAddress(bob)
+		require.True(t, found)
+		assert.Equal(t, id(1), resolved)
+	})
+
+	t.Run("accounts", func(t *testing.T) {
+		// the miner's balance was transferred from its owner
+		assertBalance(t, v, result.Accounts[0], abi.NewTokenAmount(990_000))
+		assertBalance(t, v, result.Accounts[1], abi.NewTokenAmount(2_000))
+		assertBalance(t, v, result.VerifregRoot, big.Zero())
+	})
+
+	t.Run("verifreg", func(t *testing.T) {
+		var st verifreg.State
+		vm.GetState(t, v, builtin.VerifiedRegistryActorAddr, &st)
+		assert.Equal(t, result.VerifregRoot, st.RootKey)
+	})
+
+	t.Run("multisig", func(t *testing.T) {
+		var st multisig.State
+		vm.GetState(t, v, result.Multisigs[0], &st)
+		assert.Equal(t, result.Accounts, st.Signers)
+		assert.Equal(t, uint64(2), st.NumApprovalsThreshold)
+		assert.Equal(t, abi.NewTokenAmount(5_000), st.InitialBalance)
+		assert.Equal(t, abi.ChainEpoch(1_000), st.UnlockDuration)
+		assertBalance(t, v, result.Multisigs[0], abi.NewTokenAmount(5_000))
+	})
+
+	t.Run("miner", func(t *testing.T) {
+		minerAddr := result.Miners[0]
+		assertBalance(t, v, minerAddr, abi.NewTokenAmount(10_000))
+
+		var st miner.State
+		vm.GetState(t, v, minerAddr, &st)
+		info, err := st.GetInfo(store)
+		require.NoError(t, err)
+		assert.Equal(t, result.Accounts[0], info.Owner)
+		assert.Equal(t, result.Accounts[0], info.Worker)
+
+		for _, sno := range []abi.SectorNumber{0, 1} {
+			sector, found, err := st.GetSector(store, sno)
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, expiration, sector.Expiration)
+
+			_, _, err = st.FindSector(store, sno)
+			require.NoError(t, err)
+		}
+
+		sectorSize, err := sealProof.SectorSize()
+		require.NoError(t, err)
+		expectedPower := big.Mul(big.NewInt(2), big.NewIntUnsigned(uint64(sectorSize)))
+
+		var powerSt power.State
+		vm.GetState(t, v, builtin.StoragePowerActorAddr, &powerSt)
+		claims, err := adt.AsMap(store, powerSt.Claims)
+		require.NoError(t, err)
+		var claim power.Claim
+		found, err := claims.Get(adt.AddrKey(minerAddr), &claim)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, expectedPower, claim.RawBytePower)
+		assert.Equal(t, expectedPower, claim.QualityAdjPower)
+		assert.Equal(t, int64(1), powerSt.MinerCount)
+		assert.Equal(t, expectedPower, powerSt.TotalBytesCommitted)
+	})
+
+	t.Run("state invariants hold", func(t *testing.T) {
+		vm.AssertStateInvariants(t, v)
+	})
+
+	t.Run("cron executes on the genesis state", func(t *testing.T) {
+		vm.AdvanceToEpochWithCron(t, v, 10)
+		vm.AssertStateInvariants(t, v)
+	})
+}
+
+func TestBuildErrors(t *testing.T) {
+	ctx := context.Background()
+	alice := tutil.NewBLSAddr(t, 1)
+	rootKey := tutil.NewBLSAddr(t, 2)
+
+	t.Run("duplicate account", func(t *testing.T) {
+		_, err := genesis.Build(ctx, ipld.NewADTStore(ctx), &genesis.Template{
+			VerifregRootKey: rootKey,
+			Accounts: []genesis.Account{
+				{Address: alice, Balance: big.Zero()},
+				{Address: alice, Balance: big.Zero()},
+			},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("account with ID address", func(t *testing.T) {
+		_, err := genesis.Build(ctx, ipld.NewADTStore(ctx), &genesis.Template{
+			VerifregRootKey: rootKey,
+			Accounts:        []genesis.Account{{Address: tutil.NewIDAddr(t, 1000), Balance: big.Zero()}},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("multisig threshold exceeds signers", func(t *testing.T) {
+		_, err := genesis.Build(ctx, ipld.NewADTStore(ctx), &genesis.Template{
+			VerifregRootKey: rootKey,
+			Multisigs: []genesis.Multisig{{
+				Signers:               []addr.Address{alice},
+				NumApprovalsThreshold: 2,
+				Balance:               big.Zero(),
+			}},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("miner owner is not an account", func(t *testing.T) {
+		_, err := genesis.Build(ctx, ipld.NewADTStore(ctx), &genesis.Template{
+			VerifregRootKey: rootKey,
+			Miners: []genesis.Miner{{
+				Owner:         alice,
+				Worker:        alice,
+				SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+				Balance:       big.Zero(),
+			}},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("miner balance exceeds owner balance", func(t *testing.T) {
+		_, err := genesis.Build(ctx, ipld.NewADTStore(ctx), &genesis.Template{
+			VerifregRootKey: rootKey,
+			Accounts:        []genesis.Account{{Address: alice, Balance: abi.NewTokenAmount(10)}},
+			Miners: []genesis.Miner{{
+				Owner:         alice,
+				Worker:        alice,
+				SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+				Balance:       abi.NewTokenAmount(11),
+			}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func assertBalance(t *testing.T, v *vm.VM, a addr.Address, expected abi.TokenAmount) {
+	act, found, err := v.GetActor(a)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, expected, act.Balance)
+}
@@ -0,0 +1,130 @@
+package ipld
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// Support for the CAR (content-addressable archive) v1 format: a header naming the root CIDs,
+// followed by a sequence of blocks. Each of the header and blocks is prefixed by its length as an
+// unsigned varint, and each block by its CID.
+
+type carHeader struct {
+	Roots   []cid.Cid `refmt:"roots"`
+	Version uint64    `refmt:"version"`
+}
+
+func init() {
+	cbor.RegisterCborType(carHeader{})
+}
+
+// Maximum size of a CAR section, to bound allocation when reading a corrupt file.
+const maxCARSectionSize = 32 << 20
+
+// LoadCAR reads the blocks of a CAR file into the in-memory store, returning the file's roots.
+func (mb *BlockStoreInMemory) LoadCAR(r io.Reader) ([]cid.Cid, error) {
+	br := bufio.NewReader(r)
+	hb, err := readCARSection(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CAR header: %w", err)
+	}
+	var header carHeader
+	if err := cbor.DecodeInto(hb, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode CAR header: %w", err)
+	}
+	if header.Version != 1 {
+		return nil, fmt.Errorf("unsupported CAR version %d", header.Version)
+	}
+
+	for {
+		data, err := readCARSection(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read CAR block: %w", err)
+		}
+		n, c, err := cid.CidFromBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CAR block CID: %w", err)
+		}
+		blk, err := block.NewBlockWithCid(data[n:], c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAR block %v: %w", c, err)
+		}
+		if err := mb.Put(blk); err != nil {
+			return nil, err
+		}
+	}
+	return header.Roots, nil
+}
+
+// WriteCAR writes every block in the store to a CAR file with the given roots.
+// Blocks are written in CID order, so the output is deterministic.
+func (mb *BlockStoreInMemory) WriteCAR(w io.Writer, roots ...cid.Cid) error {
+	hb, err := cbor.DumpObject(&carHeader{Roots: roots, Version: 1})
+	if err != nil {
+		return err
+	}
+	if err := writeCARSection(w, hb); err != nil {
+		return err
+	}
+
+	keys := make([]cid.Cid, 0, len(mb.data))
+	for c := range mb.data { //nolint:nomaprange
+		keys = append(keys, c)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KeyString() < keys[j].KeyString()
+	})
+	for _, c := range keys {
+		if err := writeCARSection(w, append(c.Bytes(), mb.data[c].RawData()...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCARStore reads a CAR file into a new in-memory store, returning the store and the file's roots.
+func LoadCARStore(ctx context.Context, r io.Reader) (adt.Store, []cid.Cid, error) {
+	bs := NewBlockStoreInMemory()
+	roots, err := bs.LoadCAR(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return adt.WrapStore(ctx, cbor.NewCborStore(bs)), roots, nil
+}
+
+func readCARSection(br *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err // io.EOF at a section boundary indicates the end of the file.
+	}
+	if l > maxCARSectionSize {
+		return nil, fmt.Errorf("section length %d exceeds maximum %d", l, maxCARSectionSize)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func writeCARSection(w io.Writer, data []byte) error {
+	var lb [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lb[:], uint64(len(data)))
+	if _, err := w.Write(lb[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
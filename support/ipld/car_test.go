@@ -0,0 +1,51 @@
+package ipld_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	cbg "github.com/whyrusleeping/cbor-gen"
+
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+)
+
+func TestCARRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	bs := ipld.NewBlockStoreInMemory()
+	store := adt.WrapStore(ctx, cbor.NewCborStore(bs))
+
+	arr := adt.MakeEmptyArray(store)
+	for i := uint64(0); i < 100; i++ {
+		v := cbg.CborInt(i * 3)
+		require.NoError(t, arr.Set(i, &v))
+	}
+	root, err := arr.Root()
+	require.NoError(t, err)
+
+	var first, second bytes.Buffer
+	require.NoError(t, bs.WriteCAR(&first, root))
+	require.NoError(t, bs.WriteCAR(&second, root))
+	assert.Equal(t, first.Bytes(), second.Bytes(), "CAR output should be deterministic")
+
+	loaded, roots, err := ipld.LoadCARStore(ctx, &first)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, root, roots[0])
+
+	loadedArr, err := adt.AsArray(loaded, roots[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), loadedArr.Length())
+	var v cbg.CborInt
+	found, err := loadedArr.Get(42, &v)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, cbg.CborInt(126), v)
+
+	_, _, err = ipld.LoadCARStore(ctx, bytes.NewReader(second.Bytes()[:second.Len()-1]))
+	assert.Error(t, err, "truncated CAR should fail to load")
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/minio/blake2b-simd"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
 )
 
 // Build for fluent initialization of a mock runtime.
@@ -95,3 +96,9 @@ func (b *RuntimeBuilder) WithHasher(f func(data []byte) [32]byte) *RuntimeBuilde
 	b.rt.hashfunc = f
 	return b
 }
+
+// Meters operations with a pricelist, recording a gas trace for each method invocation.
+func (b *RuntimeBuilder) WithPricelist(p runtime.Pricelist) *RuntimeBuilder {
+	b.rt.pricelist = p
+	return b
+}
@@ -0,0 +1,113 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	goruntime "runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+)
+
+// GasTrace records the gas charged during a single method invocation on the mock runtime.
+// Traces are recorded only when the runtime is built with a pricelist.
+type GasTrace struct {
+	Method  string
+	Charges []runtime.GasCharge
+}
+
+// Total returns the total gas charged, excluding virtual gas.
+func (t *GasTrace) Total() int64 {
+	total := int64(0)
+	for _, c := range t.Charges {
+		total += c.Total()
+	}
+	return total
+}
+
+// TotalsByName returns the total gas charged for each kind of charge, by charge name.
+func (t *GasTrace) TotalsByName() map[string]int64 {
+	totals := map[string]int64{}
+	for _, c := range t.Charges {
+		totals[c.Name] += c.Total()
+	}
+	return totals
+}
+
+// CountsByName returns the number of charges of each kind, by charge name.
+func (t *GasTrace) CountsByName() map[string]int {
+	counts := map[string]int{}
+	for _, c := range t.Charges {
+		counts[c.Name]++
+	}
+	return counts
+}
+
+// String summarizes the trace as a table of charges by name, most expensive first.
+func (t *GasTrace) String() string {
+	totals := t.TotalsByName()
+	counts := t.CountsByName()
+	names := make([]string, 0, len(totals))
+	for name := range totals { //nolint:nomaprange
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]] != totals[names[j]] {
+			return totals[names[i]] > totals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tcount\tgas\t\n", t.Method)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%d\t%d\t\n", name, counts[name], totals[name])
+	}
+	fmt.Fprintf(w, "total\t%d\t%d\t\n", len(t.Charges), t.Total())
+	_ = w.Flush()
+	return sb.String()
+}
+
+// GasTraces returns the gas traces for all method invocations, in order.
+func (rt *Runtime) GasTraces() []*GasTrace {
+	return rt.gasTraces
+}
+
+// LastGasTrace returns the gas trace for the most recent method invocation, or nil if none has been recorded.
+func (rt *Runtime) LastGasTrace() *GasTrace {
+	if len(rt.gasTraces) == 0 {
+		return nil
+	}
+	return rt.gasTraces[len(rt.gasTraces)-1]
+}
+
+func (rt *Runtime) ChargeCollectionOp(op runtime.CollectionOp) {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnCollectionOp(op))
+	}
+}
+
+// Whether operations are being metered, i.e. a pricelist is configured and a method is executing.
+// Operations outside a method invocation, such as test harness access to state, are not metered.
+func (rt *Runtime) metered() bool {
+	return rt.pricelist != nil && rt.inCall && len(rt.gasTraces) > 0
+}
+
+// Records a charge in the current method's gas trace.
+func (rt *Runtime) chargePrice(charge runtime.GasCharge) {
+	trace := rt.gasTraces[len(rt.gasTraces)-1]
+	trace.Charges = append(trace.Charges, charge)
+}
+
+// Starts a new gas trace for a method invocation, if a pricelist is configured.
+func (rt *Runtime) startGasTrace(method reflect.Value) {
+	if rt.pricelist == nil {
+		return
+	}
+	name := goruntime.FuncForPC(method.Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	rt.gasTraces = append(rt.gasTraces, &GasTrace{Method: name[strings.LastIndex(name, ".")+1:]})
+}
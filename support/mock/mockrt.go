@@ -59,6 +59,8 @@ type Runtime struct {
 	expectVerifySigs               []*expectVerifySig
 	expectCreateActor              *expectCreateActor
 	expectVerifySeal               *expectVerifySeal
+	expectVerifyAggregateSeals     *expectVerifyAggregateSeals
+	expectVerifyReplicaUpdates     []*expectVerifyReplicaUpdate
 	expectComputeUnsealedSectorCID *expectComputeUnsealedSectorCID
 	expectVerifyPoSt               *expectVerifyPoSt
 	expectVerifyConsensusFault     *expectVerifyConsensusFault
@@ -67,6 +69,9 @@ type Runtime struct {
 	logs []string
 	// Gas charged explicitly through rt.ChargeGas. Note: most charges are implicit
 	gasCharged int64
+	// Prices for implicit charges, recorded in a gas trace for each method invocation. Nil if not metering.
+	pricelist runtime.Pricelist
+	gasTraces []*GasTrace
 }
 
 type expectRandomness struct {
@@ -104,6 +109,16 @@ type expectVerifySeal struct {
 	result error
 }
 
+type expectVerifyAggregateSeals struct {
+	aggregate abi.AggregateSealVerifyProofAndInfos
+	result    error
+}
+
+type expectVerifyReplicaUpdate struct {
+	update abi.ReplicaUpdateInfo
+	result error
+}
+
 type expectComputeUnsealedSectorCID struct {
 	reg       abi.RegisteredSealProof
 	pieces    []abi.PieceInfo
@@ -285,6 +300,9 @@ func (rt *Runtime) Send(toAddr addr.Address, methodNum abi.MethodNum, params run
 			toAddr, methodNum, value, params, exp.to, exp.method, exp.value, exp.params)
 	}
 
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnMethodInvocation(value, methodNum))
+	}
 	if value.GreaterThan(rt.balance) {
 		rt.Abortf(exitcode.SysErrSenderStateInvalid, "cannot send value: %v exceeds balance: %v", value, rt.balance)
 	}
@@ -311,6 +329,9 @@ func (rt *Runtime) CreateActor(codeId cid.Cid, address addr.Address) {
 	if rt.inTransaction {
 		rt.Abortf(exitcode.SysErrorIllegalActor, "side-effect within transaction")
 	}
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnCreateActor())
+	}
 	exp := rt.expectCreateActor
 	if exp != nil {
 		if !exp.codeId.Equals(codeId) || exp.address != address {
@@ -330,6 +351,9 @@ func (rt *Runtime) DeleteActor(addr addr.Address) {
 	if rt.inTransaction {
 		rt.Abortf(exitcode.SysErrorIllegalActor, "side-effect within transaction")
 	}
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnDeleteActor())
+	}
 	if rt.expectDeleteActor == nil {
 		rt.failTestNow("unexpected call to delete actor %s", addr.String())
 	}
@@ -411,6 +435,9 @@ func (rt *Runtime) put(c cid.Cid, data []byte) {
 func (rt *Runtime) Get(c cid.Cid, o runtime.CBORUnmarshaler) bool {
 	// requireInCall omitted because it makes using this mock runtime as a store awkward.
 	data, found := rt.get(c)
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnIpldGet(len(data)))
+	}
 	if found {
 		err := o.UnmarshalCBOR(bytes.NewReader(data))
 		if err != nil {
@@ -429,6 +456,9 @@ func (rt *Runtime) Put(o runtime.CBORMarshaler) cid.Cid {
 		rt.Abortf(exitcode.SysErrSerialization, err.Error())
 	}
 	data := r.Bytes()
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnIpldPut(len(data)))
+	}
 	key, err := abi.CidBuilder.Sum(data)
 	if err != nil {
 		rt.Abortf(exitcode.SysErrSerialization, err.Error())
@@ -486,6 +516,9 @@ func (rt *Runtime) Transaction(st runtime.CBORer, f func() interface{}) interfac
 ///// Syscalls implementation /////
 
 func (rt *Runtime) VerifySignature(sig crypto.Signature, signer addr.Address, plaintext []byte) error {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifySignature(sig.Type, len(plaintext)))
+	}
 	if len(rt.expectVerifySigs) == 0 {
 		rt.failTest("unexpected signature verification sig: %v, signer: %s, plaintext: %v", sig, signer, plaintext)
 	}
@@ -508,10 +541,16 @@ func (rt *Runtime) VerifySignature(sig crypto.Signature, signer addr.Address, pl
 }
 
 func (rt *Runtime) HashBlake2b(data []byte) [32]byte {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnHashing(len(data)))
+	}
 	return rt.hashfunc(data)
 }
 
 func (rt *Runtime) ComputeUnsealedSectorCID(reg abi.RegisteredSealProof, pieces []abi.PieceInfo) (cid.Cid, error) {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnComputeUnsealedSectorCid(reg, pieces))
+	}
 	exp := rt.expectComputeUnsealedSectorCID
 	if exp != nil {
 		if !reflect.DeepEqual(exp.reg, reg) {
@@ -531,6 +570,9 @@ func (rt *Runtime) ComputeUnsealedSectorCID(reg abi.RegisteredSealProof, pieces
 }
 
 func (rt *Runtime) VerifySeal(seal abi.SealVerifyInfo) error {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifySeal(seal))
+	}
 	exp := rt.expectVerifySeal
 	if exp != nil {
 		if !reflect.DeepEqual(exp.seal, seal) {
@@ -560,7 +602,52 @@ func (rt *Runtime) BatchVerifySeals(vis map[addr.Address][]abi.SealVerifyInfo) (
 	return out, nil
 }
 
+func (rt *Runtime) VerifyAggregateSeals(aggregate abi.AggregateSealVerifyProofAndInfos) error {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifyAggregateSeals(aggregate))
+	}
+	exp := rt.expectVerifyAggregateSeals
+	if exp != nil {
+		if !reflect.DeepEqual(exp.aggregate, aggregate) {
+			rt.failTest("unexpected aggregate seal verification\n"+
+				"        : %v\n"+
+				"expected: %v",
+				aggregate, exp.aggregate)
+		}
+		defer func() {
+			rt.expectVerifyAggregateSeals = nil
+		}()
+		return exp.result
+	}
+	rt.failTestNow("unexpected syscall to verify aggregate seals %v", aggregate)
+	return nil
+}
+
+func (rt *Runtime) VerifyReplicaUpdate(update abi.ReplicaUpdateInfo) error {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifyReplicaUpdate(update))
+	}
+	if len(rt.expectVerifyReplicaUpdates) == 0 {
+		rt.failTestNow("unexpected syscall to verify replica update %v", update)
+	}
+
+	exp := rt.expectVerifyReplicaUpdates[0]
+	if !reflect.DeepEqual(exp.update, update) {
+		rt.failTest("unexpected replica update verification\n"+
+			"        : %v\n"+
+			"expected: %v",
+			update, exp.update)
+	}
+	defer func() {
+		rt.expectVerifyReplicaUpdates = rt.expectVerifyReplicaUpdates[1:]
+	}()
+	return exp.result
+}
+
 func (rt *Runtime) VerifyPoSt(vi abi.WindowPoStVerifyInfo) error {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifyPost(vi))
+	}
 	exp := rt.expectVerifyPoSt
 	if exp != nil {
 		if !reflect.DeepEqual(exp.post, vi) {
@@ -579,6 +666,9 @@ func (rt *Runtime) VerifyPoSt(vi abi.WindowPoStVerifyInfo) error {
 }
 
 func (rt *Runtime) VerifyConsensusFault(h1, h2, extra []byte) (*runtime.ConsensusFault, error) {
+	if rt.metered() {
+		rt.chargePrice(rt.pricelist.OnVerifyConsensusFault())
+	}
 	if rt.expectVerifyConsensusFault == nil {
 		rt.failTestNow("Unexpected syscall VerifyConsensusFault")
 		return nil, nil
@@ -764,6 +854,20 @@ func (rt *Runtime) ExpectVerifySeal(seal abi.SealVerifyInfo, result error) {
 	}
 }
 
+func (rt *Runtime) ExpectVerifyAggregateSeals(aggregate abi.AggregateSealVerifyProofAndInfos, result error) {
+	rt.expectVerifyAggregateSeals = &expectVerifyAggregateSeals{
+		aggregate: aggregate,
+		result:    result,
+	}
+}
+
+func (rt *Runtime) ExpectVerifyReplicaUpdate(update abi.ReplicaUpdateInfo, result error) {
+	rt.expectVerifyReplicaUpdates = append(rt.expectVerifyReplicaUpdates, &expectVerifyReplicaUpdate{
+		update: update,
+		result: result,
+	})
+}
+
 func (rt *Runtime) ExpectComputeUnsealedSectorCID(reg abi.RegisteredSealProof, pieces []abi.PieceInfo, cid cid.Cid, err error) {
 	rt.expectComputeUnsealedSectorCID = &expectComputeUnsealedSectorCID{
 		reg, pieces, cid, err,
@@ -818,6 +922,14 @@ func (rt *Runtime) Verify() {
 		rt.failTest("missing expected verify seal with %v", rt.expectVerifySeal.seal)
 	}
 
+	if rt.expectVerifyAggregateSeals != nil {
+		rt.failTest("missing expected verify aggregate seals with %v", rt.expectVerifyAggregateSeals.aggregate)
+	}
+
+	if len(rt.expectVerifyReplicaUpdates) > 0 {
+		rt.failTest("missing expected verify replica update %v", rt.expectVerifyReplicaUpdates)
+	}
+
 	if rt.expectComputeUnsealedSectorCID != nil {
 		rt.failTest("missing expected ComputeUnsealedSectorCID with %v", rt.expectComputeUnsealedSectorCID)
 	}
@@ -842,6 +954,8 @@ func (rt *Runtime) Reset() {
 	rt.expectCreateActor = nil
 	rt.expectVerifySigs = nil
 	rt.expectVerifySeal = nil
+	rt.expectVerifyAggregateSeals = nil
+	rt.expectVerifyReplicaUpdates = nil
 	rt.expectComputeUnsealedSectorCID = nil
 }
 
@@ -932,6 +1046,7 @@ func (rt *Runtime) Call(method interface{}, params interface{}) interface{} {
 
 	rt.inCall = true
 	defer func() { rt.inCall = false }()
+	rt.startGasTrace(meth)
 	var arg reflect.Value
 	if params != nil {
 		arg = reflect.ValueOf(params)
@@ -980,8 +1095,11 @@ func (rt *Runtime) failTestNow(msg string, args ...interface{}) {
 	rt.t.FailNow()
 }
 
-func (rt *Runtime) ChargeGas(_ string, gas, _ int64) {
+func (rt *Runtime) ChargeGas(name string, gas, virtual int64) {
 	rt.gasCharged += gas
+	if rt.metered() {
+		rt.chargePrice(runtime.GasCharge{Name: name, ComputeGas: gas, VirtualCompute: virtual})
+	}
 }
 
 type ReturnWrapper struct {
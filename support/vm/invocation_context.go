@@ -0,0 +1,597 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/minio/blake2b-simd"
+	"github.com/pkg/errors"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	states "github.com/filecoin-project/specs-actors/actors/states"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+)
+
+// The randomness returned for every request. Proofs are not verified, so the value need only be consistent.
+var fixedRandomness = abi.Randomness("i am random, trust me, i am random")
+
+// Context for an individual message invocation, including inter-actor sends.
+type invocationContext struct {
+	rt               *VM
+	topLevel         *topLevelContext
+	msg              InternalMessage
+	allowSideEffects bool
+	callerValidated  bool
+	trace            *Invocation
+}
+
+// Context for a top-level invocation sequence.
+type topLevelContext struct {
+	originatorStableAddress addr.Address // Stable (public key) address of the top-level message sender.
+	originatorCallSeq       uint64       // Call sequence number of the top-level message.
+	newActorAddressCount    uint64       // Count of calls to NewActorAddress (mutable).
+}
+
+// InternalMessage is a message sent between actors, or the top-level message after sender resolution.
+type InternalMessage struct {
+	from   addr.Address
+	to     addr.Address
+	value  abi.TokenAmount
+	method abi.MethodNum
+	params runtime.CBORMarshaler
+}
+
+var _ runtime.Runtime = (*invocationContext)(nil)
+var _ runtime.StateHandle = (*invocationContext)(nil)
+var _ runtime.Message = (*invocationContext)(nil)
+var _ runtime.Syscalls = (*invocationContext)(nil)
+
+func newInvocationContext(rt *VM, topLevel *topLevelContext, msg InternalMessage) invocationContext {
+	return invocationContext{
+		rt:               rt,
+		topLevel:         topLevel,
+		msg:              msg,
+		allowSideEffects: true,
+		callerValidated:  false,
+		trace:            newInvocation(msg),
+	}
+}
+
+// An abort carries the exit code of an actor method which did not complete successfully.
+type abort struct {
+	code exitcode.ExitCode
+	msg  string
+}
+
+func (a abort) String() string {
+	return fmt.Sprintf("abort(%v): %s", a.code, a.msg)
+}
+
+// Wraps a method return value so that it may be unmarshalled into the caller's type.
+type returnWrapper struct {
+	inner runtime.CBORMarshaler
+}
+
+func (r returnWrapper) Into(o runtime.CBORUnmarshaler) error {
+	b := bytes.Buffer{}
+	if r.inner != nil {
+		if err := r.inner.MarshalCBOR(&b); err != nil {
+			return err
+		}
+	}
+	return o.UnmarshalCBOR(&b)
+}
+
+// Executes the message, returning its result and exit code.
+// Aborts are recovered, but the caller is responsible for rolling back state if the exit code is not Ok.
+func (ic *invocationContext) invoke() (ret returnWrapper, errcode exitcode.ExitCode) {
+	// Runs after abort recovery below, recording the outcome.
+	defer func() {
+		ic.trace.Code = errcode
+		ic.trace.Return = serialize(ret.inner)
+		ic.trace.StateAfter = ic.receiverHead()
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok {
+				panic(r)
+			}
+			ic.rt.log("abort from %v to %v method %d: %s", ic.msg.from, ic.msg.to, ic.msg.method, a)
+			ic.trace.Error = a.msg
+			ret = returnWrapper{adt.Empty}
+			errcode = a.code
+		}
+	}()
+
+	// Resolve the receiver, implicitly creating an account actor for a previously unseen public key address.
+	toID, found := ic.rt.NormalizeAddress(ic.msg.to)
+	if !found {
+		if ic.msg.to.Protocol() != addr.SECP256K1 && ic.msg.to.Protocol() != addr.BLS {
+			ic.Abortf(exitcode.SysErrInvalidReceiver, "actor %v does not exist", ic.msg.to)
+		}
+		toID = ic.createAccountActor(ic.msg.to)
+	}
+	ic.msg.to = toID
+
+	toActor := ic.loadActor(toID)
+	ic.trace.setReceiver(toID, toActor.Code, toActor.Head)
+	ic.transfer(ic.msg.from, toID, ic.msg.value)
+
+	if ic.msg.method == builtin.MethodSend {
+		return returnWrapper{adt.Empty}, exitcode.Ok
+	}
+
+	impl, ok := ic.rt.actorImpls[toActor.Code]
+	if !ok {
+		ic.Abortf(exitcode.SysErrInvalidReceiver, "no implementation for actor code %v", toActor.Code)
+	}
+	exports := impl.Exports()
+	if uint64(ic.msg.method) >= uint64(len(exports)) || exports[ic.msg.method] == nil {
+		ic.Abortf(exitcode.SysErrInvalidMethod, "no method %d on actor %v", ic.msg.method, toID)
+	}
+	method := reflect.ValueOf(exports[ic.msg.method])
+
+	arg := ic.decodeParams(method.Type().In(1))
+	out := method.Call([]reflect.Value{reflect.ValueOf(ic), arg})
+
+	if !ic.callerValidated {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "caller MUST be validated during method execution")
+	}
+
+	result := out[0]
+	if result.Kind() == reflect.Ptr && result.IsNil() {
+		return returnWrapper{adt.Empty}, exitcode.Ok
+	}
+	marshaler := result.Interface().(runtime.CBORMarshaler)
+	if err := marshaler.MarshalCBOR(&bytes.Buffer{}); err != nil {
+		ic.Abortf(exitcode.SysErrSerialization, "failed to marshal return value: %v", err)
+	}
+	return returnWrapper{marshaler}, exitcode.Ok
+}
+
+// Round-trips the message parameters through their serialized form into a new value of the method's parameter type.
+func (ic *invocationContext) decodeParams(paramType reflect.Type) reflect.Value {
+	buf := bytes.Buffer{}
+	if ic.msg.params != nil {
+		if err := ic.msg.params.MarshalCBOR(&buf); err != nil {
+			ic.Abortf(exitcode.SysErrSerialization, "failed to marshal params: %v", err)
+		}
+	}
+	if paramType == reflect.TypeOf(adt.Empty) {
+		return reflect.ValueOf(adt.Empty)
+	}
+	arg := reflect.New(paramType.Elem())
+	if err := arg.Interface().(runtime.CBORUnmarshaler).UnmarshalCBOR(&buf); err != nil {
+		ic.Abortf(exitcode.ErrSerialization, "failed to decode params as %v: %v", paramType, err)
+	}
+	return arg
+}
+
+// Creates an account actor for a public key address, registering it with the Init actor.
+func (ic *invocationContext) createAccountActor(pubkey addr.Address) addr.Address {
+	var initActor states.Actor
+	found, err := ic.rt.actors.Get(adt.AddrKey(builtin.InitActorAddr), &initActor)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "no init actor to register account %v", pubkey)
+	}
+	var st init_.State
+	if err := ic.rt.store.Get(ic.rt.ctx, initActor.Head, &st); err != nil {
+		panic(err)
+	}
+	idAddr, err := st.MapAddressToNewID(ic.rt.store, pubkey)
+	if err != nil {
+		panic(err)
+	}
+	if initActor.Head, err = ic.rt.store.Put(ic.rt.ctx, &st); err != nil {
+		panic(err)
+	}
+	if err := ic.rt.SetActor(builtin.InitActorAddr, &initActor); err != nil {
+		panic(err)
+	}
+
+	ic.putActor(idAddr, &states.Actor{
+		Code:       builtin.AccountActorCodeID,
+		Head:       ic.rt.emptyObject,
+		CallSeqNum: 0,
+		Balance:    big.Zero(),
+	})
+
+	ctorCtx := newInvocationContext(ic.rt, ic.topLevel, InternalMessage{
+		from:   builtin.SystemActorAddr,
+		to:     idAddr,
+		value:  big.Zero(),
+		method: builtin.MethodConstructor,
+		params: &pubkey,
+	})
+	_, code := ctorCtx.invoke()
+	ic.trace.Subcalls = append(ic.trace.Subcalls, ctorCtx.trace)
+	if code != exitcode.Ok {
+		ic.Abortf(code, "failed to construct account actor for %v", pubkey)
+	}
+	return idAddr
+}
+
+// Returns the receiver's current state head, or undefined if the receiver is unresolved or no longer exists.
+func (ic *invocationContext) receiverHead() cid.Cid {
+	if ic.trace.Receiver == addr.Undef {
+		return cid.Undef
+	}
+	act, found, err := ic.rt.GetActor(ic.trace.Receiver)
+	if err != nil || !found {
+		return cid.Undef
+	}
+	return act.Head
+}
+
+func (ic *invocationContext) loadActor(a addr.Address) *states.Actor {
+	var act states.Actor
+	found, err := ic.rt.actors.Get(adt.AddrKey(a), &act)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		ic.Abortf(exitcode.SysErrInvalidReceiver, "actor %v not found", a)
+	}
+	return &act
+}
+
+func (ic *invocationContext) putActor(a addr.Address, act *states.Actor) {
+	if err := ic.rt.SetActor(a, act); err != nil {
+		panic(err)
+	}
+}
+
+func (ic *invocationContext) transfer(from, to addr.Address, amount abi.TokenAmount) {
+	if amount.LessThan(big.Zero()) {
+		ic.Abortf(exitcode.SysErrForbidden, "attempt to transfer negative value %v from %v to %v", amount, from, to)
+	}
+	if amount.IsZero() {
+		return
+	}
+
+	fromActor := ic.loadActor(from)
+	if fromActor.Balance.LessThan(amount) {
+		ic.Abortf(exitcode.SysErrInsufficientFunds, "sender %v insufficient balance %v to transfer %v to %v", from, fromActor.Balance, amount, to)
+	}
+	fromActor.Balance = big.Sub(fromActor.Balance, amount)
+	ic.putActor(from, fromActor)
+
+	toActor := ic.loadActor(to)
+	toActor.Balance = big.Add(toActor.Balance, amount)
+	ic.putActor(to, toActor)
+}
+
+func (ic *invocationContext) checkCallerValidation() {
+	if ic.callerValidated {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "method must validate caller identity exactly once")
+	}
+	ic.callerValidated = true
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Runtime
+///////////////////////////////////////////////////////////////////////////////
+
+func (ic *invocationContext) Message() runtime.Message {
+	return ic
+}
+
+func (ic *invocationContext) CurrEpoch() abi.ChainEpoch {
+	return ic.rt.currentEpoch
+}
+
+func (ic *invocationContext) ValidateImmediateCallerAcceptAny() {
+	ic.checkCallerValidation()
+}
+
+func (ic *invocationContext) ValidateImmediateCallerIs(addrs ...addr.Address) {
+	ic.checkCallerValidation()
+	for _, a := range addrs {
+		if a == ic.msg.from {
+			return
+		}
+	}
+	ic.Abortf(exitcode.SysErrForbidden, "caller %v is not one of %v", ic.msg.from, addrs)
+}
+
+func (ic *invocationContext) ValidateImmediateCallerType(types ...cid.Cid) {
+	ic.checkCallerValidation()
+	callerCode := ic.loadActor(ic.msg.from).Code
+	for _, t := range types {
+		if t.Equals(callerCode) {
+			return
+		}
+	}
+	ic.Abortf(exitcode.SysErrForbidden, "caller type %v is not one of %v", callerCode, types)
+}
+
+func (ic *invocationContext) CurrentBalance() abi.TokenAmount {
+	return ic.loadActor(ic.msg.to).Balance
+}
+
+func (ic *invocationContext) ResolveAddress(address addr.Address) (addr.Address, bool) {
+	return ic.rt.NormalizeAddress(address)
+}
+
+func (ic *invocationContext) GetActorCodeCID(a addr.Address) (cid.Cid, bool) {
+	act, found, err := ic.rt.GetActor(a)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		return cid.Undef, false
+	}
+	return act.Code, true
+}
+
+func (ic *invocationContext) GetRandomness(_ crypto.DomainSeparationTag, _ abi.ChainEpoch, _ []byte) abi.Randomness {
+	return fixedRandomness
+}
+
+func (ic *invocationContext) State() runtime.StateHandle {
+	return ic
+}
+
+func (ic *invocationContext) Store() runtime.Store {
+	return ic
+}
+
+func (ic *invocationContext) Send(toAddr addr.Address, methodNum abi.MethodNum, params runtime.CBORMarshaler, value abi.TokenAmount) (runtime.SendReturn, exitcode.ExitCode) {
+	if !ic.allowSideEffects {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "calling Send() is not allowed during side-effect lock")
+	}
+
+	priorRoot, err := ic.rt.Checkpoint()
+	if err != nil {
+		panic(err)
+	}
+
+	newCtx := newInvocationContext(ic.rt, ic.topLevel, InternalMessage{
+		from:   ic.msg.to,
+		to:     toAddr,
+		value:  value,
+		method: methodNum,
+		params: params,
+	})
+	ret, code := newCtx.invoke()
+	ic.trace.Subcalls = append(ic.trace.Subcalls, newCtx.trace)
+	if code != exitcode.Ok {
+		if err := ic.rt.rollback(priorRoot); err != nil {
+			panic(err)
+		}
+	}
+	return ret, code
+}
+
+func (ic *invocationContext) Abortf(errExitCode exitcode.ExitCode, msg string, args ...interface{}) {
+	panic(abort{errExitCode, fmt.Sprintf(msg, args...)})
+}
+
+func (ic *invocationContext) NewActorAddress() addr.Address {
+	var buf bytes.Buffer
+	if err := ic.topLevel.originatorStableAddress.MarshalCBOR(&buf); err != nil {
+		panic(err)
+	}
+	if err := binary.Write(&buf, binary.BigEndian, ic.topLevel.originatorCallSeq); err != nil {
+		panic(err)
+	}
+	if err := binary.Write(&buf, binary.BigEndian, ic.topLevel.newActorAddressCount); err != nil {
+		panic(err)
+	}
+	ic.topLevel.newActorAddressCount++
+
+	actorAddr, err := addr.NewActorAddress(buf.Bytes())
+	if err != nil {
+		panic(err)
+	}
+	return actorAddr
+}
+
+func (ic *invocationContext) CreateActor(codeID cid.Cid, a addr.Address) {
+	if _, ok := ic.rt.actorImpls[codeID]; !ok {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "no implementation for actor code %v", codeID)
+	}
+	if builtin.IsSingletonActor(codeID) {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "cannot create singleton actor %v", codeID)
+	}
+	if ic.msg.to != builtin.InitActorAddr {
+		ic.Abortf(exitcode.SysErrForbidden, "actor %v is not permitted to create actors", ic.msg.to)
+	}
+	if a.Protocol() != addr.ID {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "new actor address %v must be an ID address", a)
+	}
+	if _, found, err := ic.rt.GetActor(a); err != nil {
+		panic(err)
+	} else if found {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "actor %v already exists", a)
+	}
+
+	ic.putActor(a, &states.Actor{
+		Code:       codeID,
+		Head:       ic.rt.emptyObject,
+		CallSeqNum: 0,
+		Balance:    big.Zero(),
+	})
+}
+
+func (ic *invocationContext) DeleteActor(beneficiary addr.Address) {
+	receiver := ic.msg.to
+	beneficiaryID, found := ic.rt.NormalizeAddress(beneficiary)
+	if !found {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "beneficiary %v not found", beneficiary)
+	}
+	if beneficiaryID == receiver {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "benefactor cannot be beneficiary")
+	}
+	ic.transfer(receiver, beneficiaryID, ic.loadActor(receiver).Balance)
+	if err := ic.rt.deleteActor(receiver); err != nil {
+		panic(err)
+	}
+}
+
+func (ic *invocationContext) Syscalls() runtime.Syscalls {
+	return ic
+}
+
+func (ic *invocationContext) TotalFilCircSupply() abi.TokenAmount {
+	return ic.rt.circSupply
+}
+
+func (ic *invocationContext) Context() context.Context {
+	return ic.rt.ctx
+}
+
+func (ic *invocationContext) StartSpan(_ string) runtime.TraceSpan {
+	return &traceSpan{}
+}
+
+func (ic *invocationContext) ChargeGas(_ string, _ int64, _ int64) {
+	// No gas accounting.
+}
+
+func (ic *invocationContext) Log(_ runtime.LogLevel, msg string, args ...interface{}) {
+	ic.rt.log(msg, args...)
+	ic.trace.Logs = append(ic.trace.Logs, fmt.Sprintf(msg, args...))
+}
+
+type traceSpan struct{}
+
+func (t *traceSpan) End() {}
+
+///////////////////////////////////////////////////////////////////////////////
+// Message
+///////////////////////////////////////////////////////////////////////////////
+
+func (ic *invocationContext) Caller() addr.Address {
+	return ic.msg.from
+}
+
+func (ic *invocationContext) Receiver() addr.Address {
+	return ic.msg.to
+}
+
+func (ic *invocationContext) ValueReceived() abi.TokenAmount {
+	return ic.msg.value
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Store
+///////////////////////////////////////////////////////////////////////////////
+
+func (ic *invocationContext) Get(c cid.Cid, o runtime.CBORUnmarshaler) bool {
+	if err := ic.rt.store.Get(ic.rt.ctx, c, o); err != nil {
+		ic.Abortf(exitcode.SysErrorIllegalArgument, "failed to load %v: %v", c, err)
+	}
+	return true
+}
+
+func (ic *invocationContext) Put(o runtime.CBORMarshaler) cid.Cid {
+	c, err := ic.rt.store.Put(ic.rt.ctx, o)
+	if err != nil {
+		ic.Abortf(exitcode.SysErrSerialization, "failed to store object: %v", err)
+	}
+	return c
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// StateHandle
+///////////////////////////////////////////////////////////////////////////////
+
+func (ic *invocationContext) Create(obj runtime.CBORMarshaler) {
+	act := ic.loadActor(ic.msg.to)
+	if act.Head != ic.rt.emptyObject {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "failed to create state; expected empty state, found %v", act.Head)
+	}
+	act.Head = ic.Put(obj)
+	ic.putActor(ic.msg.to, act)
+}
+
+func (ic *invocationContext) Readonly(obj runtime.CBORUnmarshaler) {
+	act := ic.loadActor(ic.msg.to)
+	ic.Get(act.Head, obj)
+}
+
+func (ic *invocationContext) Transaction(obj runtime.CBORer, f func() interface{}) interface{} {
+	if !ic.allowSideEffects {
+		ic.Abortf(exitcode.SysErrorIllegalActor, "nested transaction")
+	}
+	ic.Readonly(obj)
+
+	ic.allowSideEffects = false
+	ret := f()
+	ic.allowSideEffects = true
+
+	act := ic.loadActor(ic.msg.to)
+	act.Head = ic.Put(obj)
+	ic.putActor(ic.msg.to, act)
+	return ret
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Syscalls
+///////////////////////////////////////////////////////////////////////////////
+
+func (ic *invocationContext) VerifySignature(_ crypto.Signature, _ addr.Address, _ []byte) error {
+	return nil
+}
+
+func (ic *invocationContext) HashBlake2b(data []byte) [32]byte {
+	return blake2b.Sum256(data)
+}
+
+func (ic *invocationContext) ComputeUnsealedSectorCID(_ abi.RegisteredSealProof, pieces []abi.PieceInfo) (cid.Cid, error) {
+	return tutil.MakeCID(fmt.Sprintf("%v", pieces), &market.PieceCIDPrefix), nil
+}
+
+func (ic *invocationContext) VerifySeal(_ abi.SealVerifyInfo) error {
+	return nil
+}
+
+func (ic *invocationContext) BatchVerifySeals(vis map[addr.Address][]abi.SealVerifyInfo) (map[addr.Address][]bool, error) {
+	out := make(map[addr.Address][]bool)
+	for a, infos := range vis { //nolint:nomaprange
+		results := make([]bool, len(infos))
+		for i := range results {
+			results[i] = true
+		}
+		out[a] = results
+	}
+	return out, nil
+}
+
+func (ic *invocationContext) VerifyAggregateSeals(_ abi.AggregateSealVerifyProofAndInfos) error {
+	return nil
+}
+
+func (ic *invocationContext) VerifyReplicaUpdate(_ abi.ReplicaUpdateInfo) error {
+	return nil
+}
+
+func (ic *invocationContext) VerifyPoSt(_ abi.WindowPoStVerifyInfo) error {
+	return nil
+}
+
+func (ic *invocationContext) VerifyConsensusFault(_, _, _ []byte) (*runtime.ConsensusFault, error) {
+	return nil, errors.New("consensus faults are not supported by this VM")
+}
+
+func (vm *VM) log(msg string, args ...interface{}) {
+	vm.logs = append(vm.logs, fmt.Sprintf(msg, args...))
+}
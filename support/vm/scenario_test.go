@@ -0,0 +1,207 @@
+package vm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+	vm "github.com/filecoin-project/specs-actors/support/vm"
+)
+
+func TestCommitPoStFlow(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+	owner, worker := addrs[0], addrs[0]
+
+	minerBalance := big.Mul(big.NewInt(1_000), big.NewInt(1e18))
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1
+	sectorNumber := abi.SectorNumber(100)
+	sealedCid := tutil.MakeCID("100", &miner.SealedCIDPrefix)
+
+	// create miner
+	params := power.CreateMinerParams{
+		Owner:         owner,
+		Worker:        worker,
+		SealProofType: sealProof,
+		Peer:          abi.PeerID("not really a peer id"),
+	}
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, minerBalance, builtin.MethodsPower.CreateMiner, &params)
+	minerAddrs, ok := ret.(*power.CreateMinerReturn)
+	require.True(t, ok)
+	vm.AssertStateInvariants(t, v)
+
+	// advance past the seal randomness lookback
+	v.SetEpoch(200)
+
+	// pre-commit sector
+	preCommitParams := miner.SectorPreCommitInfo{
+		SealProof:     sealProof,
+		SectorNumber:  sectorNumber,
+		SealedCID:     sealedCid,
+		SealRandEpoch: v.GetEpoch() - 1,
+		DealIDs:       nil,
+		Expiration:    v.GetEpoch() + miner.MinSectorExpiration + miner.MaxSealDuration[sealProof] + 100,
+	}
+	vm.ApplyOk(t, v, addrs[0], minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.PreCommitSector, &preCommitParams)
+
+	var minerState miner.State
+	vm.GetState(t, v, minerAddrs.IDAddress, &minerState)
+	_, found, err := minerState.GetPrecommittedSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	// prove commit, which schedules the proof for batch verification by the power actor
+	proveTime := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	vm.AdvanceToEpochWithCron(t, v, proveTime-1)
+	v.SetEpoch(proveTime)
+	proveCommitParams := miner.ProveCommitSectorParams{SectorNumber: sectorNumber}
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.ProveCommitSector, &proveCommitParams)
+
+	// cron verifies the proof and the sector is activated
+	vm.ApplyOk(t, v, builtin.SystemActorAddr, builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil)
+
+	vm.GetState(t, v, minerAddrs.IDAddress, &minerState)
+	sector, found, err := minerState.GetSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, sealedCid, sector.SealedCID)
+	assert.True(t, minerState.InitialPledgeRequirement.GreaterThan(big.Zero()))
+
+	// the sector's power is claimed on activation
+	sectorSize, err := sealProof.SectorSize()
+	require.NoError(t, err)
+	claim := getClaim(t, v, minerAddrs.IDAddress)
+	assert.Equal(t, big.NewIntUnsigned(uint64(sectorSize)), claim.RawBytePower)
+	assert.Equal(t, big.NewIntUnsigned(uint64(sectorSize)), claim.QualityAdjPower)
+	vm.AssertStateInvariants(t, v)
+
+	// advance to the sector's deadline and submit a window PoSt
+	dlIdx, pIdx, err := minerState.FindSector(v.Store(), sectorNumber)
+	require.NoError(t, err)
+	dlInfo := miner.NewDeadlineInfo(minerState.ProvingPeriodStart, dlIdx, v.GetEpoch()).NextNotElapsed()
+	vm.AdvanceToEpochWithCron(t, v, dlInfo.Open)
+
+	submitParams := miner.SubmitWindowedPoStParams{
+		Deadline: dlIdx,
+		Partitions: []miner.PoStPartition{{
+			Index:   pIdx,
+			Skipped: abi.NewBitField(),
+		}},
+		Proofs: []abi.PoStProof{{
+			PoStProof:  abi.RegisteredPoStProof_StackedDrgWindow32GiBV1,
+			ProofBytes: []byte{},
+		}},
+	}
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.SubmitWindowedPoSt, &submitParams)
+
+	vm.GetState(t, v, minerAddrs.IDAddress, &minerState)
+	deadlines, err := minerState.LoadDeadlines(v.Store())
+	require.NoError(t, err)
+	deadline, err := deadlines.LoadDeadline(v.Store(), dlIdx)
+	require.NoError(t, err)
+	proven, err := deadline.PostSubmissions.IsSet(pIdx)
+	require.NoError(t, err)
+	assert.True(t, proven)
+
+	// the deadline closes without faults
+	vm.AdvanceToEpochWithCron(t, v, dlInfo.Last())
+	vm.GetState(t, v, minerAddrs.IDAddress, &minerState)
+	assert.Equal(t, big.Zero(), minerState.FaultyPower.Raw)
+	claim = getClaim(t, v, minerAddrs.IDAddress)
+	assert.Equal(t, big.NewIntUnsigned(uint64(sectorSize)), claim.RawBytePower)
+	vm.AssertStateInvariants(t, v)
+}
+
+func TestBatchPreCommitAggregateProveCommitFlow(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(100_000), big.NewInt(1e18)), 93837778)
+	owner, worker := addrs[0], addrs[0]
+
+	minerBalance := big.Mul(big.NewInt(10_000), big.NewInt(1e18))
+	sealProof := abi.RegisteredSealProof_StackedDrg32GiBV1
+
+	// create miner
+	params := power.CreateMinerParams{
+		Owner:         owner,
+		Worker:        worker,
+		SealProofType: sealProof,
+		Peer:          abi.PeerID("not really a peer id"),
+	}
+	ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, minerBalance, builtin.MethodsPower.CreateMiner, &params)
+	minerAddrs, ok := ret.(*power.CreateMinerReturn)
+	require.True(t, ok)
+
+	// advance past the seal randomness lookback
+	v.SetEpoch(200)
+
+	// pre-commit sectors in a single batch
+	batchParams := miner.PreCommitSectorBatchParams{}
+	sectorNumbers := abi.NewBitField()
+	for i := 0; i < miner.MinAggregatedSectors; i++ {
+		sectorNumber := abi.SectorNumber(100 + i)
+		sectorNumbers.Set(uint64(sectorNumber))
+		batchParams.Sectors = append(batchParams.Sectors, miner.SectorPreCommitInfo{
+			SealProof:     sealProof,
+			SectorNumber:  sectorNumber,
+			SealedCID:     tutil.MakeCID(fmt.Sprintf("%d", sectorNumber), &miner.SealedCIDPrefix),
+			SealRandEpoch: v.GetEpoch() - 1,
+			Expiration:    v.GetEpoch() + miner.MinSectorExpiration + miner.MaxSealDuration[sealProof] + 100,
+		})
+	}
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.PreCommitSectorBatch, &batchParams)
+	vm.AssertStateInvariants(t, v)
+
+	// prove all the sectors with one aggregate proof, which activates them immediately
+	proveTime := v.GetEpoch() + miner.PreCommitChallengeDelay + 1
+	vm.AdvanceToEpochWithCron(t, v, proveTime)
+	proveParams := miner.ProveCommitAggregateParams{
+		SectorNumbers:  sectorNumbers,
+		AggregateProof: []byte{},
+	}
+	vm.ApplyOk(t, v, worker, minerAddrs.RobustAddress, big.Zero(), builtin.MethodsMiner.ProveCommitAggregate, &proveParams)
+
+	var minerState miner.State
+	vm.GetState(t, v, minerAddrs.IDAddress, &minerState)
+	for _, sector := range batchParams.Sectors {
+		onChain, found, err := minerState.GetSector(v.Store(), sector.SectorNumber)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, sector.SealedCID, onChain.SealedCID)
+	}
+	assert.Equal(t, big.Zero(), minerState.PreCommitDeposits)
+	assert.True(t, minerState.InitialPledgeRequirement.GreaterThan(big.Zero()))
+
+	sectorSize, err := sealProof.SectorSize()
+	require.NoError(t, err)
+	claim := getClaim(t, v, minerAddrs.IDAddress)
+	expectedPower := big.NewIntUnsigned(uint64(sectorSize) * uint64(miner.MinAggregatedSectors))
+	assert.Equal(t, expectedPower, claim.RawBytePower)
+	assert.Equal(t, expectedPower, claim.QualityAdjPower)
+	vm.AssertStateInvariants(t, v)
+}
+
+func getClaim(t *testing.T, v *vm.VM, minerAddr addr.Address) *power.Claim {
+	var powerState power.State
+	vm.GetState(t, v, builtin.StoragePowerActorAddr, &powerState)
+	claims, err := adt.AsMap(v.Store(), powerState.Claims)
+	require.NoError(t, err)
+
+	var claim power.Claim
+	found, err := claims.Get(adt.AddrKey(minerAddr), &claim)
+	require.NoError(t, err)
+	require.True(t, found)
+	return &claim
+}
@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/cron"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/builtin/system"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	states "github.com/filecoin-project/specs-actors/actors/states"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+)
+
+// The ID address of the account which holds the verified registry root key in VMs built by NewVMWithSingletons.
+var VerifregRoot = mustIDAddr(80)
+
+// NewVMWithSingletons creates a VM, backed by an in-memory store, containing all singleton actors.
+// Each singleton but the system and burnt funds actors is initialized by invoking its constructor.
+// The reward actor holds the full mining reward allocation.
+func NewVMWithSingletons(ctx context.Context, t testing.TB) *VM {
+	v := NewVM(ctx, ipld.NewADTStore(ctx))
+
+	require.NoError(t, v.SetActorState(builtin.SystemActorAddr, builtin.SystemActorCodeID, big.Zero(), &system.State{}))
+	require.NoError(t, v.SetActorState(builtin.BurntFundsActorAddr, builtin.AccountActorCodeID, big.Zero(),
+		&account.State{Address: builtin.BurntFundsActorAddr}))
+	require.NoError(t, v.SetActorState(VerifregRoot, builtin.AccountActorCodeID, big.Zero(),
+		&account.State{Address: tutil.NewBLSAddr(t, 80)}))
+
+	initParams := init_.ConstructorParams{NetworkName: "scenarios"}
+	constructSingleton(t, v, builtin.InitActorAddr, builtin.InitActorCodeID, big.Zero(), &initParams)
+
+	rewardBalance := big.Add(reward.SimpleTotal, reward.BaselineTotal)
+	realizedPower := big.Zero()
+	constructSingleton(t, v, builtin.RewardActorAddr, builtin.RewardActorCodeID, rewardBalance, &realizedPower)
+
+	cronParams := cron.ConstructorParams{Entries: cron.BuiltInEntries()}
+	constructSingleton(t, v, builtin.CronActorAddr, builtin.CronActorCodeID, big.Zero(), &cronParams)
+
+	constructSingleton(t, v, builtin.StoragePowerActorAddr, builtin.StoragePowerActorCodeID, big.Zero(), nil)
+	constructSingleton(t, v, builtin.StorageMarketActorAddr, builtin.StorageMarketActorCodeID, big.Zero(), nil)
+	constructSingleton(t, v, builtin.VerifiedRegistryActorAddr, builtin.VerifiedRegistryActorCodeID, big.Zero(), &VerifregRoot)
+
+	_, err := v.Checkpoint()
+	require.NoError(t, err)
+	return v
+}
+
+// Installs an actor with empty state and invokes its constructor from the system actor.
+func constructSingleton(t testing.TB, v *VM, a addr.Address, code cid.Cid, balance abi.TokenAmount, params runtime.CBORMarshaler) {
+	require.NoError(t, v.SetActor(a, &states.Actor{Code: code, Head: v.emptyObject, CallSeqNum: 0, Balance: balance}))
+	ApplyOk(t, v, builtin.SystemActorAddr, a, big.Zero(), builtin.MethodConstructor, params)
+}
+
+// CreateAccounts creates n BLS account actors, each funded with balance by the reward actor.
+// Returns the ID addresses of the new accounts.
+func CreateAccounts(ctx context.Context, t testing.TB, v *VM, n int, balance abi.TokenAmount, seed int64) []addr.Address {
+	var pkAddrs []addr.Address
+	for i := 0; i < n; i++ {
+		pkAddrs = append(pkAddrs, tutil.NewBLSAddr(t, seed+int64(i)))
+	}
+
+	// Sending value to an unknown public key address implicitly creates an account actor.
+	for _, pkAddr := range pkAddrs {
+		ApplyOk(t, v, builtin.RewardActorAddr, pkAddr, balance, builtin.MethodSend, nil)
+	}
+
+	var idAddrs []addr.Address
+	for _, pkAddr := range pkAddrs {
+		idAddr, found := v.NormalizeAddress(pkAddr)
+		require.True(t, found)
+		idAddrs = append(idAddrs, idAddr)
+	}
+	return idAddrs
+}
+
+// ApplyOk applies a top-level message and requires that it succeeds, returning its result.
+func ApplyOk(t testing.TB, v *VM, from, to addr.Address, value abi.TokenAmount, method abi.MethodNum, params runtime.CBORMarshaler) runtime.CBORMarshaler {
+	ret, code := v.ApplyMessage(from, to, value, method, params)
+	require.Equal(t, exitcode.Ok, code, "message from %v to %v method %d failed", from, to, method)
+	return ret
+}
+
+// ApplyCode applies a top-level message and requires that it exits with the given code.
+func ApplyCode(t testing.TB, v *VM, from, to addr.Address, value abi.TokenAmount, method abi.MethodNum, params runtime.CBORMarshaler, code exitcode.ExitCode) runtime.CBORMarshaler {
+	ret, actual := v.ApplyMessage(from, to, value, method, params)
+	require.Equal(t, code, actual, "message from %v to %v method %d exited with unexpected code", from, to, method)
+	return ret
+}
+
+// AdvanceToEpochWithCron executes the cron tick for every epoch after the current one, up to and including the target
+// epoch, leaving the VM at the target epoch.
+func AdvanceToEpochWithCron(t testing.TB, v *VM, target abi.ChainEpoch) {
+	for e := v.GetEpoch() + 1; e <= target; e++ {
+		v.SetEpoch(e)
+		ApplyOk(t, v, builtin.SystemActorAddr, builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil)
+	}
+}
+
+// GetState loads the state of the actor at an address, requiring that it exists.
+func GetState(t testing.TB, v *VM, a addr.Address, out runtime.CBORUnmarshaler) {
+	require.NoError(t, v.GetState(a, out))
+}
+
+// AssertStateInvariants checks the invariants of the VM's state tree, failing the test with the violations if any.
+func AssertStateInvariants(t testing.TB, v *VM) {
+	report, err := v.CheckStateInvariants()
+	require.NoError(t, err)
+	assert.True(t, report.OK(), strings.Join(report.Messages.Messages(), "\n"))
+}
+
+func mustIDAddr(id uint64) addr.Address {
+	a, err := addr.NewIDAddress(id)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
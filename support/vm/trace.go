@@ -0,0 +1,145 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/exported"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+)
+
+// MessageTrace records the execution of a top-level message applied to the VM.
+type MessageTrace struct {
+	Epoch abi.ChainEpoch
+	// State tree roots before and after the message, including the sender's call sequence number increment.
+	PreStateRoot  cid.Cid
+	PostStateRoot cid.Cid
+	Invocation    *Invocation
+}
+
+// Invocation records a message send from one actor to another, and the nested sends made while executing it.
+type Invocation struct {
+	From     addr.Address
+	To       addr.Address // As addressed by the sender.
+	Receiver addr.Address // The ID address of the receiver, or undefined if it could not be resolved.
+	Value    abi.TokenAmount
+	Method   abi.MethodNum
+	// Name of the method, if the receiver is a built-in actor.
+	MethodName string `json:",omitempty"`
+	// Serialized parameters and return value.
+	Params []byte
+	Return []byte
+	Code   exitcode.ExitCode
+	// The abort message, if the invocation aborted.
+	Error string `json:",omitempty"`
+	// Receiver's state head before and after execution. If the invocation aborted, its state changes were
+	// subsequently discarded.
+	StateBefore cid.Cid
+	StateAfter  cid.Cid
+	// Lines logged by the receiver while executing this invocation (excluding nested sends).
+	Logs     []string      `json:",omitempty"`
+	Subcalls []*Invocation `json:",omitempty"`
+}
+
+// Traces returns the traces of all top-level messages applied since the VM was created, in order.
+func (vm *VM) Traces() []*MessageTrace {
+	return vm.traces
+}
+
+// LastTrace returns the trace of the most recent top-level message, or nil if none has been applied.
+func (vm *VM) LastTrace() *MessageTrace {
+	if len(vm.traces) == 0 {
+		return nil
+	}
+	return vm.traces[len(vm.traces)-1]
+}
+
+// WriteTracesJSON writes the traces of all top-level messages as a JSON array.
+func (vm *VM) WriteTracesJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vm.traces)
+}
+
+// ReadTracesJSON reads traces written by WriteTracesJSON.
+func ReadTracesJSON(r io.Reader) ([]*MessageTrace, error) {
+	var traces []*MessageTrace
+	if err := json.NewDecoder(r).Decode(&traces); err != nil {
+		return nil, err
+	}
+	return traces, nil
+}
+
+// String renders the trace as an indented tree of sends, one per line.
+func (t *MessageTrace) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "epoch %d: %v -> %v\n", t.Epoch, t.PreStateRoot, t.PostStateRoot)
+	t.Invocation.write(&sb, 1)
+	return sb.String()
+}
+
+func (inv *Invocation) write(sb *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	method := inv.MethodName
+	if method == "" {
+		method = fmt.Sprintf("method %d", inv.Method)
+	}
+	fmt.Fprintf(sb, "%s%v -> %v %s value %v: %v", indent, inv.From, inv.To, method, inv.Value, inv.Code)
+	if inv.Error != "" {
+		fmt.Fprintf(sb, " (%s)", inv.Error)
+	}
+	sb.WriteString("\n")
+	for _, l := range inv.Logs {
+		fmt.Fprintf(sb, "%s  log: %s\n", indent, l)
+	}
+	for _, sub := range inv.Subcalls {
+		sub.write(sb, depth+1)
+	}
+}
+
+// Walk calls a function for this invocation and each nested invocation, depth-first in execution order.
+func (inv *Invocation) Walk(fn func(inv *Invocation)) {
+	fn(inv)
+	for _, sub := range inv.Subcalls {
+		sub.Walk(fn)
+	}
+}
+
+func newInvocation(msg InternalMessage) *Invocation {
+	return &Invocation{
+		From:   msg.from,
+		To:     msg.to,
+		Value:  msg.value,
+		Method: msg.method,
+		Params: serialize(msg.params),
+	}
+}
+
+// Records the receiver of an invocation once it has been resolved and loaded.
+func (inv *Invocation) setReceiver(a addr.Address, code cid.Cid, head cid.Cid) {
+	inv.Receiver = a
+	inv.StateBefore = head
+	if m, ok := exported.LookupMethod(code, inv.Method); ok {
+		inv.MethodName = m.Name
+	}
+}
+
+// Serializes a value for the trace. Values that fail to serialize are recorded as nil.
+func serialize(v runtime.CBORMarshaler) []byte {
+	if v == nil {
+		return nil
+	}
+	buf := bytes.Buffer{}
+	if err := v.MarshalCBOR(&buf); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
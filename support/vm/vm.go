@@ -0,0 +1,333 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+
+	addr "github.com/filecoin-project/go-address"
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/exported"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	states "github.com/filecoin-project/specs-actors/actors/states"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// VM is a simplified message execution framework for the purposes of testing inter-actor communication.
+// It holds a state tree of actors keyed by ID address, dispatches messages to the real implementations of the
+// built-in actors, and rolls back the state changes of any message that aborts.
+// The execution of each top-level message, including nested sends, is recorded as a MessageTrace.
+// The VM performs no gas accounting and its syscalls accept every proof.
+type VM struct {
+	ctx   context.Context
+	store adt.Store
+
+	currentEpoch abi.ChainEpoch
+	circSupply   abi.TokenAmount
+
+	actorImpls  map[cid.Cid]abi.Invokee
+	stateRoot   cid.Cid  // The last committed root.
+	actors      *adt.Map // The current (not necessarily committed) root node.
+	actorsDirty bool
+
+	emptyObject cid.Cid
+	logs        []string
+	traces      []*MessageTrace
+}
+
+// NewVM creates a VM with an empty state tree, able to execute all built-in actors.
+func NewVM(ctx context.Context, store adt.Store) *VM {
+	actors := adt.MakeEmptyMap(store)
+	actorRoot, err := actors.Root()
+	if err != nil {
+		panic(err)
+	}
+
+	emptyObject, err := store.Put(ctx, []struct{}{})
+	if err != nil {
+		panic(err)
+	}
+
+	actorImpls := make(map[cid.Cid]abi.Invokee)
+	for _, actor := range exported.BuiltinActors() {
+		actorImpls[actor.Code()] = actor
+	}
+
+	return &VM{
+		ctx:         ctx,
+		store:       store,
+		circSupply:  big.Mul(big.NewInt(1e9), big.NewInt(1e18)),
+		actorImpls:  actorImpls,
+		stateRoot:   actorRoot,
+		actors:      actors,
+		actorsDirty: false,
+		emptyObject: emptyObject,
+	}
+}
+
+// NewVMAtRoot creates a VM from an existing state tree root, e.g. as produced by a previous VM's Checkpoint.
+func NewVMAtRoot(ctx context.Context, store adt.Store, stateRoot cid.Cid) (*VM, error) {
+	vm := NewVM(ctx, store)
+	if err := vm.rollback(stateRoot); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// RegisterActor makes an additional actor implementation (e.g. a test puppet) available for execution.
+func (vm *VM) RegisterActor(code cid.Cid, actor abi.Invokee) {
+	vm.actorImpls[code] = actor
+}
+
+// GetActor returns the actor with the given ID address, if it exists.
+func (vm *VM) GetActor(a addr.Address) (*states.Actor, bool, error) {
+	na, found := vm.NormalizeAddress(a)
+	if !found {
+		return nil, false, nil
+	}
+	var act states.Actor
+	found, err := vm.actors.Get(adt.AddrKey(na), &act)
+	if err != nil {
+		return nil, false, err
+	}
+	return &act, found, nil
+}
+
+// SetActor writes an actor record directly into the state tree, bypassing message execution.
+// This is intended for building test fixtures and genesis states.
+func (vm *VM) SetActor(a addr.Address, act *states.Actor) error {
+	if a.Protocol() != addr.ID {
+		return fmt.Errorf("actor address %v must be an ID address", a)
+	}
+	if err := vm.actors.Put(adt.AddrKey(a), act); err != nil {
+		return errors.Wrapf(err, "failed to store actor %v", a)
+	}
+	vm.actorsDirty = true
+	return nil
+}
+
+// SetActorState stores the given state object and writes an actor record pointing to it, bypassing message execution.
+// Any existing actor at the address is replaced.
+func (vm *VM) SetActorState(a addr.Address, code cid.Cid, balance abi.TokenAmount, state runtime.CBORMarshaler) error {
+	head, err := vm.store.Put(vm.ctx, state)
+	if err != nil {
+		return errors.Wrapf(err, "failed to store state for %v", a)
+	}
+	return vm.SetActor(a, &states.Actor{Code: code, Head: head, CallSeqNum: 0, Balance: balance})
+}
+
+// deleteActor removes an actor from the state tree.
+func (vm *VM) deleteActor(a addr.Address) error {
+	if err := vm.actors.Delete(adt.AddrKey(a)); err != nil {
+		return errors.Wrapf(err, "failed to delete actor %v", a)
+	}
+	vm.actorsDirty = true
+	return nil
+}
+
+// Checkpoint flushes the state tree and returns its root, which becomes the point to which aborted messages roll back.
+func (vm *VM) Checkpoint() (cid.Cid, error) {
+	root, err := vm.actors.Root()
+	if err != nil {
+		return cid.Undef, err
+	}
+	vm.stateRoot = root
+	vm.actorsDirty = false
+	return root, nil
+}
+
+// rollback discards all state changes since the given root was checkpointed.
+func (vm *VM) rollback(root cid.Cid) error {
+	actors, err := adt.AsMap(vm.store, root)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load state tree at %v", root)
+	}
+	vm.actors = actors
+	vm.stateRoot = root
+	vm.actorsDirty = false
+	return nil
+}
+
+// StateRoot returns the root of the state tree, flushing any pending changes.
+func (vm *VM) StateRoot() cid.Cid {
+	root, err := vm.Checkpoint()
+	if err != nil {
+		panic(err)
+	}
+	return root
+}
+
+// GetState loads the state of the actor at an address into the argument.
+func (vm *VM) GetState(a addr.Address, out runtime.CBORUnmarshaler) error {
+	act, found, err := vm.GetActor(a)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("actor %v not found", a)
+	}
+	return vm.store.Get(vm.ctx, act.Head, out)
+}
+
+// NormalizeAddress resolves an address of any protocol to an ID address via the Init actor's address table.
+func (vm *VM) NormalizeAddress(a addr.Address) (addr.Address, bool) {
+	if a.Protocol() == addr.ID {
+		return a, true
+	}
+
+	var initActor states.Actor
+	found, err := vm.actors.Get(adt.AddrKey(builtin.InitActorAddr), &initActor)
+	if err != nil {
+		panic(errors.Wrapf(err, "failed to load init actor"))
+	}
+	if !found {
+		return addr.Undef, false
+	}
+
+	var st init_.State
+	if err := vm.store.Get(vm.ctx, initActor.Head, &st); err != nil {
+		panic(errors.Wrapf(err, "failed to load init actor state"))
+	}
+	idAddr, err := st.ResolveAddress(vm.store, a)
+	if err == init_.ErrAddressNotFound {
+		return addr.Undef, false
+	} else if err != nil {
+		panic(err)
+	}
+	return idAddr, true
+}
+
+// ApplyMessage executes a top-level message from an existing actor.
+// State changes are committed if the message succeeds and rolled back otherwise, apart from the increment of the
+// sender's call sequence number.
+func (vm *VM) ApplyMessage(from, to addr.Address, value abi.TokenAmount, method abi.MethodNum, params runtime.CBORMarshaler) (runtime.CBORMarshaler, exitcode.ExitCode) {
+	fromID, found := vm.NormalizeAddress(from)
+	if !found {
+		return nil, exitcode.SysErrSenderInvalid
+	}
+	fromActor, found, err := vm.GetActor(fromID)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		return nil, exitcode.SysErrSenderInvalid
+	}
+
+	// Checkpoint the state so that an abort anywhere in the message tree can restore it.
+	priorRoot, err := vm.Checkpoint()
+	if err != nil {
+		panic(err)
+	}
+
+	topLevel := topLevelContext{
+		originatorStableAddress: from,
+		originatorCallSeq:       fromActor.CallSeqNum,
+		newActorAddressCount:    0,
+	}
+	ctx := newInvocationContext(vm, &topLevel, InternalMessage{
+		from:   fromID,
+		to:     to,
+		value:  value,
+		method: method,
+		params: params,
+	})
+	ret, code := ctx.invoke()
+	trace := &MessageTrace{
+		Epoch:        vm.currentEpoch,
+		PreStateRoot: priorRoot,
+		Invocation:   ctx.trace,
+	}
+
+	if code != exitcode.Ok {
+		if err := vm.rollback(priorRoot); err != nil {
+			panic(err)
+		}
+	}
+
+	// The sender's call sequence number advances whether or not the message succeeds.
+	fromActor, _, err = vm.GetActor(fromID)
+	if err != nil {
+		panic(err)
+	}
+	fromActor.CallSeqNum++
+	if err := vm.SetActor(fromID, fromActor); err != nil {
+		panic(err)
+	}
+	if trace.PostStateRoot, err = vm.Checkpoint(); err != nil {
+		panic(err)
+	}
+	vm.traces = append(vm.traces, trace)
+	return ret.inner, code
+}
+
+// GetEpoch returns the epoch at which messages are executed.
+func (vm *VM) GetEpoch() abi.ChainEpoch {
+	return vm.currentEpoch
+}
+
+// SetEpoch sets the epoch at which subsequent messages are executed.
+func (vm *VM) SetEpoch(epoch abi.ChainEpoch) {
+	vm.currentEpoch = epoch
+}
+
+// SetCirculatingSupply sets the value reported to actors as the total circulating supply.
+func (vm *VM) SetCirculatingSupply(amt abi.TokenAmount) {
+	vm.circSupply = amt
+}
+
+// Store returns the VM's backing store.
+func (vm *VM) Store() adt.Store {
+	return vm.store
+}
+
+// Logs returns the messages logged by actors since the VM was created.
+func (vm *VM) Logs() []string {
+	return vm.logs
+}
+
+// CheckStateInvariants checks the invariants of every actor in the state tree and those relating different actors.
+func (vm *VM) CheckStateInvariants() (*states.Report, error) {
+	root, err := vm.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := states.LoadTree(vm.store, root)
+	if err != nil {
+		return nil, err
+	}
+	return states.CheckStateInvariants(tree)
+}
+
+// DiffSince describes the changes to the state tree since the given root.
+func (vm *VM) DiffSince(root cid.Cid) (*builtin.DiffAccumulator, error) {
+	post, err := vm.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	preTree, err := states.LoadTree(vm.store, root)
+	if err != nil {
+		return nil, err
+	}
+	postTree, err := states.LoadTree(vm.store, post)
+	if err != nil {
+		return nil, err
+	}
+	return states.DiffTrees(preTree, postTree)
+}
+
+// GetTotalActorBalance returns the sum of the balances of all actors in the state tree.
+func (vm *VM) GetTotalActorBalance() (abi.TokenAmount, error) {
+	total := big.Zero()
+	var act states.Actor
+	err := vm.actors.ForEach(&act, func(_ string) error {
+		total = big.Add(total, act.Balance)
+		return nil
+	})
+	return total, err
+}
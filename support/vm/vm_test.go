@@ -0,0 +1,279 @@
+package vm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abi "github.com/filecoin-project/specs-actors/actors/abi"
+	big "github.com/filecoin-project/specs-actors/actors/abi/big"
+	builtin "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/puppet"
+	runtime "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/filecoin-project/specs-actors/support/ipld"
+	tutil "github.com/filecoin-project/specs-actors/support/testing"
+	vm "github.com/filecoin-project/specs-actors/support/vm"
+)
+
+func TestCreateAccounts(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	balance := abi.NewTokenAmount(1_000)
+	addrs := vm.CreateAccounts(ctx, t, v, 2, balance, 93837778)
+	require.Len(t, addrs, 2)
+
+	for i, a := range addrs {
+		assert.Equal(t, tutil.NewIDAddr(t, uint64(builtin.FirstNonSingletonActorId+i)), a)
+
+		act, found, err := v.GetActor(a)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, builtin.AccountActorCodeID, act.Code)
+		assert.Equal(t, balance, act.Balance)
+
+		var st account.State
+		vm.GetState(t, v, a, &st)
+		assert.Equal(t, tutil.NewBLSAddr(t, 93837778+int64(i)), st.Address)
+
+		idAddr, found := v.NormalizeAddress(st.Address)
+		require.True(t, found)
+		assert.Equal(t, a, idAddr)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("transfer between accounts", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 2, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyOk(t, v, addrs[0], addrs[1], abi.NewTokenAmount(400), builtin.MethodSend, nil)
+		assertBalance(t, v, addrs[0], abi.NewTokenAmount(600))
+		assertBalance(t, v, addrs[1], abi.NewTokenAmount(1_400))
+
+		act, _, err := v.GetActor(addrs[0])
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), act.CallSeqNum)
+	})
+
+	t.Run("insufficient funds rolls back and increments call sequence", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 2, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyCode(t, v, addrs[0], addrs[1], abi.NewTokenAmount(1_001), builtin.MethodSend, nil, exitcode.SysErrInsufficientFunds)
+		assertBalance(t, v, addrs[0], abi.NewTokenAmount(1_000))
+		assertBalance(t, v, addrs[1], abi.NewTokenAmount(1_000))
+
+		act, _, err := v.GetActor(addrs[0])
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), act.CallSeqNum)
+	})
+
+	t.Run("unknown sender", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyCode(t, v, tutil.NewIDAddr(t, 1000), addrs[0], big.Zero(), builtin.MethodSend, nil, exitcode.SysErrSenderInvalid)
+	})
+
+	t.Run("unknown ID receiver", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyCode(t, v, addrs[0], tutil.NewIDAddr(t, 1000), abi.NewTokenAmount(1), builtin.MethodSend, nil, exitcode.SysErrInvalidReceiver)
+		assertBalance(t, v, addrs[0], abi.NewTokenAmount(1_000))
+	})
+}
+
+func TestInvocation(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid method", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyCode(t, v, addrs[0], builtin.StoragePowerActorAddr, big.Zero(), abi.MethodNum(1000), nil, exitcode.SysErrInvalidMethod)
+	})
+
+	t.Run("caller validation failure aborts", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+		vm.ApplyCode(t, v, addrs[0], builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil, exitcode.SysErrForbidden)
+	})
+
+	t.Run("return value", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+		ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.Zero(), builtin.MethodsPower.CurrentTotalPower, nil)
+		totalPower, ok := ret.(*power.CurrentTotalPowerReturn)
+		require.True(t, ok)
+		assert.Equal(t, big.Zero(), totalPower.RawBytePower)
+	})
+}
+
+func TestAbortRollsBackNestedSend(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+	v.RegisterActor(puppet.PuppetActorCodeID, puppet.Actor{})
+	puppetAddr := tutil.NewIDAddr(t, 1000)
+	require.NoError(t, v.SetActorState(puppetAddr, puppet.PuppetActorCodeID, abi.NewTokenAmount(500), &puppet.State{}))
+
+	// The power actor rejects CreateMiner from a non-account caller, after receiving the value.
+	createMinerParams := power.CreateMinerParams{
+		Owner:         addrs[0],
+		Worker:        addrs[0],
+		SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+	}
+	sendParams := puppet.SendParams{
+		To:     builtin.StoragePowerActorAddr,
+		Value:  abi.NewTokenAmount(100),
+		Method: builtin.MethodsPower.CreateMiner,
+		Params: mustSerialize(t, &createMinerParams),
+	}
+	ret := vm.ApplyOk(t, v, addrs[0], puppetAddr, big.Zero(), puppet.MethodsPuppet.Send, &sendParams)
+	sendRet, ok := ret.(*puppet.SendReturn)
+	require.True(t, ok)
+	assert.Equal(t, exitcode.SysErrForbidden, sendRet.Code)
+
+	// The value transfer to the power actor was rolled back, but the puppet's own call succeeded.
+	assertBalance(t, v, puppetAddr, abi.NewTokenAmount(500))
+	assertBalance(t, v, builtin.StoragePowerActorAddr, big.Zero())
+}
+
+func TestTraces(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nested sends", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+		preRoot := v.StateRoot()
+
+		params := power.CreateMinerParams{
+			Owner:         addrs[0],
+			Worker:        addrs[0],
+			SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+			Peer:          abi.PeerID("peer"),
+		}
+		ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.Zero(), builtin.MethodsPower.CreateMiner, &params)
+		minerAddrs := ret.(*power.CreateMinerReturn)
+
+		trace := v.LastTrace()
+		require.NotNil(t, trace)
+		assert.Equal(t, preRoot, trace.PreStateRoot)
+		assert.Equal(t, v.StateRoot(), trace.PostStateRoot)
+
+		top := trace.Invocation
+		assert.Equal(t, addrs[0], top.From)
+		assert.Equal(t, builtin.StoragePowerActorAddr, top.Receiver)
+		assert.Equal(t, "CreateMiner", top.MethodName)
+		assert.Equal(t, exitcode.Ok, top.Code)
+		assert.NotEqual(t, top.StateBefore, top.StateAfter)
+		assert.Equal(t, mustSerialize(t, &params), top.Params)
+		assert.Equal(t, mustSerialize(t, minerAddrs), top.Return)
+
+		var methods []string
+		top.Walk(func(inv *vm.Invocation) {
+			methods = append(methods, inv.MethodName)
+		})
+		assert.Equal(t, []string{"CreateMiner", "Exec", "Constructor", "PubkeyAddress", "EnrollCronEvent"}, methods)
+
+		exec := top.Subcalls[0]
+		assert.Equal(t, builtin.InitActorAddr, exec.Receiver)
+		ctor := exec.Subcalls[0]
+		assert.Equal(t, minerAddrs.IDAddress, ctor.Receiver)
+		assert.Equal(t, builtin.InitActorAddr, ctor.From)
+		assert.False(t, ctor.StateAfter.Equals(ctor.StateBefore))
+	})
+
+	t.Run("aborted send and logs", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		addrs := vm.CreateAccounts(ctx, t, v, 1, big.Mul(big.NewInt(10_000), big.NewInt(1e18)), 93837778)
+		params := power.CreateMinerParams{
+			Owner:         addrs[0],
+			Worker:        addrs[0],
+			SealProofType: abi.RegisteredSealProof_StackedDrg32GiBV1,
+		}
+		ret := vm.ApplyOk(t, v, addrs[0], builtin.StoragePowerActorAddr, big.Zero(), builtin.MethodsPower.CreateMiner, &params)
+		minerAddr := ret.(*power.CreateMinerReturn).IDAddress
+
+		// Drain the reward actor so that the block reward can't be paid in full.
+		rewardActor, _, err := v.GetActor(builtin.RewardActorAddr)
+		require.NoError(t, err)
+		rewardActor.Balance = big.Zero()
+		require.NoError(t, v.SetActor(builtin.RewardActorAddr, rewardActor))
+
+		awardParams := reward.AwardBlockRewardParams{Miner: minerAddr, Penalty: big.Zero(), GasReward: big.Zero(), WinCount: 1}
+		vm.ApplyOk(t, v, builtin.SystemActorAddr, builtin.RewardActorAddr, big.Zero(), builtin.MethodsReward.AwardBlockReward, &awardParams)
+		award := v.LastTrace().Invocation
+		require.Len(t, award.Logs, 1)
+		assert.Contains(t, award.Logs[0], "reward actor balance 0 below totalReward")
+		require.Len(t, award.Subcalls, 1)
+		assert.Equal(t, "AddLockedFund", award.Subcalls[0].MethodName)
+
+		// A message that aborts records the abort and leaves the state root unchanged apart from the call sequence.
+		vm.ApplyCode(t, v, addrs[0], builtin.CronActorAddr, big.Zero(), builtin.MethodsCron.EpochTick, nil, exitcode.SysErrForbidden)
+		tick := v.LastTrace().Invocation
+		assert.Equal(t, exitcode.SysErrForbidden, tick.Code)
+		assert.Contains(t, tick.Error, "caller "+addrs[0].String()+" is not one of")
+		assert.Contains(t, v.LastTrace().String(), "EpochTick value 0: SysErrForbidden")
+	})
+
+	t.Run("JSON round trip", func(t *testing.T) {
+		v := vm.NewVMWithSingletons(ctx, t)
+		vm.CreateAccounts(ctx, t, v, 2, abi.NewTokenAmount(1_000), 93837778)
+
+		var buf bytes.Buffer
+		require.NoError(t, v.WriteTracesJSON(&buf))
+		traces, err := vm.ReadTracesJSON(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, v.Traces(), traces)
+
+		// Sending to a new public key address creates the account, recorded as a nested constructor call.
+		create := traces[len(traces)-1].Invocation
+		assert.Equal(t, builtin.RewardActorAddr, create.From)
+		require.Len(t, create.Subcalls, 1)
+		assert.Equal(t, builtin.SystemActorAddr, create.Subcalls[0].From)
+		assert.Equal(t, "Constructor", create.Subcalls[0].MethodName)
+	})
+}
+
+func TestStateRootRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	v := vm.NewVMWithSingletons(ctx, t)
+	addrs := vm.CreateAccounts(ctx, t, v, 1, abi.NewTokenAmount(1_000), 93837778)
+
+	v2, err := vm.NewVMAtRoot(ctx, v.Store(), v.StateRoot())
+	require.NoError(t, err)
+	assertBalance(t, v2, addrs[0], abi.NewTokenAmount(1_000))
+	assert.Equal(t, v.StateRoot(), v2.StateRoot())
+
+	// a VM over a fresh store cannot load the tree
+	_, err = vm.NewVMAtRoot(ctx, ipld.NewADTStore(ctx), v.StateRoot())
+	assert.Error(t, err)
+}
+
+func assertBalance(t *testing.T, v *vm.VM, a addr.Address, expected abi.TokenAmount) {
+	act, found, err := v.GetActor(a)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, expected, act.Balance)
+}
+
+func mustSerialize(t *testing.T, o runtime.CBORMarshaler) []byte {
+	buf := bytes.Buffer{}
+	require.NoError(t, o.MarshalCBOR(&buf))
+	return buf.Bytes()
+}